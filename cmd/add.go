@@ -3,8 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
 )
 
 var addCmd = &cobra.Command{
@@ -18,24 +24,41 @@ var addCmd = &cobra.Command{
 
 		batchFile, _ := cmd.Flags().GetString("batch")
 		output, _ := cmd.Flags().GetString("output")
+		onComplete, _ := cmd.Flags().GetString("on-complete")
+		onError, _ := cmd.Flags().GetString("on-error")
+		onPause, _ := cmd.Flags().GetString("on-pause")
+		conflict, _ := cmd.Flags().GetString("conflict")
+		priority, _ := cmd.Flags().GetInt("priority")
+		at, _ := cmd.Flags().GetString("at")
+		after, _ := cmd.Flags().GetString("after")
+		group, _ := cmd.Flags().GetString("group")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		note, _ := cmd.Flags().GetString("note")
+		referrer, _ := cmd.Flags().GetString("referrer")
 
-		// Collect URLs
-		var urls []string
+		if conflict != "" && !config.IsValidConflictPolicy(conflict) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --conflict %q (want %s)\n", conflict, strings.Join(config.ConflictPolicies, ", "))
+			os.Exit(1)
+		}
 
-		// 1. URLs from args
-		urls = append(urls, args...)
+		startAt, err := utils.ParseStartTime(at, after, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
-		// 2. URLs from batch file
+		// Collect URLs: args, then the batch file (a group of its own unless --group is set)
+		urls := args
+		var batchURLs []string
 		if batchFile != "" {
-			fileUrls, err := readURLsFromFile(batchFile)
+			batchURLs, err = readURLsFromFile(batchFile)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
 				os.Exit(1)
 			}
-			urls = append(urls, fileUrls...)
 		}
 
-		if len(urls) == 0 {
+		if len(urls) == 0 && len(batchURLs) == 0 {
 			_ = cmd.Help()
 			return
 		}
@@ -49,7 +72,27 @@ var addCmd = &cobra.Command{
 		}
 
 		// Send downloads to server
-		count := processDownloads(urls, output, port)
+		opts := core.DownloadOptions{
+			OnComplete: onComplete,
+			OnError:    onError,
+			OnPause:    onPause,
+			Conflict:   conflict,
+			Priority:   priority,
+			StartAt:    startAt,
+			Group:      group,
+			DownloadMeta: types.DownloadMeta{
+				Tags:     types.NormalizeTags(tags),
+				Note:     note,
+				Referrer: referrer,
+			},
+		}
+		count := processDownloads(urls, output, port, opts)
+		if len(batchURLs) > 0 {
+			if opts.Group == "" {
+				opts.Group = utils.BatchGroupName(batchFile)
+			}
+			count += processDownloads(batchURLs, output, port, opts)
+		}
 
 		if count > 0 {
 			fmt.Printf("Successfully added %d downloads.\n", count)
@@ -61,4 +104,15 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 	addCmd.Flags().StringP("batch", "b", "", "File containing URLs to download (one per line)")
 	addCmd.Flags().StringP("output", "o", "", "Output directory")
+	addCmd.Flags().String("on-complete", "", "Shell command to run when the download completes (overrides settings)")
+	addCmd.Flags().String("on-error", "", "Shell command to run when the download fails (overrides settings)")
+	addCmd.Flags().String("on-pause", "", "Shell command to run when the download is paused (overrides settings)")
+	addCmd.Flags().String("conflict", "", "What to do if the file exists: rename, overwrite, skip or fail (overrides settings)")
+	addCmd.Flags().String("at", "", "Start at this local time, e.g. \"2026-10-17 02:00\" or \"02:00\"")
+	addCmd.Flags().String("after", "", "Start after this delay, e.g. 2h, 90m or 1d")
+	addCmd.Flags().String("group", "", "Add the downloads to this group (defaults to the batch file name for --batch)")
+	addCmd.Flags().StringSlice("tag", nil, "Tag the downloads (repeatable or comma-separated)")
+	addCmd.Flags().String("note", "", "Free-text note to keep with the downloads")
+	addCmd.Flags().String("referrer", "", "Page the downloads came from")
+	addCmd.Flags().Int("priority", 0, "Queue priority; higher values start before lower ones")
 }
@@ -0,0 +1,564 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// openAPISpec describes the versioned API. apiRoutes and the spec are
+// checked against each other by the tests.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// apiServer serves the versioned HTTP API under core.APIPrefix
+type apiServer struct {
+	service          core.DownloadService
+	defaultOutputDir string
+	port             int
+	mux              *http.ServeMux
+}
+
+// apiRoute is one endpoint of the versioned API
+type apiRoute struct {
+	method  string
+	path    string // Relative to core.APIPrefix, with {wildcards}
+	public  bool   // Served without a token
+	handler func(a *apiServer, w http.ResponseWriter, r *http.Request)
+}
+
+// apiRoutes lists every endpoint of the versioned API
+var apiRoutes = []apiRoute{
+	{http.MethodGet, "/health", true, (*apiServer).health},
+	{http.MethodGet, "/openapi.json", true, (*apiServer).openAPI},
+	{http.MethodGet, "/events", false, (*apiServer).events},
+	{http.MethodGet, core.WebSocketPath, false, (*apiServer).webSocket},
+	{http.MethodPost, core.WebSocketTicketPath, false, (*apiServer).webSocketTicket},
+
+	{http.MethodGet, "/downloads", false, (*apiServer).listDownloads},
+	{http.MethodPost, "/downloads", false, (*apiServer).addDownload},
+	{http.MethodGet, "/downloads/{id}", false, (*apiServer).getDownload},
+	{http.MethodDelete, "/downloads/{id}", false, (*apiServer).deleteDownload},
+	{http.MethodPost, "/downloads/{id}/pause", false, (*apiServer).pauseDownload},
+	{http.MethodPost, "/downloads/{id}/resume", false, (*apiServer).resumeDownload},
+	{http.MethodPost, "/downloads/{id}/move", false, (*apiServer).moveDownload},
+	{http.MethodPost, "/downloads/{id}/relink", false, (*apiServer).relinkDownload},
+	{http.MethodPost, "/downloads/{id}/refresh", false, (*apiServer).refreshDownload},
+	{http.MethodPost, "/downloads/{id}/reorder", false, (*apiServer).reorderDownload},
+
+	{http.MethodGet, "/history", false, (*apiServer).history},
+
+	{http.MethodGet, "/groups", false, (*apiServer).groups},
+	{http.MethodPost, "/groups/{name}/{action}", false, (*apiServer).groupAction},
+
+	{http.MethodGet, "/concurrency", false, (*apiServer).concurrency},
+	{http.MethodPut, "/concurrency", false, (*apiServer).setConcurrency},
+
+	{http.MethodGet, "/settings", false, (*apiServer).settings},
+	{http.MethodPost, "/settings/reload", false, (*apiServer).reloadSettings},
+	{http.MethodGet, "/settings/{key}", false, (*apiServer).setting},
+	{http.MethodPut, "/settings/{key}", false, (*apiServer).setting},
+	{http.MethodDelete, "/settings/{key}", false, (*apiServer).setting},
+}
+
+func newAPIServer(service core.DownloadService, defaultOutputDir string, port int) *apiServer {
+	a := &apiServer{
+		service:          service,
+		defaultOutputDir: defaultOutputDir,
+		port:             port,
+		mux:              http.NewServeMux(),
+	}
+	for _, route := range apiRoutes {
+		handler := route.handler
+		a.mux.HandleFunc(route.method+" "+core.APIPrefix+route.path, func(w http.ResponseWriter, r *http.Request) {
+			handler(a, w, r)
+		})
+	}
+	return a
+}
+
+// isWebSocketPath reports whether path is one of the WebSocket endpoints
+func isWebSocketPath(path string) bool {
+	return path == core.WebSocketPath || path == core.APIPrefix+core.WebSocketPath
+}
+
+// isPublicAPIPath reports whether path is an API endpoint served without a token
+func isPublicAPIPath(path string) bool {
+	for _, route := range apiRoutes {
+		if route.public && path == core.APIPrefix+route.path {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP routes a request, answering unknown paths and methods with JSON
+// errors instead of the mux's plain text
+func (a *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, pattern := a.mux.Handler(r)
+	if pattern != "" {
+		a.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// Let the mux decide between 404 and 405 (and list the allowed methods)
+	probe := &statusRecorder{header: make(http.Header)}
+	h.ServeHTTP(probe, r)
+	if probe.status == http.StatusMethodNotAllowed {
+		w.Header().Set("Allow", probe.header.Get("Allow"))
+		writeAPIError(w, &core.APIError{Status: http.StatusMethodNotAllowed, Code: core.CodeMethodNotAllowed, Message: "Method not allowed"})
+		return
+	}
+	writeAPIError(w, &core.APIError{Status: http.StatusNotFound, Code: core.CodeNotFound, Message: "No such endpoint: " + r.URL.Path})
+}
+
+// statusRecorder keeps the status and headers of a response and drops its body
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header         { return s.header }
+func (s *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (s *statusRecorder) WriteHeader(status int)      { s.status = status }
+
+// writeAPIJSON sends v as a JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
+	}
+}
+
+// writeAPIError sends err as {"error": {"code": ..., "message": ...}}
+func writeAPIError(w http.ResponseWriter, err *core.APIError) {
+	writeAPIJSON(w, err.Status, map[string]*core.APIError{"error": err})
+}
+
+// writeServiceError sends an error returned by the download service
+func writeServiceError(w http.ResponseWriter, err error) {
+	writeAPIError(w, core.NewAPIError(err))
+}
+
+func badRequest(msg string) *core.APIError {
+	return &core.APIError{Status: http.StatusBadRequest, Code: core.CodeBadRequest, Message: msg}
+}
+
+func internalError(msg string) *core.APIError {
+	return &core.APIError{Status: http.StatusInternalServerError, Code: core.CodeInternal, Message: msg}
+}
+
+// decodeAPIBody reads a JSON request body into v
+func decodeAPIBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeAPIError(w, badRequest("Invalid JSON: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+// parsePage reads the limit and offset query parameters. A limit of 0 (the
+// default) returns everything from offset on.
+func parsePage(r *http.Request) (limit, offset int, apiErr *core.APIError) {
+	q := r.URL.Query()
+	for key, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, badRequest(fmt.Sprintf("Invalid %s: %q", key, v))
+		}
+		*dst = n
+	}
+	return limit, offset, nil
+}
+
+// paginate cuts one page out of items
+func paginate[T any](items []T, limit, offset int) core.Page[T] {
+	page := core.Page[T]{Total: len(items), Limit: limit, Offset: offset}
+	if offset < len(items) {
+		items = items[offset:]
+		if limit > 0 && limit < len(items) {
+			items = items[:limit]
+		}
+		page.Items = items
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// statusResponse acknowledges an action on a download
+func statusResponse(status, id string) map[string]string {
+	return map[string]string{"status": status, "id": id}
+}
+
+func (a *apiServer) health(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, map[string]any{"status": "ok", "port": a.port})
+}
+
+func (a *apiServer) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpec)
+}
+
+func (a *apiServer) events(w http.ResponseWriter, r *http.Request) {
+	handleEvents(w, r, a.service)
+}
+
+func (a *apiServer) webSocket(w http.ResponseWriter, r *http.Request) {
+	handleWebSocket(w, r, a.defaultOutputDir, a.service)
+}
+
+func (a *apiServer) webSocketTicket(w http.ResponseWriter, r *http.Request) {
+	handleWSTicket(w, r)
+}
+
+// listDownloads returns active, waiting and finished downloads, optionally
+// filtered by ?status= and ?tag= (both repeatable), one page at a time
+func (a *apiServer) listDownloads(w http.ResponseWriter, r *http.Request) {
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		writeAPIError(w, apiErr)
+		return
+	}
+
+	statuses, err := a.service.List()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	wantStatus := r.URL.Query()["status"]
+	tags := types.NormalizeTags(r.URL.Query()["tag"])
+	filtered := statuses[:0]
+	for _, s := range statuses {
+		if len(wantStatus) > 0 && !slices.Contains(wantStatus, s.Status) {
+			continue
+		}
+		if len(tags) > 0 && !s.HasTags(tags) {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+
+	writeAPIJSON(w, http.StatusOK, paginate(filtered, limit, offset))
+}
+
+func (a *apiServer) addDownload(w http.ResponseWriter, r *http.Request) {
+	var req DownloadRequest
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+
+	resp, apiErr := submitDownload(&req, a.defaultOutputDir, a.service)
+	if apiErr != nil {
+		writeAPIError(w, apiErr)
+		return
+	}
+	status := http.StatusCreated
+	if resp.Status == "pending_approval" {
+		status = http.StatusAccepted
+	}
+	writeAPIJSON(w, status, resp)
+}
+
+func (a *apiServer) getDownload(w http.ResponseWriter, r *http.Request) {
+	status, err := a.service.GetStatus(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, status)
+}
+
+func (a *apiServer) deleteDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := a.service.Delete(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, statusResponse("deleted", id))
+}
+
+func (a *apiServer) pauseDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := a.service.Pause(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, statusResponse("paused", id))
+}
+
+func (a *apiServer) resumeDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := a.service.Resume(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, statusResponse("resumed", id))
+}
+
+func (a *apiServer) moveDownload(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path string `json:"path"`
+	}
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	if req.Path == "" {
+		writeAPIError(w, badRequest("Path is required"))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := a.service.Move(id, req.Path); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := statusResponse("moved", id)
+	resp["path"] = req.Path
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+func (a *apiServer) relinkDownload(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Force   bool              `json:"force"`
+	}
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	if req.URL == "" {
+		writeAPIError(w, badRequest("URL is required"))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := a.service.Relink(id, req.URL, req.Headers, req.Force); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := statusResponse("relinked", id)
+	resp["url"] = req.URL
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+// refreshDownload answers a refresh_request event with a fresh URL
+func (a *apiServer) refreshDownload(w http.ResponseWriter, r *http.Request) {
+	var req types.RefreshResult
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	if req.URL == "" {
+		writeAPIError(w, badRequest("URL is required"))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := a.service.RefreshURL(id, req.URL, req.Headers); err != nil {
+		writeAPIError(w, &core.APIError{Status: http.StatusConflict, Code: core.CodeConflict, Message: err.Error()})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, statusResponse("refreshed", id))
+}
+
+// reorderDownload moves a waiting download within the queue
+func (a *apiServer) reorderDownload(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		To string `json:"to"`
+	}
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	if !types.IsValidQueueMove(req.To) {
+		writeAPIError(w, badRequest("Invalid to (want top, up, down or bottom)"))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := a.service.ReorderQueue(id, req.To); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := statusResponse("moved", id)
+	resp["to"] = req.To
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+// history returns finished downloads matching the query filters (see
+// types.ParseHistoryFilter), one page at a time
+func (a *apiServer) history(w http.ResponseWriter, r *http.Request) {
+	filter, err := types.ParseHistoryFilter(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, badRequest(err.Error()))
+		return
+	}
+	entries, total, err := a.service.QueryHistory(filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []types.DownloadEntry{}
+	}
+	writeAPIJSON(w, http.StatusOK, core.Page[types.DownloadEntry]{
+		Items:  entries,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	})
+}
+
+func (a *apiServer) groups(w http.ResponseWriter, r *http.Request) {
+	limit, offset, apiErr := parsePage(r)
+	if apiErr != nil {
+		writeAPIError(w, apiErr)
+		return
+	}
+	groups, err := a.service.Groups()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, paginate(groups, limit, offset))
+}
+
+func (a *apiServer) groupAction(w http.ResponseWriter, r *http.Request) {
+	name, action := r.PathValue("name"), r.PathValue("action")
+	if !types.IsValidGroupAction(action) {
+		writeAPIError(w, badRequest("Invalid group action (want pause, resume, delete or retry)"))
+		return
+	}
+	if err := a.service.GroupAction(name, action); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]string{"status": action, "group": name})
+}
+
+// concurrencyResponse reports the download limit
+type concurrencyResponse struct {
+	MaxConcurrentDownloads int `json:"max_concurrent_downloads"`
+}
+
+func (a *apiServer) concurrency(w http.ResponseWriter, r *http.Request) {
+	n, err := a.service.MaxConcurrent()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, concurrencyResponse{MaxConcurrentDownloads: n})
+}
+
+func (a *apiServer) setConcurrency(w http.ResponseWriter, r *http.Request) {
+	var req concurrencyResponse
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	n := req.MaxConcurrentDownloads
+	if n < config.MinConcurrentDownloads || n > config.MaxConcurrentDownloads {
+		writeAPIError(w, badRequest(fmt.Sprintf("max_concurrent_downloads must be between %d and %d", config.MinConcurrentDownloads, config.MaxConcurrentDownloads)))
+		return
+	}
+	if err := a.service.SetMaxConcurrent(n); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	a.concurrency(w, r)
+}
+
+func (a *apiServer) settings(w http.ResponseWriter, r *http.Request) {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		writeAPIError(w, internalError("Failed to load settings: "+err.Error()))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, settings.Redacted())
+}
+
+func (a *apiServer) reloadSettings(w http.ResponseWriter, r *http.Request) {
+	if err := a.service.ReloadSettings(); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
+// setting reads (GET), changes (PUT with {"value": ...}) or resets (DELETE)
+// one setting
+func (a *apiServer) setting(w http.ResponseWriter, r *http.Request) {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		writeAPIError(w, internalError("Failed to load settings: "+err.Error()))
+		return
+	}
+	key := r.PathValue("key")
+	if _, err := settings.GetValue(key); err != nil {
+		writeAPIError(w, &core.APIError{Status: http.StatusNotFound, Code: core.CodeNotFound, Message: err.Error()})
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		if apiErr := refuseLocalOnlySetting(key); apiErr != nil {
+			writeAPIError(w, apiErr)
+			return
+		}
+		var value string
+		method := http.MethodDelete
+		if r.Method == http.MethodPut {
+			var req struct {
+				Value json.RawMessage `json:"value"`
+			}
+			if !decodeAPIBody(w, r, &req) {
+				return
+			}
+			value = settingValueString(req.Value)
+			method = http.MethodPost
+		}
+		if err := applySettingChange(settings, method, key, value); err != nil {
+			writeAPIError(w, badRequest(err.Error()))
+			return
+		}
+		if err := config.SaveSettings(settings); err != nil {
+			writeAPIError(w, internalError("Failed to save settings: "+err.Error()))
+			return
+		}
+		if err := a.service.ReloadSettings(); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+
+	value, err := settings.GetValue(key)
+	if err != nil {
+		writeAPIError(w, internalError(err.Error()))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, settingResponse{Key: key, Value: value})
+}
+
+// settingValueString accepts a setting value as a JSON string, number or
+// boolean and returns it in the form `surge config set` takes
+func settingValueString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,400 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+
+	"github.com/coder/websocket"
+)
+
+const testAPIToken = "test-token"
+
+// newTestAPI serves the versioned API behind the token check, with three
+// paused and two finished downloads in a fresh database. wrap, if given,
+// sees every request first.
+func newTestAPI(t *testing.T, wrap ...func(http.Handler) http.Handler) (*httptest.Server, *core.LocalDownloadService) {
+	t.Helper()
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	for i := range 3 {
+		id := fmt.Sprintf("paused-%d", i)
+		url := "https://example.com/" + id + ".bin"
+		if err := state.SaveState(url, filepath.Join(tempDir, id+".bin"), &types.DownloadState{
+			ID: id, URL: url, Filename: id + ".bin", TotalSize: 1000, Downloaded: 100,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range 2 {
+		id := fmt.Sprintf("done-%d", i)
+		if err := state.AddToMasterList(types.DownloadEntry{
+			ID: id, URL: "https://example.com/" + id + ".bin", Filename: id + ".bin",
+			Status: "completed", TotalSize: 1000, Downloaded: 1000, CompletedAt: int64(1700000000 + i),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ch := make(chan interface{}, 100)
+	svc := core.NewLocalDownloadServiceWithInput(download.NewWorkerPool(ch, 1), ch)
+	t.Cleanup(func() { _ = svc.Shutdown() })
+
+	var handler http.Handler = authMiddleware(testAPIToken, newAPIServer(svc, tempDir, 0))
+	for _, w := range wrap {
+		handler = w(handler)
+	}
+	srv := testutil.NewHTTPServerT(t, handler)
+	t.Cleanup(srv.Close)
+	return srv, svc
+}
+
+// apiRequest sends an authorized request and decodes the JSON response
+func apiRequest(t *testing.T, srv *httptest.Server, method, path, body string, out any) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+core.APIPrefix+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+type apiErrorBody struct {
+	Error core.APIError `json:"error"`
+}
+
+func TestAPIRoutes_MatchOpenAPISpec(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	documented := map[string]bool{}
+	for path, methods := range spec.Paths {
+		for method := range methods {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for _, route := range apiRoutes {
+		key := route.method + " " + route.path
+		if !documented[key] {
+			t.Errorf("%s is served but not in openapi.json", key)
+		}
+		delete(documented, key)
+	}
+	for key := range documented {
+		t.Errorf("%s is in openapi.json but not served", key)
+	}
+}
+
+func TestAPI_Errors(t *testing.T) {
+	srv, _ := newTestAPI(t)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodGet, "/downloads/missing", "", http.StatusNotFound, core.CodeNotFound},
+		{http.MethodPost, "/downloads/missing/pause", "", http.StatusNotFound, core.CodeNotFound},
+		{http.MethodGet, "/nowhere", "", http.StatusNotFound, core.CodeNotFound},
+		{http.MethodPatch, "/downloads", "", http.StatusMethodNotAllowed, core.CodeMethodNotAllowed},
+		{http.MethodPost, "/downloads", "not json", http.StatusBadRequest, core.CodeBadRequest},
+		{http.MethodGet, "/downloads?limit=-1", "", http.StatusBadRequest, core.CodeBadRequest},
+		{http.MethodPost, "/downloads/paused-0/reorder", `{"to":"top"}`, http.StatusConflict, core.CodeNotQueued},
+		{http.MethodPost, "/downloads/done-0/resume", "", http.StatusConflict, core.CodeCompleted},
+		{http.MethodPost, "/downloads/done-0/relink", `{"url":"https://example.com/new.bin"}`, http.StatusConflict, core.CodeCompleted},
+		{http.MethodPost, "/groups/none/pause", "", http.StatusNotFound, core.CodeNoGroup},
+		{http.MethodPut, "/concurrency", `{"max_concurrent_downloads":0}`, http.StatusBadRequest, core.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		var body apiErrorBody
+		resp := apiRequest(t, srv, tt.method, tt.path, tt.body, &body)
+		if resp.StatusCode != tt.status || body.Error.Code != tt.code || body.Error.Message == "" {
+			t.Errorf("%s %s = %d %+v, want %d %s", tt.method, tt.path, resp.StatusCode, body.Error, tt.status, tt.code)
+		}
+	}
+
+	resp := apiRequest(t, srv, http.MethodPatch, "/downloads", "", nil)
+	if allow := resp.Header.Get("Allow"); !strings.Contains(allow, "GET") || !strings.Contains(allow, "POST") {
+		t.Errorf("Allow = %q, want GET and POST", allow)
+	}
+}
+
+func TestAPI_Auth(t *testing.T) {
+	srv, _ := newTestAPI(t)
+
+	resp, err := srv.Client().Get(srv.URL + core.APIPrefix + "/downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body apiErrorBody
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || body.Error.Code != core.CodeUnauthorized {
+		t.Errorf("without token: %d %+v, want 401 %s", resp.StatusCode, body.Error, core.CodeUnauthorized)
+	}
+
+	for _, path := range []string{"/health", "/openapi.json"} {
+		resp, err := srv.Client().Get(srv.URL + core.APIPrefix + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s without token = %d, want 200", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestAPI_Pagination(t *testing.T) {
+	srv, _ := newTestAPI(t)
+
+	var all core.Page[types.DownloadStatus]
+	apiRequest(t, srv, http.MethodGet, "/downloads?status=paused", "", &all)
+	if all.Total != 3 || len(all.Items) != 3 {
+		t.Fatalf("paused downloads = %d of %d, want 3 of 3", len(all.Items), all.Total)
+	}
+
+	var page core.Page[types.DownloadStatus]
+	apiRequest(t, srv, http.MethodGet, "/downloads?status=paused&limit=2&offset=2", "", &page)
+	if page.Total != 3 || page.Limit != 2 || page.Offset != 2 || len(page.Items) != 1 || page.Items[0].ID != all.Items[2].ID {
+		t.Errorf("page = %+v, want the third paused download", page)
+	}
+
+	var past core.Page[types.DownloadStatus]
+	apiRequest(t, srv, http.MethodGet, "/downloads?offset=10", "", &past)
+	if past.Items == nil || len(past.Items) != 0 {
+		t.Errorf("items past the end = %v, want an empty list", past.Items)
+	}
+
+	var history core.Page[types.DownloadEntry]
+	apiRequest(t, srv, http.MethodGet, "/history?limit=1", "", &history)
+	if history.Total != 2 || len(history.Items) != 1 || history.Items[0].ID != "done-1" {
+		t.Errorf("history = %+v, want the newest of 2", history)
+	}
+}
+
+func TestRemoteDownloadService_API(t *testing.T) {
+	srv, _ := newTestAPI(t)
+	remote := core.NewRemoteDownloadService(srv.URL, testAPIToken)
+	defer func() { _ = remote.Shutdown() }()
+
+	statuses, err := remote.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	ids := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		ids = append(ids, s.ID)
+	}
+	if !slices.Contains(ids, "paused-1") || !slices.Contains(ids, "done-0") {
+		t.Errorf("List = %v", ids)
+	}
+
+	status, err := remote.GetStatus("paused-1")
+	if err != nil || status.ID != "paused-1" || status.TotalSize != 1000 {
+		t.Errorf("GetStatus = %+v, %v", status, err)
+	}
+
+	entries, total, err := remote.QueryHistory(types.HistoryFilter{Limit: 1})
+	if err != nil || total != 2 || len(entries) != 1 {
+		t.Errorf("QueryHistory = %d of %d, %v; want 1 of 2", len(entries), total, err)
+	}
+
+	// Engine errors survive the round trip
+	if _, err := remote.GetStatus("missing"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("GetStatus(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := remote.ReorderQueue("paused-0", "top"); !errors.Is(err, types.ErrNotQueued) {
+		t.Errorf("ReorderQueue error = %v, want ErrNotQueued", err)
+	}
+	var apiErr *core.APIError
+	if err := remote.Pause("missing"); !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("Pause(missing) error = %v, want a 404 APIError", err)
+	}
+
+	if err := remote.Delete("paused-2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := remote.GetStatus("paused-2"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("deleted download: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAPI_RefusesHookCommands(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	settings := config.DefaultSettings()
+	settings.Hooks.AllowedCommands = []string{"true"}
+	if err := config.SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	srv, svc := newTestAPI(t)
+	marker := filepath.Join(t.TempDir(), "pwned")
+	add := func(hook string) string {
+		return fmt.Sprintf(`{"url":"https://example.com/hooked.bin","path":%q,"skip_approval":true,"start_at":"2099-01-01T00:00:00Z","on_complete":%q}`,
+			t.TempDir(), hook)
+	}
+
+	var body apiErrorBody
+	resp := apiRequest(t, srv, http.MethodPost, "/downloads", add("touch "+marker), &body)
+	if resp.StatusCode != http.StatusForbidden || body.Error.Code != core.CodeHookNotAllowed {
+		t.Errorf("unlisted hook = %d %+v, want 403 %s", resp.StatusCode, body.Error, core.CodeHookNotAllowed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, srv.URL+core.APIPrefix+core.WebSocketPath+"?ticket="+wsTicket(t, srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.CloseNow() }()
+	reply := wsReply(t, ctx, conn, core.WSCommand{ID: "1", Op: core.WSOpAdd, Download: json.RawMessage(add("touch " + marker))})
+	if reply.Error == nil || reply.Error.Code != core.CodeHookNotAllowed {
+		t.Errorf("unlisted hook over WebSocket = %+v, want %s", reply, core.CodeHookNotAllowed)
+	}
+
+	statuses, err := svc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.URL == "https://example.com/hooked.bin" {
+			t.Errorf("refused download was queued: %+v", s)
+		}
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("hook command ran: %v", err)
+	}
+
+	var added downloadResponse
+	resp = apiRequest(t, srv, http.MethodPost, "/downloads", add(" true "), &added)
+	if resp.StatusCode != http.StatusCreated || added.ID == "" {
+		t.Errorf("allow-listed hook = %d %+v, want 201", resp.StatusCode, added)
+	}
+}
+
+func TestAPI_Settings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	srv, svc := newTestAPI(t)
+
+	maxDownloads := func() int {
+		n, err := svc.MaxConcurrent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// Set, with the change saved and applied
+	var resp settingResponse
+	r := apiRequest(t, srv, http.MethodPut, "/settings/max_concurrent_downloads", `{"value":"5"}`, &resp)
+	if r.StatusCode != http.StatusOK || resp.Value != "5" {
+		t.Fatalf("PUT = %d %+v, want 200 with value 5", r.StatusCode, resp)
+	}
+	if saved, _ := config.LoadSettings(); saved.Connections.MaxConcurrentDownloads != 5 {
+		t.Errorf("saved max_concurrent_downloads = %d", saved.Connections.MaxConcurrentDownloads)
+	}
+	if n := maxDownloads(); n != 5 {
+		t.Errorf("pool not resized, max = %d", n)
+	}
+
+	// Invalid values are rejected with the validation message
+	var body apiErrorBody
+	r = apiRequest(t, srv, http.MethodPut, "/settings/speed_ema_alpha", `{"value":"2"}`, &body)
+	if r.StatusCode != http.StatusBadRequest || !strings.Contains(body.Error.Message, "between 0 and 1") {
+		t.Errorf("invalid value = %d %+v", r.StatusCode, body.Error)
+	}
+
+	// Get one and all
+	resp = settingResponse{}
+	apiRequest(t, srv, http.MethodGet, "/settings/max_concurrent_downloads", "", &resp)
+	if resp.Value != "5" {
+		t.Errorf("GET key = %+v", resp)
+	}
+	all := config.DefaultSettings()
+	apiRequest(t, srv, http.MethodGet, "/settings", "", all)
+	if all.Connections.MaxConcurrentDownloads != 5 {
+		t.Errorf("GET all: max = %d", all.Connections.MaxConcurrentDownloads)
+	}
+
+	// Reset
+	r = apiRequest(t, srv, http.MethodDelete, "/settings/max_concurrent_downloads", "", nil)
+	if r.StatusCode != http.StatusOK || maxDownloads() != config.DefaultSettings().Connections.MaxConcurrentDownloads {
+		t.Errorf("DELETE: status %d, pool max %d", r.StatusCode, maxDownloads())
+	}
+
+	if r = apiRequest(t, srv, http.MethodGet, "/settings/nope", "", nil); r.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown key: status %d", r.StatusCode)
+	}
+}
+
+func TestAPI_SettingsRefuseCommands(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := newTestAPI(t)
+
+	for _, key := range []string{"on_complete", "on_error", "on_pause", "url_refresh_command"} {
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			var body apiErrorBody
+			resp := apiRequest(t, srv, method, "/settings/"+key, `{"value":"touch /tmp/pwned"}`, &body)
+			if resp.StatusCode != http.StatusForbidden || body.Error.Code != core.CodeLocalOnly {
+				t.Errorf("%s /settings/%s = %d %+v, want 403 %s", method, key, resp.StatusCode, body.Error, core.CodeLocalOnly)
+			}
+		}
+	}
+
+	// The hook allow-list has no key at all, so it can't be set either
+	resp := apiRequest(t, srv, http.MethodPut, "/settings/allowed_commands", `{"value":"sh"}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("PUT /settings/allowed_commands = %d, want 404", resp.StatusCode)
+	}
+
+	saved, err := config.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Hooks.OnComplete != "" || saved.Connections.URLRefreshCommand != "" || len(saved.Hooks.AllowedCommands) != 0 {
+		t.Errorf("saved settings changed: %+v %q", saved.Hooks, saved.Connections.URLRefreshCommand)
+	}
+}
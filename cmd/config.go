@@ -0,0 +1,437 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View or change settings",
+	Long: `View or change Surge settings from the command line.
+
+Keys are the setting names used in settings.json, e.g. max_connections_per_host.
+Run 'surge config list' to see them all. When Surge is running, changes go
+through it and apply immediately.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		settings, err := loadConfigSettings()
+		if err == nil {
+			var value string
+			if value, err = settings.GetValue(args[0]); err == nil {
+				fmt.Println(value)
+				return
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Long: `Change a setting and save it to settings.json.
+
+Values are checked against the setting's type and range. Durations accept
+Go syntax (30s, 5m) or a number of seconds; sizes are in bytes.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := changeConfigValue(http.MethodPost, args[0], args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s = %s\n", args[0], value)
+		warnEnvOverride(args[0])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Reset a setting to its default",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := changeConfigValue(http.MethodDelete, args[0], "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s = %s (default)\n", args[0], value)
+		warnEnvOverride(args[0])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all settings",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settings, err := loadConfigSettings()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+			data, _ := json.MarshalIndent(settings, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		metadata := config.GetSettingsMetadata()
+		for i, category := range config.CategoryOrder() {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(category)
+			for _, meta := range metadata[category] {
+				value, _ := settings.GetValue(meta.Key)
+				if name, ok := config.EnvOverride(meta.Key); ok {
+					value += "  (from " + name + ")"
+				}
+				fmt.Printf("  %-30s %s\n", meta.Key, value)
+			}
+		}
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open settings.json in your editor",
+	Long: `Open settings.json in $VISUAL or $EDITOR, then check it.
+When Surge is running and the file is valid, the changes are applied immediately.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := config.GetSettingsPath()
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			// Start from the defaults so there is something to edit
+			if err := config.SaveSettings(config.DefaultSettings()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if err := openEditor(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !reportSettingsProblems(path) {
+			os.Exit(1)
+		}
+		if port := readActivePort(); port > 0 {
+			resp, err := daemonRequest(port, http.MethodPost, "/settings/reload", nil)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: saved, but Surge did not reload: %v\n", err)
+				os.Exit(1)
+			}
+			closeBody(resp)
+			fmt.Println("Settings reloaded.")
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check settings.json for mistakes",
+	Long: `Check settings.json (or the given file) for invalid JSON, unknown keys,
+values of the wrong type and values out of range. Exits with status 1 if
+anything is wrong.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := config.GetSettingsPath()
+		if len(args) == 1 {
+			path = args[0]
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if len(args) == 1 {
+				fmt.Fprintf(os.Stderr, "Error: %s does not exist\n", path)
+				os.Exit(1)
+			}
+			fmt.Printf("No settings file at %s; defaults are in use.\n", path)
+			return
+		}
+		if !reportSettingsProblems(path) {
+			os.Exit(1)
+		}
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema for settings.json",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := json.MarshalIndent(config.SettingsSchema(), "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	},
+}
+
+// reportSettingsProblems prints the problems found in a settings file and
+// in SURGE_* variables, and reports whether there were none
+func reportSettingsProblems(path string) bool {
+	problems, err := config.CheckSettingsFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return false
+	}
+	envProblems := config.CheckEnvOverrides()
+	if len(problems) == 0 && len(envProblems) == 0 {
+		fmt.Printf("%s is valid.\n", path)
+		return true
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%s has %d problem(s):\n", path, len(problems))
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  - %v\n", p)
+		}
+	}
+	if len(envProblems) > 0 {
+		fmt.Fprintf(os.Stderr, "Environment has %d problem(s):\n", len(envProblems))
+		for _, p := range envProblems {
+			fmt.Fprintf(os.Stderr, "  - %v\n", p)
+		}
+	}
+	return false
+}
+
+// warnSettingsProblems prints a short warning when settings.json or a
+// SURGE_* variable has mistakes that LoadSettings would silently ignore
+func warnSettingsProblems() {
+	problems, err := config.CheckSettingsFile(config.GetSettingsPath())
+	if err != nil {
+		return
+	}
+	problems = append(problems, config.CheckEnvOverrides()...)
+	if len(problems) == 0 {
+		return
+	}
+	for _, p := range problems {
+		utils.Debug("settings: %v", p)
+		fmt.Fprintf(os.Stderr, "Warning: settings: %v\n", p)
+	}
+	fmt.Fprintln(os.Stderr, "Run 'surge config validate' for details.")
+}
+
+// warnEnvOverride notes that a SURGE_* variable hides a saved value
+func warnEnvOverride(key string) {
+	if name, ok := config.EnvOverride(key); ok {
+		fmt.Fprintf(os.Stderr, "Note: %s is set and overrides the saved value while it stays set.\n", name)
+	}
+}
+
+// openEditor opens path in $VISUAL, $EDITOR or a platform default
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	parts := strings.Fields(editor)
+	c := exec.Command(parts[0], append(parts[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// loadConfigSettings returns the settings of the running instance, or the
+// ones in settings.json when none is running
+func loadConfigSettings() (*config.Settings, error) {
+	if port := readActivePort(); port > 0 {
+		resp, err := daemonRequest(port, http.MethodGet, "/settings", nil)
+		if err == nil {
+			defer closeBody(resp)
+			settings := config.DefaultSettings()
+			if err := json.NewDecoder(resp.Body).Decode(settings); err != nil {
+				return nil, fmt.Errorf("invalid response from server: %w", err)
+			}
+			return settings, nil
+		}
+		if !errors.Is(err, errDaemonUnreachable) {
+			return nil, err
+		}
+		utils.Debug("config: %v; reading settings.json", err)
+	}
+	return config.LoadSettings()
+}
+
+// changeConfigValue sets (POST) or resets (DELETE) a setting and returns the
+// stored value. A running instance makes the change itself so it applies
+// immediately.
+func changeConfigValue(method, key, value string) (string, error) {
+	if meta, _ := config.LookupSetting(key); meta.LocalOnly {
+		return changeLocalOnlyValue(method, key, value)
+	}
+	if port := readActivePort(); port > 0 {
+		var body any
+		if method == http.MethodPost {
+			method, body = http.MethodPut, map[string]string{"value": value}
+		}
+		resp, err := daemonRequest(port, method, "/settings/"+url.PathEscape(key), body)
+		if err == nil {
+			defer closeBody(resp)
+			var result settingResponse
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				return "", fmt.Errorf("invalid response from server: %w", err)
+			}
+			return result.Value, nil
+		}
+		if !errors.Is(err, errDaemonUnreachable) {
+			return "", err
+		}
+		utils.Debug("config: %v; editing settings.json", err)
+	}
+	return saveConfigValue(method, key, value)
+}
+
+// changeLocalOnlyValue edits a setting the server refuses to change over
+// HTTP in settings.json, then has a running instance reload it
+func changeLocalOnlyValue(method, key, value string) (string, error) {
+	stored, err := saveConfigValue(method, key, value)
+	if err != nil {
+		return "", err
+	}
+	if port := readActivePort(); port > 0 {
+		resp, err := daemonRequest(port, http.MethodPost, "/settings/reload", nil)
+		if err != nil && !errors.Is(err, errDaemonUnreachable) {
+			return "", fmt.Errorf("saved, but Surge did not reload: %w", err)
+		}
+		if err == nil {
+			closeBody(resp)
+		}
+	}
+	return stored, nil
+}
+
+// saveConfigValue sets or resets a setting in settings.json
+func saveConfigValue(method, key, value string) (string, error) {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+	if err := applySettingChange(settings, method, key, value); err != nil {
+		return "", err
+	}
+	if err := config.SaveSettings(settings); err != nil {
+		return "", fmt.Errorf("failed to save settings: %w", err)
+	}
+	return settings.GetValue(key)
+}
+
+// refuseLocalOnlySetting rejects changes over HTTP to settings that hold
+// shell commands. Those only change in settings.json, which takes access to
+// this machine rather than just the API token.
+func refuseLocalOnlySetting(key string) *core.APIError {
+	if meta, ok := config.LookupSetting(key); !ok || !meta.LocalOnly {
+		return nil
+	}
+	return &core.APIError{
+		Status:  http.StatusForbidden,
+		Code:    core.CodeLocalOnly,
+		Message: fmt.Sprintf("%s runs a shell command and can only be changed in settings.json", config.SettingPath(key)),
+	}
+}
+
+// applySettingChange sets or resets one key on settings
+func applySettingChange(settings *config.Settings, method, key, value string) error {
+	if method == http.MethodDelete {
+		return settings.ResetValue(key)
+	}
+	return settings.SetValue(key, value)
+}
+
+// settingResponse is the body returned by /settings for a single key
+type settingResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// errDaemonUnreachable marks a request that never reached a running server,
+// e.g. because the port file is stale
+var errDaemonUnreachable = errors.New("server not reachable")
+
+// daemonRequest sends an authenticated request to path under the local
+// server's /api/v1, with body as JSON when non-nil. It returns an error for
+// non-2xx responses, including the server's message.
+func daemonRequest(port int, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s%s", port, core.APIPrefix, path), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+ensureAuthToken())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDaemonUnreachable, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer closeBody(resp)
+		data, _ := io.ReadAll(resp.Body)
+		var wrapper struct {
+			Error *core.APIError `json:"error"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err == nil && wrapper.Error != nil && wrapper.Error.Message != "" {
+			return nil, errors.New(wrapper.Error.Message)
+		}
+		if msg := strings.TrimSpace(string(data)); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		utils.Debug("Error closing response body: %v", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configEditCmd, configValidateCmd, configSchemaCmd)
+	configListCmd.Flags().Bool("json", false, "Output settings.json content")
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Find and repair problems",
+	Long: `Check Surge's data for problems left behind by crashes.
+
+--state cross-checks the download database against the disk. It finds partial
+files (<name>.surge) no download owns, unfinished downloads whose partial file is
+gone, completed downloads whose file is gone, and downloads whose remaining
+chunks don't add up to the bytes left.
+
+Each problem lists the fixes that apply, suggested one first:
+  adopt          mark the download completed, since the finished file is on disk
+  purge          delete the download and its partial file, or the stray file
+  mark_error     mark the download failed so a retry starts over
+  rebuild_tasks  recompute the remaining chunks from the saved chunk map
+
+Stray partial files have no suggested fix, since they may be all that is left
+of a download. --fix leaves them alone unless --prefer purge is given.
+
+Nothing changes without --fix. Use --prefer to pick another fix where it applies.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		// --state is the only check so far, so it is also the default
+		fix, _ := cmd.Flags().GetBool("fix")
+		prefer, _ := cmd.Flags().GetString("prefer")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if prefer != "" && !isStateFix(prefer) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --prefer %q (want adopt, purge, mark_error or rebuild_tasks)\n", prefer)
+			os.Exit(1)
+		}
+		if fix && readActivePort() > 0 {
+			fmt.Fprintln(os.Stderr, "Error: Surge is running. Stop it before repairing its state.")
+			os.Exit(1)
+		}
+
+		var dirs []string
+		if settings, err := config.LoadSettings(); err == nil {
+			dirs = append(dirs, settings.General.DefaultDownloadDir)
+		}
+		issues, err := state.CheckState(dirs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking state: %v\n", err)
+			os.Exit(1)
+		}
+		for i := range issues {
+			issues[i].Fix = chooseStateFix(issues[i], prefer)
+		}
+
+		if jsonOutput {
+			if !fix {
+				data, _ := json.MarshalIndent(issues, "", "  ")
+				fmt.Println(string(data))
+				return
+			}
+		} else {
+			printStateIssues(os.Stdout, issues)
+		}
+		if !fix {
+			if len(issues) > 0 {
+				fmt.Println("\nRun 'surge doctor --state --fix' to apply the suggested fixes.")
+			}
+			return
+		}
+
+		var out io.Writer = os.Stdout
+		if jsonOutput {
+			out = io.Discard
+		}
+		failed := repairStateIssues(out, issues)
+		if jsonOutput {
+			data, _ := json.MarshalIndent(issues, "", "  ")
+			fmt.Println(string(data))
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// isStateFix reports whether fix names a state repair
+func isStateFix(fix string) bool {
+	switch fix {
+	case state.FixAdopt, state.FixPurge, state.FixMarkError, state.FixRebuildTasks:
+		return true
+	}
+	return false
+}
+
+// chooseStateFix returns prefer if it applies to issue, else the suggested fix
+func chooseStateFix(issue state.StateIssue, prefer string) string {
+	for _, f := range issue.Fixes {
+		if f == prefer {
+			return f
+		}
+	}
+	return issue.Fix
+}
+
+// repairStateIssues applies each issue's chosen fix and returns how many
+// failed. Issues without a fix are left alone. A failed issue's Fix is cleared.
+func repairStateIssues(out io.Writer, issues []state.StateIssue) int {
+	failed := 0
+	for i, issue := range issues {
+		if issue.Fix == "" {
+			_, _ = fmt.Fprintf(out, "Left alone: %s (use --prefer %s)\n", issue.Path, strings.Join(issue.Fixes, " or --prefer "))
+			continue
+		}
+		if err := state.RepairState(issue, issue.Fix); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s %s: %v\n", issue.Fix, issue.Path, err)
+			issues[i].Fix = ""
+			failed++
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "Fixed (%s): %s\n", issue.Fix, issue.Path)
+	}
+	return failed
+}
+
+func printStateIssues(out io.Writer, issues []state.StateIssue) {
+	if len(issues) == 0 {
+		_, _ = fmt.Fprintln(out, "No problems found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROBLEM\tID\tFILENAME\tDETAIL\tFIX")
+	_, _ = fmt.Fprintln(w, "-------\t--\t--------\t------\t---")
+	for _, issue := range issues {
+		id := issue.DownloadID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		if id == "" {
+			id = "-"
+		}
+		filename := issue.Filename
+		if len(filename) > 30 {
+			filename = filename[:27] + "..."
+		}
+		fix := issue.Fix
+		if fix == "" {
+			fix = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", issue.Kind, id, filename, issue.Detail, fix)
+	}
+	_ = w.Flush()
+	_, _ = fmt.Fprintf(out, "\nProblems found: %d\n", len(issues))
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Bool("state", false, "Cross-check the download database against the disk (the default)")
+	doctorCmd.Flags().Bool("fix", false, "Apply a fix to each problem found")
+	doctorCmd.Flags().String("prefer", "", "Apply this fix wherever it applies: adopt, purge, mark_error or rebuild_tasks")
+	doctorCmd.Flags().Bool("json", false, "Output in JSON format")
+}
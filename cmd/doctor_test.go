@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestChooseStateFix(t *testing.T) {
+	issue := state.StateIssue{Fix: state.FixMarkError, Fixes: []string{state.FixMarkError, state.FixPurge}}
+	if got := chooseStateFix(issue, ""); got != state.FixMarkError {
+		t.Errorf("no preference = %q, want the suggested fix", got)
+	}
+	if got := chooseStateFix(issue, state.FixPurge); got != state.FixPurge {
+		t.Errorf("prefer purge = %q", got)
+	}
+	if got := chooseStateFix(issue, state.FixAdopt); got != state.FixMarkError {
+		t.Errorf("prefer a fix that doesn't apply = %q, want the suggested fix", got)
+	}
+}
+
+func TestPrintStateIssues(t *testing.T) {
+	var buf bytes.Buffer
+	printStateIssues(&buf, nil)
+	if !strings.Contains(buf.String(), "No problems found") {
+		t.Errorf("empty output = %q", buf.String())
+	}
+
+	buf.Reset()
+	printStateIssues(&buf, []state.StateIssue{{
+		Kind: state.IssueMissingFile, DownloadID: "0123456789abcdef", Filename: "a.iso", Detail: "gone", Fix: state.FixPurge,
+	}})
+	out := buf.String()
+	for _, want := range []string{"missing_file", "01234567", "a.iso", "purge", "Problems found: 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRepairStateIssues_OrphanNeedsPreferPurge(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, "stray.bin"+types.IncompleteSuffix)
+	if err := os.WriteFile(orphan, make([]byte, 10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixAll := func(prefer string) string {
+		t.Helper()
+		issues, err := state.CheckState([]string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 1 || issues[0].Kind != state.IssueOrphanPartial {
+			t.Fatalf("issues = %+v, want one orphan", issues)
+		}
+		for i := range issues {
+			issues[i].Fix = chooseStateFix(issues[i], prefer)
+		}
+		var buf bytes.Buffer
+		if failed := repairStateIssues(&buf, issues); failed != 0 {
+			t.Fatalf("%d repairs failed", failed)
+		}
+		return buf.String()
+	}
+
+	// --fix alone
+	if out := fixAll(""); !strings.Contains(out, "Left alone") {
+		t.Errorf("output = %q, want the orphan left alone", out)
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Fatalf("orphan deleted without --prefer purge: %v", err)
+	}
+
+	// --fix --prefer purge
+	if out := fixAll(state.FixPurge); !strings.Contains(out, "Fixed (purge)") {
+		t.Errorf("output = %q, want the orphan purged", out)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("orphan kept with --prefer purge")
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export all downloads to a JSON file",
+	Long: `Write every download to a portable JSON file: queued, scheduled and paused
+downloads with their remaining chunks, mirrors and headers, failed downloads and
+completed history. Without a file, the JSON goes to stdout.
+
+To move an unfinished download to another machine, pause it, export, and copy
+its partial file (<name>.surge) along with the export.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		if readActivePort() > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: Surge is running; active downloads are exported as of their last checkpoint (up to %s old).\n", types.CheckpointInterval)
+		}
+
+		exp, err := state.ExportDownloads()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting downloads: %v\n", err)
+			os.Exit(1)
+		}
+		if noHistory, _ := cmd.Flags().GetBool("no-history"); noHistory {
+			kept := exp.Downloads[:0]
+			for _, d := range exp.Downloads {
+				if d.Status != "completed" {
+					kept = append(kept, d)
+				}
+			}
+			exp.Downloads = kept
+		}
+
+		data, err := json.MarshalIndent(exp, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding export: %v\n", err)
+			os.Exit(1)
+		}
+		data = append(data, '\n')
+
+		if len(args) == 0 || args[0] == "-" {
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+		// Headers may carry cookies, so keep the file private
+		if err := os.WriteFile(args[0], data, 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d downloads to %s\n", len(exp.Downloads), args[0])
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import downloads from an export file",
+	Long: `Add the downloads in a file written by 'surge export'. Downloads whose ID is
+already known are skipped.
+
+Use --remap to move destination paths, e.g. --remap /home/me/Downloads=/data.
+An unfinished download keeps its progress only if its partial file (<name>.surge)
+is found at the new destination; otherwise it starts over. Surge must not be
+running.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		if readActivePort() > 0 {
+			fmt.Fprintln(os.Stderr, "Error: Surge is running. Stop it before importing downloads.")
+			os.Exit(1)
+		}
+
+		remapFlags, _ := cmd.Flags().GetStringArray("remap")
+		remaps, err := parseRemaps(remapFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		exp, err := readExport(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var imported, skipped, restarted int
+		for _, d := range exp.Downloads {
+			d.DestPath = remapPath(d.DestPath, remaps)
+			partial := d.DestPath + types.IncompleteSuffix
+			missing := false
+			if d.HasProgress() {
+				if _, err := os.Stat(partial); err != nil {
+					missing = true
+					d.DropProgress()
+				}
+			}
+
+			ok, err := state.ImportDownload(d)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", d.Filename, err)
+				os.Exit(1)
+			}
+			if !ok {
+				skipped++
+				continue
+			}
+			imported++
+			if missing {
+				fmt.Fprintf(os.Stderr, "Warning: %s: partial file not found at %s; it will start over\n", d.Filename, partial)
+				restarted++
+			}
+		}
+
+		fmt.Printf("Imported %d downloads", imported)
+		if skipped > 0 {
+			fmt.Printf(", skipped %d already present", skipped)
+		}
+		if restarted > 0 {
+			fmt.Printf(", %d will start over", restarted)
+		}
+		fmt.Println(".")
+		if imported > 0 {
+			fmt.Println("Queued downloads start the next time Surge starts; resume paused ones with 'surge resume <id>'.")
+		}
+	},
+}
+
+// readExport reads an export file, or stdin for "-"
+func readExport(path string) (*state.Export, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var exp state.Export
+	if err := json.Unmarshal(data, &exp); err != nil {
+		return nil, fmt.Errorf("%s is not a Surge export: %w", path, err)
+	}
+	if exp.Version == 0 || exp.Version > state.ExportVersion {
+		return nil, fmt.Errorf("%s has unsupported export version %d", path, exp.Version)
+	}
+	return &exp, nil
+}
+
+// pathRemap replaces the From prefix of a destination path with To
+type pathRemap struct {
+	From string
+	To   string
+}
+
+// parseRemaps parses --remap values of the form old=new
+func parseRemaps(values []string) ([]pathRemap, error) {
+	var remaps []pathRemap
+	for _, v := range values {
+		from, to, ok := strings.Cut(v, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid --remap %q: expected old=new", v)
+		}
+		remaps = append(remaps, pathRemap{From: from, To: to})
+	}
+	return remaps, nil
+}
+
+// remapPath applies the first remap whose prefix matches path at a directory
+// boundary. Paths exported on Windows may use backslashes; those after the
+// prefix become this platform's separator.
+func remapPath(path string, remaps []pathRemap) string {
+	for _, r := range remaps {
+		from := strings.TrimRight(r.From, `/\`)
+		if !strings.HasPrefix(path, from) {
+			continue
+		}
+		rest := path[len(from):]
+		if rest != "" && rest[0] != '/' && rest[0] != '\\' {
+			continue // "/data" must not match "/database"
+		}
+		rest = strings.ReplaceAll(rest, `\`, "/")
+		return filepath.Join(r.To, filepath.FromSlash(rest))
+	}
+	return path
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	exportCmd.Flags().Bool("no-history", false, "Leave out completed downloads")
+	importCmd.Flags().StringArray("remap", nil, "Replace a destination path prefix, as old=new (repeatable)")
+}
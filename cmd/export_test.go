@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRemapPath(t *testing.T) {
+	remaps, err := parseRemaps([]string{"/home/me/Downloads=/data", `C:\Users\me\Downloads=/win`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/home/me/Downloads/a.iso", filepath.FromSlash("/data/a.iso")},
+		{"/home/me/Downloads/sub/b.zip", filepath.FromSlash("/data/sub/b.zip")},
+		{"/home/me/DownloadsOld/c.zip", "/home/me/DownloadsOld/c.zip"},
+		{`C:\Users\me\Downloads\iso\d.iso`, filepath.FromSlash("/win/iso/d.iso")},
+		{"/elsewhere/e.bin", "/elsewhere/e.bin"},
+	}
+	for _, tt := range tests {
+		if got := remapPath(tt.in, remaps); got != tt.want {
+			t.Errorf("remapPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRemaps_Invalid(t *testing.T) {
+	for _, v := range []string{"no-equals", "=/data", "/data="} {
+		if _, err := parseRemaps([]string{v}); err == nil {
+			t.Errorf("parseRemaps(%q) succeeded, want error", v)
+		}
+	}
+}
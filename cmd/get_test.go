@@ -18,6 +18,9 @@ import (
 
 func TestCLI_NewEndpoints(t *testing.T) {
 	requireTCPListener(t)
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
 	// Initialize GlobalPool for tests
 	GlobalProgressCh = make(chan any, 100)
 	GlobalPool = download.NewWorkerPool(GlobalProgressCh, 4)
@@ -259,3 +262,80 @@ func TestCLI_DeleteEndpoint_CleansPausedStateAndPartialFile(t *testing.T) {
 		}
 	}
 }
+
+func TestCLI_DownloadsEndpoint_FiltersByTag(t *testing.T) {
+	requireTCPListener(t)
+
+	tempDir := t.TempDir()
+	originalConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if err := os.Setenv("XDG_CONFIG_HOME", tempDir); err != nil {
+		t.Fatalf("failed to set XDG_CONFIG_HOME: %v", err)
+	}
+	defer func() {
+		if originalConfigHome == "" {
+			_ = os.Unsetenv("XDG_CONFIG_HOME")
+		} else {
+			_ = os.Setenv("XDG_CONFIG_HOME", originalConfigHome)
+		}
+		state.CloseDB()
+	}()
+
+	state.CloseDB()
+	initializeGlobalState()
+
+	GlobalProgressCh = make(chan any, 100)
+	GlobalPool = download.NewWorkerPool(GlobalProgressCh, 2)
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "tagged", URL: "https://example.com/a.iso", DestPath: filepath.Join(tempDir, "a.iso"), Filename: "a.iso", Status: "completed",
+			DownloadMeta: types.DownloadMeta{Tags: []string{"linux", "iso"}}},
+		{ID: "untagged", URL: "https://example.com/b.bin", DestPath: filepath.Join(tempDir, "b.bin"), Filename: "b.bin", Status: "completed"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("failed to seed %s: %v", e.ID, err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	svc := core.NewLocalDownloadService(GlobalPool)
+	go startHTTPServer(ln, port, "", svc)
+	time.Sleep(50 * time.Millisecond)
+
+	token := ensureAuthToken()
+	list := func(query string) []string {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d%s/downloads%s", port, core.APIPrefix, query), nil)
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to request list: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		var page core.Page[types.DownloadStatus]
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode list: %v", err)
+		}
+		var ids []string
+		for _, st := range page.Items {
+			ids = append(ids, st.ID)
+		}
+		return ids
+	}
+
+	if ids := list(""); len(ids) != 2 {
+		t.Errorf("unfiltered list = %v, want both downloads", ids)
+	}
+	if ids := list("?tag=ISO"); len(ids) != 1 || ids[0] != "tagged" {
+		t.Errorf("?tag=ISO = %v, want [tagged]", ids)
+	}
+	if ids := list("?tag=linux&tag=work"); len(ids) != 0 {
+		t.Errorf("?tag=linux&tag=work = %v, want none", ids)
+	}
+}
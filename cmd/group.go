@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "List download groups",
+	Long:  `List download groups (batch imports and --group) with their combined progress.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		var groups []types.GroupStatus
+		if port := readActivePort(); port > 0 {
+			var err error
+			groups, err = core.NewRemoteDownloadService(fmt.Sprintf("http://127.0.0.1:%d", port), ensureAuthToken()).Groups()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			entries, err := state.ListAllDownloads()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing downloads: %v\n", err)
+				os.Exit(1)
+			}
+			statuses := make([]types.DownloadStatus, 0, len(entries))
+			for _, e := range entries {
+				statuses = append(statuses, types.DownloadStatus{
+					ID:         e.ID,
+					Status:     e.Status,
+					TotalSize:  e.TotalSize,
+					Downloaded: e.Downloaded,
+					Group:      e.Group,
+				})
+			}
+			groups = types.SummarizeGroups(statuses)
+		}
+
+		if jsonOutput {
+			if groups == nil {
+				groups = []types.GroupStatus{}
+			}
+			data, _ := json.MarshalIndent(groups, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(groups) == 0 {
+			fmt.Println("No download groups.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "GROUP\tSTATUS\tDONE\tPROGRESS\tSPEED\tSIZE")
+		_, _ = fmt.Fprintln(w, "-----\t------\t----\t--------\t-----\t----")
+		for _, g := range groups {
+			speed := "-"
+			if g.Speed > 0 {
+				speed = fmt.Sprintf("%.1f MB/s", g.Speed)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d/%d\t%.1f%%\t%s\t%s\n",
+				g.Name, g.Status, g.Completed, g.Count, g.Progress, speed, formatSize(g.TotalSize))
+		}
+		_ = w.Flush()
+	},
+}
+
+// newGroupActionCmd builds `surge group <action> <name>`
+func newGroupActionCmd(use, action, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <name>",
+		Short: short,
+		Long:  short + ". Requires a running Surge instance.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			initializeGlobalState()
+
+			name := args[0]
+			port := readActivePort()
+			if port == 0 {
+				fmt.Fprintln(os.Stderr, "Error: Surge is not running.")
+				os.Exit(1)
+			}
+
+			if err := core.NewRemoteDownloadService(fmt.Sprintf("http://127.0.0.1:%d", port), ensureAuthToken()).GroupAction(name, action); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Group %q: %s done\n", name, action)
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(groupCmd)
+	groupCmd.Flags().Bool("json", false, "Output in JSON format")
+	groupCmd.AddCommand(
+		newGroupActionCmd("pause", types.GroupPause, "Pause every download in a group"),
+		newGroupActionCmd("resume", types.GroupResume, "Resume the paused downloads in a group"),
+		newGroupActionCmd("rm", types.GroupDelete, "Remove every download in a group"),
+		newGroupActionCmd("retry", types.GroupRetry, "Retry the failed downloads in a group"),
+	)
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Search finished downloads",
+	Long: `List completed and failed downloads, newest first.
+
+Filter with --status, --since/--until (a date such as 2026-10-01 or an age such
+as 7d), --host, --min-size/--max-size (e.g. 500MB) and --name (a glob such as
+"*.iso"). Use --stats for totals, average speed, busiest hosts and failure rate.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		filter, err := historyFilterFromFlags(cmd, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		csvOutput, _ := cmd.Flags().GetBool("csv")
+		if jsonOutput && csvOutput {
+			fmt.Fprintln(os.Stderr, "Error: use either --json or --csv")
+			os.Exit(1)
+		}
+
+		if stats, _ := cmd.Flags().GetBool("stats"); stats {
+			// Stats cover every match, not one page
+			filter.Limit, filter.Offset = 0, 0
+			entries, _, err := state.QueryHistory(filter)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+				os.Exit(1)
+			}
+			printHistoryStats(os.Stdout, types.NewHistoryStats(entries), jsonOutput)
+			return
+		}
+
+		entries, total, err := state.QueryHistory(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch {
+		case jsonOutput:
+			data, _ := json.MarshalIndent(entries, "", "  ")
+			fmt.Println(string(data))
+		case csvOutput:
+			if err := writeHistoryCSV(os.Stdout, entries); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+				os.Exit(1)
+			}
+		default:
+			printHistoryTable(entries)
+			if len(entries) < total {
+				fmt.Printf("\nShowing %d of %d. Use --limit and --offset to see more.\n", len(entries), total)
+			}
+		}
+	},
+}
+
+// historyFilterFromFlags builds a history filter from the command's flags
+func historyFilterFromFlags(cmd *cobra.Command, now time.Time) (types.HistoryFilter, error) {
+	var f types.HistoryFilter
+
+	status, _ := cmd.Flags().GetString("status")
+	for _, s := range strings.Split(status, ",") {
+		switch s = strings.TrimSpace(s); s {
+		case "all":
+			f.Status = []string{"completed", "error"}
+		case "completed", "error":
+			f.Status = append(f.Status, s)
+		case "failed":
+			f.Status = append(f.Status, "error")
+		default:
+			return f, fmt.Errorf("invalid --status %q (want completed, error or all)", s)
+		}
+	}
+
+	for flag, dst := range map[string]*int64{"since": &f.Since, "until": &f.Until} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			t, err := utils.ParsePastTime(v, now)
+			if err != nil {
+				return f, fmt.Errorf("--%s: %w", flag, err)
+			}
+			*dst = t.Unix()
+		}
+	}
+	for flag, dst := range map[string]*int64{"min-size": &f.MinSize, "max-size": &f.MaxSize} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			n, err := utils.ParseSize(v)
+			if err != nil {
+				return f, fmt.Errorf("--%s: %w", flag, err)
+			}
+			*dst = n
+		}
+	}
+
+	f.Host, _ = cmd.Flags().GetString("host")
+	f.Filename, _ = cmd.Flags().GetString("name")
+	f.Sort, _ = cmd.Flags().GetString("sort")
+	if !types.IsValidHistorySort(f.Sort) {
+		return f, fmt.Errorf("invalid --sort %q (want date, size, name, speed or duration)", f.Sort)
+	}
+	f.Reverse, _ = cmd.Flags().GetBool("reverse")
+	f.Limit, _ = cmd.Flags().GetInt("limit")
+	f.Offset, _ = cmd.Flags().GetInt("offset")
+	if f.Limit < 0 || f.Offset < 0 {
+		return f, fmt.Errorf("--limit and --offset must not be negative")
+	}
+	return f, nil
+}
+
+func printHistoryTable(entries []types.DownloadEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No downloads found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FINISHED\tFILENAME\tSTATUS\tSIZE\tTIME\tSPEED\tHOST")
+	_, _ = fmt.Fprintln(w, "--------\t--------\t------\t----\t----\t-----\t----")
+	for _, e := range entries {
+		finished := "-"
+		if e.CompletedAt > 0 {
+			finished = time.Unix(e.CompletedAt, 0).Format("2006-01-02 15:04")
+		}
+		filename := e.Filename
+		if len(filename) > 30 {
+			filename = filename[:27] + "..."
+		}
+		took, speed := "-", "-"
+		if e.Status == "completed" && e.TimeTaken > 0 {
+			took = (time.Duration(e.TimeTaken) * time.Millisecond).Round(time.Second).String()
+			speed = utils.ConvertBytesToHumanReadable(int64(e.Speed())) + "/s"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", finished, filename, e.Status, formatSize(e.TotalSize), took, speed, e.Host())
+	}
+	_ = w.Flush()
+}
+
+// historyCSVHeader names the columns written by writeHistoryCSV
+var historyCSVHeader = []string{
+	"id", "filename", "status", "url", "host", "dest_path", "total_size", "downloaded",
+	"finished_at", "time_taken_ms", "speed_bytes_per_sec", "category", "group", "tags",
+}
+
+func writeHistoryCSV(out io.Writer, entries []types.DownloadEntry) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(historyCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		finished := ""
+		if e.CompletedAt > 0 {
+			finished = time.Unix(e.CompletedAt, 0).UTC().Format(time.RFC3339)
+		}
+		if err := w.Write([]string{
+			e.ID, e.Filename, e.Status, e.URL, e.Host(), e.DestPath,
+			strconv.FormatInt(e.TotalSize, 10), strconv.FormatInt(e.Downloaded, 10),
+			finished, strconv.FormatInt(e.TimeTaken, 10), strconv.FormatInt(int64(e.Speed()), 10),
+			e.Category, e.Group, strings.Join(e.Tags, ","),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printHistoryStats(out io.Writer, st types.HistoryStats, jsonOutput bool) {
+	if jsonOutput {
+		data, _ := json.MarshalIndent(st, "", "  ")
+		_, _ = fmt.Fprintln(out, string(data))
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "Downloads:     %d (%d completed, %d failed)\n", st.Downloads, st.Completed, st.Failed)
+	_, _ = fmt.Fprintf(out, "Total size:    %s\n", utils.ConvertBytesToHumanReadable(st.TotalBytes))
+	_, _ = fmt.Fprintf(out, "Total time:    %s\n", (time.Duration(st.TotalTime) * time.Millisecond).Round(time.Second))
+	_, _ = fmt.Fprintf(out, "Average speed: %s/s\n", utils.ConvertBytesToHumanReadable(int64(st.AvgSpeed)))
+	_, _ = fmt.Fprintf(out, "Failure rate:  %.1f%%\n", st.FailureRate*100)
+	if len(st.TopHosts) > 0 {
+		_, _ = fmt.Fprintln(out, "Busiest hosts:")
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, h := range st.TopHosts {
+			_, _ = fmt.Fprintf(w, "  %s\t%d downloads\t%s\n", h.Host, h.Downloads, utils.ConvertBytesToHumanReadable(h.Bytes))
+		}
+		_ = w.Flush()
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("status", "all", "Only downloads with this status: completed, error or all")
+	historyCmd.Flags().String("since", "", "Only downloads finished at or after this date or age (e.g. 2026-10-01, 7d)")
+	historyCmd.Flags().String("until", "", "Only downloads finished before this date or age")
+	historyCmd.Flags().String("host", "", "Only downloads from this host or its subdomains")
+	historyCmd.Flags().String("min-size", "", "Only files at least this large (e.g. 100MB)")
+	historyCmd.Flags().String("max-size", "", "Only files at most this large")
+	historyCmd.Flags().String("name", "", "Only filenames matching this glob (e.g. \"*.iso\")")
+	historyCmd.Flags().String("sort", types.HistorySortDate, "Sort by date, size, name, speed or duration")
+	historyCmd.Flags().Bool("reverse", false, "Reverse the sort order")
+	historyCmd.Flags().Int("limit", 0, "Show at most this many downloads (0 = all)")
+	historyCmd.Flags().Int("offset", 0, "Skip this many downloads")
+	historyCmd.Flags().Bool("stats", false, "Print aggregate statistics instead of a list")
+	historyCmd.Flags().Bool("json", false, "Output in JSON format")
+	historyCmd.Flags().Bool("csv", false, "Output in CSV format")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestHistoryFilterFromFlags(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	cmd := historyCmd
+	for flag, v := range map[string]string{"status": "failed", "since": "7d", "min-size": "1MB", "sort": "size", "limit": "5"} {
+		def := cmd.Flags().Lookup(flag).DefValue
+		t.Cleanup(func() { _ = cmd.Flags().Set(flag, def) })
+		if err := cmd.Flags().Set(flag, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := historyFilterFromFlags(cmd, now)
+	if err != nil {
+		t.Fatalf("historyFilterFromFlags failed: %v", err)
+	}
+	if len(f.Status) != 1 || f.Status[0] != "error" {
+		t.Errorf("Status = %v, want [error]", f.Status)
+	}
+	if f.Since != now.Add(-7*24*time.Hour).Unix() || f.MinSize != 1<<20 || f.Sort != types.HistorySortSize || f.Limit != 5 {
+		t.Errorf("filter = %+v", f)
+	}
+
+	_ = cmd.Flags().Set("sort", "color")
+	if _, err := historyFilterFromFlags(cmd, now); err == nil {
+		t.Error("expected an error for an unknown sort key")
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []types.DownloadEntry{{
+		ID: "abc", Filename: "a, b.iso", Status: "completed", URL: "https://example.com/a.iso",
+		TotalSize: 2000, Downloaded: 2000, CompletedAt: 1700000000, TimeTaken: 1000,
+		DownloadMeta: types.DownloadMeta{Tags: []string{"linux", "iso"}},
+	}}
+	if err := writeHistoryCSV(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(records) != 2 || len(records[1]) != len(historyCSVHeader) {
+		t.Fatalf("records = %v", records)
+	}
+	row := records[1]
+	if row[1] != "a, b.iso" || row[4] != "example.com" || row[8] != "2023-11-14T22:13:20Z" || row[10] != "2000" || row[13] != "linux,iso" {
+		t.Errorf("row = %v", row)
+	}
+}
@@ -173,3 +173,47 @@ func TestHandleDownload_PathResolution(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleDownload_RejectsUnknownConflictPolicy(t *testing.T) {
+	GlobalPool = download.NewWorkerPool(nil, 1)
+
+	body, _ := json.Marshal(DownloadRequest{
+		URL:             "http://example.com/file",
+		DownloadOptions: core.DownloadOptions{Conflict: "clobber"},
+	})
+	req := httptest.NewRequest("POST", "/download", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handleDownload(w, req, t.TempDir(), core.NewLocalDownloadService(GlobalPool))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(GlobalPool.GetAll()) != 0 {
+		t.Error("download was queued despite invalid conflict policy")
+	}
+}
+
+func TestHandleDownload_RejectsInvalidStartTime(t *testing.T) {
+	GlobalPool = download.NewWorkerPool(nil, 1)
+
+	for _, r := range []DownloadRequest{
+		{URL: "http://example.com/file", At: "whenever"},
+		{URL: "http://example.com/file", After: "soon"},
+		{URL: "http://example.com/file", At: "02:00", After: "1h"},
+		{URL: "http://example.com/file", At: "2001-01-01 00:00"},
+	} {
+		body, _ := json.Marshal(r)
+		req := httptest.NewRequest("POST", "/download", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		handleDownload(w, req, t.TempDir(), core.NewLocalDownloadService(GlobalPool))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("at=%q after=%q: expected 400, got %d: %s", r.At, r.After, w.Code, w.Body.String())
+		}
+	}
+	if len(GlobalPool.GetAll()) != 0 {
+		t.Error("download was queued despite invalid start time")
+	}
+}
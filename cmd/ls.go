@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -25,6 +26,8 @@ var lsCmd = &cobra.Command{
 
 		jsonOutput, _ := cmd.Flags().GetBool("json")
 		watch, _ := cmd.Flags().GetBool("watch")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		tags = types.NormalizeTags(tags)
 
 		// If ID provided, show details for that download
 		if len(args) == 1 {
@@ -36,28 +39,29 @@ var lsCmd = &cobra.Command{
 			for {
 				// Clear screen first for watch mode
 				fmt.Print("\033[H\033[2J")
-				printDownloads(jsonOutput)
+				printDownloads(jsonOutput, tags)
 				time.Sleep(1 * time.Second)
 			}
 		} else {
-			printDownloads(jsonOutput)
+			printDownloads(jsonOutput, tags)
 		}
 	},
 }
 
 // downloadInfo is a unified structure for display
 type downloadInfo struct {
-	ID         string  `json:"id"`
-	URL        string  `json:"url,omitempty"`
-	Filename   string  `json:"filename"`
-	Status     string  `json:"status"`
-	Progress   float64 `json:"progress"`
-	TotalSize  int64   `json:"total_size"`
-	Downloaded int64   `json:"downloaded"`
-	Speed      float64 `json:"speed,omitempty"`
+	ID         string   `json:"id"`
+	URL        string   `json:"url,omitempty"`
+	Filename   string   `json:"filename"`
+	Status     string   `json:"status"`
+	Progress   float64  `json:"progress"`
+	TotalSize  int64    `json:"total_size"`
+	Downloaded int64    `json:"downloaded"`
+	Speed      float64  `json:"speed,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
 }
 
-func printDownloads(jsonOutput bool) {
+func printDownloads(jsonOutput bool, tags []string) {
 	var downloads []downloadInfo
 
 	// Try to get from running server first
@@ -66,6 +70,9 @@ func printDownloads(jsonOutput bool) {
 		serverDownloads, err := GetRemoteDownloads(port)
 		if err == nil {
 			for _, s := range serverDownloads {
+				if !s.HasTags(tags) {
+					continue
+				}
 				downloads = append(downloads, downloadInfo{
 					ID:         s.ID,
 					Filename:   s.Filename,
@@ -74,6 +81,7 @@ func printDownloads(jsonOutput bool) {
 					TotalSize:  s.TotalSize,
 					Downloaded: s.Downloaded,
 					Speed:      s.Speed,
+					Tags:       s.Tags,
 				})
 			}
 		}
@@ -88,6 +96,9 @@ func printDownloads(jsonOutput bool) {
 		}
 
 		for _, d := range dbDownloads {
+			if !d.HasTags(tags) {
+				continue
+			}
 			var progress float64
 			if d.TotalSize > 0 {
 				progress = float64(d.Downloaded) * 100 / float64(d.TotalSize)
@@ -99,6 +110,7 @@ func printDownloads(jsonOutput bool) {
 				Progress:   progress,
 				TotalSize:  d.TotalSize,
 				Downloaded: d.Downloaded,
+				Tags:       d.Tags,
 			})
 		}
 	}
@@ -120,8 +132,8 @@ func printDownloads(jsonOutput bool) {
 
 	// Table output
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "ID\tFILENAME\tSTATUS\tPROGRESS\tSPEED\tSIZE")
-	_, _ = fmt.Fprintln(w, "--\t--------\t------\t--------\t-----\t----")
+	_, _ = fmt.Fprintln(w, "ID\tFILENAME\tSTATUS\tPROGRESS\tSPEED\tSIZE\tTAGS")
+	_, _ = fmt.Fprintln(w, "--\t--------\t------\t--------\t-----\t----\t----")
 
 	for _, d := range downloads {
 		progress := fmt.Sprintf("%.1f%%", d.Progress)
@@ -147,7 +159,7 @@ func printDownloads(jsonOutput bool) {
 			filename = filename[:22] + "..."
 		}
 
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, filename, d.Status, progress, speed, size)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", id, filename, d.Status, progress, speed, size, strings.Join(d.Tags, ","))
 	}
 	_ = w.Flush()
 }
@@ -229,6 +241,9 @@ func showDownloadDetails(partialID string, jsonOutput bool) {
 		TotalSize:  found.TotalSize,
 		Downloaded: found.Downloaded,
 		Progress:   progress,
+		Group:      found.Group,
+
+		DownloadMeta: found.DownloadMeta,
 	}
 	printDownloadDetail(status, jsonOutput)
 }
@@ -252,10 +267,23 @@ func printDownloadDetail(d types.DownloadStatus, jsonOutput bool) {
 	if d.Error != "" {
 		fmt.Printf("Error:      %s\n", d.Error)
 	}
+	if d.Group != "" {
+		fmt.Printf("Group:      %s\n", d.Group)
+	}
+	if len(d.Tags) > 0 {
+		fmt.Printf("Tags:       %s\n", strings.Join(d.Tags, ", "))
+	}
+	if d.Referrer != "" {
+		fmt.Printf("Referrer:   %s\n", d.Referrer)
+	}
+	if d.Note != "" {
+		fmt.Printf("Note:       %s\n", d.Note)
+	}
 }
 
 func init() {
 	rootCmd.AddCommand(lsCmd)
 	lsCmd.Flags().Bool("json", false, "Output in JSON format")
 	lsCmd.Flags().Bool("watch", false, "Watch mode: refresh every second")
+	lsCmd.Flags().StringSlice("tag", nil, "Only list downloads carrying this tag (repeatable; all must match)")
 }
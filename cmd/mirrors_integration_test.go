@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/testutil"
 )
 
@@ -55,7 +56,7 @@ func TestMirrors_CLI_Integration(t *testing.T) {
 	arg := fmt.Sprintf("%s,%s,%s", primaryURL, mirror1, mirror2)
 
 	// Simulate "surge add <arg>"
-	processDownloads([]string{arg}, ".", port)
+	processDownloads([]string{arg}, ".", port, core.DownloadOptions{})
 
 	// 3. Verify the server received the correct request
 	select {
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <ID> <path>",
+	Short: "Move or rename a download",
+	Long: `Move or rename a download's file. path is the new file path, or a directory
+to move it into. Works for paused, queued, running and completed downloads:
+a running download stops briefly and continues from the new location.
+Unfinished downloads move their partial file (<name>.surge), across
+filesystems if needed.
+
+If you already moved the file yourself, point mv at its new location to
+update the download.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		newPath := absMovePath(args[1])
+
+		if port := readActivePort(); port > 0 {
+			service := core.NewRemoteDownloadService(fmt.Sprintf("http://127.0.0.1:%d", port), ensureAuthToken())
+			if err := service.Move(id, newPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Moved download %s to %s\n", id[:8], newPath)
+			return
+		}
+
+		dest, err := core.MoveDownload(id, newPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Moved download %s to %s (offline mode)\n", id[:8], dest)
+	},
+}
+
+// absMovePath resolves path against the working directory, which the
+// daemon doesn't share, keeping a trailing separator that marks a directory
+func absMovePath(path string) string {
+	abs := utils.EnsureAbsPath(path)
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		abs += string(filepath.Separator)
+	}
+	return abs
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Show the download queue",
+	Long:  `Show queued downloads in the order they will start, with their priority.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		type queuedInfo struct {
+			ID       string
+			Filename string
+			Priority int
+		}
+		var queued []queuedInfo
+
+		port := readActivePort()
+		if port > 0 {
+			statuses, err := GetRemoteDownloads(port)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+				os.Exit(1)
+			}
+			sort.SliceStable(statuses, func(i, j int) bool {
+				return statuses[i].QueuePosition < statuses[j].QueuePosition
+			})
+			for _, s := range statuses {
+				if s.QueuePosition > 0 {
+					queued = append(queued, queuedInfo{ID: s.ID, Filename: s.Filename, Priority: s.Priority})
+				}
+			}
+		} else {
+			// Offline: paused entries come back in queue order already
+			entries, err := state.LoadPausedDownloads()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading queue: %v\n", err)
+				os.Exit(1)
+			}
+			for _, e := range entries {
+				if e.Status == "queued" {
+					queued = append(queued, queuedInfo{ID: e.ID, Filename: e.Filename, Priority: e.Priority})
+				}
+			}
+		}
+
+		if len(queued) == 0 {
+			fmt.Println("Queue is empty.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "#\tID\tFILENAME\tPRIORITY")
+		_, _ = fmt.Fprintln(w, "-\t--\t--------\t--------")
+		for i, q := range queued {
+			id := q.ID
+			if len(id) > 8 {
+				id = id[:8]
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%d\n", i+1, id, q.Filename, q.Priority)
+		}
+		_ = w.Flush()
+	},
+}
+
+var queueMoveCmd = &cobra.Command{
+	Use:   "move <ID> <top|up|down|bottom>",
+	Short: "Reorder a queued download",
+	Long:  `Move a queued download within the queue. Requires a running Surge instance.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		op := args[1]
+		if !types.IsValidQueueMove(op) {
+			fmt.Fprintf(os.Stderr, "Error: invalid position %q (expected top, up, down or bottom)\n", op)
+			os.Exit(1)
+		}
+
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		port := readActivePort()
+		if port == 0 {
+			fmt.Fprintln(os.Stderr, "Error: Surge is not running; the queue can only be reordered while downloads are queued")
+			os.Exit(1)
+		}
+
+		service := core.NewRemoteDownloadService(fmt.Sprintf("http://127.0.0.1:%d", port), ensureAuthToken())
+		if err := service.ReorderQueue(id, op); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Moved download %s %s\n", id[:8], op)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueMoveCmd)
+}
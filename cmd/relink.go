@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+)
+
+var relinkCmd = &cobra.Command{
+	Use:   "relink <ID> <url>",
+	Short: "Point a paused download at a new URL",
+	Long: `Replace the URL of a paused download, e.g. after its link expired or the
+file moved to another host. The download keeps its progress and continues
+from the new URL when resumed.
+
+The new URL is checked first: it must serve a file of the same size and, if
+both servers report one, with the same ETag. Use --force to skip the check
+when you know it is the same file.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		newURL := strings.TrimSpace(args[1])
+		force, _ := cmd.Flags().GetBool("force")
+
+		if port := readActivePort(); port > 0 {
+			service := core.NewRemoteDownloadService(fmt.Sprintf("http://127.0.0.1:%d", port), ensureAuthToken())
+			if err := service.Relink(id, newURL, nil, force); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Relinked download %s to %s\n", id[:8], newURL)
+			return
+		}
+
+		if err := core.RelinkDownload(id, newURL, nil, force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Relinked download %s to %s (offline mode)\n", id[:8], newURL)
+	},
+}
+
+func init() {
+	relinkCmd.Flags().Bool("force", false, "Skip the size and ETag check")
+	rootCmd.AddCommand(relinkCmd)
+}
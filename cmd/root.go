@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/tui"
 	"github.com/surge-downloader/surge/internal/utils"
 
@@ -51,6 +53,15 @@ var rootCmd = &cobra.Command{
 	Version: Version,
 	Args:    cobra.ArbitraryArgs,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// An alternate settings file must be in place before anything loads settings
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			configPath = os.Getenv(config.EnvConfigPath)
+		}
+		if configPath != "" {
+			config.SetSettingsPath(utils.EnsureAbsPath(configPath))
+		}
+
 		// Initialize Global Progress Channel
 		GlobalProgressCh = make(chan any, 100)
 
@@ -85,6 +96,8 @@ var rootCmd = &cobra.Command{
 
 		// Initialize Service
 		GlobalService = core.NewLocalDownloadServiceWithInput(GlobalPool, GlobalProgressCh)
+		startWebhookDispatcher()
+		startHistoryJanitor()
 
 		portFlag, _ := cmd.Flags().GetInt("port")
 		batchFile, _ := cmd.Flags().GetString("batch")
@@ -125,18 +138,18 @@ var rootCmd = &cobra.Command{
 			var urls []string
 			urls = append(urls, args...)
 
+			if len(urls) > 0 {
+				processDownloads(urls, outputDir, 0, core.DownloadOptions{}) // 0 port = internal direct add
+			}
+
 			if batchFile != "" {
 				fileUrls, err := readURLsFromFile(batchFile)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
 				} else {
-					urls = append(urls, fileUrls...)
+					processDownloads(fileUrls, outputDir, 0, core.DownloadOptions{Group: utils.BatchGroupName(batchFile)})
 				}
 			}
-
-			if len(urls) > 0 {
-				processDownloads(urls, outputDir, 0) // 0 port = internal direct add
-			}
 		}()
 
 		// Start TUI (default mode)
@@ -195,6 +208,24 @@ func startTUI(port int, exitWhenDone bool, noResume bool) {
 	}
 }
 
+// startWebhookDispatcher delivers download events to configured webhook endpoints
+func startWebhookDispatcher() {
+	if GlobalService == nil {
+		return
+	}
+	if err := core.NewWebhookDispatcher(GlobalService).Start(context.Background()); err != nil {
+		utils.Debug("Failed to start webhook dispatcher: %v", err)
+	}
+}
+
+// startHistoryJanitor applies the history retention settings periodically
+func startHistoryJanitor() {
+	if GlobalProgressCh == nil {
+		return
+	}
+	core.NewHistoryJanitor(GlobalProgressCh).Start(context.Background())
+}
+
 // StartHeadlessConsumer starts a goroutine to consume progress messages and log to stdout
 func StartHeadlessConsumer() {
 	go func() {
@@ -254,6 +285,58 @@ func StartHeadlessConsumer() {
 					id = id[:8]
 				}
 				fmt.Printf("Removed: %s [%s]\n", m.Filename, id)
+			case events.DownloadMovedMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				fmt.Printf("Moved: %s [%s] -> %s\n", m.Filename, id, m.DestPath)
+			case events.DownloadRelinkedMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				fmt.Printf("Relinked: %s [%s] -> %s\n", m.Filename, id, m.URL)
+			case events.HistoryPrunedMsg:
+				fmt.Printf("History cleanup: removed %d finished downloads", len(m.DownloadIDs))
+				if m.FilesDeleted > 0 {
+					fmt.Printf(" and deleted %d files", m.FilesDeleted)
+				}
+				fmt.Println()
+			case events.URLRefreshRequestMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				fmt.Printf("Link expired (HTTP %d): %s [%s], waiting for refresh\n", m.StatusCode, m.Filename, id)
+			case events.HookResultMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				if m.Err != "" {
+					fmt.Printf("Hook %s: %s [%s] failed: %s\n", m.Event, m.Filename, id, m.Err)
+				} else {
+					fmt.Printf("Hook %s: %s [%s] exited with status %d\n", m.Event, m.Filename, id, m.ExitCode)
+				}
+			case events.ExtractStartedMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				fmt.Printf("Extracting: %s [%s] -> %s\n", m.Filename, id, m.DestDir)
+			case events.ExtractCompleteMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				fmt.Printf("Extracted: %s [%s] (%d entries in %s)\n", m.Filename, id, m.Entries, m.Elapsed.Round(time.Millisecond))
+			case events.ExtractErrorMsg:
+				id := m.DownloadID
+				if len(id) > 8 {
+					id = id[:8]
+				}
+				fmt.Printf("Extract failed: %s [%s]: %s\n", m.Filename, id, m.Err)
 			}
 		}
 	}()
@@ -306,82 +389,14 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 
 	// SSE Events Endpoint (Protected)
 	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
-		// Set headers for SSE
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-
-		// Get event stream
-		stream, cleanup, err := service.StreamEvents(r.Context())
-		if err != nil {
-			http.Error(w, "Failed to subscribe to events", http.StatusInternalServerError)
-			return
-		}
-		defer cleanup()
-
-		// Flush headers immediately
-		flusher, ok := w.(http.Flusher)
-		if !ok {
-			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-			return
-		}
-		flusher.Flush()
-
-		// Send events
-		// Create a closer notifier
-		done := r.Context().Done()
-
-		for {
-			select {
-			case <-done:
-				return
-			case msg, ok := <-stream:
-				if !ok {
-					return
-				}
-
-				// Encode message to JSON
-				data, err := json.Marshal(msg)
-				if err != nil {
-					utils.Debug("Error marshaling event: %v", err)
-					continue
-				}
-
-				// Determine event type name based on struct
-				// Events are in internal/engine/events package
-				eventType := "unknown"
-				switch msg.(type) {
-				case events.DownloadStartedMsg:
-					eventType = "started"
-				case events.DownloadCompleteMsg:
-					eventType = "complete"
-				case events.DownloadErrorMsg:
-					eventType = "error"
-				case events.ProgressMsg:
-					eventType = "progress"
-				case events.DownloadPausedMsg:
-					eventType = "paused"
-				case events.DownloadResumedMsg:
-					eventType = "resumed"
-				case events.DownloadQueuedMsg:
-					eventType = "queued"
-				case events.DownloadRemovedMsg:
-					eventType = "removed"
-				case events.DownloadRequestMsg:
-					eventType = "request"
-				}
+		handleEvents(w, r, service)
+	})
 
-				// SSE Format:
-				// event: <type>
-				// data: <json>
-				// \n
-				_, _ = fmt.Fprintf(w, "event: %s\n", eventType)
-				_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
-				flusher.Flush()
-			}
-		}
+	// WebSocket: the events above plus commands on one socket (Protected)
+	mux.HandleFunc(core.WebSocketPath, func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(w, r, defaultOutputDir, service)
 	})
+	mux.HandleFunc(core.WebSocketTicketPath, handleWSTicket)
 
 	// Download endpoint (Protected + Public for simple GET status if needed? No, let's protect all for now)
 	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
@@ -495,6 +510,10 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 		}
 	})
 
+	// Versioned API (Protected, except health and the OpenAPI document). The
+	// endpoints above are kept for the browser extension and older clients.
+	mux.Handle(core.APIPrefix+"/", newAPIServer(service, defaultOutputDir, port))
+
 	// Wrap mux with Auth and CORS (CORS outermost to ensure 401/403 include headers)
 	handler := corsMiddleware(authMiddleware(authToken, mux))
 
@@ -504,6 +523,114 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 	}
 }
 
+// handleEvents streams download events as Server-Sent Events
+func handleEvents(w http.ResponseWriter, r *http.Request, service core.DownloadService) {
+	// Set headers for SSE
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// Get event stream
+	stream, cleanup, err := service.StreamEvents(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to subscribe to events", http.StatusInternalServerError)
+		return
+	}
+	defer cleanup()
+
+	// Flush headers immediately
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	flusher.Flush()
+
+	// Send events
+	// Create a closer notifier
+	done := r.Context().Done()
+
+	for {
+		select {
+		case <-done:
+			return
+		case msg, ok := <-stream:
+			if !ok {
+				return
+			}
+
+			// Encode message to JSON
+			data, err := json.Marshal(msg)
+			if err != nil {
+				utils.Debug("Error marshaling event: %v", err)
+				continue
+			}
+
+			// Determine event type name based on struct
+			// Events are in internal/engine/events package
+			eventType := eventTypeName(msg)
+
+			// SSE Format:
+			// event: <type>
+			// data: <json>
+			// \n
+			_, _ = fmt.Fprintf(w, "event: %s\n", eventType)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// eventTypeName is the name an event is sent under on /events
+func eventTypeName(msg interface{}) string {
+	switch msg.(type) {
+	case events.DownloadStartedMsg:
+		return "started"
+	case events.DownloadCompleteMsg:
+		return "complete"
+	case events.DownloadErrorMsg:
+		return "error"
+	case events.ProgressMsg:
+		return "progress"
+	case events.DownloadPausedMsg:
+		return "paused"
+	case events.DownloadResumedMsg:
+		return "resumed"
+	case events.DownloadQueuedMsg:
+		return "queued"
+	case events.DownloadRemovedMsg:
+		return "removed"
+	case events.DownloadRequestMsg:
+		return "request"
+	case events.URLRefreshRequestMsg:
+		return "refresh_request"
+	case events.HookResultMsg:
+		return "hook"
+	case events.ExtractStartedMsg:
+		return "extract_started"
+	case events.ExtractProgressMsg:
+		return "extract_progress"
+	case events.ExtractCompleteMsg:
+		return "extract_complete"
+	case events.ExtractErrorMsg:
+		return "extract_error"
+	case events.DownloadScheduledMsg:
+		return "scheduled"
+	case events.QueueReorderedMsg:
+		return "queue_reordered"
+	case events.ConcurrencyChangedMsg:
+		return "concurrency_changed"
+	case events.HistoryPrunedMsg:
+		return "history_pruned"
+	case events.DownloadMovedMsg:
+		return "moved"
+	case events.DownloadRelinkedMsg:
+		return "relinked"
+	}
+	return "unknown"
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
@@ -523,8 +650,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 
 func authMiddleware(token string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow health check without auth
-		if r.URL.Path == "/health" {
+		// Allow health check (and the API description) without auth
+		if r.URL.Path == "/health" || isPublicAPIPath(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -535,18 +662,26 @@ func authMiddleware(token string, next http.Handler) http.Handler {
 			return
 		}
 
-		// Check for Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
+		// Check for Authorization header. Browsers can't set headers on a
+		// WebSocket, so they open it with a single-use ?ticket= instead.
+		providedToken := ""
+		if authHeader := r.Header.Get("Authorization"); authHeader != "" {
 			if strings.HasPrefix(authHeader, "Bearer ") {
-				providedToken := strings.TrimPrefix(authHeader, "Bearer ")
-				if len(providedToken) == len(token) && subtle.ConstantTimeCompare([]byte(providedToken), []byte(token)) == 1 {
-					next.ServeHTTP(w, r)
-					return
-				}
+				providedToken = strings.TrimPrefix(authHeader, "Bearer ")
 			}
+		} else if isWebSocketPath(r.URL.Path) && wsTickets.redeem(r.URL.Query().Get("ticket")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if providedToken != "" && len(providedToken) == len(token) && subtle.ConstantTimeCompare([]byte(providedToken), []byte(token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
 		}
 
+		if strings.HasPrefix(r.URL.Path, core.APIPrefix+"/") {
+			writeAPIError(w, &core.APIError{Status: http.StatusUnauthorized, Code: core.CodeUnauthorized, Message: "Unauthorized"})
+			return
+		}
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 	})
 }
@@ -575,6 +710,10 @@ type DownloadRequest struct {
 	Mirrors              []string          `json:"mirrors,omitempty"`
 	SkipApproval         bool              `json:"skip_approval,omitempty"` // Extension validated request, skip TUI prompt
 	Headers              map[string]string `json:"headers,omitempty"`       // Custom HTTP headers from browser (cookies, auth, etc.)
+	At                   string            `json:"at,omitempty"`            // Local start time, e.g. "2026-10-17 02:00" (sets start_at)
+	After                string            `json:"after,omitempty"`         // Start delay, e.g. "2h" (sets start_at)
+
+	core.DownloadOptions // Per-download options (hooks, ...)
 }
 
 func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir string, service core.DownloadService) {
@@ -610,13 +749,6 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		return
 	}
 
-	// Load settings once for use throughout the function
-	settings, err := config.LoadSettings()
-	if err != nil {
-		// Fallback to defaults if loading fails (though LoadSettings handles missing file)
-		settings = config.DefaultSettings()
-	}
-
 	var req DownloadRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
@@ -628,26 +760,109 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		}
 	}()
 
-	if req.URL == "" {
-		http.Error(w, "URL is required", http.StatusBadRequest)
+	resp, apiErr := submitDownload(&req, defaultOutputDir, service)
+	if apiErr != nil {
+		if apiErr.Code == core.CodeApprovalRequired {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(apiErr.Status)
+			if err := json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": apiErr.Message,
+			}); err != nil {
+				utils.Debug("Failed to encode response: %v", err)
+			}
+			return
+		}
+		http.Error(w, apiErr.Message, apiErr.Status)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status == "pending_approval" {
+		// Return 202 Accepted to indicate it's pending approval
+		w.WriteHeader(http.StatusAccepted)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
+	}
+}
+
+// downloadResponse answers an accepted download request
+type downloadResponse struct {
+	Status  string `json:"status"` // queued, scheduled or pending_approval
+	Message string `json:"message"`
+	ID      string `json:"id"`
+}
+
+// checkHookCommands refuses per-download hook commands missing from
+// hooks.allowed_commands. Adding a download only takes the API token, which
+// the browser extension holds too, so it must not run arbitrary commands.
+func checkHookCommands(opts core.DownloadOptions, allowed []string) *core.APIError {
+	for _, command := range []string{opts.OnComplete, opts.OnError, opts.OnPause} {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+		if !slices.ContainsFunc(allowed, func(a string) bool { return strings.TrimSpace(a) == command }) {
+			return &core.APIError{
+				Status:  http.StatusForbidden,
+				Code:    core.CodeHookNotAllowed,
+				Message: fmt.Sprintf("Hook command %q is not in hooks.allowed_commands", command),
+			}
+		}
+	}
+	return nil
+}
+
+// submitDownload validates a download request and queues it, or sends it to
+// the TUI for approval. It is shared by /download and the versioned API.
+func submitDownload(req *DownloadRequest, defaultOutputDir string, service core.DownloadService) (*downloadResponse, *core.APIError) {
+	// Load settings once for use throughout the function
+	settings, err := config.LoadSettings()
+	if err != nil {
+		// Fallback to defaults if loading fails (though LoadSettings handles missing file)
+		settings = config.DefaultSettings()
+	}
+
+	if req.URL == "" {
+		return nil, badRequest("URL is required")
+	}
+
 	if strings.Contains(req.Path, "..") || strings.Contains(req.Filename, "..") {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
-		return
+		return nil, badRequest("Invalid path")
 	}
 	if strings.Contains(req.Filename, "/") || strings.Contains(req.Filename, "\\") {
-		http.Error(w, "Invalid filename", http.StatusBadRequest)
-		return
+		return nil, badRequest("Invalid filename")
+	}
+	if req.Conflict != "" && !config.IsValidConflictPolicy(req.Conflict) {
+		return nil, badRequest("Invalid conflict policy")
+	}
+	if apiErr := checkHookCommands(req.DownloadOptions, settings.Hooks.AllowedCommands); apiErr != nil {
+		return nil, apiErr
+	}
+	if req.At != "" || req.After != "" {
+		startAt, err := utils.ParseStartTime(req.At, req.After, time.Now())
+		if err != nil {
+			return nil, badRequest("Invalid start time: " + err.Error())
+		}
+		req.StartAt = startAt
+	}
+	req.Tags = types.NormalizeTags(req.Tags)
+	if req.Referrer == "" {
+		// Older extensions only forward the browser's Referer header
+		for name, value := range req.Headers {
+			if strings.EqualFold(name, "Referer") {
+				req.Referrer = value
+				break
+			}
+		}
 	}
 
 	utils.Debug("Received download request: URL=%s, Path=%s", req.URL, req.Path)
 
 	downloadID := uuid.New().String()
 	if service == nil {
-		http.Error(w, "Service unavailable", http.StatusInternalServerError)
-		return
+		return nil, internalError("Service unavailable")
 	}
 
 	// Prepare output path
@@ -663,23 +878,20 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		}
 		outPath = filepath.Join(baseDir, req.Path)
 		if err := os.MkdirAll(outPath, 0o755); err != nil {
-			http.Error(w, "Failed to create directory: "+err.Error(), http.StatusInternalServerError)
-			return
+			return nil, internalError("Failed to create directory: " + err.Error())
 		}
 
 	} else if outPath == "" {
 		if defaultOutputDir != "" {
 			outPath = defaultOutputDir
 			if err := os.MkdirAll(outPath, 0o755); err != nil {
-				http.Error(w, "Failed to create output directory: "+err.Error(), http.StatusInternalServerError)
-				return
+				return nil, internalError("Failed to create output directory: " + err.Error())
 			}
 		} else {
 			if settings.General.DefaultDownloadDir != "" {
 				outPath = settings.General.DefaultDownloadDir
 				if err := os.MkdirAll(outPath, 0o755); err != nil {
-					http.Error(w, "Failed to create output directory: "+err.Error(), http.StatusInternalServerError)
-					return
+					return nil, internalError("Failed to create output directory: " + err.Error())
 				}
 			} else {
 				outPath = "."
@@ -742,62 +954,60 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 					Path:     outPath, // Use the path we resolved (default or requested)
 					Mirrors:  mirrorsForAdd,
 					Headers:  req.Headers,
-				}); err != nil {
-					http.Error(w, "Failed to notify TUI: "+err.Error(), http.StatusInternalServerError)
-					return
-				}
+					Conflict: req.Conflict,
+					Priority: req.Priority,
+					StartAt:  req.StartAt,
+					Group:    req.Group,
 
-				w.Header().Set("Content-Type", "application/json")
-				// Return 202 Accepted to indicate it's pending approval
-				w.WriteHeader(http.StatusAccepted)
-				if err := json.NewEncoder(w).Encode(map[string]string{
-					"status":  "pending_approval",
-					"message": "Download request sent to TUI for confirmation",
-					"id":      downloadID, // ID might change if user modifies it, but useful for tracking
+					DownloadMeta: req.DownloadMeta,
 				}); err != nil {
-					utils.Debug("Failed to encode response: %v", err)
+					return nil, internalError("Failed to notify TUI: " + err.Error())
 				}
-				return
+
+				return &downloadResponse{
+					Status:  "pending_approval",
+					Message: "Download request sent to TUI for confirmation",
+					ID:      downloadID, // ID might change if user modifies it, but useful for tracking
+				}, nil
 			} else {
 				// Headless mode check
 				if settings.General.ExtensionPrompt || (settings.General.WarnOnDuplicate && isDuplicate) {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusConflict)
-					if err := json.NewEncoder(w).Encode(map[string]string{
-						"status":  "error",
-						"message": "Download rejected: Duplicate download or approval required (Headless mode)",
-					}); err != nil {
-						utils.Debug("Failed to encode response: %v", err)
+					return nil, &core.APIError{
+						Status:  http.StatusConflict,
+						Code:    core.CodeApprovalRequired,
+						Message: "Download rejected: Duplicate download or approval required (Headless mode)",
 					}
-					return
 				}
 			}
 		}
 	}
 
-	// Add via service
-	newID, err := service.Add(urlForAdd, outPath, req.Filename, mirrorsForAdd, req.Headers)
+	// Add via service; category rules only apply when neither the client nor
+	// the server's --output picked a directory
+	req.AutoRoute = req.Path == "" && defaultOutputDir == ""
+	newID, err := service.AddWithOptions(urlForAdd, outPath, req.Filename, mirrorsForAdd, req.Headers, req.DownloadOptions)
 	if err != nil {
-		http.Error(w, "Failed to add download: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, internalError("Failed to add download: " + err.Error())
 	}
 
 	// Increment active downloads counter
 	atomic.AddInt32(&activeDownloads, 1)
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status":  "queued",
-		"message": "Download queued successfully",
-		"id":      newID,
-	}); err != nil {
-		utils.Debug("Failed to encode response: %v", err)
+	resp := &downloadResponse{
+		Status:  "queued",
+		Message: "Download queued successfully",
+		ID:      newID,
 	}
+	if req.StartAt.After(time.Now()) {
+		resp.Status = "scheduled"
+		resp.Message = "Download scheduled for " + req.StartAt.Format(time.RFC3339)
+	}
+	return resp, nil
 }
 
 // processDownloads handles the logic of adding downloads either to local pool or remote server
 // Returns the number of successfully added downloads
-func processDownloads(urls []string, outputDir string, port int) int {
+func processDownloads(urls []string, outputDir string, port int, opts core.DownloadOptions) int {
 	successCount := 0
 
 	// If port > 0, we are sending to a remote server
@@ -808,7 +1018,7 @@ func processDownloads(urls []string, outputDir string, port int) int {
 			if url == "" {
 				continue
 			}
-			err := sendToServer(url, mirrors, outputDir, port)
+			err := sendToServer(url, mirrors, outputDir, port, opts)
 			if err != nil {
 				fmt.Printf("Error adding %s: %v\n", url, err)
 			} else {
@@ -860,7 +1070,9 @@ func processDownloads(urls []string, outputDir string, port int) int {
 		// But processDownloads is called from QUEUE init routine, primarily for CLI args.
 		// If CLI args provided, user probably wants them added immediately.
 
-		_, err := GlobalService.Add(url, outPath, "", mirrors, nil)
+		addOpts := opts
+		addOpts.AutoRoute = outputDir == ""
+		_, err := GlobalService.AddWithOptions(url, outPath, "", mirrors, nil, addOpts)
 		if err != nil {
 			fmt.Printf("Error adding %s: %v\n", url, err)
 			continue
@@ -879,6 +1091,7 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("config", "", "Settings file to use instead of settings.json in the Surge config directory")
 	rootCmd.Flags().StringP("batch", "b", "", "File containing URLs to download (one per line)")
 	rootCmd.Flags().IntP("port", "p", 0, "Port to listen on (default: 8080 or first available)")
 	rootCmd.Flags().StringP("output", "o", "", "Default output directory")
@@ -911,6 +1124,8 @@ func initializeGlobalState() {
 		retention = config.DefaultSettings().General.LogRetentionCount
 	}
 	utils.CleanupLogs(retention)
+
+	warnSettingsProblems()
 }
 
 func resumePausedDownloads() {
@@ -181,6 +181,8 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 
 	// Initialize Service
 	GlobalService = core.NewLocalDownloadServiceWithInput(GlobalPool, GlobalProgressCh)
+	startWebhookDispatcher()
+	startHistoryJanitor()
 
 	saveActivePort(port)
 	defer removeActivePort()
@@ -192,18 +194,18 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 		var urls []string
 		urls = append(urls, args...)
 
+		if len(urls) > 0 {
+			processDownloads(urls, outputDir, 0, core.DownloadOptions{})
+		}
+
 		if batchFile != "" {
 			fileUrls, err := readURLsFromFile(batchFile)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
 			} else {
-				urls = append(urls, fileUrls...)
+				processDownloads(fileUrls, outputDir, 0, core.DownloadOptions{Group: utils.BatchGroupName(batchFile)})
 			}
 		}
-
-		if len(urls) > 0 {
-			processDownloads(urls, outputDir, 0)
-		}
 	}()
 
 	fmt.Printf("Surge %s running in server mode.\n", Version)
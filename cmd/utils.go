@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
@@ -65,11 +66,12 @@ func ParseURLArg(arg string) (string, []string) {
 }
 
 // sendToServer sends a download request to a running surge server
-func sendToServer(url string, mirrors []string, outPath string, port int) error {
+func sendToServer(url string, mirrors []string, outPath string, port int, opts core.DownloadOptions) error {
 	reqBody := DownloadRequest{
-		URL:     url,
-		Mirrors: mirrors,
-		Path:    outPath,
+		URL:             url,
+		Mirrors:         mirrors,
+		Path:            outPath,
+		DownloadOptions: opts,
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// wsOriginPatterns are the browser origins allowed to open the WebSocket
+// besides the daemon's own: the browser extension and pages served from
+// this machine. Clients outside a browser send no Origin.
+var wsOriginPatterns = []string{
+	"chrome-extension://*",
+	"moz-extension://*",
+	"localhost", "localhost:*",
+	"127.0.0.1", "127.0.0.1:*",
+	"[::1]", "[::1]:*",
+}
+
+// wsTickets holds the tickets issued for opening the WebSocket that have
+// not been used yet
+var wsTickets = &ticketStore{tickets: make(map[string]time.Time)}
+
+// ticketStore hands out single-use tickets that expire after
+// core.WebSocketTicketTTL
+type ticketStore struct {
+	mu      sync.Mutex
+	tickets map[string]time.Time // Ticket to expiry
+}
+
+// issue returns a new ticket
+func (s *ticketStore) issue() (core.WSTicket, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return core.WSTicket{}, err
+	}
+	now := time.Now()
+	ticket := core.WSTicket{Ticket: hex.EncodeToString(b), ExpiresAt: now.Add(core.WebSocketTicketTTL)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, expires := range s.tickets {
+		if now.After(expires) {
+			delete(s.tickets, t)
+		}
+	}
+	s.tickets[ticket.Ticket] = ticket.ExpiresAt
+	return ticket, nil
+}
+
+// redeem reports whether ticket is valid, using it up
+func (s *ticketStore) redeem(ticket string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	return ok && time.Now().Before(expires)
+}
+
+// handleWSTicket issues a ticket for opening the WebSocket without an
+// Authorization header. Reaching it takes the token.
+func handleWSTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, &core.APIError{Status: http.StatusMethodNotAllowed, Code: core.CodeMethodNotAllowed, Message: "Method not allowed"})
+		return
+	}
+	ticket, err := wsTickets.issue()
+	if err != nil {
+		writeAPIError(w, internalError("Failed to issue ticket: "+err.Error()))
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, ticket)
+}
+
+// handleWebSocket carries the event stream of /events over a WebSocket and
+// answers commands (add, pause, resume, delete) sent on the same socket
+func handleWebSocket(w http.ResponseWriter, r *http.Request, defaultOutputDir string, service core.DownloadService) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: wsOriginPatterns})
+	if err != nil {
+		utils.Debug("WebSocket handshake failed: %v", err)
+		return
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	// The request's context ends with the handler's HTTP lifetime, not the socket's
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, cleanup, err := service.StreamEvents(ctx)
+	if err != nil {
+		_ = wsjson.Write(ctx, conn, core.WSMessage{Status: http.StatusInternalServerError, Error: internalError("Failed to subscribe to events")})
+		return
+	}
+	defer cleanup()
+
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			var cmd core.WSCommand
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				apiErr := badRequest("Invalid JSON: " + err.Error())
+				_ = wsjson.Write(ctx, conn, core.WSMessage{Status: apiErr.Status, Error: apiErr})
+				continue
+			}
+			// A slow command (a pause waiting on its workers) must not hold up
+			// the ones behind it; replies carry the command ID to match them up
+			go func() {
+				if err := wsjson.Write(ctx, conn, runWSCommand(cmd, defaultOutputDir, service)); err != nil {
+					cancel()
+				}
+			}()
+		}
+	}()
+
+	ping := time.NewTicker(core.WebSocketPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			// Clients answer our pings, so silence means the client is gone
+			pingCtx, cancelPing := context.WithTimeout(ctx, core.WebSocketPingInterval)
+			err := conn.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				return
+			}
+		case msg, ok := <-stream:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(msg)
+			if err != nil {
+				utils.Debug("Error marshaling event: %v", err)
+				continue
+			}
+			if err := wsjson.Write(ctx, conn, core.WSMessage{Event: eventTypeName(msg), Data: data}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// runWSCommand carries out a command received over the WebSocket and builds
+// its answer, with the same result or error as the matching API endpoint
+func runWSCommand(cmd core.WSCommand, defaultOutputDir string, service core.DownloadService) core.WSMessage {
+	reply := core.WSMessage{ReplyTo: cmd.ID, Status: http.StatusOK}
+	fail := func(apiErr *core.APIError) core.WSMessage {
+		reply.Status = apiErr.Status
+		reply.Error = apiErr
+		return reply
+	}
+
+	var result any
+	switch cmd.Op {
+	case core.WSOpAdd:
+		var req DownloadRequest
+		if err := json.Unmarshal(cmd.Download, &req); err != nil {
+			return fail(badRequest("Invalid download: " + err.Error()))
+		}
+		resp, apiErr := submitDownload(&req, defaultOutputDir, service)
+		if apiErr != nil {
+			return fail(apiErr)
+		}
+		reply.Status = http.StatusCreated
+		if resp.Status == "pending_approval" {
+			reply.Status = http.StatusAccepted
+		}
+		result = resp
+
+	case core.WSOpPause, core.WSOpResume, core.WSOpDelete:
+		id := cmd.DownloadID
+		if id == "" {
+			return fail(badRequest("Missing download_id"))
+		}
+		var err error
+		switch cmd.Op {
+		case core.WSOpPause:
+			err = service.Pause(id)
+			result = statusResponse("paused", id)
+		case core.WSOpResume:
+			err = service.Resume(id)
+			result = statusResponse("resumed", id)
+		case core.WSOpDelete:
+			err = service.Delete(id)
+			result = statusResponse("deleted", id)
+		}
+		if err != nil {
+			return fail(core.NewAPIError(err))
+		}
+
+	default:
+		return fail(badRequest("Unknown op: " + cmd.Op))
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fail(internalError(err.Error()))
+	}
+	reply.Data = data
+	return reply
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// wsTicket asks for a ticket to open the WebSocket with
+func wsTicket(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	var ticket core.WSTicket
+	resp := apiRequest(t, srv, http.MethodPost, core.WebSocketTicketPath, "", &ticket)
+	if resp.StatusCode != http.StatusCreated || ticket.Ticket == "" || !ticket.ExpiresAt.After(time.Now()) {
+		t.Fatalf("POST %s = %d %+v", core.WebSocketTicketPath, resp.StatusCode, ticket)
+	}
+	return ticket.Ticket
+}
+
+// wsReply sends a command and returns its answer, skipping events
+func wsReply(t *testing.T, ctx context.Context, conn *websocket.Conn, cmd core.WSCommand) core.WSMessage {
+	t.Helper()
+	if err := wsjson.Write(ctx, conn, cmd); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var msg core.WSMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("no answer to %s: %v", cmd.Op, err)
+		}
+		if msg.ReplyTo == cmd.ID {
+			return msg
+		}
+	}
+}
+
+func TestWebSocket_Commands(t *testing.T) {
+	srv, _ := newTestAPI(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wsURL := srv.URL + core.APIPrefix + core.WebSocketPath
+	for _, query := range []string{"", "?token=" + testAPIToken, "?ticket=made-up"} {
+		_, resp, err := websocket.Dial(ctx, wsURL+query, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Dial %q: error = %v, want a 401 handshake error", query, err)
+		}
+	}
+
+	// Browsers open it with a ticket, from the extension or a local page
+	ticket := wsTicket(t, srv)
+	conn, _, err := websocket.Dial(ctx, wsURL+"?ticket="+ticket, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"chrome-extension://abcdefghijklmnop"}},
+	})
+	if err != nil {
+		t.Fatalf("Dial with ?ticket= failed: %v", err)
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	// A ticket opens one connection
+	if _, resp, err := websocket.Dial(ctx, wsURL+"?ticket="+ticket, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("reused ticket: error = %v, want a 401 handshake error", err)
+	}
+	// Other web pages can't use the socket even with a ticket
+	_, resp, err := websocket.Dial(ctx, wsURL+"?ticket="+wsTicket(t, srv), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://evil.example"}},
+	})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign origin: error = %v, want a 403 handshake error", err)
+	}
+
+	reply := wsReply(t, ctx, conn, core.WSCommand{ID: "1", Op: core.WSOpPause, DownloadID: "missing"})
+	if reply.Error == nil || reply.Error.Code != core.CodeNotFound || reply.Status != http.StatusNotFound {
+		t.Errorf("pause missing = %+v, want a 404 not_found error", reply)
+	}
+	reply = wsReply(t, ctx, conn, core.WSCommand{ID: "2", Op: "explode"})
+	if reply.Error == nil || reply.Error.Code != core.CodeBadRequest {
+		t.Errorf("unknown op = %+v, want bad_request", reply)
+	}
+	reply = wsReply(t, ctx, conn, core.WSCommand{ID: "3", Op: core.WSOpAdd, Download: json.RawMessage(`{"path":"/tmp"}`)})
+	if reply.Error == nil || reply.Status != http.StatusBadRequest {
+		t.Errorf("add without URL = %+v, want a 400 error", reply)
+	}
+
+	reply = wsReply(t, ctx, conn, core.WSCommand{ID: "4", Op: core.WSOpDelete, DownloadID: "paused-2"})
+	var result map[string]string
+	if reply.Error != nil || json.Unmarshal(reply.Data, &result) != nil || result["status"] != "deleted" {
+		t.Fatalf("delete = %+v", reply)
+	}
+
+	// The removal is also sent as an event
+	for {
+		var msg core.WSMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("expected a removed event: %v", err)
+		}
+		if msg.Event == "removed" && strings.Contains(string(msg.Data), "paused-2") {
+			break
+		}
+	}
+}
+
+// slowPauseService holds every Pause until release is closed
+type slowPauseService struct {
+	core.DownloadService
+	release chan struct{}
+}
+
+func (s slowPauseService) Pause(string) error {
+	<-s.release
+	return nil
+}
+
+func TestWebSocket_SlowCommandDoesNotBlock(t *testing.T) {
+	_, svc := newTestAPI(t)
+	slow := slowPauseService{DownloadService: svc, release: make(chan struct{})}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(w, r, t.TempDir(), slow)
+	}))
+	defer srv.Close()
+	defer close(slow.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	if err := wsjson.Write(ctx, conn, core.WSCommand{ID: "slow", Op: core.WSOpPause, DownloadID: "paused-0"}); err != nil {
+		t.Fatal(err)
+	}
+	// Later commands are still read and answered while the pause waits
+	reply := wsReply(t, ctx, conn, core.WSCommand{ID: "fast", Op: core.WSOpResume, DownloadID: "missing"})
+	if reply.Error == nil || reply.Error.Code != core.CodeNotFound {
+		t.Errorf("resume missing = %+v, want not_found", reply)
+	}
+}
+
+// requestLog records the method and path of every request
+type requestLog struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (l *requestLog) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.mu.Lock()
+		l.requests = append(l.requests, r.Method+" "+r.URL.Path)
+		l.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *requestLog) has(prefix string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, req := range l.requests {
+		if strings.HasPrefix(req, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForStream publishes events until one comes through the remote stream
+func waitForStream(t *testing.T, svc *core.LocalDownloadService, stream <-chan interface{}) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		_ = svc.Publish(events.ConcurrencyChangedMsg{MaxDownloads: 2})
+		select {
+		case msg := <-stream:
+			if _, ok := msg.(events.ConcurrencyChangedMsg); ok {
+				return
+			}
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no events came through the remote stream")
+		}
+	}
+}
+
+func TestRemoteDownloadService_WebSocket(t *testing.T) {
+	var log requestLog
+	srv, svc := newTestAPI(t, log.wrap)
+
+	remote := core.NewRemoteDownloadService(srv.URL, testAPIToken)
+	defer func() { _ = remote.Shutdown() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, cleanup, err := remote.StreamEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	waitForStream(t, svc, stream)
+
+	if err := remote.Pause("missing"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("Pause(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := remote.Delete("paused-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if log.has("DELETE ") || log.has("POST ") || log.has("GET "+core.APIPrefix+"/events") {
+		t.Errorf("requests = %v, want everything over the WebSocket", log.requests)
+	}
+	if !log.has("GET " + core.APIPrefix + core.WebSocketPath) {
+		t.Errorf("requests = %v, want a WebSocket connection", log.requests)
+	}
+}
+
+func TestRemoteDownloadService_FallsBackToSSE(t *testing.T) {
+	var log requestLog
+	withoutWebSocket := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// An older daemon without the WebSocket
+			if r.URL.Path == core.APIPrefix+core.WebSocketPath {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	srv, svc := newTestAPI(t, withoutWebSocket, log.wrap)
+
+	remote := core.NewRemoteDownloadService(srv.URL, testAPIToken)
+	defer func() { _ = remote.Shutdown() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, cleanup, err := remote.StreamEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	waitForStream(t, svc, stream)
+
+	if err := remote.Delete("paused-1"); err != nil {
+		t.Fatalf("Delete over HTTP failed: %v", err)
+	}
+	if !log.has("GET "+core.APIPrefix+"/events") || !log.has("DELETE "+core.APIPrefix+"/downloads/paused-1") {
+		t.Errorf("requests = %v, want SSE and a plain DELETE", log.requests)
+	}
+}
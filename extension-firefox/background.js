@@ -267,7 +267,7 @@ async function validateAuthToken() {
 
 // === Download Sending ===
 
-async function sendToSurge(url, filename, absolutePath) {
+async function sendToSurge(url, filename, absolutePath, referrer) {
   const port = await findSurgePort();
   if (!port) {
     console.error('[Surge] No server found');
@@ -285,6 +285,11 @@ async function sendToSurge(url, filename, absolutePath) {
       body.path = absolutePath;
     }
 
+    // Page the download was started from, kept as source metadata
+    if (referrer) {
+      body.referrer = referrer;
+    }
+
     // Include captured headers for authenticated downloads
     const headers = getCapturedHeaders(url);
     if (headers) {
@@ -463,6 +468,214 @@ function extractPathInfo(downloadItem) {
   return { filename, directory };
 }
 
+// === URL Refresh ===
+// With url_refresh_extension on, Surge sends a refresh_request event when a
+// download's link expires. We ask the user to start the download again from
+// its page, then catch the new browser download and hand its URL to Surge
+// instead of starting a second download.
+
+const REFRESH_ALARM = 'surgeEventSocket';
+const REFRESH_MAX_AGE_MS = 600000; // In case the socket missed the end of the wait
+
+// Downloads waiting on a fresh link
+// Key: Surge download ID, Value: { url, filename, referrer, timestamp }
+const pendingRefreshes = new Map();
+let eventSocket = null;
+let eventSocketConnecting = false;
+
+// Listen for Surge events on its WebSocket. Browsers can't send the token on
+// a WebSocket, so we open it with a single-use ticket.
+async function connectEventSocket() {
+  if (eventSocket || eventSocketConnecting) return;
+  eventSocketConnecting = true;
+
+  try {
+    const port = await findSurgePort();
+    if (!port) return;
+
+    const auth = await authHeaders();
+    const controller = new AbortController();
+    const timeoutId = setTimeout(() => controller.abort(), 5000);
+    const response = await fetch(`http://127.0.0.1:${port}/api/v1/ws/ticket`, {
+      method: 'POST',
+      headers: auth,
+      signal: controller.signal,
+    });
+    clearTimeout(timeoutId);
+    if (!response.ok) return;
+    const { ticket } = await response.json();
+
+    const socket = new WebSocket(`ws://127.0.0.1:${port}/api/v1/ws?ticket=${encodeURIComponent(ticket)}`);
+    socket.onmessage = (event) => {
+      let msg;
+      try {
+        msg = JSON.parse(event.data);
+      } catch {
+        return;
+      }
+      if (msg.event === 'refresh_request') {
+        handleRefreshRequest(msg.data);
+      } else if (msg.data && pendingRefreshes.has(msg.data.DownloadID)) {
+        // Surge stops waiting once the download fails, finishes or is removed
+        if (['error', 'complete', 'removed'].includes(msg.event)) {
+          clearPendingRefresh(msg.data.DownloadID);
+        }
+      }
+    };
+    socket.onclose = () => {
+      if (eventSocket === socket) {
+        eventSocket = null;
+      }
+    };
+    eventSocket = socket;
+  } catch (error) {
+    console.log('[Surge] Could not open event socket:', error.message);
+  } finally {
+    eventSocketConnecting = false;
+  }
+}
+
+// Reconnect if the socket dropped; the alarm also wakes the event page
+browser.alarms.create(REFRESH_ALARM, { periodInMinutes: 1 });
+browser.alarms.onAlarm.addListener((alarm) => {
+  if (alarm.name === REFRESH_ALARM) {
+    connectEventSocket();
+  }
+});
+
+async function fetchReferrer(id) {
+  const port = await findSurgePort();
+  if (!port) return '';
+
+  try {
+    const auth = await authHeaders();
+    const controller = new AbortController();
+    const timeoutId = setTimeout(() => controller.abort(), 5000);
+    const response = await fetch(`http://127.0.0.1:${port}/api/v1/downloads/${encodeURIComponent(id)}`, {
+      headers: auth,
+      signal: controller.signal,
+    });
+    clearTimeout(timeoutId);
+    if (!response.ok) return '';
+    const data = await response.json();
+    return data.referrer || '';
+  } catch {
+    return '';
+  }
+}
+
+async function handleRefreshRequest(req) {
+  if (!req || !req.DownloadID) return;
+
+  const referrer = await fetchReferrer(req.DownloadID);
+  pendingRefreshes.set(req.DownloadID, {
+    url: req.URL,
+    filename: req.Filename,
+    referrer,
+    timestamp: Date.now(),
+  });
+
+  const name = req.Filename || req.URL.split('/').pop();
+  browser.notifications.create(`surge-refresh-${req.DownloadID}`, {
+    type: 'basic',
+    iconUrl: 'icons/icon48.png',
+    title: 'Surge - Link Expired',
+    message: referrer
+      ? `Click to reopen the page, then start ${name} again`
+      : `Start ${name} again in the browser to refresh its link`,
+  });
+}
+
+function clearPendingRefresh(id) {
+  pendingRefreshes.delete(id);
+  browser.notifications.clear(`surge-refresh-${id}`);
+}
+
+// Find the pending refresh a new browser download answers: the same file
+// name, or the same host and path with a new query string
+function findPendingRefresh(downloadItem) {
+  const { filename } = extractPathInfo(downloadItem);
+  let url;
+  try {
+    url = new URL(downloadItem.finalUrl || downloadItem.url);
+  } catch {
+    return null;
+  }
+
+  for (const [id, pending] of pendingRefreshes) {
+    if (Date.now() - pending.timestamp > REFRESH_MAX_AGE_MS) {
+      clearPendingRefresh(id);
+      continue;
+    }
+
+    if (filename && filename === pending.filename) {
+      return id;
+    }
+    try {
+      const old = new URL(pending.url);
+      if (old.host === url.host && old.pathname === url.pathname) {
+        return id;
+      }
+    } catch {
+      // Keep looking
+    }
+  }
+  return null;
+}
+
+async function sendRefreshedUrl(id, downloadItem) {
+  const port = await findSurgePort();
+  if (!port) return false;
+
+  const url = downloadItem.finalUrl || downloadItem.url;
+  const body = { url };
+  const headers = getCapturedHeaders(url) || getCapturedHeaders(downloadItem.url);
+  if (headers) {
+    body.headers = headers;
+  }
+
+  try {
+    const auth = await authHeaders();
+    const response = await fetch(`http://127.0.0.1:${port}/api/v1/downloads/${encodeURIComponent(id)}/refresh`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...auth,
+      },
+      body: JSON.stringify(body),
+    });
+    if (!response.ok) {
+      console.error('[Surge] Failed to refresh download:', response.status, await response.text());
+    }
+    return response.ok;
+  } catch (error) {
+    console.error('[Surge] Error refreshing download:', error);
+    return false;
+  }
+}
+
+async function handleRefreshDownload(id, downloadItem) {
+  try {
+    await browser.downloads.cancel(downloadItem.id);
+    await browser.downloads.erase({ id: downloadItem.id });
+  } catch (e) {
+    console.log('[Surge] Error canceling refresh download:', e);
+  }
+
+  const pending = pendingRefreshes.get(id);
+  clearPendingRefresh(id);
+
+  const success = await sendRefreshedUrl(id, downloadItem);
+  browser.notifications.create({
+    type: 'basic',
+    iconUrl: 'icons/icon48.png',
+    title: success ? 'Surge' : 'Surge Error',
+    message: success
+      ? `Link refreshed: ${pending.filename || pending.url.split('/').pop()}`
+      : 'Failed to send the new link to Surge',
+  });
+}
+
 // === Download Interception ===
 // Firefox doesn't support onDeterminingFilename, so we use a two-phase approach:
 // 1. onCreated: Store the download as "pending" 
@@ -474,6 +687,15 @@ browser.downloads.onCreated.addListener(async (downloadItem) => {
   if (processedIds.has(downloadItem.id)) {
     return;
   }
+
+  // A download started again for an expired link refreshes it in Surge
+  const refreshId = findPendingRefresh(downloadItem);
+  if (refreshId) {
+    processedIds.add(downloadItem.id);
+    setTimeout(() => processedIds.delete(downloadItem.id), 120000);
+    await handleRefreshDownload(refreshId, downloadItem);
+    return;
+  }
   
   console.log('[Surge] Download created:', downloadItem.url);
 
@@ -569,7 +791,8 @@ async function handleDownloadIntercept(downloadItem) {
     const result = await sendToSurge(
       downloadItem.url,
       filename,
-      "" 
+      "",
+      downloadItem.referrer
     );
 
     if (result.success) {
@@ -623,6 +846,12 @@ browser.notifications.onClicked.addListener((notificationId) => {
     }
     // Clear notification
     browser.notifications.clear(notificationId);
+  } else if (notificationId.startsWith('surge-refresh-')) {
+    // Reopen the page the download came from so the user can start it again
+    const pending = pendingRefreshes.get(notificationId.slice('surge-refresh-'.length));
+    if (pending && pending.referrer) {
+      browser.tabs.create({ url: pending.referrer });
+    }
   }
 });
 
@@ -698,7 +927,8 @@ browser.runtime.onMessage.addListener((message, sender) => {
             const result = await sendToSurge(
               pending.url,
               pending.filename,
-              pending.directory
+              pending.directory,
+              pending.downloadItem.referrer
             );
             console.log('[Surge] sendToSurge result:', result);
             
@@ -772,6 +1002,7 @@ browser.runtime.onMessage.addListener((message, sender) => {
 async function initialize() {
   console.log('[Surge] Extension initializing...');
   await checkSurgeHealth();
+  await connectEventSocket();
   console.log('[Surge] Extension loaded');
 }
 
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CategoryRule routes matching downloads to Dir. A rule matches when any of
+// its conditions does; rules are checked in order and the first match wins.
+type CategoryRule struct {
+	Name       string   `json:"name"`
+	Dir        string   `json:"dir"`                   // Destination directory ("~" expands to home)
+	Extensions []string `json:"extensions,omitempty"`  // File extensions without the dot, e.g. "iso"
+	MimeTypes  []string `json:"mime_types,omitempty"`  // Exact ("video/mp4") or prefix ("video/") matches
+	Hosts      []string `json:"hosts,omitempty"`       // Host or parent domain, e.g. "example.com"
+	URLPattern string   `json:"url_pattern,omitempty"` // Go regular expression matched against the URL
+
+	urlRE *regexp.Regexp // URLPattern, set by CompileCategoryRules
+}
+
+// CompileCategoryRules compiles each rule's URL pattern once, so matching
+// never recompiles it. Rules with an invalid pattern never match by URL;
+// their errors are returned, naming the rule by its settings.json path.
+func CompileCategoryRules(rules []CategoryRule) []error {
+	var errs []error
+	for i := range rules {
+		rules[i].urlRE = nil
+		if rules[i].URLPattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(rules[i].URLPattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("categories[%d].url_pattern: %w", i, err))
+			continue
+		}
+		rules[i].urlRE = re
+	}
+	return errs
+}
+
+// MatchCategory returns the first rule that matches the download, if any.
+// contentType is the server's Content-Type header and may be empty.
+func MatchCategory(rules []CategoryRule, rawURL, filename, contentType string) (CategoryRule, bool) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	mediaType := ""
+	if contentType != "" {
+		if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+			mediaType = strings.ToLower(mt)
+		}
+	}
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+		if ext == "" {
+			ext = strings.TrimPrefix(strings.ToLower(path.Ext(u.Path)), ".")
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.Dir == "" {
+			continue
+		}
+		if rule.matches(rawURL, ext, mediaType, host) {
+			return rule, true
+		}
+	}
+	return CategoryRule{}, false
+}
+
+func (r CategoryRule) matches(rawURL, ext, mediaType, host string) bool {
+	for _, e := range r.Extensions {
+		if ext != "" && strings.EqualFold(strings.TrimPrefix(e, "."), ext) {
+			return true
+		}
+	}
+	for _, m := range r.MimeTypes {
+		m = strings.ToLower(m)
+		if mediaType == "" || m == "" {
+			continue
+		}
+		if mediaType == m || (strings.HasSuffix(m, "/") && strings.HasPrefix(mediaType, m)) {
+			return true
+		}
+	}
+	for _, h := range r.Hosts {
+		h = strings.ToLower(strings.TrimPrefix(h, "."))
+		if host != "" && h != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+			return true
+		}
+	}
+	if r.urlRE != nil && r.urlRE.MatchString(rawURL) {
+		return true
+	}
+	return false
+}
+
+// ExpandDir resolves a leading "~" in the rule's directory to the user's home.
+func (r CategoryRule) ExpandDir() string {
+	dir := r.Dir
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	return dir
+}
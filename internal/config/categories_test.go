@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchCategory(t *testing.T) {
+	rules := []CategoryRule{
+		{Name: "ISOs", Dir: "~/isos", Extensions: []string{"iso", ".img"}},
+		{Name: "Videos", Dir: "/videos", MimeTypes: []string{"video/"}},
+		{Name: "GitHub", Dir: "/gh", Hosts: []string{"github.com"}},
+		{Name: "Nightly", Dir: "/nightly", URLPattern: `/nightly/\d+/`},
+		{Name: "No dir", Extensions: []string{"zip"}},
+	}
+	if errs := CompileCategoryRules(rules); len(errs) != 0 {
+		t.Fatalf("CompileCategoryRules: %v", errs)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		filename    string
+		contentType string
+		want        string
+	}{
+		{"extension from filename", "https://example.com/download?id=1", "ubuntu.ISO", "", "ISOs"},
+		{"extension from url", "https://example.com/disk.img", "", "", "ISOs"},
+		{"mime prefix with params", "https://cdn.example.com/a", "a", "video/mp4; codecs=avc1", "Videos"},
+		{"host subdomain", "https://objects.github.com/file.bin", "file.bin", "", "GitHub"},
+		{"host lookalike", "https://notgithub.com/file.bin", "file.bin", "", ""},
+		{"url regex", "https://builds.example.org/nightly/42/app.tar", "app.tar", "", "Nightly"},
+		{"rule without dir is ignored", "https://example.com/a.zip", "a.zip", "", ""},
+		{"first match wins", "https://github.com/x.iso", "x.iso", "", "ISOs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := MatchCategory(rules, tt.url, tt.filename, tt.contentType)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("expected no match, got %q", rule.Name)
+				}
+				return
+			}
+			if !ok || rule.Name != tt.want {
+				t.Errorf("MatchCategory = %q (%v), want %q", rule.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryRule_ExpandDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	if got := (CategoryRule{Dir: "~/isos"}).ExpandDir(); got != filepath.Join(home, "isos") {
+		t.Errorf("ExpandDir = %q", got)
+	}
+	if got := (CategoryRule{Dir: "/abs/dir"}).ExpandDir(); got != "/abs/dir" {
+		t.Errorf("ExpandDir = %q", got)
+	}
+}
+
+func TestLoadSettings_CompilesCategoryPatterns(t *testing.T) {
+	t.Setenv("SURGE_CATEGORIES", `[{"name":"Nightly","dir":"/nightly","url_pattern":"/nightly/"},{"name":"Bad","dir":"/bad","url_pattern":"("}]`)
+	useSettingsFile(t)
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule, ok := MatchCategory(s.Categories, "https://example.com/nightly/app", "app", ""); !ok || rule.Name != "Nightly" {
+		t.Errorf("MatchCategory = %q, %v; want Nightly", rule.Name, ok)
+	}
+	if errs := s.Validate(); len(errs) != 1 {
+		t.Errorf("Validate = %v, want the invalid url_pattern", errs)
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix starts every environment variable that overrides a setting.
+// The rest of the name is the settings.json path in upper case with dots
+// replaced by underscores, e.g. SURGE_CONNECTIONS_MAX_CONNECTIONS_PER_HOST.
+const EnvPrefix = "SURGE_"
+
+// EnvConfigPath names an alternate settings file, like --config
+const EnvConfigPath = "SURGE_CONFIG"
+
+// envTarget is a settings field that an environment variable can override
+type envTarget struct {
+	name  string // Variable name
+	path  string // settings.json path, e.g. "connections.user_agent"
+	key   string // Setting key when the field has metadata, else ""
+	index []int  // Field index path within Settings
+}
+
+var envTargets = buildEnvTargets()
+
+func buildEnvTargets() []envTarget {
+	var targets []envTarget
+	st := reflect.TypeOf(Settings{})
+	for i := 0; i < st.NumField(); i++ {
+		sec := st.Field(i)
+		secName := jsonName(sec)
+		if sec.Type.Kind() != reflect.Struct {
+			// Lists such as categories are given as JSON
+			targets = append(targets, envTarget{name: envName(secName), path: secName, index: []int{i}})
+			continue
+		}
+		for j := 0; j < sec.Type.NumField(); j++ {
+			key := jsonName(sec.Type.Field(j))
+			t := envTarget{name: envName(secName + "." + key), path: secName + "." + key, index: []int{i, j}}
+			if _, ok := LookupSetting(key); ok {
+				t.key = key
+			}
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+func envName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// EnvName returns the environment variable that overrides a setting key
+func EnvName(key string) string {
+	for _, t := range envTargets {
+		if t.key == key {
+			return t.name
+		}
+	}
+	return ""
+}
+
+// EnvOverride reports the variable overriding a setting key, if one is set
+func EnvOverride(key string) (string, bool) {
+	name := EnvName(key)
+	if name == "" {
+		return "", false
+	}
+	_, ok := os.LookupEnv(name)
+	return name, ok
+}
+
+// activeEnvTargets returns the targets whose variable is set
+func activeEnvTargets() []envTarget {
+	var active []envTarget
+	for _, t := range envTargets {
+		if _, ok := os.LookupEnv(t.name); ok {
+			active = append(active, t)
+		}
+	}
+	return active
+}
+
+// applyEnvOverrides sets every field whose variable is set. Values are
+// checked like `surge config set`; invalid ones are skipped and returned.
+func applyEnvOverrides(s *Settings) []error {
+	var errs []error
+	for _, t := range activeEnvTargets() {
+		raw := os.Getenv(t.name)
+		if t.key != "" {
+			if err := s.SetValue(t.key, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+			}
+			continue
+		}
+
+		field := reflect.ValueOf(s).Elem().FieldByIndex(t.index)
+		v := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(raw), v.Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: must be JSON for %s: %w", t.name, t.path, err))
+			continue
+		}
+		field.Set(v.Elem())
+	}
+	return errs
+}
+
+// CheckEnvOverrides reports invalid SURGE_* setting values and variables
+// that look like setting overrides but match no setting.
+func CheckEnvOverrides() []error {
+	errs := applyEnvOverrides(DefaultSettings())
+
+	known := make(map[string]bool, len(envTargets))
+	var names []string
+	for _, t := range envTargets {
+		known[t.name] = true
+		names = append(names, t.name)
+	}
+	var sections []string
+	st := reflect.TypeOf(Settings{})
+	for i := 0; i < st.NumField(); i++ {
+		if st.Field(i).Type.Kind() == reflect.Struct {
+			sections = append(sections, envName(jsonName(st.Field(i)))+"_")
+		}
+	}
+
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if known[name] {
+			continue
+		}
+		for _, prefix := range sections {
+			if strings.HasPrefix(name, prefix) {
+				unknown = append(unknown, name)
+				break
+			}
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, unknownKeyError(name, names))
+	}
+	return errs
+}
+
+// withoutEnvOverrides returns a copy of s in which every field overridden
+// from the environment holds its value from the settings file instead, so
+// saving never writes environment values to disk.
+func withoutEnvOverrides(s *Settings) *Settings {
+	active := activeEnvTargets()
+	if len(active) == 0 {
+		return s
+	}
+
+	base, err := loadSettingsFile()
+	if err != nil {
+		base = DefaultSettings()
+	}
+	out := *s
+	dst := reflect.ValueOf(&out).Elem()
+	src := reflect.ValueOf(base).Elem()
+	for _, t := range active {
+		dst.FieldByIndex(t.index).Set(src.FieldByIndex(t.index))
+	}
+	return &out
+}
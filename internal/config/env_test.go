@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useSettingsFile points the package at a settings file in a temp dir
+func useSettingsFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "settings.json")
+	SetSettingsPath(path)
+	t.Cleanup(func() { SetSettingsPath("") })
+	return path
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("max_connections_per_host"); got != "SURGE_CONNECTIONS_MAX_CONNECTIONS_PER_HOST" {
+		t.Errorf("EnvName = %q", got)
+	}
+	if got := EnvName("no_such_key"); got != "" {
+		t.Errorf("EnvName(unknown) = %q, want empty", got)
+	}
+}
+
+func TestLoadSettings_EnvOverrides(t *testing.T) {
+	path := useSettingsFile(t)
+	if err := os.WriteFile(path, []byte(`{"connections":{"user_agent":"FromFile"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SURGE_CONNECTIONS_MAX_CONNECTIONS_PER_HOST", "12")
+	t.Setenv("SURGE_CONNECTIONS_USER_AGENT", "FromEnv")
+	t.Setenv("SURGE_PERFORMANCE_STALL_TIMEOUT", "2m")
+	t.Setenv("SURGE_CATEGORIES", `[{"name":"ISOs","dir":"/data/isos","extensions":["iso"]}]`)
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if s.Connections.MaxConnectionsPerHost != 12 {
+		t.Errorf("MaxConnectionsPerHost = %d, want 12", s.Connections.MaxConnectionsPerHost)
+	}
+	if s.Connections.UserAgent != "FromEnv" {
+		t.Errorf("UserAgent = %q, want FromEnv", s.Connections.UserAgent)
+	}
+	if s.Performance.StallTimeout != 2*time.Minute {
+		t.Errorf("StallTimeout = %v, want 2m", s.Performance.StallTimeout)
+	}
+	if len(s.Categories) != 1 || s.Categories[0].Dir != "/data/isos" {
+		t.Errorf("Categories = %+v", s.Categories)
+	}
+
+	if name, ok := EnvOverride("user_agent"); !ok || name != "SURGE_CONNECTIONS_USER_AGENT" {
+		t.Errorf("EnvOverride(user_agent) = %q, %v", name, ok)
+	}
+	if _, ok := EnvOverride("auto_resume"); ok {
+		t.Error("EnvOverride(auto_resume) reported an unset variable")
+	}
+}
+
+func TestLoadSettings_InvalidEnvIgnored(t *testing.T) {
+	useSettingsFile(t)
+	t.Setenv("SURGE_CONNECTIONS_MAX_CONNECTIONS_PER_HOST", "999")
+	t.Setenv("SURGE_CATEGORIES", "not json")
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	def := DefaultSettings()
+	if s.Connections.MaxConnectionsPerHost != def.Connections.MaxConnectionsPerHost {
+		t.Errorf("out-of-range value was applied: %d", s.Connections.MaxConnectionsPerHost)
+	}
+	if len(s.Categories) != len(def.Categories) {
+		t.Errorf("invalid categories JSON was applied: %+v", s.Categories)
+	}
+
+	errs := CheckEnvOverrides()
+	if len(errs) != 2 {
+		t.Fatalf("CheckEnvOverrides = %v, want 2 problems", errs)
+	}
+}
+
+func TestCheckEnvOverrides_UnknownName(t *testing.T) {
+	t.Setenv("SURGE_CONNECTIONS_MAX_CONNECTION_PER_HOST", "4")
+	t.Setenv("SURGE_SOMETHING_ELSE", "x") // Not a settings section; not ours to judge
+
+	errs := CheckEnvOverrides()
+	if len(errs) != 1 {
+		t.Fatalf("CheckEnvOverrides = %v, want 1 problem", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "SURGE_CONNECTIONS_MAX_CONNECTIONS_PER_HOST") {
+		t.Errorf("expected a suggestion, got %v", errs[0])
+	}
+}
+
+func TestSaveSettings_DoesNotPersistEnv(t *testing.T) {
+	path := useSettingsFile(t)
+	if err := os.WriteFile(path, []byte(`{"connections":{"user_agent":"FromFile"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SURGE_CONNECTIONS_USER_AGENT", "FromEnv")
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	s.Connections.MaxConnectionsPerHost = 4
+	if err := SaveSettings(s); err != nil {
+		t.Fatalf("SaveSettings: %v", err)
+	}
+
+	_ = os.Unsetenv("SURGE_CONNECTIONS_USER_AGENT") // t.Setenv restores it afterwards
+	saved, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if saved.Connections.UserAgent != "FromFile" {
+		t.Errorf("UserAgent = %q, want the file's value", saved.Connections.UserAgent)
+	}
+	if saved.Connections.MaxConnectionsPerHost != 4 {
+		t.Errorf("MaxConnectionsPerHost = %d, want 4", saved.Connections.MaxConnectionsPerHost)
+	}
+}
+
+func TestSetSettingsPath(t *testing.T) {
+	path := useSettingsFile(t)
+	if got := GetSettingsPath(); got != path {
+		t.Errorf("GetSettingsPath = %q, want %q", got, path)
+	}
+	if err := SaveSettings(DefaultSettings()); err != nil {
+		t.Fatalf("SaveSettings: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("settings not written to the alternate path: %v", err)
+	}
+
+	SetSettingsPath("")
+	if GetSettingsPath() == path {
+		t.Error("clearing the path did not restore the default")
+	}
+}
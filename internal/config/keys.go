@@ -0,0 +1,394 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Setting keys are unique across sections, so a bare key such as
+// "max_connections_per_host" names exactly one field of Settings.
+
+// settingField locates a setting inside Settings
+type settingField struct {
+	section string // JSON name of the section, e.g. "connections"
+	index   []int  // Field index path for reflect.Value.FieldByIndex
+}
+
+var settingFields = buildSettingFields()
+
+func buildSettingFields() map[string]settingField {
+	fields := make(map[string]settingField)
+	st := reflect.TypeOf(Settings{})
+	for i := 0; i < st.NumField(); i++ {
+		sec := st.Field(i)
+		if sec.Type.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < sec.Type.NumField(); j++ {
+			fields[jsonName(sec.Type.Field(j))] = settingField{section: jsonName(sec), index: []int{i, j}}
+		}
+	}
+	return fields
+}
+
+// jsonName returns the JSON key of a struct field
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// LookupSetting returns the metadata for a setting key
+func LookupSetting(key string) (SettingMeta, bool) {
+	for _, metas := range GetSettingsMetadata() {
+		for _, meta := range metas {
+			if meta.Key == key {
+				return meta, true
+			}
+		}
+	}
+	return SettingMeta{}, false
+}
+
+// SettingKeys returns every editable setting key in UI order
+func SettingKeys() []string {
+	metadata := GetSettingsMetadata()
+	var keys []string
+	for _, category := range CategoryOrder() {
+		for _, meta := range metadata[category] {
+			keys = append(keys, meta.Key)
+		}
+	}
+	return keys
+}
+
+// SettingPath returns the dotted settings.json path of a key, e.g.
+// "connections.max_connections_per_host"
+func SettingPath(key string) string {
+	if f, ok := settingFields[key]; ok {
+		return f.section + "." + key
+	}
+	return key
+}
+
+// field returns the addressable value of a setting
+func (s *Settings) field(key string) (SettingMeta, reflect.Value, error) {
+	meta, ok := LookupSetting(key)
+	f, found := settingFields[key]
+	if !ok || !found {
+		return meta, reflect.Value{}, unknownSettingError(key)
+	}
+	return meta, reflect.ValueOf(s).Elem().FieldByIndex(f.index), nil
+}
+
+// GetValue returns a setting formatted for display. Durations use Go
+// duration syntax ("1m30s"); sizes are in bytes.
+func (s *Settings) GetValue(key string) (string, error) {
+	meta, v, err := s.field(key)
+	if err != nil {
+		return "", err
+	}
+	return formatValue(meta, v), nil
+}
+
+// SetValue parses value for the setting's type, checks it against the
+// setting's range or allowed values and stores it.
+func (s *Settings) SetValue(key, value string) error {
+	meta, v, err := s.field(key)
+	if err != nil {
+		return err
+	}
+	parsed, err := parseValue(meta, value)
+	if err != nil {
+		return err
+	}
+	if err := checkValue(meta, parsed); err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(parsed).Convert(v.Type()))
+	return nil
+}
+
+// ResetValue restores a setting to its default
+func (s *Settings) ResetValue(key string) error {
+	_, v, err := s.field(key)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(DefaultSettings()).Elem().FieldByIndex(settingFields[key].index))
+	return nil
+}
+
+// Validate checks every setting against its type's range or allowed values.
+// Errors name the setting by its settings.json path.
+func (s *Settings) Validate() []error {
+	var errs []error
+	for _, key := range SettingKeys() {
+		meta, v, err := s.field(key)
+		if err != nil {
+			continue
+		}
+		if err := checkValue(meta, v.Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", SettingPath(key), err))
+		}
+	}
+	errs = append(errs, CompileCategoryRules(s.Categories)...)
+	for i, ep := range s.Webhooks.Endpoints {
+		if ep.URL == "" {
+			errs = append(errs, fmt.Errorf("webhooks.endpoints[%d]: url is required", i))
+		}
+	}
+	return errs
+}
+
+func formatValue(meta SettingMeta, v reflect.Value) string {
+	switch meta.Type {
+	case "duration":
+		return time.Duration(v.Int()).String()
+	case "bool":
+		return strconv.FormatBool(v.Bool())
+	case "int", "int64":
+		return strconv.FormatInt(v.Int(), 10)
+	case "float64":
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	return v.String()
+}
+
+// parseValue converts command-line input to the setting's Go type
+func parseValue(meta SettingMeta, value string) (any, error) {
+	trimmed := strings.TrimSpace(value)
+	switch meta.Type {
+	case "bool":
+		b, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", meta.Key)
+		}
+		return b, nil
+	case "int":
+		n, err := strconv.Atoi(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number", meta.Key)
+		}
+		return n, nil
+	case "int64":
+		n, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number", meta.Key)
+		}
+		return n, nil
+	case "float64":
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", meta.Key)
+		}
+		return f, nil
+	case "duration":
+		// A bare number means seconds, as in the TUI
+		if secs, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return time.Duration(secs * float64(time.Second)), nil
+		}
+		d, err := time.ParseDuration(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a duration such as 30s or 5m", meta.Key)
+		}
+		return d, nil
+	}
+	if len(meta.Options) > 0 {
+		return strings.ToLower(trimmed), nil
+	}
+	return value, nil
+}
+
+// checkValue reports whether v is within the setting's range or options
+func checkValue(meta SettingMeta, v any) error {
+	var num float64
+	switch x := v.(type) {
+	case string:
+		if len(meta.Options) > 0 && !containsString(meta.Options, x) {
+			return fmt.Errorf("%s must be one of %s (got %q)", meta.Key, strings.Join(meta.Options, ", "), x)
+		}
+		return nil
+	case bool:
+		return nil
+	case time.Duration:
+		if x < 0 {
+			return fmt.Errorf("%s must not be negative", meta.Key)
+		}
+		return nil
+	case int:
+		num = float64(x)
+	case int64:
+		num = float64(x)
+	case float64:
+		num = x
+	default:
+		return nil
+	}
+
+	if num < meta.Min || (meta.Max > 0 && num > meta.Max) {
+		if meta.Max > 0 {
+			return fmt.Errorf("%s must be between %g and %g (got %g)", meta.Key, meta.Min, meta.Max, num)
+		}
+		return fmt.Errorf("%s must be at least %g (got %g)", meta.Key, meta.Min, num)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownSettingError names the closest known key, if any
+func unknownSettingError(key string) error {
+	if s := suggestKey(key, SettingKeys()); s != "" {
+		return fmt.Errorf("unknown setting %q (did you mean %q?)", key, s)
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// suggestKey returns the candidate closest to key, or "" if none is close
+func suggestKey(key string, candidates []string) string {
+	best, bestDist := "", len(key)/3+2
+	for _, c := range candidates {
+		if d := editDistance(key, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// CheckSettingsJSON reports problems in settings.json content: invalid JSON,
+// unknown or misplaced keys, values of the wrong type and values outside
+// their range. LoadSettings ignores all of these, so a typo would otherwise
+// go unnoticed.
+func CheckSettingsJSON(data []byte) []error {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return []error{fmt.Errorf("invalid JSON: %w", err)}
+	}
+
+	var errs []error
+	sections := make(map[string]reflect.Type)
+	var sectionNames []string
+	st := reflect.TypeOf(Settings{})
+	for i := 0; i < st.NumField(); i++ {
+		name := jsonName(st.Field(i))
+		sections[name] = st.Field(i).Type
+		sectionNames = append(sectionNames, name)
+	}
+
+	for _, name := range sortedKeys(top) {
+		t, ok := sections[name]
+		if !ok {
+			errs = append(errs, unknownKeyError(name, sectionNames))
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(top[name], &obj); err != nil {
+			errs = append(errs, fmt.Errorf("%s: must be an object", name))
+			continue
+		}
+		var known []string
+		for j := 0; j < t.NumField(); j++ {
+			known = append(known, jsonName(t.Field(j)))
+		}
+		for _, key := range sortedKeys(obj) {
+			if containsString(known, key) {
+				continue
+			}
+			if f, ok := settingFields[key]; ok {
+				errs = append(errs, fmt.Errorf("%s.%s: setting belongs in %q", name, key, f.section))
+			} else {
+				errs = append(errs, unknownKeyError(name+"."+key, prefixAll(name+".", known)))
+			}
+		}
+	}
+
+	settings := DefaultSettings()
+	if err := json.Unmarshal(data, settings); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			errs = append(errs, fmt.Errorf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value))
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	return append(errs, settings.Validate()...)
+}
+
+// CheckSettingsFile runs CheckSettingsJSON on a settings file. A missing
+// file has no problems.
+func CheckSettingsFile(path string) ([]error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return CheckSettingsJSON(data), nil
+}
+
+func unknownKeyError(key string, candidates []string) error {
+	if s := suggestKey(key, candidates); s != "" {
+		return fmt.Errorf("%s: unknown key (did you mean %q?)", key, s)
+	}
+	return fmt.Errorf("%s: unknown key", key)
+}
+
+func prefixAll(prefix string, list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = prefix + s
+	}
+	return out
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
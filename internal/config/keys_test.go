@@ -0,0 +1,152 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettings_SetAndGetValue(t *testing.T) {
+	s := DefaultSettings()
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"max_connections_per_host", "16", "16"},
+		{"sequential_download", "true", "true"},
+		{"speed_ema_alpha", "0.5", "0.5"},
+		{"stall_timeout", "10", "10s"}, // Bare numbers are seconds
+		{"hook_timeout", "2m", "2m0s"},
+		{"conflict_policy", " Overwrite ", "overwrite"},
+		{"user_agent", "Agent/1.0", "Agent/1.0"},
+	}
+	for _, tt := range tests {
+		if err := s.SetValue(tt.key, tt.value); err != nil {
+			t.Errorf("SetValue(%q, %q): %v", tt.key, tt.value, err)
+			continue
+		}
+		if got, _ := s.GetValue(tt.key); got != tt.want {
+			t.Errorf("GetValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	if s.Connections.MaxConnectionsPerHost != 16 || s.Performance.StallTimeout != 10*time.Second {
+		t.Error("SetValue did not update the struct fields")
+	}
+}
+
+func TestSettings_SetValueRejectsInvalid(t *testing.T) {
+	s := DefaultSettings()
+
+	tests := []struct {
+		key, value, wantErr string
+	}{
+		{"max_connections_per_host", "65", "between 1 and 64"},
+		{"max_connections_per_host", "0", "between 1 and 64"},
+		{"max_connections_per_host", "many", "whole number"},
+		{"speed_ema_alpha", "1.5", "between 0 and 1"},
+		{"sequential_download", "maybe", "true or false"},
+		{"stall_timeout", "soon", "duration"},
+		{"stall_timeout", "-5s", "negative"},
+		{"conflict_policy", "clobber", "one of rename, overwrite, skip, fail"},
+		{"max_conections_per_host", "4", `did you mean "max_connections_per_host"`},
+	}
+	for _, tt := range tests {
+		err := s.SetValue(tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("SetValue(%q, %q) error = %v, want it to mention %q", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+
+	if s.Connections.MaxConnectionsPerHost != DefaultSettings().Connections.MaxConnectionsPerHost {
+		t.Error("rejected value was stored")
+	}
+}
+
+func TestSettings_ResetValue(t *testing.T) {
+	s := DefaultSettings()
+	_ = s.SetValue("max_task_retries", "9")
+
+	if err := s.ResetValue("max_task_retries"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Performance.MaxTaskRetries != DefaultSettings().Performance.MaxTaskRetries {
+		t.Errorf("max_task_retries = %d after reset", s.Performance.MaxTaskRetries)
+	}
+}
+
+func TestSettings_DefaultsAreValid(t *testing.T) {
+	if errs := DefaultSettings().Validate(); len(errs) > 0 {
+		t.Errorf("default settings fail validation: %v", errs)
+	}
+
+	// Every metadata key must map onto a Settings field
+	s := DefaultSettings()
+	for _, key := range SettingKeys() {
+		if _, err := s.GetValue(key); err != nil {
+			t.Errorf("GetValue(%q): %v", key, err)
+		}
+	}
+}
+
+func TestCheckSettingsJSON(t *testing.T) {
+	data := `{
+		"genral": {},
+		"connections": {"max_conections_per_host": 4, "max_concurrent_downloads": 50},
+		"general": {"min_chunk_size": 1},
+		"performance": {"speed_ema_alpha": "fast"}
+	}`
+
+	errs := CheckSettingsJSON([]byte(data))
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+
+	for _, want := range []string{
+		`genral: unknown key (did you mean "general"?)`,
+		`connections.max_conections_per_host: unknown key (did you mean "connections.max_connections_per_host"?)`,
+		`general.min_chunk_size: setting belongs in "chunks"`,
+		`performance.speed_ema_alpha: expected float64, got string`,
+		`connections.max_concurrent_downloads: max_concurrent_downloads must be between 1 and 10`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("missing problem %q in:\n%s", want, joined)
+		}
+	}
+	if len(errs) != 5 {
+		t.Errorf("got %d problems, want 5:\n%s", len(errs), joined)
+	}
+
+	if errs := CheckSettingsJSON([]byte("{not json")); len(errs) != 1 || !strings.Contains(errs[0].Error(), "invalid JSON") {
+		t.Errorf("broken JSON: %v", errs)
+	}
+
+	valid, _ := json.Marshal(DefaultSettings())
+	if errs := CheckSettingsJSON(valid); len(errs) != 0 {
+		t.Errorf("saved defaults reported problems: %v", errs)
+	}
+}
+
+func TestSettingsSchema(t *testing.T) {
+	schema := SettingsSchema()
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("schema does not marshal: %v", err)
+	}
+
+	props := schema["properties"].(map[string]any)
+	conns := props["connections"].(map[string]any)["properties"].(map[string]any)
+	perHost := conns["max_connections_per_host"].(map[string]any)
+	if perHost["type"] != "integer" || perHost["minimum"] != float64(1) || perHost["maximum"] != float64(64) {
+		t.Errorf("max_connections_per_host schema = %v", perHost)
+	}
+	policy := conns["concurrent_shrink_policy"].(map[string]any)
+	if enum, _ := policy["enum"].([]string); len(enum) != 2 {
+		t.Errorf("concurrent_shrink_policy enum = %v", policy["enum"])
+	}
+	if _, ok := props["categories"]; !ok {
+		t.Error("schema is missing categories")
+	}
+}
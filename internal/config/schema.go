@@ -0,0 +1,110 @@
+package config
+
+import "reflect"
+
+// SchemaURL is the JSON Schema dialect used by SettingsSchema
+const SchemaURL = "https://json-schema.org/draft/2020-12/schema"
+
+// SettingsSchema returns a JSON Schema describing settings.json, built from
+// the Settings struct and the settings metadata. Editors can use it for
+// completion and validation.
+func SettingsSchema() map[string]any {
+	defaults := reflect.ValueOf(DefaultSettings()).Elem()
+	st := defaults.Type()
+
+	props := make(map[string]any)
+	for i := 0; i < st.NumField(); i++ {
+		sec := st.Field(i)
+		if sec.Type.Kind() != reflect.Struct {
+			props[jsonName(sec)] = typeSchema(sec.Type)
+			continue
+		}
+
+		secProps := make(map[string]any)
+		for j := 0; j < sec.Type.NumField(); j++ {
+			f := sec.Type.Field(j)
+			key := jsonName(f)
+			if meta, ok := LookupSetting(key); ok {
+				secProps[key] = metaSchema(meta, defaults.Field(i).Field(j))
+			} else {
+				secProps[key] = typeSchema(f.Type)
+			}
+		}
+		props[jsonName(sec)] = map[string]any{
+			"type":                 "object",
+			"properties":           secProps,
+			"additionalProperties": false,
+		}
+	}
+
+	return map[string]any{
+		"$schema":              SchemaURL,
+		"title":                "Surge settings",
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+// metaSchema describes one documented setting, with its range and default
+func metaSchema(meta SettingMeta, def reflect.Value) map[string]any {
+	schema := map[string]any{
+		"title":       meta.Label,
+		"description": meta.Description,
+		"default":     def.Interface(),
+	}
+	switch meta.Type {
+	case "bool":
+		schema["type"] = "boolean"
+	case "int", "int64":
+		schema["type"] = "integer"
+	case "float64":
+		schema["type"] = "number"
+	case "duration":
+		schema["type"] = "integer"
+		schema["description"] = meta.Description + " Stored in nanoseconds."
+		schema["minimum"] = 0
+	default:
+		schema["type"] = "string"
+	}
+
+	if schema["type"] == "integer" || schema["type"] == "number" {
+		if meta.Type != "duration" {
+			schema["minimum"] = meta.Min
+		}
+		if meta.Max > 0 {
+			schema["maximum"] = meta.Max
+		}
+	}
+	if len(meta.Options) > 0 {
+		schema["enum"] = meta.Options
+	}
+	return schema
+}
+
+// typeSchema describes a field that has no settings metadata (lists of
+// category rules and webhook endpoints) from its Go type
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": []string{"array", "null"}, "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			props[jsonName(t.Field(i))] = typeSchema(t.Field(i).Type)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	}
+	return map[string]any{}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,10 @@ type Settings struct {
 	Connections ConnectionSettings  `json:"connections"`
 	Chunks      ChunkSettings       `json:"chunks"`
 	Performance PerformanceSettings `json:"performance"`
+	Hooks       HookSettings        `json:"hooks"`
+	Webhooks    WebhookSettings     `json:"webhooks"`
+	History     HistorySettings     `json:"history"`
+	Categories  []CategoryRule      `json:"categories"`
 }
 
 // GeneralSettings contains application behavior settings.
@@ -26,6 +31,55 @@ type GeneralSettings struct {
 	ClipboardMonitor  bool `json:"clipboard_monitor"`
 	Theme             int  `json:"theme"`
 	LogRetentionCount int  `json:"log_retention_count"`
+
+	FilenameTemplate string `json:"filename_template"`
+	FilenameProfile  string `json:"filename_profile"`
+	ConflictPolicy   string `json:"conflict_policy"`
+}
+
+// File conflict policies, applied when a new download's target file already exists
+const (
+	ConflictRename    = "rename"    // Pick a free "name(1).ext"
+	ConflictOverwrite = "overwrite" // Replace the existing file
+	ConflictSkip      = "skip"      // Adopt the existing file if size/ETag match, otherwise rename
+	ConflictFail      = "fail"      // Fail the download
+)
+
+// ConflictPolicies lists the valid conflict policy names
+var ConflictPolicies = []string{ConflictRename, ConflictOverwrite, ConflictSkip, ConflictFail}
+
+// IsValidConflictPolicy reports whether policy is a known conflict policy
+func IsValidConflictPolicy(policy string) bool {
+	for _, p := range ConflictPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// FilenameProfiles lists the filename sanitization profiles. It mirrors
+// utils.FilenameProfiles, which config cannot import.
+var FilenameProfiles = []string{"posix", "windows", "ascii"}
+
+// Bounds for max_concurrent_downloads
+const (
+	MinConcurrentDownloads = 1
+	MaxConcurrentDownloads = 10
+)
+
+// Shrink policies, applied to running downloads when max_concurrent_downloads is lowered
+const (
+	ShrinkFinish = "finish" // Let downloads over the limit finish; start no new ones until below it
+	ShrinkPause  = "pause"  // Pause downloads over the limit and put them back at the front of the queue
+)
+
+// ShrinkPolicies lists the valid shrink policy names
+var ShrinkPolicies = []string{ShrinkFinish, ShrinkPause}
+
+// IsValidShrinkPolicy reports whether policy is a known shrink policy
+func IsValidShrinkPolicy(policy string) bool {
+	return policy == ShrinkFinish || policy == ShrinkPause
 }
 
 const (
@@ -39,9 +93,14 @@ type ConnectionSettings struct {
 	MaxConnectionsPerHost  int    `json:"max_connections_per_host"`
 	MaxGlobalConnections   int    `json:"max_global_connections"`
 	MaxConcurrentDownloads int    `json:"max_concurrent_downloads"`
+	ConcurrentShrinkPolicy string `json:"concurrent_shrink_policy"`
 	UserAgent              string `json:"user_agent"`
 	ProxyURL               string `json:"proxy_url"`
 	SequentialDownload     bool   `json:"sequential_download"`
+
+	URLRefreshCommand   string        `json:"url_refresh_command"`
+	URLRefreshExtension bool          `json:"url_refresh_extension"`
+	URLRefreshTimeout   time.Duration `json:"url_refresh_timeout"`
 }
 
 // ChunkSettings contains download chunk configuration.
@@ -59,12 +118,73 @@ type PerformanceSettings struct {
 	SpeedEmaAlpha         float64       `json:"speed_ema_alpha"`
 }
 
+// HookSettings contains shell commands run when a download changes state
+// and post-processing applied to completed downloads.
+type HookSettings struct {
+	OnComplete  string        `json:"on_complete"`
+	OnError     string        `json:"on_error"`
+	OnPause     string        `json:"on_pause"`
+	HookTimeout time.Duration `json:"hook_timeout"`
+
+	// AllowedCommands are the per-download hook commands accepted over HTTP
+	// (e.g. from surge add --on-complete). Only editable in settings.json.
+	AllowedCommands []string `json:"allowed_commands"`
+
+	ExtractArchives           bool `json:"extract_archives"`
+	DeleteArchiveAfterExtract bool `json:"delete_archive_after_extract"`
+}
+
+// WebhookSettings contains outbound webhook endpoints and delivery tuning.
+type WebhookSettings struct {
+	Endpoints          []WebhookEndpoint `json:"endpoints"`
+	WebhookMaxAttempts int               `json:"webhook_max_attempts"`
+	WebhookTimeout     time.Duration     `json:"webhook_timeout"`
+}
+
+// HistorySettings controls how long finished downloads are kept.
+// Zero values keep history forever.
+type HistorySettings struct {
+	HistoryRetentionDays int  `json:"history_retention_days"`
+	HistoryMaxEntries    int  `json:"history_max_entries"`
+	ErrorRetentionDays   int  `json:"error_retention_days"`
+	DeleteHistoryFiles   bool `json:"delete_history_files"`
+}
+
+// WebhookEndpoint is a URL that receives download events.
+type WebhookEndpoint struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // HMAC-SHA256 key for X-Surge-Signature
+	Events []string `json:"events,omitempty"` // Event types to send; empty means all
+	Format string   `json:"format,omitempty"` // "json" (default) or "slack"
+}
+
+// Wants reports whether the endpoint is subscribed to event.
+func (e WebhookEndpoint) Wants(event string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, ev := range e.Events {
+		if strings.EqualFold(ev, event) {
+			return true
+		}
+	}
+	return false
+}
+
 // SettingMeta provides metadata for a single setting (for UI rendering).
 type SettingMeta struct {
 	Key         string // JSON key name
 	Label       string // Human-readable label
 	Description string // Help text displayed in right pane
 	Type        string // "string", "int", "int64", "bool", "duration", "float64"
+
+	Min     float64  // Lowest allowed value for numeric settings
+	Max     float64  // Highest allowed value for numeric settings; 0 means no upper bound
+	Options []string // Allowed values for string settings; empty allows any
+
+	// LocalOnly settings hold shell commands. The HTTP API refuses to change
+	// them, so an API token never lets a client run commands.
+	LocalOnly bool
 }
 
 // GetSettingsMetadata returns metadata for all settings organized by category.
@@ -78,32 +198,53 @@ func GetSettingsMetadata() map[string][]SettingMeta {
 			{Key: "skip_update_check", Label: "Skip Update Check", Description: "Disable automatic check for new versions on startup.", Type: "bool"},
 
 			{Key: "clipboard_monitor", Label: "Clipboard Monitor", Description: "Watch clipboard for URLs and prompt to download them.", Type: "bool"},
-			{Key: "theme", Label: "App Theme", Description: "UI Theme (System, Light, Dark).", Type: "int"},
+			{Key: "theme", Label: "App Theme", Description: "UI Theme (System, Light, Dark).", Type: "int", Min: ThemeAdaptive, Max: ThemeDark},
 			{Key: "log_retention_count", Label: "Log Retention Count", Description: "Number of recent log files to keep.", Type: "int"},
+			{Key: "filename_template", Label: "Filename Template", Description: "Template for new filenames, e.g. {host}/{date}/{name}{ext}. Leave empty to keep the server's name.", Type: "string"},
+			{Key: "filename_profile", Label: "Filename Profile", Description: "Filename sanitization: posix, windows (NTFS/SMB shares) or ascii.", Type: "string", Options: FilenameProfiles},
+			{Key: "conflict_policy", Label: "File Conflict Policy", Description: "When the target file exists: rename, overwrite, skip (if same size/ETag) or fail.", Type: "string", Options: ConflictPolicies},
 		},
 		"Network": {
-			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host (1-64).", Type: "int"},
-			{Key: "max_global_connections", Label: "Max Global Connections", Description: "Maximum total concurrent connections across all downloads.", Type: "int"},
-			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Requires restart.", Type: "int"},
+			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host (1-64).", Type: "int", Min: 1, Max: 64},
+			{Key: "max_global_connections", Label: "Max Global Connections", Description: "Maximum total concurrent connections across all downloads.", Type: "int", Min: 1},
+			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Applies immediately.", Type: "int", Min: MinConcurrentDownloads, Max: MaxConcurrentDownloads},
+			{Key: "concurrent_shrink_policy", Label: "Shrink Policy", Description: "When the limit is lowered: finish (running downloads complete) or pause (extra downloads go back to the queue).", Type: "string", Options: ShrinkPolicies},
 			{Key: "user_agent", Label: "User Agent", Description: "Custom User-Agent string for HTTP requests. Leave empty for default.", Type: "string"},
 			{Key: "proxy_url", Label: "Proxy URL", Description: "HTTP/HTTPS proxy URL (e.g. http://127.0.0.1:1700). Leave empty to use system default.", Type: "string"},
 			{Key: "sequential_download", Label: "Sequential Download", Description: "Download pieces in order (Streaming Mode). May be slower.", Type: "bool"},
-			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size in MB (e.g., 2).", Type: "int64"},
-			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker in KB (e.g., 512).", Type: "int"},
+			{Key: "url_refresh_command", Label: "URL Refresh Command", Description: "Command run when a link expires (401/403/410). Prints a fresh URL to stdout. Leave empty to disable.", Type: "string", LocalOnly: true},
+			{Key: "url_refresh_extension", Label: "URL Refresh via Extension", Description: "Ask the browser extension for a fresh link when a download's URL expires.", Type: "bool"},
+			{Key: "url_refresh_timeout", Label: "URL Refresh Timeout", Description: "How long to wait for a refreshed URL before failing (e.g., 60s).", Type: "duration"},
+			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size in bytes, at least 4096 (e.g., 2097152 for 2 MB; the TUI shows MB).", Type: "int64", Min: 4 * KB},
+			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker in bytes, at least 1024 (e.g., 524288 for 512 KB; the TUI shows KB).", Type: "int", Min: KB},
 		},
 		"Performance": {
-			{Key: "max_task_retries", Label: "Max Task Retries", Description: "Number of times to retry a failed chunk before giving up.", Type: "int"},
-			{Key: "slow_worker_threshold", Label: "Slow Worker Threshold", Description: "Restart workers slower than this fraction of mean speed (0.0-1.0).", Type: "float64"},
+			{Key: "max_task_retries", Label: "Max Task Retries", Description: "Number of times to retry a failed chunk before giving up.", Type: "int", Min: 1},
+			{Key: "slow_worker_threshold", Label: "Slow Worker Threshold", Description: "Restart workers slower than this fraction of mean speed (0.0-1.0).", Type: "float64", Min: 0, Max: 1},
 			{Key: "slow_worker_grace_period", Label: "Slow Worker Grace", Description: "Grace period before checking worker speed (e.g., 5s).", Type: "duration"},
 			{Key: "stall_timeout", Label: "Stall Timeout", Description: "Restart workers with no data for this duration (e.g., 5s).", Type: "duration"},
-			{Key: "speed_ema_alpha", Label: "Speed EMA Alpha", Description: "Exponential moving average smoothing factor (0.0-1.0).", Type: "float64"},
+			{Key: "speed_ema_alpha", Label: "Speed EMA Alpha", Description: "Exponential moving average smoothing factor (0.0-1.0).", Type: "float64", Min: 0, Max: 1},
+		},
+		"Automation": {
+			{Key: "on_complete", Label: "On Complete Hook", Description: "Shell command to run when a download finishes. Leave empty to disable.", Type: "string", LocalOnly: true},
+			{Key: "on_error", Label: "On Error Hook", Description: "Shell command to run when a download fails. Leave empty to disable.", Type: "string", LocalOnly: true},
+			{Key: "on_pause", Label: "On Pause Hook", Description: "Shell command to run when a download is paused. Leave empty to disable.", Type: "string", LocalOnly: true},
+			{Key: "hook_timeout", Label: "Hook Timeout", Description: "Kill hook commands that run longer than this (e.g., 300s).", Type: "duration"},
+			{Key: "extract_archives", Label: "Extract Archives", Description: "Unpack zip, tar, tar.gz, tar.xz and tar.zst downloads into a folder next to the archive.", Type: "bool"},
+			{Key: "delete_archive_after_extract", Label: "Delete After Extract", Description: "Delete the archive once it has been extracted successfully.", Type: "bool"},
+			{Key: "webhook_max_attempts", Label: "Webhook Max Attempts", Description: "Delivery attempts per webhook before it is dropped. Endpoints are configured in settings.json.", Type: "int", Min: 1},
+			{Key: "webhook_timeout", Label: "Webhook Timeout", Description: "HTTP timeout for each webhook delivery attempt (e.g., 10s).", Type: "duration"},
+			{Key: "history_retention_days", Label: "History Retention (days)", Description: "Forget completed downloads after this many days. 0 keeps them forever.", Type: "int", Min: 0},
+			{Key: "history_max_entries", Label: "History Max Entries", Description: "Keep only this many of the newest completed downloads. 0 means no limit.", Type: "int", Min: 0},
+			{Key: "error_retention_days", Label: "Failed Retention (days)", Description: "Forget failed downloads after this many days. 0 keeps them until removed.", Type: "int", Min: 0},
+			{Key: "delete_history_files", Label: "Delete Files With History", Description: "When history is cleaned up automatically, also delete the downloaded files and partial files.", Type: "bool"},
 		},
 	}
 }
 
 // CategoryOrder returns the order of categories for UI tabs.
 func CategoryOrder() []string {
-	return []string{"General", "Network", "Performance"}
+	return []string{"General", "Network", "Performance", "Automation"}
 }
 
 const (
@@ -142,13 +283,18 @@ func DefaultSettings() *Settings {
 			ClipboardMonitor:  true,
 			Theme:             ThemeAdaptive,
 			LogRetentionCount: 5,
+
+			FilenameProfile: "posix",
+			ConflictPolicy:  ConflictRename,
 		},
 		Connections: ConnectionSettings{
 			MaxConnectionsPerHost:  32,
 			MaxGlobalConnections:   100,
 			MaxConcurrentDownloads: 3,
+			ConcurrentShrinkPolicy: ShrinkFinish,
 			UserAgent:              "", // Empty means use default UA
 			SequentialDownload:     false,
+			URLRefreshTimeout:      60 * time.Second,
 		},
 		Chunks: ChunkSettings{
 			MinChunkSize:     2 * MB,
@@ -161,16 +307,49 @@ func DefaultSettings() *Settings {
 			StallTimeout:          3 * time.Second,
 			SpeedEmaAlpha:         0.3,
 		},
+		Hooks: HookSettings{
+			HookTimeout: 5 * time.Minute,
+		},
+		Webhooks: WebhookSettings{
+			WebhookMaxAttempts: 8,
+			WebhookTimeout:     10 * time.Second,
+		},
 	}
 }
 
+// settingsPath overrides the settings file location when set (--config)
+var settingsPath string
+
+// SetSettingsPath makes LoadSettings and SaveSettings use path instead of
+// settings.json in the Surge directory. An empty path restores the default.
+func SetSettingsPath(path string) {
+	settingsPath = path
+}
+
 // GetSettingsPath returns the path to the settings JSON file.
 func GetSettingsPath() string {
+	if settingsPath != "" {
+		return settingsPath
+	}
 	return filepath.Join(GetSurgeDir(), "settings.json")
 }
 
 // LoadSettings loads settings from disk. Returns defaults if file doesn't exist.
+// SURGE_* environment variables override values from the file; invalid ones
+// are ignored (see CheckEnvOverrides).
 func LoadSettings() (*Settings, error) {
+	settings, err := loadSettingsFile()
+	if err != nil {
+		return nil, err
+	}
+	applyEnvOverrides(settings)
+	// Invalid patterns are reported by Validate (surge config validate)
+	_ = CompileCategoryRules(settings.Categories)
+	return settings, nil
+}
+
+// loadSettingsFile loads the settings file without environment overrides
+func loadSettingsFile() (*Settings, error) {
 	path := GetSettingsPath()
 
 	data, err := os.ReadFile(path)
@@ -190,9 +369,11 @@ func LoadSettings() (*Settings, error) {
 	return settings, nil
 }
 
-// SaveSettings saves settings to disk atomically.
+// SaveSettings saves settings to disk atomically. Values that come from
+// SURGE_* environment variables are not written; the file keeps its own.
 func SaveSettings(s *Settings) error {
 	path := GetSettingsPath()
+	s = withoutEnvOverrides(s)
 
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
@@ -228,6 +409,26 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+
+	FilenameTemplate string
+	FilenameProfile  string
+}
+
+// RedactedSecret stands in for secrets in settings served over the API
+const RedactedSecret = "********"
+
+// Redacted returns a copy of s with secrets (webhook signing keys) masked,
+// for showing settings to API clients
+func (s *Settings) Redacted() *Settings {
+	c := *s
+	c.Webhooks.Endpoints = make([]WebhookEndpoint, len(s.Webhooks.Endpoints))
+	copy(c.Webhooks.Endpoints, s.Webhooks.Endpoints)
+	for i := range c.Webhooks.Endpoints {
+		if c.Webhooks.Endpoints[i].Secret != "" {
+			c.Webhooks.Endpoints[i].Secret = RedactedSecret
+		}
+	}
+	return &c
 }
 
 // ToRuntimeConfig creates a RuntimeConfig from user Settings
@@ -245,5 +446,7 @@ func (s *Settings) ToRuntimeConfig() *RuntimeConfig {
 		SlowWorkerGracePeriod: s.Performance.SlowWorkerGracePeriod,
 		StallTimeout:          s.Performance.StallTimeout,
 		SpeedEmaAlpha:         s.Performance.SpeedEmaAlpha,
+		FilenameTemplate:      s.General.FilenameTemplate,
+		FilenameProfile:       s.General.FilenameProfile,
 	}
 }
@@ -272,6 +272,19 @@ func TestLoadSettings_PartialJSON(t *testing.T) {
 	}
 }
 
+func TestRedacted(t *testing.T) {
+	s := DefaultSettings()
+	s.Webhooks.Endpoints = []WebhookEndpoint{{URL: "https://a.example", Secret: "s3cret"}, {URL: "https://b.example"}}
+
+	r := s.Redacted()
+	if r.Webhooks.Endpoints[0].Secret != RedactedSecret || r.Webhooks.Endpoints[1].Secret != "" {
+		t.Errorf("redacted endpoints = %+v", r.Webhooks.Endpoints)
+	}
+	if s.Webhooks.Endpoints[0].Secret != "s3cret" {
+		t.Error("Redacted changed the original settings")
+	}
+}
+
 func TestToRuntimeConfig(t *testing.T) {
 	settings := DefaultSettings()
 	runtime := settings.ToRuntimeConfig()
@@ -364,7 +377,7 @@ func TestCategoryOrder(t *testing.T) {
 	}
 
 	// Should have all expected categories
-	expectedCount := 3 // General, Network, Performance
+	expectedCount := 4 // General, Network, Performance, Automation
 	if len(order) != expectedCount {
 		t.Errorf("Expected %d categories, got %d", expectedCount, len(order))
 	}
@@ -581,3 +594,19 @@ func TestDefaultSettings_Fallback(t *testing.T) {
 		}
 	}
 }
+
+func TestIsValidShrinkPolicy(t *testing.T) {
+	if DefaultSettings().Connections.ConcurrentShrinkPolicy != ShrinkFinish {
+		t.Errorf("default shrink policy should be %q", ShrinkFinish)
+	}
+	for _, policy := range []string{ShrinkFinish, ShrinkPause} {
+		if !IsValidShrinkPolicy(policy) {
+			t.Errorf("IsValidShrinkPolicy(%q) = false", policy)
+		}
+	}
+	for _, policy := range []string{"", "cancel", "Pause"} {
+		if IsValidShrinkPolicy(policy) {
+			t.Errorf("IsValidShrinkPolicy(%q) = true", policy)
+		}
+	}
+}
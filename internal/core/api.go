@@ -0,0 +1,93 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// APIPrefix is the path prefix of the versioned HTTP API
+const APIPrefix = "/api/v1"
+
+// Error codes of the versioned API
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeApprovalRequired = "approval_required"
+	CodeNotQueued        = "not_queued"
+	CodeRunning          = "download_running"
+	CodeCompleted        = "download_completed"
+	CodeSourceMismatch   = "source_mismatch"
+	CodeFileExists       = "file_exists"
+	CodeNoGroup          = "no_group"
+	CodeHookNotAllowed   = "hook_not_allowed"
+	CodeLocalOnly        = "local_only"
+	CodeInternal         = "internal_error"
+)
+
+// apiErrorCodes maps the engine's errors to API error codes and statuses
+var apiErrorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{types.ErrNotFound, CodeNotFound, http.StatusNotFound},
+	{types.ErrNoGroup, CodeNoGroup, http.StatusNotFound},
+	{types.ErrNotQueued, CodeNotQueued, http.StatusConflict},
+	{types.ErrRunning, CodeRunning, http.StatusConflict},
+	{types.ErrCompleted, CodeCompleted, http.StatusConflict},
+	{types.ErrSourceMismatch, CodeSourceMismatch, http.StatusConflict},
+	{types.ErrFileExists, CodeFileExists, http.StatusConflict},
+}
+
+// APIError is the error returned by the versioned API, sent as
+// {"error": {"code": ..., "message": ...}}
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.Status, e.Message)
+}
+
+// Unwrap returns the engine error the code stands for, so errors.Is works
+// the same against a remote daemon as locally
+func (e *APIError) Unwrap() error {
+	for _, c := range apiErrorCodes {
+		if c.code == e.Code {
+			return c.err
+		}
+	}
+	return nil
+}
+
+// NewAPIError classifies an error from the download service for an API
+// response. Errors the engine doesn't name are internal errors.
+func NewAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, c := range apiErrorCodes {
+		if errors.Is(err, c.err) {
+			return &APIError{Status: c.status, Code: c.code, Message: err.Error()}
+		}
+	}
+	return &APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: err.Error()}
+}
+
+// Page is one page of a list returned by the versioned API. Limit 0 means
+// the page holds everything from Offset on.
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"` // Matches before paging
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
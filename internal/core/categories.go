@@ -0,0 +1,21 @@
+package core
+
+import (
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// categoryRouter returns a router for the configured category rules, or nil if there are none
+func categoryRouter(rules []config.CategoryRule) types.CategoryRouter {
+	if len(rules) == 0 {
+		return nil
+	}
+	return func(url, filename, contentType string) (string, string, bool) {
+		rule, ok := config.MatchCategory(rules, url, filename, contentType)
+		if !ok {
+			return "", "", false
+		}
+		return rule.Name, utils.EnsureAbsPath(rule.ExpandDir()), true
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"os"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/extract"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// extractProgressInterval throttles ExtractProgressMsg events
+const extractProgressInterval = 250 * time.Millisecond
+
+// extractArchivesEnabled reports whether completed archives are unpacked
+func (s *LocalDownloadService) extractArchivesEnabled() bool {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.settings.Hooks.ExtractArchives
+}
+
+// archiveFormat returns the archive format of a completed download, or
+// extract.FormatNone if it is not an archive
+func (s *LocalDownloadService) archiveFormat(destPath string) extract.Format {
+	if destPath == "" {
+		return extract.FormatNone
+	}
+
+	format, err := extract.Detect(destPath)
+	if err != nil {
+		utils.Debug("Extract: failed to inspect %s: %v", destPath, err)
+		return extract.FormatNone
+	}
+	return format
+}
+
+// runExtraction unpacks a completed download next to it, then runs the
+// on-complete hook (if any) so it sees the extracted files.
+// The caller marks the download as extracting before starting it.
+func (s *LocalDownloadService) runExtraction(env hookEnv, format extract.Format, command string, timeout time.Duration) {
+	s.settingsMu.RLock()
+	deleteArchive := s.settings.Hooks.DeleteArchiveAfterExtract
+	s.settingsMu.RUnlock()
+
+	destDir := extract.DestDir(env.DestPath)
+	s.publishExtractEvent(events.ExtractStartedMsg{
+		DownloadID: env.ID,
+		Filename:   env.Filename,
+		Archive:    env.DestPath,
+		DestDir:    destDir,
+		Format:     string(format),
+	})
+
+	start := time.Now()
+	var lastReport time.Time
+	entries, err := extract.Extract(s.ctx, env.DestPath, destDir, format, func(done, total int64) {
+		if time.Since(lastReport) < extractProgressInterval && done < total {
+			return
+		}
+		lastReport = time.Now()
+		s.publishExtractEvent(events.ExtractProgressMsg{DownloadID: env.ID, Done: done, Total: total})
+	})
+	s.setExtracting(env.ID, false)
+	if err != nil {
+		utils.Debug("Extract: %s failed: %v", env.DestPath, err)
+		s.publishExtractEvent(events.ExtractErrorMsg{DownloadID: env.ID, Filename: env.Filename, Err: err.Error()})
+	} else {
+		deleted := false
+		if deleteArchive {
+			if rmErr := os.Remove(env.DestPath); rmErr != nil {
+				utils.Debug("Extract: failed to delete archive %s: %v", env.DestPath, rmErr)
+			} else {
+				deleted = true
+			}
+		}
+		env.ExtractDir = destDir
+		s.publishExtractEvent(events.ExtractCompleteMsg{
+			DownloadID:     env.ID,
+			Filename:       env.Filename,
+			DestDir:        destDir,
+			Entries:        entries,
+			Elapsed:        time.Since(start),
+			ArchiveDeleted: deleted,
+		})
+	}
+
+	if command != "" {
+		s.runHook(command, timeout, env)
+	}
+}
+
+func (s *LocalDownloadService) publishExtractEvent(msg interface{}) {
+	if s.ctx.Err() != nil {
+		return // Service shut down mid-extraction
+	}
+	if err := s.Publish(msg); err != nil {
+		utils.Debug("Failed to publish extract event: %v", err)
+	}
+}
+
+func (s *LocalDownloadService) setExtracting(id string, extracting bool) {
+	s.extractingMu.Lock()
+	defer s.extractingMu.Unlock()
+	if extracting {
+		s.extracting[id] = true
+	} else {
+		delete(s.extracting, id)
+	}
+}
+
+// isExtracting reports whether a completed download is still being unpacked
+func (s *LocalDownloadService) isExtracting(id string) bool {
+	s.extractingMu.Lock()
+	defer s.extractingMu.Unlock()
+	return s.extracting[id]
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func seedCompleted(t *testing.T, id, destPath string) {
+	t.Helper()
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:       id,
+		URL:      "https://example.com/" + filepath.Base(destPath),
+		DestPath: destPath,
+		Filename: filepath.Base(destPath),
+		Status:   "completed",
+	}); err != nil {
+		t.Fatalf("failed to seed download: %v", err)
+	}
+}
+
+func waitForExtractResult(t *testing.T, stream <-chan interface{}, id string) interface{} {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-stream:
+			switch m := msg.(type) {
+			case events.ExtractCompleteMsg:
+				if m.DownloadID == id {
+					return m
+				}
+			case events.ExtractErrorMsg:
+				if m.DownloadID == id {
+					return m
+				}
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for extraction result")
+		}
+	}
+}
+
+func TestExtract_CompletedArchiveIsUnpacked(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "hook.out")
+	svc, stream := newHookTestService(t, config.HookSettings{
+		OnComplete:                `echo "$SURGE_EXTRACT_DIR" > "` + outFile + `"`,
+		HookTimeout:               5 * time.Second,
+		ExtractArchives:           true,
+		DeleteArchiveAfterExtract: true,
+	})
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "bundle.zip")
+	writeTestZip(t, archive, map[string]string{"a/readme.txt": "hi"})
+
+	id := "extract-id"
+	seedCompleted(t, id, archive)
+	if err := svc.Publish(events.DownloadCompleteMsg{DownloadID: id, Filename: "bundle.zip"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	m, ok := waitForExtractResult(t, stream, id).(events.ExtractCompleteMsg)
+	if !ok {
+		t.Fatal("expected ExtractCompleteMsg")
+	}
+	wantDir := filepath.Join(dir, "bundle")
+	if m.DestDir != wantDir || m.Entries != 1 || !m.ArchiveDeleted {
+		t.Errorf("unexpected result: %+v", m)
+	}
+	if content, err := os.ReadFile(filepath.Join(wantDir, "a", "readme.txt")); err != nil || string(content) != "hi" {
+		t.Errorf("extracted file = %q, %v", content, err)
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Error("archive should have been deleted")
+	}
+
+	// The on-complete hook runs after extraction and sees the extracted folder
+	waitForHookResult(t, stream, id)
+	out, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("hook output missing: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != wantDir {
+		t.Errorf("SURGE_EXTRACT_DIR = %q, want %q", got, wantDir)
+	}
+
+	if status, err := svc.GetStatus(id); err != nil || status.Status != "completed" {
+		t.Errorf("status after extraction = %+v, %v", status, err)
+	}
+}
+
+func TestExtract_BadArchiveReportsErrorAndKeepsFile(t *testing.T) {
+	svc, stream := newHookTestService(t, config.HookSettings{ExtractArchives: true, DeleteArchiveAfterExtract: true})
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "evil.zip")
+	writeTestZip(t, archive, map[string]string{"../escape.txt": "x"})
+
+	id := "extract-bad-id"
+	seedCompleted(t, id, archive)
+	if err := svc.Publish(events.DownloadCompleteMsg{DownloadID: id, Filename: "evil.zip"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	if _, ok := waitForExtractResult(t, stream, id).(events.ExtractErrorMsg); !ok {
+		t.Fatal("expected ExtractErrorMsg")
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Errorf("archive should be kept after a failed extraction: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "escape.txt")); !os.IsNotExist(err) {
+		t.Error("entry escaped the destination directory")
+	}
+}
+
+func TestExtract_DisabledByDefault(t *testing.T) {
+	svc, stream := newHookTestService(t, config.HookSettings{})
+
+	archive := filepath.Join(t.TempDir(), "keep.zip")
+	writeTestZip(t, archive, map[string]string{"f.txt": "x"})
+	id := "extract-off-id"
+	seedCompleted(t, id, archive)
+
+	if err := svc.Publish(events.DownloadCompleteMsg{DownloadID: id, Filename: "keep.zip"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	timeout := time.After(300 * time.Millisecond)
+	for {
+		select {
+		case msg := <-stream:
+			if _, ok := msg.(events.ExtractStartedMsg); ok {
+				t.Fatal("extraction should not run when disabled")
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// Groups returns combined progress for every download group.
+func (s *LocalDownloadService) Groups() ([]types.GroupStatus, error) {
+	statuses, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return types.SummarizeGroups(statuses), nil
+}
+
+// GroupAction applies pause, resume, delete or retry to every download in a
+// group. Members the action does not apply to (e.g. completed downloads on
+// pause) are left alone.
+func (s *LocalDownloadService) GroupAction(name string, action string) error {
+	if !types.IsValidGroupAction(action) {
+		return fmt.Errorf("invalid group action %q (want pause, resume, delete or retry)", action)
+	}
+
+	statuses, err := s.List()
+	if err != nil {
+		return err
+	}
+	var members []types.DownloadStatus
+	for _, st := range statuses {
+		if st.Group == name {
+			members = append(members, st)
+		}
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("%w %q", types.ErrNoGroup, name)
+	}
+
+	var errs []error
+	switch action {
+	case types.GroupPause:
+		for _, m := range members {
+			switch m.Status {
+			case "downloading", "queued", "scheduled":
+				if err := s.Pause(m.ID); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", m.ID, err))
+				}
+			}
+		}
+	case types.GroupResume, types.GroupRetry:
+		want := "paused"
+		if action == types.GroupRetry {
+			want = "error"
+		}
+		var ids []string
+		for _, m := range members {
+			if m.Status == want {
+				ids = append(ids, m.ID)
+			}
+		}
+		for i, err := range s.ResumeBatch(ids) {
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", ids[i], err))
+			}
+		}
+	case types.GroupDelete:
+		for _, m := range members {
+			if err := s.Delete(m.ID); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", m.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
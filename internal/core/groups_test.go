@@ -0,0 +1,60 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestLocalDownloadService_GroupAction(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	ch := make(chan interface{}, 20)
+	pool := download.NewWorkerPool(ch, 1)
+	svc := NewLocalDownloadServiceWithInput(pool, ch)
+	defer func() { _ = svc.Shutdown() }()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "g1", URL: "https://example.com/1", DestPath: filepath.Join(tempDir, "1"), Filename: "1", Status: "completed", Group: "batch"},
+		{ID: "g2", URL: "https://example.com/2", DestPath: filepath.Join(tempDir, "2"), Filename: "2", Status: "error", Group: "batch"},
+		{ID: "other", URL: "https://example.com/3", DestPath: filepath.Join(tempDir, "3"), Filename: "3", Status: "completed"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("failed to seed %s: %v", e.ID, err)
+		}
+	}
+
+	groups, err := svc.Groups()
+	if err != nil {
+		t.Fatalf("Groups: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "batch" || groups[0].Count != 2 || groups[0].Failed != 1 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	if err := svc.GroupAction("batch", "explode"); err == nil {
+		t.Error("expected error for invalid action")
+	}
+	if err := svc.GroupAction("missing", types.GroupPause); !errors.Is(err, types.ErrNoGroup) {
+		t.Errorf("expected ErrNoGroup, got %v", err)
+	}
+
+	if err := svc.GroupAction("batch", types.GroupDelete); err != nil {
+		t.Fatalf("group delete: %v", err)
+	}
+	for _, id := range []string{"g1", "g2"} {
+		if e, _ := state.GetDownload(id); e != nil {
+			t.Errorf("expected %s to be deleted", id)
+		}
+	}
+	if e, _ := state.GetDownload("other"); e == nil {
+		t.Error("download outside the group should be kept")
+	}
+}
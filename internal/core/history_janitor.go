@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// historyJanitorInterval is how often the janitor applies retention settings
+const historyJanitorInterval = time.Hour
+
+// HistoryJanitor periodically drops finished downloads according to the
+// history retention settings, so long-running daemons don't accumulate rows.
+type HistoryJanitor struct {
+	events       chan<- interface{}
+	loadSettings func() (*config.Settings, error)
+	interval     time.Duration
+	now          func() time.Time
+}
+
+// NewHistoryJanitor creates a janitor that reports what it prunes on events
+func NewHistoryJanitor(events chan<- interface{}) *HistoryJanitor {
+	return &HistoryJanitor{
+		events:       events,
+		loadSettings: config.LoadSettings,
+		interval:     historyJanitorInterval,
+		now:          time.Now,
+	}
+}
+
+// Start runs a cleanup pass now and then every interval until ctx is done.
+// Settings are re-read on each pass, so edits apply without a restart.
+func (j *HistoryJanitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := j.Run(); err != nil {
+				utils.Debug("History janitor: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run applies the retention settings once and returns how many downloads
+// were dropped
+func (j *HistoryJanitor) Run() (int, error) {
+	settings, err := j.loadSettings()
+	if err != nil || settings == nil {
+		return 0, err
+	}
+	policy := retentionPolicy(settings.History)
+	if policy.IsZero() {
+		return 0, nil
+	}
+
+	pruned, err := state.PruneHistory(policy, j.now())
+	if err != nil || len(pruned) == 0 {
+		return 0, err
+	}
+
+	msg := events.HistoryPrunedMsg{DownloadIDs: make([]string, len(pruned))}
+	for i, e := range pruned {
+		msg.DownloadIDs[i] = e.ID
+		if settings.History.DeleteHistoryFiles && deleteHistoryFile(e) {
+			msg.FilesDeleted++
+		}
+	}
+	utils.Debug("History janitor: dropped %d downloads, deleted %d files", len(pruned), msg.FilesDeleted)
+
+	if j.events != nil {
+		j.events <- msg
+	}
+	return len(pruned), nil
+}
+
+// retentionPolicy converts history settings into a state retention policy
+func retentionPolicy(h config.HistorySettings) state.RetentionPolicy {
+	const day = 24 * time.Hour
+	return state.RetentionPolicy{
+		MaxAge:      time.Duration(h.HistoryRetentionDays) * day,
+		MaxEntries:  h.HistoryMaxEntries,
+		ErrorMaxAge: time.Duration(h.ErrorRetentionDays) * day,
+	}
+}
+
+// deleteHistoryFile removes what a dropped download left on disk: the file
+// of a completed download, or the partial file of a failed one. Files that
+// a remaining download still points at (after an overwrite or re-download)
+// are kept. It reports whether a file was removed.
+func deleteHistoryFile(e types.DownloadEntry) bool {
+	if e.DestPath == "" {
+		return false
+	}
+	if inUse, err := state.DestPathInUse(e.DestPath); err != nil || inUse {
+		if err != nil {
+			utils.Debug("History janitor: keeping %s: %v", e.DestPath, err)
+		}
+		return false
+	}
+	path := e.DestPath
+	if e.Status != "completed" {
+		path += types.IncompleteSuffix
+	}
+	if err := os.Remove(path); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			utils.Debug("History janitor: failed to delete %s: %v", path, err)
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestHistoryJanitorRun(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	dir := t.TempDir()
+	now := time.Unix(100*86400, 0)
+	day := int64(86400)
+	entries := []types.DownloadEntry{
+		{ID: "old", Filename: "old.bin", Status: "completed", CompletedAt: now.Unix() - 40*day},
+		{ID: "new", Filename: "new.bin", Status: "completed", CompletedAt: now.Unix() - day},
+		{ID: "failed", Filename: "failed.bin", Status: "error", CompletedAt: now.Unix() - 10*day},
+		{ID: "paused", Filename: "paused.bin", Status: "paused"},
+	}
+	for _, e := range entries {
+		e.URL = "https://example.com/" + e.Filename
+		e.DestPath = filepath.Join(dir, e.Filename)
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"old.bin", "new.bin", "failed.bin" + types.IncompleteSuffix} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := config.DefaultSettings()
+	ch := make(chan interface{}, 1)
+	j := NewHistoryJanitor(ch)
+	j.loadSettings = func() (*config.Settings, error) { return settings, nil }
+	j.now = func() time.Time { return now }
+
+	// Default settings keep everything
+	if n, err := j.Run(); err != nil || n != 0 {
+		t.Fatalf("Run with defaults = %d, %v; want 0", n, err)
+	}
+
+	settings.History.HistoryRetentionDays = 30
+	settings.History.ErrorRetentionDays = 7
+	settings.History.DeleteHistoryFiles = true
+	n, err := j.Run()
+	if err != nil || n != 2 {
+		t.Fatalf("Run = %d, %v; want 2", n, err)
+	}
+
+	msg := (<-ch).(events.HistoryPrunedMsg)
+	if len(msg.DownloadIDs) != 2 || msg.FilesDeleted != 2 {
+		t.Errorf("event = %+v, want 2 downloads and 2 files", msg)
+	}
+	for _, name := range []string{"old.bin", "failed.bin" + types.IncompleteSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not deleted", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.bin")); err != nil {
+		t.Errorf("new.bin should be kept: %v", err)
+	}
+	for _, id := range []string{"new", "paused"} {
+		if e, _ := state.GetDownload(id); e == nil {
+			t.Errorf("%s should be kept", id)
+		}
+	}
+}
+
+func TestHistoryJanitorKeepsSharedFile(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	// A re-download to the same path left two completed rows for one file
+	path := filepath.Join(t.TempDir(), "file.bin")
+	now := time.Unix(100*86400, 0)
+	for id, completedAt := range map[string]int64{"first": now.Unix() - 40*86400, "second": now.Unix()} {
+		if err := state.AddToMasterList(types.DownloadEntry{
+			ID: id, URL: "https://example.com/file.bin", DestPath: path, Filename: "file.bin",
+			Status: "completed", CompletedAt: completedAt,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := config.DefaultSettings()
+	settings.History.HistoryRetentionDays = 30
+	settings.History.DeleteHistoryFiles = true
+	j := NewHistoryJanitor(nil)
+	j.loadSettings = func() (*config.Settings, error) { return settings, nil }
+	j.now = func() time.Time { return now }
+
+	if n, err := j.Run(); err != nil || n != 1 {
+		t.Fatalf("Run = %d, %v; want 1", n, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file of the remaining download was deleted: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/extract"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Hook event names, exposed to hook commands as SURGE_EVENT
+const (
+	HookEventComplete = "complete"
+	HookEventError    = "error"
+	HookEventPause    = "pause"
+)
+
+// maxHookOutput caps how much hook output is kept for the log
+const maxHookOutput = 1024
+
+// hookEnv describes the download a hook runs for
+type hookEnv struct {
+	Event      string
+	ID         string
+	URL        string
+	DestPath   string
+	Filename   string
+	Size       int64
+	Downloaded int64
+	Elapsed    time.Duration
+	Err        string
+	ExtractDir string
+}
+
+// environ returns the SURGE_* variables passed to hook commands
+func (e hookEnv) environ() []string {
+	return []string{
+		"SURGE_EVENT=" + e.Event,
+		"SURGE_ID=" + e.ID,
+		"SURGE_URL=" + e.URL,
+		"SURGE_DEST=" + e.DestPath,
+		"SURGE_FILENAME=" + e.Filename,
+		"SURGE_SIZE=" + strconv.FormatInt(e.Size, 10),
+		"SURGE_DOWNLOADED=" + strconv.FormatInt(e.Downloaded, 10),
+		"SURGE_ELAPSED=" + strconv.FormatFloat(e.Elapsed.Seconds(), 'f', 3, 64),
+		"SURGE_ERROR=" + e.Err,
+		"SURGE_EXTRACT_DIR=" + e.ExtractDir,
+	}
+}
+
+// dispatchHooks starts the configured hook for lifecycle events, unpacking
+// completed archives first when extraction is enabled.
+// Everything but the extracting mark runs in the background, so neither the
+// database lookups nor a slow command ever stall event delivery.
+func (s *LocalDownloadService) dispatchHooks(msg interface{}) {
+	var env hookEnv
+	switch m := msg.(type) {
+	case events.DownloadCompleteMsg:
+		env = hookEnv{Event: HookEventComplete, ID: m.DownloadID, Filename: m.Filename, Size: m.Total, Downloaded: m.Total, Elapsed: m.Elapsed}
+	case events.DownloadErrorMsg:
+		env = hookEnv{Event: HookEventError, ID: m.DownloadID, Filename: m.Filename}
+		if m.Err != nil {
+			env.Err = m.Err.Error()
+		}
+	case events.DownloadPausedMsg:
+		env = hookEnv{Event: HookEventPause, ID: m.DownloadID, Filename: m.Filename, Downloaded: m.Downloaded}
+	case events.DownloadRemovedMsg:
+		// The saved row went with the download's own row
+		s.forgetOptions(m.DownloadID)
+		return
+	default:
+		return
+	}
+
+	// Mark before returning so status queries never see a finished download
+	// mid-extraction; runHooks clears it again if the file is no archive
+	mayExtract := env.Event == HookEventComplete && s.extractArchivesEnabled()
+	if mayExtract {
+		s.setExtracting(env.ID, true)
+	}
+	go s.runHooks(env, mayExtract)
+}
+
+// runHooks looks up the hook for env and runs it, extracting a completed
+// archive first when mayExtract is set
+func (s *LocalDownloadService) runHooks(env hookEnv, mayExtract bool) {
+	command, timeout := s.hookCommand(env.ID, env.Event)
+	// Failed and paused downloads may be retried, so they keep their hooks
+	if env.Event == HookEventComplete {
+		s.clearOptions(env.ID)
+	}
+	if command == "" && !mayExtract {
+		return
+	}
+
+	s.fillHookEnv(&env)
+	if mayExtract {
+		if format := s.archiveFormat(env.DestPath); format != extract.FormatNone {
+			s.runExtraction(env, format, command, timeout)
+			return
+		}
+		s.setExtracting(env.ID, false)
+	}
+
+	if command != "" {
+		s.runHook(command, timeout, env)
+	}
+}
+
+// hookCommand returns the command for event, preferring per-download options over settings
+func (s *LocalDownloadService) hookCommand(id, event string) (string, time.Duration) {
+	s.settingsMu.RLock()
+	hooks := s.settings.Hooks
+	s.settingsMu.RUnlock()
+	opts := s.getOptions(id)
+
+	var command string
+	switch event {
+	case HookEventComplete:
+		command = firstNonEmpty(opts.OnComplete, hooks.OnComplete)
+	case HookEventError:
+		command = firstNonEmpty(opts.OnError, hooks.OnError)
+	case HookEventPause:
+		command = firstNonEmpty(opts.OnPause, hooks.OnPause)
+	}
+	return strings.TrimSpace(command), hooks.HookTimeout
+}
+
+// fillHookEnv completes env with URL, path and size from the pool or the database
+func (s *LocalDownloadService) fillHookEnv(env *hookEnv) {
+	if s.Pool != nil {
+		for _, cfg := range s.Pool.GetAll() {
+			if cfg.ID != env.ID {
+				continue
+			}
+			env.URL = cfg.URL
+			env.DestPath = cfg.DestPath
+			if env.Filename == "" {
+				env.Filename = cfg.Filename
+			}
+			if cfg.State != nil {
+				if env.DestPath == "" {
+					env.DestPath = cfg.State.DestPath
+				}
+				downloaded, total, elapsed, _, _, _ := cfg.State.GetProgress()
+				if env.Size == 0 {
+					env.Size = total
+				}
+				if env.Downloaded == 0 {
+					env.Downloaded = downloaded
+				}
+				if env.Elapsed == 0 {
+					env.Elapsed = elapsed
+				}
+			}
+			return
+		}
+	}
+
+	entry, err := state.GetDownload(env.ID)
+	if err != nil || entry == nil {
+		return
+	}
+	env.URL = entry.URL
+	env.DestPath = entry.DestPath
+	if env.Filename == "" {
+		env.Filename = entry.Filename
+	}
+	if env.Size == 0 {
+		env.Size = entry.TotalSize
+	}
+	if env.Downloaded == 0 {
+		env.Downloaded = entry.Downloaded
+	}
+	if env.Elapsed == 0 {
+		env.Elapsed = time.Duration(entry.TimeTaken) * time.Millisecond
+	}
+}
+
+// runHook executes command through the system shell and publishes its exit status
+func (s *LocalDownloadService) runHook(command string, timeout time.Duration, env hookEnv) {
+	ctx := s.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env.environ()...)
+	// Don't wait forever on pipes held open by children of a killed shell
+	cmd.WaitDelay = time.Second
+	if dir := hookWorkDir(env.DestPath); dir != "" {
+		cmd.Dir = dir
+	}
+
+	out, err := cmd.CombinedOutput()
+
+	result := events.HookResultMsg{
+		DownloadID: env.ID,
+		Filename:   env.Filename,
+		Event:      env.Event,
+		Command:    command,
+		Output:     tailOutput(out),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.ExitCode = -1
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Err = fmt.Sprintf("timed out after %s", timeout)
+		} else {
+			result.Err = err.Error()
+		}
+	}
+
+	utils.Debug("Hook %s for %s exited %d: %s", env.Event, env.ID, result.ExitCode, result.Output)
+	if s.ctx.Err() != nil {
+		return // Service shut down while the hook ran
+	}
+	if pubErr := s.Publish(result); pubErr != nil {
+		utils.Debug("Failed to publish hook result: %v", pubErr)
+	}
+}
+
+// hookWorkDir runs hooks next to the downloaded file when its directory exists
+func hookWorkDir(destPath string) string {
+	if destPath == "" {
+		return ""
+	}
+	dir := filepath.Dir(destPath)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return ""
+}
+
+// tailOutput keeps the last maxHookOutput bytes of hook output
+func tailOutput(out []byte) string {
+	if len(out) > maxHookOutput {
+		out = out[len(out)-maxHookOutput:]
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
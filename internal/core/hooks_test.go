@@ -0,0 +1,219 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// newHookTestService returns a service with isolated state and the given hook settings
+func newHookTestService(t *testing.T, hooks config.HookSettings) (*LocalDownloadService, <-chan interface{}) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell hook commands")
+	}
+
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	svc := NewLocalDownloadService(nil)
+	t.Cleanup(func() { _ = svc.Shutdown() })
+
+	settings := config.DefaultSettings()
+	settings.Hooks = hooks
+	svc.settingsMu.Lock()
+	svc.settings = settings
+	svc.settingsMu.Unlock()
+
+	stream, cleanup, err := svc.StreamEvents(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stream events: %v", err)
+	}
+	t.Cleanup(cleanup)
+	return svc, stream
+}
+
+func waitForHookResult(t *testing.T, stream <-chan interface{}, id string) events.HookResultMsg {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-stream:
+			if m, ok := msg.(events.HookResultMsg); ok && m.DownloadID == id {
+				return m
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for HookResultMsg")
+		}
+	}
+}
+
+func TestHooks_OnCompleteReceivesEnvironment(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "hook.out")
+	svc, stream := newHookTestService(t, config.HookSettings{
+		OnComplete:  `echo "$SURGE_EVENT|$SURGE_ID|$SURGE_URL|$SURGE_DEST|$SURGE_SIZE" > "` + outFile + `"`,
+		HookTimeout: 5 * time.Second,
+	})
+
+	id := "hook-complete-id"
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:        id,
+		URL:       "https://example.com/file.bin",
+		DestPath:  destPath,
+		Filename:  "file.bin",
+		Status:    "completed",
+		TotalSize: 1234,
+	}); err != nil {
+		t.Fatalf("failed to seed download: %v", err)
+	}
+
+	if err := svc.Publish(events.DownloadCompleteMsg{DownloadID: id, Filename: "file.bin", Total: 1234}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	result := waitForHookResult(t, stream, id)
+	if result.Event != HookEventComplete || result.ExitCode != 0 || result.Err != "" {
+		t.Fatalf("unexpected hook result: %+v", result)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("hook did not write output: %v", err)
+	}
+	want := "complete|" + id + "|https://example.com/file.bin|" + destPath + "|1234"
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("hook env = %q, want %q", got, want)
+	}
+}
+
+func TestHooks_OnErrorReportsExitStatus(t *testing.T) {
+	svc, stream := newHookTestService(t, config.HookSettings{
+		OnError:     `echo "$SURGE_ERROR"; exit 3`,
+		HookTimeout: 5 * time.Second,
+	})
+
+	id := "hook-error-id"
+	if err := svc.Publish(events.DownloadErrorMsg{DownloadID: id, Filename: "bad.bin", Err: errors.New("boom")}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	result := waitForHookResult(t, stream, id)
+	if result.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", result.ExitCode)
+	}
+	if result.Output != "boom" {
+		t.Errorf("expected error message in output, got %q", result.Output)
+	}
+}
+
+func TestHooks_PerDownloadOverridesSettings(t *testing.T) {
+	svc, stream := newHookTestService(t, config.HookSettings{
+		OnPause:     `exit 1`,
+		HookTimeout: 5 * time.Second,
+	})
+
+	id := "hook-override-id"
+	svc.setOptions(id, DownloadOptions{OnPause: `echo override`})
+
+	if err := svc.Publish(events.DownloadPausedMsg{DownloadID: id, Filename: "p.bin"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	result := waitForHookResult(t, stream, id)
+	if result.ExitCode != 0 || result.Output != "override" {
+		t.Errorf("expected per-download hook to run, got %+v", result)
+	}
+
+	// Pause keeps options for the eventual completion
+	if svc.getOptions(id).OnPause == "" {
+		t.Error("expected options to survive a pause")
+	}
+}
+
+func TestHooks_PerDownloadSurviveRestart(t *testing.T) {
+	svc, _ := newHookTestService(t, config.HookSettings{HookTimeout: 5 * time.Second})
+
+	id := "hook-restart-id"
+	svc.setOptions(id, DownloadOptions{OnComplete: `echo saved`, Priority: 3})
+
+	// A second service on the same database stands in for a restart
+	restarted := NewLocalDownloadService(nil)
+	defer func() { _ = restarted.Shutdown() }()
+	if command, _ := restarted.hookCommand(id, HookEventComplete); command != "echo saved" {
+		t.Errorf("hook after restart = %q, want the saved override", command)
+	}
+
+	// A failed download may be retried, so it keeps its hooks
+	if err := restarted.Publish(events.DownloadErrorMsg{DownloadID: id, Err: errors.New("boom")}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := restarted.Publish(events.DownloadCompleteMsg{DownloadID: id}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hooks, err := state.LoadDownloadHooks(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hooks.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("hooks still saved after completion: %+v", hooks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHooks_ErrorKeepsPerDownloadHooks(t *testing.T) {
+	svc, stream := newHookTestService(t, config.HookSettings{HookTimeout: 5 * time.Second})
+
+	id := "hook-retry-id"
+	svc.setOptions(id, DownloadOptions{OnError: `echo failed`, OnComplete: `echo done`})
+
+	if err := svc.Publish(events.DownloadErrorMsg{DownloadID: id, Err: errors.New("boom")}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if result := waitForHookResult(t, stream, id); result.Output != "failed" {
+		t.Fatalf("unexpected hook result: %+v", result)
+	}
+
+	// The retry still runs the per-download completion hook
+	if err := svc.Publish(events.DownloadCompleteMsg{DownloadID: id}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if result := waitForHookResult(t, stream, id); result.Output != "done" {
+		t.Errorf("hook after retry = %+v, want the per-download override", result)
+	}
+}
+
+func TestHooks_Timeout(t *testing.T) {
+	svc, stream := newHookTestService(t, config.HookSettings{
+		OnComplete:  `sleep 5`,
+		HookTimeout: 100 * time.Millisecond,
+	})
+
+	id := "hook-timeout-id"
+	if err := svc.Publish(events.DownloadCompleteMsg{DownloadID: id, Filename: "slow.bin"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	result := waitForHookResult(t, stream, id)
+	if result.ExitCode != -1 || result.Err == "" {
+		t.Errorf("expected timeout failure, got %+v", result)
+	}
+}
@@ -2,10 +2,39 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"github.com/surge-downloader/surge/internal/engine/types"
 )
 
+// DownloadOptions holds optional per-download settings.
+// Zero values fall back to the global settings.
+type DownloadOptions struct {
+	OnComplete string `json:"on_complete,omitempty"` // Hook command overriding hooks.on_complete
+	OnError    string `json:"on_error,omitempty"`    // Hook command overriding hooks.on_error
+	OnPause    string `json:"on_pause,omitempty"`    // Hook command overriding hooks.on_pause
+	Conflict   string `json:"conflict,omitempty"`    // File conflict policy overriding general.conflict_policy
+	Priority   int    `json:"priority,omitempty"`    // Queue priority, higher starts first
+
+	// StartAt holds the download back until this time (zero = start now)
+	StartAt time.Time `json:"start_at,omitzero"`
+
+	// Group ties the download to others (e.g. one batch file) for combined
+	// progress and group operations
+	Group string `json:"group,omitempty"`
+
+	types.DownloadMeta // Tags, note and referrer kept with the download
+
+	// AutoRoute applies category rules to pick the directory. Set by callers
+	// that received no explicit path; never sent over the wire.
+	AutoRoute bool `json:"-"`
+}
+
+// hooks returns the hook commands among the options
+func (o DownloadOptions) hooks() types.DownloadHooks {
+	return types.DownloadHooks{OnComplete: o.OnComplete, OnError: o.OnError, OnPause: o.OnPause}
+}
+
 // DownloadService defines the interface for interacting with the download engine.
 // This abstraction allows the TUI to switch between a local embedded backend
 // and a remote daemon connection.
@@ -16,9 +45,16 @@ type DownloadService interface {
 	// History returns completed downloads
 	History() ([]types.DownloadEntry, error)
 
+	// QueryHistory returns the finished downloads matching a filter, one
+	// page at a time, with the number that matched before paging.
+	QueryHistory(f types.HistoryFilter) ([]types.DownloadEntry, int, error)
+
 	// Add queues a new download.
 	Add(url string, path string, filename string, mirrors []string, headers map[string]string) (string, error)
 
+	// AddWithOptions queues a new download with per-download options.
+	AddWithOptions(url string, path string, filename string, mirrors []string, headers map[string]string, opts DownloadOptions) (string, error)
+
 	// Pause pauses an active download.
 	Pause(id string) error
 
@@ -31,6 +67,42 @@ type DownloadService interface {
 	// Delete cancels and removes a download.
 	Delete(id string) error
 
+	// Move moves or renames a download's file. newPath is the new file path,
+	// or a directory to move it into. Running downloads continue from there.
+	Move(id string, newPath string) error
+
+	// Relink points a stopped download at a new URL, keeping its progress.
+	// Unless force is set, the new URL must serve the same file. Non-nil
+	// headers replace the saved request headers, which otherwise only go to
+	// a new URL on the same host.
+	Relink(id string, url string, headers map[string]string, force bool) error
+
+	// ReorderQueue moves a waiting download within the queue. op is one of
+	// types.QueueMoveTop, QueueMoveUp, QueueMoveDown or QueueMoveBottom.
+	ReorderQueue(id string, op string) error
+
+	// MaxConcurrent returns how many downloads run at once.
+	MaxConcurrent() (int, error)
+
+	// SetMaxConcurrent changes how many downloads run at once, applying it to
+	// the running pool and saving it to settings.
+	SetMaxConcurrent(n int) error
+
+	// ReloadSettings rereads settings.json and applies it to running
+	// downloads.
+	ReloadSettings() error
+
+	// Groups returns combined progress for every download group.
+	Groups() ([]types.GroupStatus, error)
+
+	// GroupAction applies an operation to every download in a group. action
+	// is one of types.GroupPause, GroupResume, GroupDelete or GroupRetry.
+	GroupAction(name string, action string) error
+
+	// RefreshURL supplies a fresh URL (and optional headers) for a download
+	// whose link expired and is waiting on a refresh request.
+	RefreshURL(id string, url string, headers map[string]string) error
+
 	// StreamEvents returns a channel that receives real-time download events.
 	// For local mode, this is a direct channel.
 	// For remote mode, this is sourced from SSE.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"time"
 
@@ -17,7 +18,10 @@ import (
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
-// ReloadSettings reloads settings from disk
+// ReloadSettings reloads settings from disk and applies them to running
+// downloads, so retries, health checks, the user agent and per-host
+// connection limits change without a pause/resume. A new
+// max_concurrent_downloads resizes the pool.
 func (s *LocalDownloadService) ReloadSettings() error {
 	settings, err := config.LoadSettings()
 	if err != nil {
@@ -26,9 +30,24 @@ func (s *LocalDownloadService) ReloadSettings() error {
 	s.settingsMu.Lock()
 	s.settings = settings
 	s.settingsMu.Unlock()
+
+	if s.Pool != nil {
+		s.Pool.UpdateRuntime(types.ConvertRuntimeConfig(settings.ToRuntimeConfig()))
+		if n := settings.Connections.MaxConcurrentDownloads; n != s.Pool.MaxDownloads() {
+			return s.SetMaxConcurrent(n)
+		}
+	}
 	return nil
 }
 
+// currentSettings returns the settings the service runs with. Reloads swap
+// in a new value, so the result must be treated as read-only.
+func (s *LocalDownloadService) currentSettings() *config.Settings {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.settings
+}
+
 // LocalDownloadService implements DownloadService for the local embedded engine.
 type LocalDownloadService struct {
 	Pool    *download.WorkerPool
@@ -47,6 +66,19 @@ type LocalDownloadService struct {
 	// Settings Cache
 	settings   *config.Settings
 	settingsMu sync.RWMutex
+
+	// Per-download options, kept for the lifetime of the download. Hook
+	// commands are also in the database (see setOptions).
+	options   map[string]DownloadOptions
+	optionsMu sync.RWMutex
+
+	// Downloads waiting for a client to supply a refreshed URL
+	refreshWaiters map[string]chan *types.RefreshResult
+	refreshMu      sync.Mutex
+
+	// Completed downloads whose archive is being extracted
+	extracting   map[string]bool
+	extractingMu sync.Mutex
 }
 
 const (
@@ -66,9 +98,11 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 		inputCh = make(chan interface{}, 100)
 	}
 	s := &LocalDownloadService{
-		Pool:      pool,
-		InputCh:   inputCh,
-		listeners: make([]chan interface{}, 0),
+		Pool:       pool,
+		InputCh:    inputCh,
+		listeners:  make([]chan interface{}, 0),
+		options:    make(map[string]DownloadOptions),
+		extracting: make(map[string]bool),
 	}
 
 	// Load initial settings
@@ -95,6 +129,8 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 
 func (s *LocalDownloadService) broadcastLoop() {
 	for msg := range s.InputCh {
+		s.dispatchHooks(msg)
+
 		s.listenerMu.Lock()
 		for _, ch := range s.listeners {
 			// Check message type
@@ -206,6 +242,64 @@ func (s *LocalDownloadService) reportProgressLoop() {
 	}
 }
 
+// setOptions records per-download options (no-op for zero options). Hook
+// commands are also saved to the database so they outlive a restart.
+func (s *LocalDownloadService) setOptions(id string, opts DownloadOptions) {
+	if reflect.DeepEqual(opts, DownloadOptions{}) {
+		return
+	}
+	s.optionsMu.Lock()
+	s.options[id] = opts
+	s.optionsMu.Unlock()
+
+	if hooks := opts.hooks(); !hooks.IsZero() {
+		if err := state.SaveDownloadHooks(id, hooks); err != nil {
+			utils.Debug("Failed to save hooks for %s: %v", id, err)
+		}
+	}
+}
+
+// getOptions returns the per-download options for id, falling back to the
+// hooks saved in the database for downloads added before a restart
+func (s *LocalDownloadService) getOptions(id string) DownloadOptions {
+	s.optionsMu.RLock()
+	opts, ok := s.options[id]
+	s.optionsMu.RUnlock()
+	if ok {
+		return opts
+	}
+
+	hooks, err := state.LoadDownloadHooks(id)
+	if err != nil {
+		utils.Debug("Failed to load hooks for %s: %v", id, err)
+		return DownloadOptions{}
+	}
+	if hooks.IsZero() {
+		return DownloadOptions{}
+	}
+	opts = DownloadOptions{OnComplete: hooks.OnComplete, OnError: hooks.OnError, OnPause: hooks.OnPause}
+	s.optionsMu.Lock()
+	s.options[id] = opts
+	s.optionsMu.Unlock()
+	return opts
+}
+
+// clearOptions forgets per-download options, saved hooks included, once a
+// download has completed
+func (s *LocalDownloadService) clearOptions(id string) {
+	s.forgetOptions(id)
+	if err := state.DeleteDownloadHooks(id); err != nil {
+		utils.Debug("Failed to delete hooks for %s: %v", id, err)
+	}
+}
+
+// forgetOptions drops the in-memory options for id
+func (s *LocalDownloadService) forgetOptions(id string) {
+	s.optionsMu.Lock()
+	delete(s.options, id)
+	s.optionsMu.Unlock()
+}
+
 // StreamEvents returns a channel that receives real-time download events.
 func (s *LocalDownloadService) StreamEvents(ctx context.Context) (<-chan interface{}, func(), error) {
 	if ctx == nil {
@@ -283,10 +377,21 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 		activeConfigs := s.Pool.GetAll()
 		for _, cfg := range activeConfigs {
 			status := types.DownloadStatus{
-				ID:       cfg.ID,
-				URL:      cfg.URL,
-				Filename: cfg.Filename,
-				Status:   "downloading",
+				ID:           cfg.ID,
+				URL:          cfg.URL,
+				Filename:     cfg.Filename,
+				Status:       "downloading",
+				Category:     cfg.Category,
+				Priority:     cfg.Priority,
+				Group:        cfg.Group,
+				DownloadMeta: cfg.DownloadMeta,
+			}
+			if pos := s.Pool.QueuePosition(cfg.ID); pos > 0 {
+				status.Status = "queued"
+				status.QueuePosition = pos
+			} else if !cfg.StartAt.IsZero() {
+				status.Status = "scheduled"
+				status.StartAt = cfg.StartAt.Unix()
 			}
 
 			if cfg.State != nil {
@@ -360,25 +465,41 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 			}
 
 			statuses = append(statuses, types.DownloadStatus{
-				ID:          d.ID,
-				URL:         d.URL,
-				Filename:    d.Filename,
-				DestPath:    d.DestPath,
-				Status:      d.Status,
-				TotalSize:   d.TotalSize,
-				Downloaded:  d.Downloaded,
-				Progress:    progress,
-				Speed:       speed,
-				Connections: 0,
+				ID:           d.ID,
+				URL:          d.URL,
+				Filename:     d.Filename,
+				DestPath:     d.DestPath,
+				Status:       d.Status,
+				TotalSize:    d.TotalSize,
+				Downloaded:   d.Downloaded,
+				Progress:     progress,
+				Speed:        speed,
+				Connections:  0,
+				Category:     d.Category,
+				Priority:     d.Priority,
+				StartAt:      d.StartAt,
+				Group:        d.Group,
+				DownloadMeta: d.DownloadMeta,
 			})
 		}
 	}
 
+	for i := range statuses {
+		if statuses[i].Status == "completed" && s.isExtracting(statuses[i].ID) {
+			statuses[i].Status = "extracting"
+		}
+	}
+
 	return statuses, nil
 }
 
 // Add queues a new download.
 func (s *LocalDownloadService) Add(url string, path string, filename string, mirrors []string, headers map[string]string) (string, error) {
+	return s.AddWithOptions(url, path, filename, mirrors, headers, DownloadOptions{})
+}
+
+// AddWithOptions queues a new download with per-download options.
+func (s *LocalDownloadService) AddWithOptions(url string, path string, filename string, mirrors []string, headers map[string]string, opts DownloadOptions) (string, error) {
 	if s.Pool == nil {
 		return "", fmt.Errorf("worker pool not initialized")
 	}
@@ -415,8 +536,22 @@ func (s *LocalDownloadService) Add(url string, path string, filename string, mir
 		State:      state,
 		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
 		Headers:    headers,
+		Refresher:  s.urlRefresher(settings),
+	}
+	if opts.AutoRoute {
+		cfg.Router = categoryRouter(settings.Categories)
+	}
+	cfg.Conflict = opts.Conflict
+	if cfg.Conflict == "" {
+		cfg.Conflict = settings.General.ConflictPolicy
 	}
+	cfg.Priority = opts.Priority
+	cfg.StartAt = opts.StartAt
+	cfg.Group = opts.Group
+	cfg.DownloadMeta = opts.DownloadMeta
+	cfg.Tags = types.NormalizeTags(opts.Tags)
 
+	s.setOptions(id, opts)
 	s.Pool.Add(cfg)
 
 	return id, nil
@@ -446,7 +581,7 @@ func (s *LocalDownloadService) Pause(id string) error {
 		return nil // Already stopped
 	}
 
-	return fmt.Errorf("download not found")
+	return types.ErrNotFound
 }
 
 // Resume resumes a paused download.
@@ -463,11 +598,11 @@ func (s *LocalDownloadService) Resume(id string) error {
 	// Cold Resume Logic
 	entry, err := state.GetDownload(id)
 	if err != nil || entry == nil {
-		return fmt.Errorf("download not found")
+		return types.ErrNotFound
 	}
 
 	if entry.Status == "completed" {
-		return fmt.Errorf("download already completed")
+		return types.ErrCompleted
 	}
 
 	s.settingsMu.RLock()
@@ -506,24 +641,46 @@ func (s *LocalDownloadService) Resume(id string) error {
 		dmState.Downloaded.Store(entry.Downloaded)
 		dmState.DestPath = entry.DestPath
 		mirrorURLs = []string{entry.URL}
+
+		// Without saved state (never started, paused while waiting, or
+		// failed) restart it fresh in the directory it was meant for
+		if entry.DestPath != "" {
+			outputPath = entry.DestPath
+			if entry.Filename != "" {
+				outputPath = filepath.Dir(entry.DestPath)
+			}
+		}
 	}
 
 	cfg := types.DownloadConfig{
-		URL:        entry.URL,
-		OutputPath: outputPath,
-		DestPath:   entry.DestPath,
-		ID:         id,
-		Filename:   entry.Filename,
-		Verbose:    false,
-		IsResume:   true,
-		ProgressCh: s.InputCh,
-		State:      dmState,
-		SavedState: savedState, // Pass loaded state to avoid re-query
-		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
-		Mirrors:    mirrorURLs,
+		URL:          entry.URL,
+		OutputPath:   outputPath,
+		DestPath:     entry.DestPath,
+		ID:           id,
+		Filename:     entry.Filename,
+		Verbose:      false,
+		IsResume:     true,
+		ProgressCh:   s.InputCh,
+		State:        dmState,
+		SavedState:   savedState, // Pass loaded state to avoid re-query
+		Runtime:      types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+		Mirrors:      mirrorURLs,
+		Refresher:    s.urlRefresher(settings),
+		Category:     entry.Category,
+		Priority:     entry.Priority,
+		Group:        entry.Group,
+		Headers:      entry.Headers,
+		DownloadMeta: entry.DownloadMeta,
+	}
+	if entry.Status == "scheduled" && entry.StartAt > 0 {
+		cfg.StartAt = time.Unix(entry.StartAt, 0)
 	}
 
 	s.Pool.Add(cfg)
+	if cfg.StartAt.After(time.Now()) {
+		// The pool announced it as scheduled; it hasn't resumed yet
+		return nil
+	}
 	if s.InputCh != nil {
 		s.InputCh <- events.DownloadResumedMsg{
 			DownloadID: id,
@@ -583,13 +740,22 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 		return errs
 	}
 
+	// Category, priority, group and headers live on the master list, not in the state
+	entries := make(map[string]types.DownloadEntry)
+	if list, err := state.LoadMasterList(); err == nil {
+		for _, e := range list.Downloads {
+			entries[e.ID] = e
+		}
+	}
+
 	// 3. Process loaded states
 	for _, id := range toLoad {
 		idx := idMap[id]
 		savedState, ok := states[id]
 		if !ok {
-			// Not found or completed (since LoadStates filters out completed)
-			errs[idx] = fmt.Errorf("download not found or completed")
+			// Queued downloads that never started have no saved state;
+			// the single-download path knows how to restart those
+			errs[idx] = s.Resume(id)
 			continue
 		}
 
@@ -613,18 +779,24 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 		dmState.DestPath = savedState.DestPath
 
 		cfg := types.DownloadConfig{
-			URL:        savedState.URL,
-			OutputPath: outputPath,
-			DestPath:   savedState.DestPath,
-			ID:         id,
-			Filename:   savedState.Filename,
-			Verbose:    false,
-			IsResume:   true,
-			ProgressCh: s.InputCh,
-			State:      dmState,
-			SavedState: savedState, // Pass loaded state to avoid re-query
-			Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
-			Mirrors:    mirrorURLs,
+			URL:          savedState.URL,
+			OutputPath:   outputPath,
+			DestPath:     savedState.DestPath,
+			ID:           id,
+			Filename:     savedState.Filename,
+			Verbose:      false,
+			IsResume:     true,
+			ProgressCh:   s.InputCh,
+			State:        dmState,
+			SavedState:   savedState, // Pass loaded state to avoid re-query
+			Runtime:      types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+			Mirrors:      mirrorURLs,
+			Refresher:    s.urlRefresher(settings),
+			Category:     entries[id].Category,
+			Priority:     entries[id].Priority,
+			Group:        entries[id].Group,
+			Headers:      entries[id].Headers,
+			DownloadMeta: entries[id].DownloadMeta,
 		}
 
 		s.Pool.Add(cfg)
@@ -698,19 +870,28 @@ func (s *LocalDownloadService) GetStatus(id string) (*types.DownloadStatus, erro
 		}
 
 		status := types.DownloadStatus{
-			ID:         entry.ID,
-			URL:        entry.URL,
-			Filename:   entry.Filename,
-			TotalSize:  entry.TotalSize,
-			Downloaded: entry.Downloaded,
-			Progress:   progress,
-			Speed:      speed,
-			Status:     entry.Status,
+			ID:           entry.ID,
+			URL:          entry.URL,
+			Filename:     entry.Filename,
+			DestPath:     entry.DestPath,
+			TotalSize:    entry.TotalSize,
+			Downloaded:   entry.Downloaded,
+			Progress:     progress,
+			Speed:        speed,
+			Status:       entry.Status,
+			Category:     entry.Category,
+			Priority:     entry.Priority,
+			StartAt:      entry.StartAt,
+			Group:        entry.Group,
+			DownloadMeta: entry.DownloadMeta,
+		}
+		if status.Status == "completed" && s.isExtracting(id) {
+			status.Status = "extracting"
 		}
 		return &status, nil
 	}
 
-	return nil, fmt.Errorf("download not found")
+	return nil, types.ErrNotFound
 }
 
 // History returns completed downloads
@@ -718,3 +899,8 @@ func (s *LocalDownloadService) History() ([]types.DownloadEntry, error) {
 	// For local service, we can directly access the state DB
 	return state.LoadCompletedDownloads()
 }
+
+// QueryHistory returns the finished downloads matching f.
+func (s *LocalDownloadService) QueryHistory(f types.HistoryFilter) ([]types.DownloadEntry, int, error) {
+	return state.QueryHistory(f)
+}
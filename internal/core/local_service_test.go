@@ -81,3 +81,34 @@ func TestLocalDownloadService_Delete_DBOnlyBroadcastsRemoved(t *testing.T) {
 		t.Fatalf("expected entry to be removed, got %+v", entry)
 	}
 }
+
+func TestLocalDownloadService_GetStatusMatchesList(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	defer state.CloseDB()
+
+	svc := NewLocalDownloadService(download.NewWorkerPool(make(chan interface{}, 1), 1))
+	defer func() { _ = svc.Shutdown() }()
+
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID: "db-only", URL: "https://example.com/a.iso", DestPath: "/tmp/a.iso", Filename: "a.iso",
+		Status: "paused", Priority: 4, StartAt: 1234, Group: "isos",
+		DownloadMeta: types.DownloadMeta{Tags: []string{"linux"}, Note: "lab", Referrer: "https://example.com"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.GetStatus("db-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := svc.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	want := list[0]
+	if got.Priority != want.Priority || got.StartAt != want.StartAt || got.Group != want.Group ||
+		got.Note != want.Note || got.Referrer != want.Referrer || !got.HasTags(want.Tags) {
+		t.Errorf("GetStatus = %+v, List = %+v", *got, want)
+	}
+}
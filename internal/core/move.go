@@ -0,0 +1,170 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Move moves or renames a download's file. newPath is the new file path, or
+// a directory to move it into. A running download is stopped for the move
+// and continues from the new location.
+func (s *LocalDownloadService) Move(id string, newPath string) error {
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return types.ErrNotFound
+	}
+	dir, filename, err := moveTarget(entry, newPath)
+	if err != nil {
+		return err
+	}
+
+	var moved *types.DownloadEntry
+	var dest string
+	move := func() error {
+		// Re-read: a running download saved its path and name while stopping
+		moved, err = state.GetDownload(id)
+		if err != nil {
+			return err
+		}
+		if moved == nil {
+			return types.ErrNotFound
+		}
+		dest, err = moveDownloadFiles(moved, dir, filename)
+		return err
+	}
+	if s.Pool != nil {
+		err = s.Pool.Relocate(id, dir, filename, move)
+	} else {
+		err = move()
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.InputCh != nil && dest != moved.DestPath {
+		s.InputCh <- events.DownloadMovedMsg{
+			DownloadID: id,
+			Filename:   firstNonEmpty(filename, moved.Filename),
+			OldPath:    moved.DestPath,
+			DestPath:   dest,
+		}
+	}
+	return nil
+}
+
+// MoveDownload moves or renames a download while Surge isn't running and
+// returns its new path
+func MoveDownload(id string, newPath string) (string, error) {
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", types.ErrNotFound
+	}
+	dir, filename, err := moveTarget(entry, newPath)
+	if err != nil {
+		return "", err
+	}
+	return moveDownloadFiles(entry, dir, filename)
+}
+
+// moveTarget splits newPath into the directory and filename a download
+// moves to. A directory (existing, or written with a trailing separator)
+// keeps the download's filename, which is empty until it has been probed.
+func moveTarget(entry *types.DownloadEntry, newPath string) (dir, filename string, err error) {
+	if strings.TrimSpace(newPath) == "" {
+		return "", "", fmt.Errorf("a destination path is required")
+	}
+	isDir := strings.HasSuffix(newPath, "/") || strings.HasSuffix(newPath, string(os.PathSeparator))
+	abs := utils.EnsureAbsPath(newPath)
+	if info, err := os.Stat(abs); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if isDir {
+		return abs, entry.Filename, nil
+	}
+	return filepath.Dir(abs), filepath.Base(abs), nil
+}
+
+// moveDownloadFiles moves what a stopped download has on disk into dir and
+// records its new path, which it returns. An unprobed download has no
+// filename yet, so its new path is dir itself. A completed download's file is
+// moved; any other download's partial file is, if it has one yet. A file
+// the user already moved to the destination by hand is adopted as is.
+func moveDownloadFiles(entry *types.DownloadEntry, dir, filename string) (string, error) {
+	if filename == "" {
+		filename = entry.Filename
+	}
+	// Not probed yet: nothing is on disk and the name isn't known, so only
+	// the directory changes
+	if filename == "" {
+		if err := state.SetDestPath(entry.ID, dir, ""); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+	dest := filepath.Join(dir, filename)
+	if dest == entry.DestPath {
+		return dest, nil
+	}
+
+	src, dst := entry.DestPath, dest
+	completed := entry.Status == "completed"
+	if !completed {
+		src += types.IncompleteSuffix
+		dst += types.IncompleteSuffix
+		// The finished file must not collide with one already there
+		if fileExists(dest) {
+			return "", fmt.Errorf("%w: %s", types.ErrFileExists, dest)
+		}
+	}
+	srcExists := entry.Filename != "" && fileExists(src)
+	dstExists := fileExists(dst)
+
+	moved := false
+	switch {
+	case srcExists && dstExists:
+		return "", fmt.Errorf("%w: %s", types.ErrFileExists, dst)
+	case srcExists:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		if err := utils.MoveFile(src, dst); err != nil {
+			return "", fmt.Errorf("failed to move %s: %w", src, err)
+		}
+		moved = true
+	case dstExists:
+		utils.Debug("Move: %s is already at %s", entry.ID, dst)
+	case completed:
+		return "", fmt.Errorf("%s is missing", src)
+	}
+
+	if err := state.SetDestPath(entry.ID, dest, filename); err != nil {
+		if moved {
+			if undoErr := utils.MoveFile(dst, src); undoErr != nil {
+				utils.Debug("Move: failed to move %s back: %v", dst, undoErr)
+			}
+		}
+		return "", err
+	}
+	return dest, nil
+}
+
+// fileExists reports whether anything exists at path
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
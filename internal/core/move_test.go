@@ -0,0 +1,221 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// seedPausedDownload saves a paused download with a partial file in dir
+func seedPausedDownload(t *testing.T, dir, id string) string {
+	t.Helper()
+	url := "https://example.com/" + id + ".bin"
+	destPath := filepath.Join(dir, id+".bin")
+	if err := os.WriteFile(destPath+types.IncompleteSuffix, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SaveState(url, destPath, &types.DownloadState{
+		ID:         id,
+		URL:        url,
+		DestPath:   destPath,
+		Filename:   id + ".bin",
+		TotalSize:  1000,
+		Downloaded: 200,
+		Tasks:      []types.Task{{Offset: 200, Length: 800}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return destPath
+}
+
+func TestMoveDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	oldPath := seedPausedDownload(t, tempDir, "paused")
+	newDir := filepath.Join(tempDir, "elsewhere")
+
+	// Into a directory (trailing separator: it doesn't exist yet)
+	dest, err := MoveDownload("paused", newDir+string(filepath.Separator))
+	if err != nil {
+		t.Fatalf("MoveDownload failed: %v", err)
+	}
+	if want := filepath.Join(newDir, "paused.bin"); dest != want {
+		t.Errorf("dest = %q, want %q", dest, want)
+	}
+	if _, err := os.Stat(oldPath + types.IncompleteSuffix); !os.IsNotExist(err) {
+		t.Error("old partial file should be gone")
+	}
+	if _, err := os.Stat(dest + types.IncompleteSuffix); err != nil {
+		t.Errorf("partial file not at new path: %v", err)
+	}
+
+	// Resume finds the state at the new path
+	entry, _ := state.GetDownload("paused")
+	if entry == nil || entry.DestPath != dest {
+		t.Fatalf("entry = %+v, want dest_path %q", entry, dest)
+	}
+	if s, err := state.LoadState(entry.URL, dest); err != nil || len(s.Tasks) != 1 {
+		t.Errorf("LoadState at new path = %+v, %v; want the saved tasks", s, err)
+	}
+
+	// Rename in place
+	renamed := filepath.Join(newDir, "renamed.bin")
+	if dest, err = MoveDownload("paused", renamed); err != nil || dest != renamed {
+		t.Fatalf("rename = %q, %v; want %q", dest, err, renamed)
+	}
+	if entry, _ := state.GetDownload("paused"); entry.Filename != "renamed.bin" {
+		t.Errorf("Filename = %q, want renamed.bin", entry.Filename)
+	}
+}
+
+func TestMoveDownload_RefusesExistingFile(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	oldPath := seedPausedDownload(t, tempDir, "paused")
+	taken := filepath.Join(tempDir, "taken.bin")
+	if err := os.WriteFile(taken, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MoveDownload("paused", taken); !errors.Is(err, types.ErrFileExists) {
+		t.Fatalf("error = %v, want ErrFileExists", err)
+	}
+	if _, err := os.Stat(oldPath + types.IncompleteSuffix); err != nil {
+		t.Error("partial file should stay put after a refused move")
+	}
+}
+
+func TestMoveDownload_AdoptsFileMovedByHand(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	oldPath := seedPausedDownload(t, tempDir, "paused")
+	newPath := filepath.Join(tempDir, "by-hand.bin")
+	if err := os.Rename(oldPath+types.IncompleteSuffix, newPath+types.IncompleteSuffix); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MoveDownload("paused", newPath); err != nil {
+		t.Fatalf("MoveDownload failed: %v", err)
+	}
+	if entry, _ := state.GetDownload("paused"); entry == nil || entry.DestPath != newPath {
+		t.Errorf("entry = %+v, want dest_path %q", entry, newPath)
+	}
+}
+
+func TestMoveDownload_Unprobed(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	// Queued before the probe: no filename, and the path is the directory
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID: "q", URL: "https://example.com/q", DestPath: tempDir, Status: "queued",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	otherDir := filepath.Join(tempDir, "other")
+	if err := os.Mkdir(otherDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := MoveDownload("q", otherDir)
+	if err != nil {
+		t.Fatalf("MoveDownload failed: %v", err)
+	}
+	if dest != otherDir {
+		t.Errorf("dest = %q, want %q", dest, otherDir)
+	}
+	if entry, _ := state.GetDownload("q"); entry == nil || entry.DestPath != otherDir || entry.Filename != "" {
+		t.Errorf("entry = %+v, want dest_path %q and no filename", entry, otherDir)
+	}
+}
+
+func TestMoveDownload_Completed(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	oldPath := filepath.Join(tempDir, "done.bin")
+	if err := os.WriteFile(oldPath, []byte("done"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID: "done", URL: "https://example.com/done.bin", DestPath: oldPath,
+		Filename: "done.bin", Status: "completed", TotalSize: 4, Downloaded: 4,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	newPath := filepath.Join(tempDir, "archive", "done.bin")
+	if _, err := MoveDownload("done", newPath); err != nil {
+		t.Fatalf("MoveDownload failed: %v", err)
+	}
+	if data, err := os.ReadFile(newPath); err != nil || string(data) != "done" {
+		t.Errorf("moved file = %q, %v", data, err)
+	}
+
+	// A completed download whose file is gone can't be moved
+	if err := os.Remove(newPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MoveDownload("done", oldPath); err == nil {
+		t.Error("moving a missing completed file should fail")
+	}
+}
+
+func TestLocalDownloadService_Move_BroadcastsMoved(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	ch := make(chan interface{}, 20)
+	pool := download.NewWorkerPool(ch, 1)
+	svc := NewLocalDownloadServiceWithInput(pool, ch)
+	defer func() { _ = svc.Shutdown() }()
+	streamCh, cleanup, err := svc.StreamEvents(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stream events: %v", err)
+	}
+	defer cleanup()
+
+	oldPath := seedPausedDownload(t, tempDir, "paused")
+	newPath := filepath.Join(tempDir, "moved.bin")
+	if err := svc.Move("paused", newPath); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	deadline := time.After(500 * time.Millisecond)
+	for {
+		select {
+		case msg := <-streamCh:
+			if m, ok := msg.(events.DownloadMovedMsg); ok {
+				if m.DownloadID != "paused" || m.OldPath != oldPath || m.DestPath != newPath || m.Filename != "moved.bin" {
+					t.Errorf("moved event = %+v", m)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected DownloadMovedMsg")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ReorderQueue moves a waiting download within the queue.
+func (s *LocalDownloadService) ReorderQueue(id string, op string) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if !types.IsValidQueueMove(op) {
+		return fmt.Errorf("invalid queue move %q (want top, up, down or bottom)", op)
+	}
+	return s.Pool.Move(id, op)
+}
+
+// MaxConcurrent returns the worker pool's download limit.
+func (s *LocalDownloadService) MaxConcurrent() (int, error) {
+	if s.Pool == nil {
+		return 0, fmt.Errorf("worker pool not initialized")
+	}
+	return s.Pool.MaxDownloads(), nil
+}
+
+// SetMaxConcurrent resizes the worker pool and persists the new limit.
+// Downloads over a lowered limit are handled by connections.concurrent_shrink_policy.
+func (s *LocalDownloadService) SetMaxConcurrent(n int) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if n < config.MinConcurrentDownloads || n > config.MaxConcurrentDownloads {
+		return fmt.Errorf("max concurrent downloads must be between %d and %d", config.MinConcurrentDownloads, config.MaxConcurrentDownloads)
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	changed := s.Pool.MaxDownloads() != n
+	s.Pool.SetMaxDownloads(n, settings.Connections.ConcurrentShrinkPolicy)
+
+	if settings.Connections.MaxConcurrentDownloads != n {
+		settings.Connections.MaxConcurrentDownloads = n
+		if err := config.SaveSettings(settings); err != nil {
+			return fmt.Errorf("failed to save settings: %w", err)
+		}
+	}
+	s.settingsMu.Lock()
+	s.settings = settings
+	s.settingsMu.Unlock()
+
+	if changed {
+		if err := s.Publish(events.ConcurrencyChangedMsg{MaxDownloads: n}); err != nil {
+			utils.Debug("Failed to publish concurrency change: %v", err)
+		}
+	}
+	return nil
+}
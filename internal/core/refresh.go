@@ -0,0 +1,190 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// urlRefresher builds the refresh hook for new downloads from the current settings.
+// Returns nil when no refresh source is configured, leaving expired links to fail as before.
+func (s *LocalDownloadService) urlRefresher(settings *config.Settings) types.URLRefresher {
+	command := strings.TrimSpace(settings.Connections.URLRefreshCommand)
+	useExtension := settings.Connections.URLRefreshExtension
+	if command == "" && !useExtension {
+		return nil
+	}
+
+	timeout := settings.Connections.URLRefreshTimeout
+	if timeout <= 0 {
+		timeout = types.DefaultURLRefreshWait
+	}
+
+	return func(ctx context.Context, req types.RefreshRequest) (*types.RefreshResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var cmdErr error
+		if command != "" {
+			result, err := runRefreshCommand(ctx, command, req)
+			if err == nil {
+				return result, nil
+			}
+			cmdErr = err
+			utils.Debug("URL refresh command failed for %s: %v", req.ID, err)
+		}
+
+		if useExtension {
+			return s.requestRefresh(ctx, req)
+		}
+		return nil, cmdErr
+	}
+}
+
+// runRefreshCommand runs the user's refresh command through the system shell
+// and parses the new URL from its output
+func runRefreshCommand(ctx context.Context, command string, req types.RefreshRequest) (*types.RefreshResult, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"SURGE_ID="+req.ID,
+		"SURGE_URL="+req.URL,
+		"SURGE_DEST="+req.DestPath,
+		"SURGE_STATUS="+strconv.Itoa(req.StatusCode),
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return parseRefreshOutput(out)
+}
+
+// parseRefreshOutput accepts either a JSON object ({"url": ..., "headers": {...}})
+// or a plain URL on the first line followed by optional "Header: value" lines
+func parseRefreshOutput(out []byte) (*types.RefreshResult, error) {
+	trimmed := bytes.TrimSpace(out)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("refresh command printed no URL")
+	}
+
+	if trimmed[0] == '{' {
+		var result types.RefreshResult
+		if err := json.Unmarshal(trimmed, &result); err != nil {
+			return nil, fmt.Errorf("invalid refresh output: %w", err)
+		}
+		if result.URL == "" {
+			return nil, fmt.Errorf("refresh output has no url")
+		}
+		return &result, nil
+	}
+
+	result := &types.RefreshResult{}
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if result.URL == "" {
+			result.URL = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if result.Headers == nil {
+			result.Headers = make(map[string]string)
+		}
+		result.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// requestRefresh publishes a refresh request to connected clients and waits
+// for one of them to answer through RefreshURL
+func (s *LocalDownloadService) requestRefresh(ctx context.Context, req types.RefreshRequest) (*types.RefreshResult, error) {
+	ch := make(chan *types.RefreshResult, 1)
+
+	s.refreshMu.Lock()
+	if s.refreshWaiters == nil {
+		s.refreshWaiters = make(map[string]chan *types.RefreshResult)
+	}
+	s.refreshWaiters[req.ID] = ch
+	s.refreshMu.Unlock()
+
+	defer func() {
+		s.refreshMu.Lock()
+		if s.refreshWaiters[req.ID] == ch {
+			delete(s.refreshWaiters, req.ID)
+		}
+		s.refreshMu.Unlock()
+	}()
+
+	if err := s.Publish(events.URLRefreshRequestMsg{
+		DownloadID: req.ID,
+		URL:        req.URL,
+		Filename:   filepath.Base(req.DestPath),
+		StatusCode: req.StatusCode,
+	}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-ch:
+		return result, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out waiting for a refreshed URL")
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// RefreshURL supplies a fresh URL for a download that is waiting on an expired link.
+func (s *LocalDownloadService) RefreshURL(id string, url string, headers map[string]string) error {
+	if url == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	s.refreshMu.Lock()
+	ch, ok := s.refreshWaiters[id]
+	s.refreshMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no URL refresh pending for download %s", id)
+	}
+
+	select {
+	case ch <- &types.RefreshResult{URL: url, Headers: headers}:
+		return nil
+	default:
+		return fmt.Errorf("URL refresh for download %s already answered", id)
+	}
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestParseRefreshOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		url     string
+		headers map[string]string
+		wantErr bool
+	}{
+		{name: "plain url", out: "https://example.com/new?sig=1\n", url: "https://example.com/new?sig=1"},
+		{
+			name:    "url with headers",
+			out:     "https://example.com/new\nCookie: a=b\nAuthorization: Bearer x\n",
+			url:     "https://example.com/new",
+			headers: map[string]string{"Cookie": "a=b", "Authorization": "Bearer x"},
+		},
+		{
+			name:    "json",
+			out:     `{"url": "https://example.com/json", "headers": {"X-Token": "t"}}`,
+			url:     "https://example.com/json",
+			headers: map[string]string{"X-Token": "t"},
+		},
+		{name: "empty", out: "  \n", wantErr: true},
+		{name: "json without url", out: `{"headers": {}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseRefreshOutput([]byte(tt.out))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.URL != tt.url {
+				t.Errorf("URL = %q, want %q", result.URL, tt.url)
+			}
+			for k, v := range tt.headers {
+				if result.Headers[k] != v {
+					t.Errorf("header %s = %q, want %q", k, result.Headers[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestURLRefresher_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	settings := config.DefaultSettings()
+	settings.Connections.URLRefreshCommand = `echo "$SURGE_URL-renewed"; echo "X-Status: $SURGE_STATUS"`
+
+	svc := &LocalDownloadService{}
+	refresher := svc.urlRefresher(settings)
+	if refresher == nil {
+		t.Fatal("expected refresher when a command is configured")
+	}
+
+	result, err := refresher(context.Background(), types.RefreshRequest{
+		ID:         "cmd-id",
+		URL:        "https://example.com/file",
+		StatusCode: 403,
+	})
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if result.URL != "https://example.com/file-renewed" {
+		t.Errorf("unexpected URL: %s", result.URL)
+	}
+	if result.Headers["X-Status"] != "403" {
+		t.Errorf("expected status passed through env, got %v", result.Headers)
+	}
+}
+
+func TestURLRefresher_DisabledByDefault(t *testing.T) {
+	svc := &LocalDownloadService{}
+	if svc.urlRefresher(config.DefaultSettings()) != nil {
+		t.Error("expected no refresher with default settings")
+	}
+}
+
+func TestURLRefresher_Extension(t *testing.T) {
+	svc := NewLocalDownloadService(nil)
+	defer func() { _ = svc.Shutdown() }()
+
+	streamCh, cleanup, err := svc.StreamEvents(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stream events: %v", err)
+	}
+	defer cleanup()
+
+	settings := config.DefaultSettings()
+	settings.Connections.URLRefreshExtension = true
+	settings.Connections.URLRefreshTimeout = 2 * time.Second
+	refresher := svc.urlRefresher(settings)
+
+	// Answer the refresh request like the extension would
+	go func() {
+		for msg := range streamCh {
+			if m, ok := msg.(events.URLRefreshRequestMsg); ok && m.DownloadID == "ext-id" {
+				if err := svc.RefreshURL(m.DownloadID, "https://example.com/renewed", map[string]string{"Cookie": "c=1"}); err != nil {
+					t.Errorf("RefreshURL failed: %v", err)
+				}
+				return
+			}
+		}
+	}()
+
+	result, err := refresher(context.Background(), types.RefreshRequest{
+		ID:         "ext-id",
+		URL:        "https://example.com/expired",
+		DestPath:   filepath.Join(t.TempDir(), "file.bin"),
+		StatusCode: 401,
+	})
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if result.URL != "https://example.com/renewed" || result.Headers["Cookie"] != "c=1" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Nothing is waiting any more
+	if err := svc.RefreshURL("ext-id", "https://example.com/late", nil); err == nil {
+		t.Error("expected error when no refresh is pending")
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// Relink points a stopped download at a new URL, keeping its progress and
+// remaining tasks. Unless force is set, the new URL must serve the same file:
+// the same size and, when both are known, the same ETag. A running download
+// must be paused first. Non-nil headers replace the saved request headers;
+// otherwise they are kept only if the new URL is on the same host.
+func (s *LocalDownloadService) Relink(id string, newURL string, headers map[string]string, force bool) error {
+	entry, etag, headers, err := checkRelink(id, newURL, headers, force)
+	if err != nil {
+		return err
+	}
+
+	commit := func() error { return state.SetURL(id, newURL, etag, headers) }
+	if s.Pool != nil {
+		err = s.Pool.Relink(id, newURL, headers, commit)
+	} else {
+		err = commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.InputCh != nil {
+		s.InputCh <- events.DownloadRelinkedMsg{
+			DownloadID: id,
+			Filename:   entry.Filename,
+			OldURL:     entry.URL,
+			URL:        newURL,
+		}
+	}
+	return nil
+}
+
+// RelinkDownload points a download at a new URL while Surge isn't running
+func RelinkDownload(id string, newURL string, headers map[string]string, force bool) error {
+	_, etag, headers, err := checkRelink(id, newURL, headers, force)
+	if err != nil {
+		return err
+	}
+	return state.SetURL(id, newURL, etag, headers)
+}
+
+// checkRelink loads the download and probes newURL, returning the ETag and
+// request headers to record for it. With force set, a failed probe or a
+// different file is accepted.
+func checkRelink(id string, newURL string, headers map[string]string, force bool) (*types.DownloadEntry, string, map[string]string, error) {
+	u, err := url.Parse(newURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", nil, fmt.Errorf("invalid URL: %s", newURL)
+	}
+
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if entry == nil {
+		return nil, "", nil, types.ErrNotFound
+	}
+	if entry.Status == "completed" {
+		return nil, "", nil, types.ErrCompleted
+	}
+	headers = relinkHeaders(entry.URL, u, entry.Headers, headers)
+
+	probe, err := engine.ProbeServer(context.Background(), newURL, entry.Filename, headers)
+	if err != nil {
+		if force {
+			return entry, "", headers, nil
+		}
+		return nil, "", nil, fmt.Errorf("failed to probe new URL: %w", err)
+	}
+	if !force {
+		if err := matchSource(entry, probe); err != nil {
+			return nil, "", nil, err
+		}
+	}
+	return entry, probe.ETag, headers, nil
+}
+
+// relinkHeaders picks the request headers for a download's new URL. The saved
+// ones were captured for the old host and may carry its cookies or
+// credentials, so they only go to the same host unless the caller supplied
+// replacements.
+func relinkHeaders(oldURL string, newURL *url.URL, saved, supplied map[string]string) map[string]string {
+	if supplied != nil {
+		return supplied
+	}
+	if old, err := url.Parse(oldURL); err == nil && strings.EqualFold(old.Host, newURL.Host) {
+		return saved
+	}
+	return nil
+}
+
+// matchSource checks that probe describes the file entry was downloading, so
+// its remaining tasks can continue from the new source
+func matchSource(entry *types.DownloadEntry, probe *engine.ProbeResult) error {
+	if entry.TotalSize > 0 && probe.FileSize != entry.TotalSize {
+		return fmt.Errorf("%w: size is %d bytes, expected %d", types.ErrSourceMismatch, probe.FileSize, entry.TotalSize)
+	}
+	if entry.ETag != "" && probe.ETag != "" && probe.ETag != entry.ETag {
+		return fmt.Errorf("%w: ETag is %s, expected %s", types.ErrSourceMismatch, probe.ETag, entry.ETag)
+	}
+	if entry.Downloaded > 0 && !probe.SupportsRange {
+		return fmt.Errorf("%w: server doesn't support resuming", types.ErrSourceMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// newFileServer serves size bytes at every path with the given ETag
+func newFileServer(t *testing.T, size int, etag string) *httptest.Server {
+	t.Helper()
+	data := bytes.Repeat([]byte("x"), size)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// seedRelinkDownload saves a paused download of 1000 bytes with the given ETag
+func seedRelinkDownload(t *testing.T, dir, etag string) string {
+	t.Helper()
+	destPath := seedPausedDownload(t, dir, "paused")
+	if err := state.SetETag("paused", etag); err != nil {
+		t.Fatal(err)
+	}
+	return destPath
+}
+
+func TestRelinkDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	destPath := seedRelinkDownload(t, tempDir, `"v1"`)
+
+	// A different file is refused
+	wrongSize := newFileServer(t, 999, `"v1"`).URL + "/paused.bin"
+	if err := RelinkDownload("paused", wrongSize, nil, false); !errors.Is(err, types.ErrSourceMismatch) {
+		t.Errorf("different size: error = %v, want ErrSourceMismatch", err)
+	}
+	wrongETag := newFileServer(t, 1000, `"v2"`).URL + "/paused.bin"
+	if err := RelinkDownload("paused", wrongETag, nil, false); !errors.Is(err, types.ErrSourceMismatch) {
+		t.Errorf("different ETag: error = %v, want ErrSourceMismatch", err)
+	}
+	if entry, _ := state.GetDownload("paused"); entry.URL != "https://example.com/paused.bin" {
+		t.Fatalf("URL = %q, want it unchanged after a refused relink", entry.URL)
+	}
+
+	// The same file keeps the saved progress
+	newURL := newFileServer(t, 1000, `"v1"`).URL + "/paused.bin"
+	if err := RelinkDownload("paused", newURL, nil, false); err != nil {
+		t.Fatalf("RelinkDownload failed: %v", err)
+	}
+	saved, err := state.LoadState(newURL, destPath)
+	if err != nil {
+		t.Fatalf("LoadState by new URL failed: %v", err)
+	}
+	if saved.Downloaded != 200 || len(saved.Tasks) != 1 {
+		t.Errorf("saved = %+v, want the progress kept", saved)
+	}
+
+	// Force takes a different file and records its ETag
+	if err := RelinkDownload("paused", wrongETag, nil, true); err != nil {
+		t.Fatalf("forced RelinkDownload failed: %v", err)
+	}
+	if entry, _ := state.GetDownload("paused"); entry.URL != wrongETag || entry.ETag != `"v2"` {
+		t.Errorf("entry = %+v, want the forced URL and its ETag", entry)
+	}
+
+	if err := RelinkDownload("paused", "not a url", nil, true); err == nil {
+		t.Error("an invalid URL should be refused")
+	}
+}
+
+func TestLocalDownloadService_Relink_BroadcastsRelinked(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	ch := make(chan interface{}, 20)
+	pool := download.NewWorkerPool(ch, 1)
+	svc := NewLocalDownloadServiceWithInput(pool, ch)
+	defer func() { _ = svc.Shutdown() }()
+	streamCh, cleanup, err := svc.StreamEvents(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stream events: %v", err)
+	}
+	defer cleanup()
+
+	seedRelinkDownload(t, tempDir, "")
+	newURL := newFileServer(t, 1000, "").URL + "/paused.bin"
+	if err := svc.Relink("paused", newURL, nil, false); err != nil {
+		t.Fatalf("Relink failed: %v", err)
+	}
+
+	deadline := time.After(500 * time.Millisecond)
+	for {
+		select {
+		case msg := <-streamCh:
+			if m, ok := msg.(events.DownloadRelinkedMsg); ok {
+				if m.DownloadID != "paused" || m.OldURL != "https://example.com/paused.bin" || m.URL != newURL {
+					t.Errorf("relinked event = %+v", m)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected DownloadRelinkedMsg")
+		}
+	}
+}
+
+func TestRelinkDownload_Headers(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	seedRelinkDownload(t, tempDir, "")
+	credentials := map[string]string{"Cookie": "session=old-host", "Authorization": "Bearer old-host"}
+	setHeaders := func(headers map[string]string) {
+		t.Helper()
+		entry, err := state.GetDownload("paused")
+		if err != nil || entry == nil {
+			t.Fatalf("GetDownload: %v", err)
+		}
+		entry.Headers = headers
+		if err := state.AddToMasterList(*entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var received []http.Header
+	data := bytes.Repeat([]byte("x"), 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Clone())
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	// Another host gets none of the saved headers, and they aren't kept
+	setHeaders(credentials)
+	if err := RelinkDownload("paused", srv.URL+"/a.bin", nil, false); err != nil {
+		t.Fatalf("RelinkDownload failed: %v", err)
+	}
+	if len(received) == 0 {
+		t.Fatal("new URL was not probed")
+	}
+	for _, h := range received {
+		if h.Get("Cookie") != "" || h.Get("Authorization") != "" {
+			t.Errorf("new host received %v", h)
+		}
+	}
+	if entry, _ := state.GetDownload("paused"); len(entry.Headers) != 0 {
+		t.Errorf("saved headers = %v, want none", entry.Headers)
+	}
+
+	// The same host keeps them
+	setHeaders(credentials)
+	received = nil
+	if err := RelinkDownload("paused", srv.URL+"/b.bin", nil, false); err != nil {
+		t.Fatalf("RelinkDownload failed: %v", err)
+	}
+	if len(received) == 0 || received[0].Get("Cookie") != "session=old-host" {
+		t.Errorf("same host received %v, want the saved cookie", received)
+	}
+	if entry, _ := state.GetDownload("paused"); entry.Headers["Cookie"] != "session=old-host" {
+		t.Errorf("saved headers = %v, want them kept", entry.Headers)
+	}
+
+	// Supplied headers replace the saved ones
+	received = nil
+	if err := RelinkDownload("paused", srv.URL+"/c.bin", map[string]string{"Cookie": "session=new-host"}, false); err != nil {
+		t.Fatalf("RelinkDownload failed: %v", err)
+	}
+	if len(received) == 0 || received[0].Get("Cookie") != "session=new-host" || received[0].Get("Authorization") != "" {
+		t.Errorf("new host received %v, want only the supplied cookie", received)
+	}
+	if entry, _ := state.GetDownload("paused"); len(entry.Headers) != 1 || entry.Headers["Cookie"] != "session=new-host" {
+		t.Errorf("saved headers = %v, want the supplied ones", entry.Headers)
+	}
+}
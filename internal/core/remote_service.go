@@ -5,17 +5,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/types"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 )
 
+// wsCallTimeout bounds how long a command sent over the WebSocket waits for
+// its answer
+const wsCallTimeout = 30 * time.Second
+
 // RemoteDownloadService implements DownloadService for a remote daemon.
 type RemoteDownloadService struct {
 	BaseURL   string
@@ -24,6 +35,17 @@ type RemoteDownloadService struct {
 	SSEClient *http.Client
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// WebSocket streams events over /ws instead of SSE and, while the socket
+	// is open, sends add/pause/resume/delete over it too. Daemons without
+	// /ws fall back to SSE and plain requests.
+	WebSocket bool
+
+	wsMu      sync.Mutex
+	ws        *websocket.Conn
+	wsPending map[string]chan WSMessage
+	wsSeq     uint64
+	wsMissing atomic.Bool // The daemon has no /ws
 }
 
 // NewRemoteDownloadService creates a new remote service instance.
@@ -34,6 +56,7 @@ func NewRemoteDownloadService(baseURL string, token string) *RemoteDownloadServi
 		Token:     token,
 		Client:    &http.Client{Timeout: 30 * time.Second},
 		SSEClient: &http.Client{},
+		WebSocket: true,
 		ctx:       ctx,
 		cancel:    cancel,
 	}
@@ -49,7 +72,7 @@ func (s *RemoteDownloadService) doRequest(method, path string, body interface{})
 		bodyReader = bytes.NewBuffer(jsonBody)
 	}
 
-	req, err := http.NewRequestWithContext(s.ctx, method, s.BaseURL+path, bodyReader)
+	req, err := http.NewRequestWithContext(s.ctx, method, s.BaseURL+APIPrefix+path, bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -68,45 +91,82 @@ func (s *RemoteDownloadService) doRequest(method, path string, body interface{})
 		defer func() { _ = resp.Body.Close() }()
 		// Limit error body read to 1KB to prevent DoS
 		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, parseAPIError(resp.StatusCode, bodyBytes)
 	}
 
 	return resp, nil
 }
 
+// parseAPIError turns an error response into an *APIError, falling back to
+// the body as the message when it isn't a JSON error
+func parseAPIError(status int, body []byte) error {
+	var wrapper struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &wrapper); err == nil && wrapper.Error != nil {
+		wrapper.Error.Status = status
+		return wrapper.Error
+	}
+	return &APIError{Status: status, Message: strings.TrimSpace(string(body))}
+}
+
+// call sends a request and discards the response body
+func (s *RemoteDownloadService) call(method, path string, body interface{}) error {
+	resp, err := s.doRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// downloadPath is the API path of a download, plus an optional action
+func downloadPath(id string, action string) string {
+	path := "/downloads/" + url.PathEscape(id)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
 // List returns the status of all active and completed downloads.
 func (s *RemoteDownloadService) List() ([]types.DownloadStatus, error) {
-	resp, err := s.doRequest("GET", "/list", nil)
+	resp, err := s.doRequest("GET", "/downloads", nil)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	var statuses []types.DownloadStatus
-	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+	var page Page[types.DownloadStatus]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
 		return nil, err
 	}
-	return statuses, nil
+	return page.Items, nil
 }
 
 // History returns completed downloads
 func (s *RemoteDownloadService) History() ([]types.DownloadEntry, error) {
-	resp, err := s.doRequest("GET", "/history", nil)
+	history, _, err := s.QueryHistory(types.HistoryFilter{})
+	return history, err
+}
+
+// QueryHistory returns the finished downloads matching f.
+func (s *RemoteDownloadService) QueryHistory(f types.HistoryFilter) ([]types.DownloadEntry, int, error) {
+	resp, err := s.doRequest("GET", "/history?"+f.Values().Encode(), nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	var history []types.DownloadEntry
-	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
-		return nil, err
+	var page Page[types.DownloadEntry]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, 0, err
 	}
-	return history, nil
+	return page.Items, page.Total, nil
 }
 
 // GetStatus returns a status for a single download by id.
 func (s *RemoteDownloadService) GetStatus(id string) (*types.DownloadStatus, error) {
-	resp, err := s.doRequest("GET", "/download?id="+url.QueryEscape(id), nil)
+	resp, err := s.doRequest("GET", downloadPath(id, ""), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +181,11 @@ func (s *RemoteDownloadService) GetStatus(id string) (*types.DownloadStatus, err
 
 // Add queues a new download.
 func (s *RemoteDownloadService) Add(url string, path string, filename string, mirrors []string, headers map[string]string) (string, error) {
+	return s.AddWithOptions(url, path, filename, mirrors, headers, DownloadOptions{})
+}
+
+// AddWithOptions queues a new download with per-download options.
+func (s *RemoteDownloadService) AddWithOptions(url string, path string, filename string, mirrors []string, headers map[string]string, opts DownloadOptions) (string, error) {
 	req := map[string]interface{}{
 		"url":           url,
 		"path":          path,
@@ -128,15 +193,37 @@ func (s *RemoteDownloadService) Add(url string, path string, filename string, mi
 		"mirrors":       mirrors,
 		"headers":       headers,
 		"skip_approval": true,
+		"on_complete":   opts.OnComplete,
+		"on_error":      opts.OnError,
+		"on_pause":      opts.OnPause,
+		"conflict":      opts.Conflict,
+		"priority":      opts.Priority,
+		"group":         opts.Group,
+		"tags":          opts.Tags,
+		"note":          opts.Note,
+		"referrer":      opts.Referrer,
+	}
+	if !opts.StartAt.IsZero() {
+		req["start_at"] = opts.StartAt
 	}
 
-	resp, err := s.doRequest("POST", "/download", req)
+	var result map[string]string
+	if data, ok, err := s.wsCall(WSCommand{Op: WSOpAdd}, req); ok {
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return "", err
+		}
+		return result["id"], nil
+	}
+
+	resp, err := s.doRequest("POST", "/downloads", req)
 	if err != nil {
 		return "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	var result map[string]string
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", err
 	}
@@ -145,22 +232,18 @@ func (s *RemoteDownloadService) Add(url string, path string, filename string, mi
 
 // Pause pauses an active download.
 func (s *RemoteDownloadService) Pause(id string) error {
-	resp, err := s.doRequest("POST", "/pause?id="+url.QueryEscape(id), nil)
-	if err != nil {
+	if _, ok, err := s.wsCall(WSCommand{Op: WSOpPause, DownloadID: id}, nil); ok {
 		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
-	return nil
+	return s.call("POST", downloadPath(id, "pause"), nil)
 }
 
 // Resume resumes a paused download.
 func (s *RemoteDownloadService) Resume(id string) error {
-	resp, err := s.doRequest("POST", "/resume?id="+url.QueryEscape(id), nil)
-	if err != nil {
+	if _, ok, err := s.wsCall(WSCommand{Op: WSOpResume, DownloadID: id}, nil); ok {
 		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
-	return nil
+	return s.call("POST", downloadPath(id, "resume"), nil)
 }
 
 // ResumeBatch resumes multiple paused downloads efficiently.
@@ -174,14 +257,86 @@ func (s *RemoteDownloadService) ResumeBatch(ids []string) []error {
 
 // Delete cancels and removes a download.
 func (s *RemoteDownloadService) Delete(id string) error {
-	resp, err := s.doRequest("POST", "/delete?id="+url.QueryEscape(id), nil)
-	// Some APIs use DELETE method, checking previous implementation in server it supports both POST and DELETE
-	// but mostly POST for actions. Let's stick to POST as per server implementation.
-	if err != nil {
+	if _, ok, err := s.wsCall(WSCommand{Op: WSOpDelete, DownloadID: id}, nil); ok {
 		return err
 	}
+	return s.call("DELETE", downloadPath(id, ""), nil)
+}
+
+// RefreshURL supplies a fresh URL for a download waiting on an expired link.
+func (s *RemoteDownloadService) RefreshURL(id string, newURL string, headers map[string]string) error {
+	req := map[string]interface{}{
+		"url":     newURL,
+		"headers": headers,
+	}
+	return s.call("POST", downloadPath(id, "refresh"), req)
+}
+
+// Move moves or renames a download's file on the daemon's machine.
+func (s *RemoteDownloadService) Move(id string, newPath string) error {
+	return s.call("POST", downloadPath(id, "move"), map[string]string{"path": newPath})
+}
+
+// Relink points a stopped download on the daemon at a new URL.
+func (s *RemoteDownloadService) Relink(id string, newURL string, headers map[string]string, force bool) error {
+	body := map[string]any{"url": newURL, "force": force}
+	if headers != nil {
+		body["headers"] = headers
+	}
+	return s.call("POST", downloadPath(id, "relink"), body)
+}
+
+// ReorderQueue moves a waiting download within the queue.
+func (s *RemoteDownloadService) ReorderQueue(id string, op string) error {
+	return s.call("POST", downloadPath(id, "reorder"), map[string]string{"to": op})
+}
+
+// MaxConcurrent returns how many downloads the daemon runs at once.
+func (s *RemoteDownloadService) MaxConcurrent() (int, error) {
+	resp, err := s.doRequest("GET", "/concurrency", nil)
+	if err != nil {
+		return 0, err
+	}
 	defer func() { _ = resp.Body.Close() }()
-	return nil
+
+	var body struct {
+		MaxConcurrentDownloads int `json:"max_concurrent_downloads"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	return body.MaxConcurrentDownloads, nil
+}
+
+// SetMaxConcurrent changes how many downloads the daemon runs at once.
+func (s *RemoteDownloadService) SetMaxConcurrent(n int) error {
+	return s.call("PUT", "/concurrency", map[string]int{"max_concurrent_downloads": n})
+}
+
+// ReloadSettings asks the daemon to reread settings.json and apply it to its
+// running downloads.
+func (s *RemoteDownloadService) ReloadSettings() error {
+	return s.call("POST", "/settings/reload", nil)
+}
+
+// Groups returns combined progress for every download group.
+func (s *RemoteDownloadService) Groups() ([]types.GroupStatus, error) {
+	resp, err := s.doRequest("GET", "/groups", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var page Page[types.GroupStatus]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// GroupAction applies an operation to every download in a group.
+func (s *RemoteDownloadService) GroupAction(name string, action string) error {
+	return s.call("POST", "/groups/"+url.PathEscape(name)+"/"+url.PathEscape(action), nil)
 }
 
 // Shutdown stops the service.
@@ -218,7 +373,7 @@ func (s *RemoteDownloadService) streamWithReconnect(ctx context.Context, ch chan
 		default:
 		}
 
-		err := s.connectSSE(ctx, ch)
+		err := s.connectEvents(ctx, ch)
 		if err == nil {
 			return // Clean shutdown (e.g. server closed stream cleanly or context canceled during request)
 		}
@@ -238,8 +393,175 @@ func (s *RemoteDownloadService) streamWithReconnect(ctx context.Context, ch chan
 	}
 }
 
+// connectEvents streams events until the connection drops, over the
+// WebSocket when possible
+func (s *RemoteDownloadService) connectEvents(ctx context.Context, ch chan interface{}) error {
+	if s.WebSocket && !s.wsMissing.Load() {
+		err := s.connectWS(ctx, ch)
+		if !errors.Is(err, errNoWebSocket) {
+			return err
+		}
+		s.wsMissing.Store(true)
+	}
+	return s.connectSSE(ctx, ch)
+}
+
+// errNoWebSocket means the daemon predates the WebSocket
+var errNoWebSocket = errors.New("daemon has no WebSocket endpoint")
+
+// wsReadLimit caps a message from the daemon. Queue order events list every
+// waiting download, so they can outgrow the library's 32 KiB default.
+const wsReadLimit = 4 << 20
+
+func (s *RemoteDownloadService) connectWS(ctx context.Context, ch chan interface{}) error {
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer "+s.Token)
+	conn, resp, err := websocket.Dial(ctx, s.BaseURL+APIPrefix+WebSocketPath, &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return errNoWebSocket
+		}
+		return err
+	}
+	conn.SetReadLimit(wsReadLimit)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopService := context.AfterFunc(s.ctx, cancel)
+	defer stopService()
+	go keepAliveWS(ctx, conn)
+
+	s.wsMu.Lock()
+	s.ws = conn
+	s.wsPending = make(map[string]chan WSMessage)
+	s.wsMu.Unlock()
+	defer s.dropWS(conn)
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+		var msg WSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		if msg.ReplyTo != "" {
+			s.wsMu.Lock()
+			reply := s.wsPending[msg.ReplyTo]
+			delete(s.wsPending, msg.ReplyTo)
+			s.wsMu.Unlock()
+			if reply != nil {
+				reply <- msg
+			}
+			continue
+		}
+
+		event, ok := decodeEvent(msg.Event, msg.Data)
+		if !ok {
+			continue
+		}
+		// Non-blocking send
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// keepAliveWS pings the daemon until ctx ends, closing conn once a ping
+// goes unanswered
+func keepAliveWS(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(WebSocketPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, WebSocketPingInterval)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				_ = conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// dropWS forgets a closed socket, failing the commands still waiting on it
+func (s *RemoteDownloadService) dropWS(conn *websocket.Conn) {
+	_ = conn.CloseNow()
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	if s.ws != conn {
+		return
+	}
+	for _, reply := range s.wsPending {
+		close(reply)
+	}
+	s.ws = nil
+	s.wsPending = nil
+}
+
+// wsCall sends a command over the open WebSocket, with body as its
+// download. ok is false when no socket is open (or the command couldn't be
+// sent), in which case the caller should use a plain request instead.
+func (s *RemoteDownloadService) wsCall(cmd WSCommand, body interface{}) (data json.RawMessage, ok bool, err error) {
+	if body != nil {
+		if cmd.Download, err = json.Marshal(body); err != nil {
+			return nil, true, err
+		}
+	}
+
+	s.wsMu.Lock()
+	conn := s.ws
+	if conn == nil {
+		s.wsMu.Unlock()
+		return nil, false, nil
+	}
+	s.wsSeq++
+	cmd.ID = strconv.FormatUint(s.wsSeq, 10)
+	reply := make(chan WSMessage, 1)
+	s.wsPending[cmd.ID] = reply
+	s.wsMu.Unlock()
+
+	forget := func() {
+		s.wsMu.Lock()
+		if s.wsPending != nil {
+			delete(s.wsPending, cmd.ID)
+		}
+		s.wsMu.Unlock()
+	}
+	if err := wsjson.Write(s.ctx, conn, cmd); err != nil {
+		forget()
+		return nil, false, nil
+	}
+
+	timer := time.NewTimer(wsCallTimeout)
+	defer timer.Stop()
+	select {
+	case msg, open := <-reply:
+		if !open {
+			return nil, true, fmt.Errorf("connection lost before %s was answered", cmd.Op)
+		}
+		if msg.Error != nil {
+			msg.Error.Status = msg.Status
+			return nil, true, msg.Error
+		}
+		return msg.Data, true, nil
+	case <-timer.C:
+		forget()
+		return nil, true, fmt.Errorf("no answer to %s within %s", cmd.Op, wsCallTimeout)
+	case <-s.ctx.Done():
+		forget()
+		return nil, true, s.ctx.Err()
+	}
+}
+
 func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/events", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+APIPrefix+"/events", nil)
 	if err != nil {
 		return err
 	}
@@ -292,65 +614,8 @@ func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interfac
 		if eventType == "" || len(dataLines) == 0 {
 			continue
 		}
-		jsonData := strings.Join(dataLines, "\n")
-
-		var msg interface{}
-		switch eventType {
-		case "progress":
-			var m events.ProgressMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "started":
-			var m events.DownloadStartedMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "complete":
-			var m events.DownloadCompleteMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "error":
-			var m events.DownloadErrorMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "paused":
-			var m events.DownloadPausedMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "resumed":
-			var m events.DownloadResumedMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "queued":
-			var m events.DownloadQueuedMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "removed":
-			var m events.DownloadRemovedMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		case "request":
-			var m events.DownloadRequestMsg
-			if err := json.Unmarshal([]byte(jsonData), &m); err != nil {
-				continue
-			}
-			msg = m
-		default:
+		msg, ok := decodeEvent(eventType, []byte(strings.Join(dataLines, "\n")))
+		if !ok {
 			continue
 		}
 
@@ -362,3 +627,136 @@ func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interfac
 		}
 	}
 }
+
+// decodeEvent parses an event sent by the daemon under the given type name
+func decodeEvent(eventType string, data []byte) (interface{}, bool) {
+	switch eventType {
+	case "progress":
+		var m events.ProgressMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "started":
+		var m events.DownloadStartedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "complete":
+		var m events.DownloadCompleteMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "error":
+		var m events.DownloadErrorMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "paused":
+		var m events.DownloadPausedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "resumed":
+		var m events.DownloadResumedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "queued":
+		var m events.DownloadQueuedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "removed":
+		var m events.DownloadRemovedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "request":
+		var m events.DownloadRequestMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "hook":
+		var m events.HookResultMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "refresh_request":
+		var m events.URLRefreshRequestMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "extract_started":
+		var m events.ExtractStartedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "extract_progress":
+		var m events.ExtractProgressMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "extract_complete":
+		var m events.ExtractCompleteMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "extract_error":
+		var m events.ExtractErrorMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "scheduled":
+		var m events.DownloadScheduledMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "concurrency_changed":
+		var m events.ConcurrencyChangedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "history_pruned":
+		var m events.HistoryPrunedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "moved":
+		var m events.DownloadMovedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "relinked":
+		var m events.DownloadRelinkedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	case "queue_reordered":
+		var m events.QueueReorderedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	}
+	return nil, false
+}
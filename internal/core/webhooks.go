@@ -0,0 +1,332 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Webhook event names
+const (
+	WebhookEventQueued    = "queued"
+	WebhookEventStarted   = "started"
+	WebhookEventCompleted = "completed"
+	WebhookEventError     = "error"
+	WebhookEventRemoved   = "removed"
+	WebhookEventPaused    = "paused"
+	WebhookEventResumed   = "resumed"
+)
+
+const (
+	webhookPollInterval = 2 * time.Second
+	webhookBatchSize    = 50
+	webhookBaseDelay    = 10 * time.Second
+	webhookMaxDelay     = time.Hour
+)
+
+// WebhookPayload is the JSON body POSTed to webhook endpoints
+type WebhookPayload struct {
+	Event      string                `json:"event"`
+	Timestamp  time.Time             `json:"timestamp"`
+	DownloadID string                `json:"download_id"`
+	Filename   string                `json:"filename,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Download   *types.DownloadStatus `json:"download,omitempty"`
+}
+
+// WebhookDispatcher turns download events into queued webhook deliveries
+// and delivers them with retries. The queue lives in the state DB so
+// undelivered webhooks survive restarts.
+type WebhookDispatcher struct {
+	service      DownloadService
+	client       *http.Client
+	loadSettings func() (*config.Settings, error)
+	wake         chan struct{}
+	baseDelay    time.Duration
+}
+
+// NewWebhookDispatcher creates a dispatcher for events from service
+func NewWebhookDispatcher(service DownloadService) *WebhookDispatcher {
+	loadSettings := config.LoadSettings
+	// The local service keeps its settings current through ReloadSettings,
+	// so events needn't re-read settings.json
+	if local, ok := service.(*LocalDownloadService); ok {
+		loadSettings = func() (*config.Settings, error) { return local.currentSettings(), nil }
+	}
+	return &WebhookDispatcher{
+		service:      service,
+		client:       &http.Client{},
+		loadSettings: loadSettings,
+		wake:         make(chan struct{}, 1),
+		baseDelay:    webhookBaseDelay,
+	}
+}
+
+// Start subscribes to the service's events and runs the delivery loop until ctx is done
+func (w *WebhookDispatcher) Start(ctx context.Context) error {
+	stream, cleanup, err := w.service.StreamEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer cleanup()
+		for msg := range stream {
+			w.handleEvent(msg)
+		}
+	}()
+	go w.deliveryLoop(ctx)
+	return nil
+}
+
+// webhookEvent maps a download event to its webhook event name and payload
+func webhookEvent(msg interface{}) (WebhookPayload, bool) {
+	var p WebhookPayload
+	switch m := msg.(type) {
+	case events.DownloadQueuedMsg:
+		p = WebhookPayload{Event: WebhookEventQueued, DownloadID: m.DownloadID, Filename: m.Filename}
+	case events.DownloadStartedMsg:
+		p = WebhookPayload{Event: WebhookEventStarted, DownloadID: m.DownloadID, Filename: m.Filename}
+	case events.DownloadCompleteMsg:
+		p = WebhookPayload{Event: WebhookEventCompleted, DownloadID: m.DownloadID, Filename: m.Filename}
+	case events.DownloadErrorMsg:
+		p = WebhookPayload{Event: WebhookEventError, DownloadID: m.DownloadID, Filename: m.Filename}
+		if m.Err != nil {
+			p.Error = m.Err.Error()
+		}
+	case events.DownloadRemovedMsg:
+		p = WebhookPayload{Event: WebhookEventRemoved, DownloadID: m.DownloadID, Filename: m.Filename}
+	case events.DownloadPausedMsg:
+		p = WebhookPayload{Event: WebhookEventPaused, DownloadID: m.DownloadID, Filename: m.Filename}
+	case events.DownloadResumedMsg:
+		p = WebhookPayload{Event: WebhookEventResumed, DownloadID: m.DownloadID, Filename: m.Filename}
+	default:
+		return p, false
+	}
+	p.Timestamp = time.Now().UTC()
+	return p, true
+}
+
+// handleEvent queues one delivery per endpoint subscribed to the event
+func (w *WebhookDispatcher) handleEvent(msg interface{}) {
+	payload, ok := webhookEvent(msg)
+	if !ok {
+		return
+	}
+
+	settings, err := w.loadSettings()
+	if err != nil || settings == nil || len(settings.Webhooks.Endpoints) == 0 {
+		return
+	}
+
+	if payload.Event != WebhookEventRemoved {
+		if status, err := w.service.GetStatus(payload.DownloadID); err == nil {
+			payload.Download = status
+			if payload.Filename == "" {
+				payload.Filename = status.Filename
+			}
+		}
+	}
+
+	queued := false
+	for _, ep := range settings.Webhooks.Endpoints {
+		if ep.URL == "" || !ep.Wants(payload.Event) {
+			continue
+		}
+		body, err := encodeWebhookBody(ep, payload)
+		if err != nil {
+			utils.Debug("Webhook: failed to encode %s payload: %v", payload.Event, err)
+			continue
+		}
+		if _, err := state.EnqueueWebhookDelivery(types.WebhookDelivery{
+			EndpointURL: ep.URL,
+			EndpointKey: webhookEndpointKey(ep),
+			Event:       payload.Event,
+			Payload:     body,
+		}); err != nil {
+			utils.Debug("Webhook: %v", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// deliveryLoop sends due deliveries when woken or on a timer (for retries and restarts)
+func (w *WebhookDispatcher) deliveryLoop(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// deliverDue attempts every delivery whose retry time has come
+func (w *WebhookDispatcher) deliverDue(ctx context.Context) {
+	due, err := state.LoadDueWebhookDeliveries(time.Now().Unix(), webhookBatchSize)
+	if err != nil || len(due) == 0 {
+		return
+	}
+
+	settings, err := w.loadSettings()
+	if err != nil || settings == nil {
+		settings = config.DefaultSettings()
+	}
+	endpoints := make(map[string]config.WebhookEndpoint, len(settings.Webhooks.Endpoints))
+	byURL := make(map[string]config.WebhookEndpoint, len(settings.Webhooks.Endpoints))
+	for _, ep := range settings.Webhooks.Endpoints {
+		endpoints[webhookEndpointKey(ep)] = ep
+		if _, ok := byURL[ep.URL]; !ok {
+			byURL[ep.URL] = ep
+		}
+	}
+	maxAttempts := settings.Webhooks.WebhookMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		ep, ok := endpoints[d.EndpointKey]
+		if d.EndpointKey == "" {
+			// Queued before deliveries recorded their endpoint key
+			ep, ok = byURL[d.EndpointURL]
+		}
+		if !ok {
+			// Endpoint was removed or changed in settings - nothing to deliver to
+			_ = state.DeleteWebhookDelivery(d.ID)
+			continue
+		}
+
+		err := w.send(ctx, ep, d, settings.Webhooks.WebhookTimeout)
+		if err == nil {
+			_ = state.DeleteWebhookDelivery(d.ID)
+			continue
+		}
+
+		attempts := d.Attempts + 1
+		if attempts >= maxAttempts {
+			utils.Debug("Webhook: giving up on %s delivery to %s after %d attempts: %v", d.Event, d.EndpointURL, attempts, err)
+			_ = state.DeleteWebhookDelivery(d.ID)
+			continue
+		}
+		next := time.Now().Add(w.backoff(attempts)).Unix()
+		utils.Debug("Webhook: %s delivery to %s failed (attempt %d): %v", d.Event, d.EndpointURL, attempts, err)
+		_ = state.RescheduleWebhookDelivery(d.ID, attempts, next, err.Error())
+	}
+}
+
+// webhookEndpointKey identifies an endpoint by everything that shapes its
+// deliveries, so endpoints sharing a URL never swap secrets or formats
+func webhookEndpointKey(ep config.WebhookEndpoint) string {
+	h := sha256.New()
+	for _, part := range []string{ep.URL, ep.Secret, strings.ToLower(ep.Format), strings.Join(ep.Events, ",")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// backoff returns the delay before retry number attempts (exponential, capped)
+func (w *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := w.baseDelay << (attempts - 1)
+	if delay <= 0 || delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
+
+// send POSTs a single delivery, treating any non-2xx response as a failure
+func (w *WebhookDispatcher) send(ctx context.Context, ep config.WebhookEndpoint, d types.WebhookDelivery, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Surge-Webhook")
+	req.Header.Set("X-Surge-Event", d.Event)
+	req.Header.Set("X-Surge-Delivery", strconv.FormatInt(d.ID, 10))
+	if ep.Secret != "" {
+		req.Header.Set("X-Surge-Signature", SignWebhookPayload(ep.Secret, d.Payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the X-Surge-Signature value for body: "sha256=" + hex HMAC-SHA256
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// encodeWebhookBody renders payload in the endpoint's format
+func encodeWebhookBody(ep config.WebhookEndpoint, payload WebhookPayload) ([]byte, error) {
+	if strings.EqualFold(ep.Format, "slack") {
+		return json.Marshal(map[string]string{"text": slackText(payload)})
+	}
+	return json.Marshal(payload)
+}
+
+// slackText renders a one-line summary for Slack-compatible incoming webhooks
+func slackText(p WebhookPayload) string {
+	name := p.Filename
+	if name == "" {
+		name = p.DownloadID
+	}
+	switch p.Event {
+	case WebhookEventCompleted:
+		return fmt.Sprintf("Surge: download completed: %s", name)
+	case WebhookEventError:
+		return fmt.Sprintf("Surge: download failed: %s (%s)", name, p.Error)
+	default:
+		return fmt.Sprintf("Surge: download %s: %s", p.Event, name)
+	}
+}
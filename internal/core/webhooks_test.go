@@ -0,0 +1,220 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	failures int // respond 500 to this many requests first
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *webhookRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func newWebhookTestDispatcher(t *testing.T, settings *config.Settings) (*WebhookDispatcher, *LocalDownloadService) {
+	t.Helper()
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	svc := NewLocalDownloadService(nil)
+	t.Cleanup(func() { _ = svc.Shutdown() })
+
+	w := NewWebhookDispatcher(svc)
+	w.loadSettings = func() (*config.Settings, error) { return settings, nil }
+	w.baseDelay = 10 * time.Millisecond
+	return w, svc
+}
+
+func waitForDeliveries(t *testing.T, rec *webhookRecorder, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for rec.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d deliveries, got %d", n, rec.count())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWebhooks_SignedDeliveryAndEventFilter(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	settings := config.DefaultSettings()
+	settings.Webhooks.Endpoints = []config.WebhookEndpoint{
+		{URL: srv.URL, Secret: "s3cret", Events: []string{WebhookEventCompleted, WebhookEventError}},
+	}
+	w, svc := newWebhookTestDispatcher(t, settings)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	svc.Publish(events.DownloadStartedMsg{DownloadID: "id-1", Filename: "a.bin"}) // filtered out
+	svc.Publish(events.DownloadErrorMsg{DownloadID: "id-1", Filename: "a.bin", Err: errors.New("boom")})
+
+	waitForDeliveries(t, rec, 1)
+	time.Sleep(100 * time.Millisecond)
+	if got := rec.count(); got != 1 {
+		t.Fatalf("expected only the error event to be delivered, got %d deliveries", got)
+	}
+
+	rec.mu.Lock()
+	req, body := rec.requests[0], rec.bodies[0]
+	rec.mu.Unlock()
+
+	if got := req.Header.Get("X-Surge-Event"); got != WebhookEventError {
+		t.Errorf("X-Surge-Event = %q, want %q", got, WebhookEventError)
+	}
+	if got, want := req.Header.Get("X-Surge-Signature"), SignWebhookPayload("s3cret", body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.Event != WebhookEventError || payload.DownloadID != "id-1" || payload.Error != "boom" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestWebhooks_SameURLKeepsEachSecret(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	settings := config.DefaultSettings()
+	settings.Webhooks.Endpoints = []config.WebhookEndpoint{
+		{URL: srv.URL, Secret: "first", Events: []string{WebhookEventCompleted}},
+		{URL: srv.URL, Secret: "second", Events: []string{WebhookEventError}},
+	}
+	w, _ := newWebhookTestDispatcher(t, settings)
+
+	w.handleEvent(events.DownloadCompleteMsg{DownloadID: "id-a"})
+	w.handleEvent(events.DownloadErrorMsg{DownloadID: "id-b", Err: errors.New("boom")})
+	w.deliverDue(context.Background())
+	waitForDeliveries(t, rec, 2)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for i, req := range rec.requests {
+		secret := "first"
+		if req.Header.Get("X-Surge-Event") == WebhookEventError {
+			secret = "second"
+		}
+		if got, want := req.Header.Get("X-Surge-Signature"), SignWebhookPayload(secret, rec.bodies[i]); got != want {
+			t.Errorf("%s delivery signed with the wrong secret", req.Header.Get("X-Surge-Event"))
+		}
+	}
+}
+
+func TestWebhooks_RetriesUntilSuccess(t *testing.T) {
+	rec := &webhookRecorder{failures: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	settings := config.DefaultSettings()
+	settings.Webhooks.Endpoints = []config.WebhookEndpoint{{URL: srv.URL}}
+	w, _ := newWebhookTestDispatcher(t, settings)
+
+	w.handleEvent(events.DownloadCompleteMsg{DownloadID: "id-2", Filename: "b.bin"})
+
+	ctx := context.Background()
+	deadline := time.Now().Add(5 * time.Second)
+	for rec.count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery never succeeded")
+		}
+		w.deliverDue(ctx)
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	due, err := state.LoadDueWebhookDeliveries(time.Now().Add(time.Hour).Unix(), 10)
+	if err != nil {
+		t.Fatalf("LoadDueWebhookDeliveries failed: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("expected delivered webhook to be removed from queue, %d remain", len(due))
+	}
+}
+
+func TestWebhooks_DropsAfterMaxAttempts(t *testing.T) {
+	rec := &webhookRecorder{failures: 100}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	settings := config.DefaultSettings()
+	settings.Webhooks.Endpoints = []config.WebhookEndpoint{{URL: srv.URL}}
+	settings.Webhooks.WebhookMaxAttempts = 2
+	w, _ := newWebhookTestDispatcher(t, settings)
+
+	w.handleEvent(events.DownloadCompleteMsg{DownloadID: "id-3"})
+
+	ctx := context.Background()
+	w.deliverDue(ctx)
+	time.Sleep(50 * time.Millisecond)
+	w.deliverDue(ctx)
+
+	due, err := state.LoadDueWebhookDeliveries(time.Now().Add(time.Hour).Unix(), 10)
+	if err != nil {
+		t.Fatalf("LoadDueWebhookDeliveries failed: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("expected delivery to be dropped after max attempts, %d remain", len(due))
+	}
+}
+
+func TestWebhooks_SlackFormat(t *testing.T) {
+	body, err := encodeWebhookBody(config.WebhookEndpoint{Format: "slack"}, WebhookPayload{
+		Event:    WebhookEventCompleted,
+		Filename: "c.iso",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]string
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg["text"] != "Surge: download completed: c.iso" {
+		t.Errorf("unexpected slack text: %q", msg["text"])
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebSocketPath is where the daemon accepts WebSocket connections, both at
+// the root and under APIPrefix. The socket carries the same events as the
+// SSE stream and accepts commands (see WSCommand).
+const WebSocketPath = "/ws"
+
+// WebSocketPingInterval is how often the daemon pings an open socket, so
+// proxies don't drop it and either side notices a dead peer
+const WebSocketPingInterval = 30 * time.Second
+
+// WebSocketTicketPath issues tickets for opening the WebSocket from a
+// browser, which can't send an Authorization header on it. A ticket is
+// passed as ?ticket= and opens a single connection.
+const WebSocketTicketPath = WebSocketPath + "/ticket"
+
+// WebSocketTicketTTL is how long an unused ticket stays valid
+const WebSocketTicketTTL = 30 * time.Second
+
+// WSTicket is the answer to WebSocketTicketPath
+type WSTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Commands accepted over the WebSocket
+const (
+	WSOpAdd    = "add"
+	WSOpPause  = "pause"
+	WSOpResume = "resume"
+	WSOpDelete = "delete"
+)
+
+// WSCommand is a request sent to the daemon over the WebSocket
+type WSCommand struct {
+	ID         string          `json:"id"` // Echoed as reply_to in the answer
+	Op         string          `json:"op"` // One of the WSOp constants
+	DownloadID string          `json:"download_id,omitempty"`
+	Download   json.RawMessage `json:"download,omitempty"` // For add: the body of POST /downloads
+}
+
+// WSMessage is sent by the daemon over the WebSocket: either an event (Event
+// and Data, as on the SSE stream) or the answer to a command (ReplyTo with
+// Data or Error)
+type WSMessage struct {
+	Event   string          `json:"event,omitempty"`
+	ReplyTo string          `json:"reply_to,omitempty"`
+	Status  int             `json:"status,omitempty"` // HTTP status the command would have had
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   *APIError       `json:"error,omitempty"`
+}
@@ -0,0 +1,82 @@
+package download_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestTUIDownload_RoutesByCategory(t *testing.T) {
+	tmpDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	server := testutil.NewMockServerT(t,
+		testutil.WithFileSize(64*1024),
+		testutil.WithRangeSupport(true),
+		testutil.WithContentType("video/mp4"),
+		testutil.WithFilename("clip.mp4"),
+	)
+	defer server.Close()
+
+	defaultDir := filepath.Join(tmpDir, "downloads")
+	videoDir := filepath.Join(tmpDir, "videos")
+
+	var gotType string
+	progressCh := make(chan any, 100)
+	cfg := types.DownloadConfig{
+		URL:        server.URL(),
+		OutputPath: defaultDir,
+		ID:         "category-test",
+		ProgressCh: progressCh,
+		State:      types.NewProgressState("category-test", 0),
+		Runtime:    &types.RuntimeConfig{},
+		Router: func(url, filename, contentType string) (string, string, bool) {
+			gotType = contentType
+			if contentType == "video/mp4" {
+				return "Videos", videoDir, true
+			}
+			return "", "", false
+		},
+	}
+
+	if err := download.TUIDownload(context.Background(), &cfg); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if gotType != "video/mp4" {
+		t.Errorf("router got content type %q", gotType)
+	}
+	if cfg.Category != "Videos" {
+		t.Errorf("Category = %q, want Videos", cfg.Category)
+	}
+	if filepath.Dir(cfg.DestPath) != videoDir {
+		t.Errorf("DestPath = %q, want file in %q", cfg.DestPath, videoDir)
+	}
+	if _, err := os.Stat(cfg.DestPath); err != nil {
+		t.Errorf("routed file missing: %v", err)
+	}
+
+	var started *events.DownloadStartedMsg
+	for len(progressCh) > 0 {
+		if m, ok := (<-progressCh).(events.DownloadStartedMsg); ok {
+			started = &m
+		}
+	}
+	if started == nil || started.Category != "Videos" {
+		t.Errorf("DownloadStartedMsg category not set: %+v", started)
+	}
+
+	entry, err := state.GetDownload(cfg.ID)
+	if err != nil || entry == nil || entry.Category != "Videos" {
+		t.Errorf("persisted entry = %+v, %v", entry, err)
+	}
+}
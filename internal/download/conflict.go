@@ -0,0 +1,143 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// fileTaken reports whether path or its incomplete .surge file exists
+func fileTaken(path string) bool {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return true
+	}
+	_, err := os.Stat(path + types.IncompleteSuffix)
+	return !os.IsNotExist(err)
+}
+
+// resolveConflict applies a conflict policy to a fresh download's target path.
+// It returns the path to download to, or adopt=true when the existing file
+// already matches the remote one and nothing needs downloading.
+func resolveConflict(id, policy, destPath string, probe *engine.ProbeResult) (path string, adopt bool, err error) {
+	if !fileTaken(destPath) {
+		return destPath, false, nil
+	}
+
+	switch policy {
+	case config.ConflictOverwrite:
+		// The existing file stays until the new one is complete: finishing
+		// renames the .surge file over it in one step
+		utils.Debug("Conflict: overwriting %s", destPath)
+		partial := destPath + types.IncompleteSuffix
+		if _, err := os.Stat(partial); os.IsNotExist(err) {
+			return destPath, false, nil
+		}
+		owner, err := state.UnfinishedDownloadAt(destPath, id)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to check %s: %w", partial, err)
+		}
+		if owner != "" {
+			return "", false, fmt.Errorf("%w: %s belongs to download %s", types.ErrFileExists, partial, owner)
+		}
+		// Nobody resumes a stale partial, so the new download starts over
+		if err := os.Remove(partial); err != nil && !os.IsNotExist(err) {
+			return "", false, fmt.Errorf("failed to replace existing file: %w", err)
+		}
+		return destPath, false, nil
+
+	case config.ConflictSkip:
+		if sameAsRemote(destPath, probe) {
+			utils.Debug("Conflict: %s matches remote, skipping download", destPath)
+			return destPath, true, nil
+		}
+		return uniqueFilePath(destPath), false, nil
+
+	case config.ConflictFail:
+		return "", false, fmt.Errorf("%w: %s", types.ErrFileExists, destPath)
+
+	default:
+		return uniqueFilePath(destPath), false, nil
+	}
+}
+
+// sameAsRemote reports whether the finished file at path matches the probed
+// resource: the sizes must be equal and, when both sides have an ETag, so must
+// the ETags
+func sameAsRemote(path string, probe *engine.ProbeResult) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if probe.FileSize <= 0 || info.Size() != probe.FileSize {
+		return false
+	}
+	if probe.ETag == "" {
+		return true
+	}
+	etag, err := state.CompletedETag(path)
+	if err != nil {
+		utils.Debug("Conflict: ETag lookup failed: %v", err)
+	}
+	return etag == "" || etag == probe.ETag
+}
+
+// adoptExisting records an already present, identical file as this download's
+// result and reports it as completed without transferring anything
+func adoptExisting(cfg *types.DownloadConfig, destPath string, probe *engine.ProbeResult) error {
+	cfg.DestPath = destPath
+	cfg.Filename = filepath.Base(destPath)
+
+	if cfg.State != nil {
+		cfg.State.SetTotalSize(probe.FileSize)
+		cfg.State.Downloaded.Store(probe.FileSize)
+	}
+
+	if cfg.ProgressCh != nil {
+		cfg.ProgressCh <- events.DownloadStartedMsg{
+			DownloadID:   cfg.ID,
+			URL:          cfg.URL,
+			Filename:     cfg.Filename,
+			Total:        probe.FileSize,
+			DestPath:     destPath,
+			Category:     cfg.Category,
+			Group:        cfg.Group,
+			DownloadMeta: cfg.DownloadMeta,
+			State:        cfg.State,
+		}
+	}
+
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:           cfg.ID,
+		URL:          cfg.URL,
+		URLHash:      state.URLHash(cfg.URL),
+		DestPath:     destPath,
+		Filename:     cfg.Filename,
+		Status:       "completed",
+		TotalSize:    probe.FileSize,
+		Downloaded:   probe.FileSize,
+		CompletedAt:  time.Now().Unix(),
+		Category:     cfg.Category,
+		Group:        cfg.Group,
+		DownloadMeta: cfg.DownloadMeta,
+		ETag:         probe.ETag,
+	}); err != nil {
+		utils.Debug("Failed to persist adopted download: %v", err)
+	}
+
+	if cfg.ProgressCh != nil {
+		cfg.ProgressCh <- events.DownloadCompleteMsg{
+			DownloadID: cfg.ID,
+			Filename:   cfg.Filename,
+			Total:      probe.FileSize,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func writeExisting(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestResolveConflict_Policies(t *testing.T) {
+	probe := &engine.ProbeResult{FileSize: 5}
+
+	t.Run("free path is used as is", func(t *testing.T) {
+		for _, policy := range config.ConflictPolicies {
+			p := filepath.Join(t.TempDir(), "new.bin")
+			got, adopt, err := resolveConflict("new", policy, p, probe)
+			if err != nil || adopt || got != p {
+				t.Errorf("%s: got %q, %v, %v", policy, got, adopt, err)
+			}
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		dir := t.TempDir()
+		p := writeExisting(t, dir, "file.bin", "hello")
+		got, adopt, err := resolveConflict("new", config.ConflictRename, p, probe)
+		if err != nil || adopt || got != filepath.Join(dir, "file(1).bin") {
+			t.Errorf("got %q, %v, %v", got, adopt, err)
+		}
+	})
+
+	t.Run("overwrite keeps the file until done", func(t *testing.T) {
+		dir := t.TempDir()
+		state.CloseDB()
+		state.Configure(filepath.Join(dir, "surge.db"))
+		defer state.CloseDB()
+
+		p := writeExisting(t, dir, "file.bin", "hello")
+		writeExisting(t, dir, "file.bin"+types.IncompleteSuffix, "partial")
+		got, adopt, err := resolveConflict("new", config.ConflictOverwrite, p, probe)
+		if err != nil || adopt || got != p {
+			t.Fatalf("got %q, %v, %v", got, adopt, err)
+		}
+		if _, err := os.Stat(p); err != nil {
+			t.Error("existing file was removed before the new one finished")
+		}
+		if _, err := os.Stat(p + types.IncompleteSuffix); !os.IsNotExist(err) {
+			t.Error("stale .surge was not removed")
+		}
+	})
+
+	t.Run("overwrite refuses another download's partial", func(t *testing.T) {
+		dir := t.TempDir()
+		state.CloseDB()
+		state.Configure(filepath.Join(dir, "surge.db"))
+		defer state.CloseDB()
+
+		p := writeExisting(t, dir, "file.bin", "hello")
+		writeExisting(t, dir, "file.bin"+types.IncompleteSuffix, "partial")
+		if err := state.AddToMasterList(types.DownloadEntry{ID: "other", URL: "http://example.com/file.bin", DestPath: p, Status: "paused"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := resolveConflict("new", config.ConflictOverwrite, p, probe); !errors.Is(err, types.ErrFileExists) {
+			t.Errorf("expected ErrFileExists, got %v", err)
+		}
+		if _, err := os.Stat(p + types.IncompleteSuffix); err != nil {
+			t.Error("another download's .surge was removed")
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		p := writeExisting(t, t.TempDir(), "file.bin", "hello")
+		_, _, err := resolveConflict("new", config.ConflictFail, p, probe)
+		if !errors.Is(err, types.ErrFileExists) {
+			t.Errorf("expected ErrFileExists, got %v", err)
+		}
+	})
+
+	t.Run("skip adopts same size", func(t *testing.T) {
+		p := writeExisting(t, t.TempDir(), "file.bin", "hello")
+		got, adopt, err := resolveConflict("new", config.ConflictSkip, p, probe)
+		if err != nil || !adopt || got != p {
+			t.Errorf("got %q, %v, %v", got, adopt, err)
+		}
+	})
+
+	t.Run("skip renames on size mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		p := writeExisting(t, dir, "file.bin", "hello world")
+		got, adopt, err := resolveConflict("new", config.ConflictSkip, p, probe)
+		if err != nil || adopt || got != filepath.Join(dir, "file(1).bin") {
+			t.Errorf("got %q, %v, %v", got, adopt, err)
+		}
+	})
+}
+
+func TestResolveConflict_SkipComparesETag(t *testing.T) {
+	tmpDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	p := writeExisting(t, tmpDir, "file.bin", "hello")
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID: "old", URL: "http://example.com/file.bin", DestPath: p, Filename: "file.bin",
+		Status: "completed", TotalSize: 5, Downloaded: 5, CompletedAt: 1, ETag: `"v1"`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, adopt, _ := resolveConflict("new", config.ConflictSkip, p, &engine.ProbeResult{FileSize: 5, ETag: `"v1"`}); !adopt {
+		t.Error("matching ETag should adopt the existing file")
+	}
+	if got, adopt, _ := resolveConflict("new", config.ConflictSkip, p, &engine.ProbeResult{FileSize: 5, ETag: `"v2"`}); adopt || got == p {
+		t.Errorf("changed ETag should download to a new name, got %q adopt=%v", got, adopt)
+	}
+}
+
+func TestTUIDownload_SkipConflictAdoptsIdenticalFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	const size = 8 * 1024
+	server := testutil.NewMockServerT(t,
+		testutil.WithFileSize(size),
+		testutil.WithRangeSupport(true),
+		testutil.WithFilename("same.bin"),
+	)
+	defer server.Close()
+
+	existing := filepath.Join(tmpDir, "same.bin")
+	if err := os.WriteFile(existing, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	progressCh := make(chan any, 100)
+	cfg := types.DownloadConfig{
+		URL:        server.URL(),
+		OutputPath: tmpDir,
+		ID:         "adopt-test",
+		ProgressCh: progressCh,
+		State:      types.NewProgressState("adopt-test", 0),
+		Runtime:    &types.RuntimeConfig{},
+		Conflict:   config.ConflictSkip,
+	}
+
+	if err := TUIDownload(context.Background(), &cfg); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if cfg.DestPath != existing {
+		t.Errorf("DestPath = %q, want existing %q", cfg.DestPath, existing)
+	}
+	if server.Stats().TotalRequests > 1 {
+		t.Errorf("expected only the probe request, got %d", server.Stats().TotalRequests)
+	}
+
+	var completed bool
+	for len(progressCh) > 0 {
+		if _, ok := (<-progressCh).(events.DownloadCompleteMsg); ok {
+			completed = true
+		}
+	}
+	if !completed {
+		t.Error("adopted download did not report completion")
+	}
+
+	entry, err := state.GetDownload(cfg.ID)
+	if err != nil || entry == nil || entry.Status != "completed" {
+		t.Errorf("persisted entry = %+v, %v", entry, err)
+	}
+}
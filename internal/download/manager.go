@@ -82,7 +82,16 @@ func uniqueFilePath(path string) string {
 func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	// Probe server once to get all metadata
 	utils.Debug("TUIDownload: Probing server... %s", cfg.URL)
+	stateURL := cfg.URL // Saved state is keyed by the URL in use when it was paused
 	probe, err := engine.ProbeServer(ctx, cfg.URL, cfg.Filename, cfg.Headers)
+	if status, ok := types.IsRefreshableError(err); ok && cfg.Refresher != nil {
+		// Link already expired (e.g. resuming a paused signed URL) - renew and probe again
+		if refreshErr := refreshConfigURL(ctx, cfg, status); refreshErr == nil {
+			probe, err = engine.ProbeServer(ctx, cfg.URL, cfg.Filename, cfg.Headers)
+		} else {
+			utils.Debug("TUIDownload: %v", refreshErr)
+		}
+	}
 	if err != nil {
 		utils.Debug("TUIDownload: Probe failed: %v\n", err)
 		return err
@@ -95,6 +104,19 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		utils.Debug("Download %s completed in %v", cfg.URL, time.Since(start))
 	}()
 
+	// Route fresh downloads without an explicit path by category rules
+	if cfg.Router != nil && !cfg.IsResume {
+		name := probe.Filename
+		if cfg.Filename != "" {
+			name = cfg.Filename
+		}
+		if category, dir, ok := cfg.Router(cfg.URL, name, probe.ContentType); ok {
+			utils.Debug("Routing %s to %s (category %s)", name, dir, category)
+			cfg.OutputPath = dir
+			cfg.Category = category
+		}
+	}
+
 	// Construct proper output path
 	destPath := cfg.OutputPath
 
@@ -107,11 +129,28 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 	if info, err := os.Stat(cfg.OutputPath); err == nil && info.IsDir() {
 		// Use cfg.Filename if TUI provided one, otherwise use probe.Filename
-		filename := probe.Filename
+		// (optionally reshaped by the filename template)
+		profile := cfg.Runtime.GetFilenameProfile()
+		filename := utils.SanitizeFilename(probe.Filename, profile)
 		if cfg.Filename != "" {
-			filename = cfg.Filename
+			filename = utils.SanitizeFilename(cfg.Filename, profile)
+		} else if tmpl := cfg.Runtime.GetFilenameTemplate(); tmpl != "" && !cfg.IsResume {
+			filename = utils.RenderFilenameTemplate(tmpl, utils.FilenameVars{
+				Filename: probe.Filename,
+				URL:      cfg.URL,
+				ID:       cfg.ID,
+				Time:     time.Now(),
+				Category: cfg.Category,
+			}, profile)
 		}
 		destPath = filepath.Join(cfg.OutputPath, filename)
+
+		// Templates may introduce subdirectories
+		if dir := filepath.Dir(destPath); dir != cfg.OutputPath {
+			if mkErr := os.MkdirAll(dir, 0o755); mkErr != nil {
+				utils.Debug("Failed to create template directory: %v", mkErr)
+			}
+		}
 	}
 
 	// Check if this is a resume (explicitly marked by TUI)
@@ -121,7 +160,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			savedState = cfg.SavedState
 		} else {
 			// Resume: use the provided destination path for state lookup
-			savedState, _ = state.LoadState(cfg.URL, cfg.DestPath)
+			savedState, _ = state.LoadState(stateURL, cfg.DestPath)
 		}
 
 		// Restore mirrors from state if found
@@ -149,8 +188,15 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		destPath = savedState.DestPath
 		utils.Debug("Resuming download, using saved destPath: %s", destPath)
 	} else {
-		// Fresh download without TUI-provided filename: generate unique filename if file already exists
-		destPath = uniqueFilePath(destPath)
+		// Fresh download: apply the conflict policy if the file already exists
+		var adopt bool
+		destPath, adopt, err = resolveConflict(cfg.ID, cfg.Conflict, destPath, probe)
+		if err != nil {
+			return err
+		}
+		if adopt {
+			return adoptExisting(cfg, destPath, probe)
+		}
 	}
 	finalFilename := filepath.Base(destPath)
 	utils.Debug("Destination path: %s", destPath)
@@ -162,12 +208,15 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	// Send download started message
 	if cfg.ProgressCh != nil {
 		cfg.ProgressCh <- events.DownloadStartedMsg{
-			DownloadID: cfg.ID,
-			URL:        cfg.URL,
-			Filename:   finalFilename,
-			Total:      probe.FileSize,
-			DestPath:   destPath,
-			State:      cfg.State,
+			DownloadID:   cfg.ID,
+			URL:          cfg.URL,
+			Filename:     finalFilename,
+			Total:        probe.FileSize,
+			DestPath:     destPath,
+			Category:     cfg.Category,
+			Group:        cfg.Group,
+			DownloadMeta: cfg.DownloadMeta,
+			State:        cfg.State,
 		}
 	}
 
@@ -176,6 +225,13 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		cfg.State.SetTotalSize(probe.FileSize)
 	}
 
+	// Remember which file this is, so a new URL can be checked against it
+	if !isResume && probe.ETag != "" {
+		if err := state.SetETag(cfg.ID, probe.ETag); err != nil {
+			utils.Debug("Failed to persist ETag: %v", err)
+		}
+	}
+
 	// Choose downloader based on probe results
 	var downloadErr error
 	if probe.SupportsRange && probe.FileSize > 0 {
@@ -205,20 +261,41 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
 		d.Headers = cfg.Headers // Forward custom headers from browser extension
+		d.Refresher = cfg.Refresher
+		d.RuntimeUpdates = cfg.RuntimeUpdates
 		utils.Debug("Calling Download with mirrors: %v", cfg.Mirrors)
 		downloadErr = d.Download(ctx, cfg.URL, cfg.Mirrors, activeMirrors, destPath, probe.FileSize, cfg.Verbose)
+
+		// Workers may have renewed an expired URL; keep it so pause/resume finds the saved state
+		cfg.URL = d.URL
+		cfg.Headers = d.Headers
 	} else {
 		// Fallback to single-threaded downloader
 		utils.Debug("Using single-threaded downloader")
 		d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
 		d.Headers = cfg.Headers // Forward custom headers from browser extension
 		downloadErr = d.Download(ctx, cfg.URL, destPath, probe.FileSize, probe.Filename, cfg.Verbose)
+
+		// No range support means no partial state to keep, so just restart on a renewed link
+		if status, ok := types.IsRefreshableError(downloadErr); ok && cfg.Refresher != nil {
+			if refreshErr := refreshConfigURL(ctx, cfg, status); refreshErr == nil {
+				d.Headers = cfg.Headers
+				downloadErr = d.Download(ctx, cfg.URL, destPath, probe.FileSize, probe.Filename, cfg.Verbose)
+			} else {
+				utils.Debug("TUIDownload: %v", refreshErr)
+			}
+		}
 	}
 
 	// Only send completion if NO error AND not paused
 	// Check specifically for ErrPaused to avoid treating it as error
 	if errors.Is(downloadErr, types.ErrPaused) {
 		utils.Debug("Download paused cleanly")
+		if cfg.Category != "" {
+			if err := state.SetDownloadCategory(cfg.ID, cfg.Category); err != nil {
+				utils.Debug("Failed to persist category: %v", err)
+			}
+		}
 		return nil // Return nil so worker can remove it from active map
 	}
 
@@ -232,16 +309,20 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 		// Persist to history before sending event
 		if err := state.AddToMasterList(types.DownloadEntry{
-			ID:          cfg.ID,
-			URL:         cfg.URL,
-			URLHash:     state.URLHash(cfg.URL),
-			DestPath:    destPath,
-			Filename:    finalFilename,
-			Status:      "completed",
-			TotalSize:   probe.FileSize,
-			Downloaded:  probe.FileSize,
-			CompletedAt: time.Now().Unix(),
-			TimeTaken:   elapsed.Milliseconds(),
+			ID:           cfg.ID,
+			URL:          cfg.URL,
+			URLHash:      state.URLHash(cfg.URL),
+			DestPath:     destPath,
+			Filename:     finalFilename,
+			Status:       "completed",
+			TotalSize:    probe.FileSize,
+			Downloaded:   probe.FileSize,
+			CompletedAt:  time.Now().Unix(),
+			TimeTaken:    elapsed.Milliseconds(),
+			Category:     cfg.Category,
+			Group:        cfg.Group,
+			DownloadMeta: cfg.DownloadMeta,
+			ETag:         probe.ETag,
 		}); err != nil {
 			utils.Debug("Failed to persist completed download: %v", err)
 		}
@@ -263,14 +344,18 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 		// Persist error state
 		if err := state.AddToMasterList(types.DownloadEntry{
-			ID:         cfg.ID,
-			URL:        cfg.URL,
-			URLHash:    state.URLHash(cfg.URL),
-			DestPath:   destPath,
-			Filename:   finalFilename,
-			Status:     "error",
-			TotalSize:  probe.FileSize,
-			Downloaded: cfg.State.Downloaded.Load(),
+			ID:           cfg.ID,
+			URL:          cfg.URL,
+			URLHash:      state.URLHash(cfg.URL),
+			DestPath:     destPath,
+			Filename:     finalFilename,
+			Status:       "error",
+			TotalSize:    probe.FileSize,
+			Downloaded:   cfg.State.Downloaded.Load(),
+			CompletedAt:  time.Now().Unix(), // When it failed, for history queries
+			Category:     cfg.Category,
+			Group:        cfg.Group,
+			DownloadMeta: cfg.DownloadMeta,
 		}); err != nil {
 			utils.Debug("Failed to persist error state: %v", err)
 		}
@@ -279,6 +364,35 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	return downloadErr
 }
 
+// refreshConfigURL asks cfg.Refresher for a replacement URL and applies it to cfg
+func refreshConfigURL(ctx context.Context, cfg *types.DownloadConfig, statusCode int) error {
+	result, err := cfg.Refresher(ctx, types.RefreshRequest{
+		ID:         cfg.ID,
+		URL:        cfg.URL,
+		DestPath:   cfg.DestPath,
+		StatusCode: statusCode,
+	})
+	if err != nil {
+		return fmt.Errorf("url refresh failed: %w", err)
+	}
+	if result == nil || result.URL == "" {
+		return fmt.Errorf("url refresh returned no URL")
+	}
+
+	cfg.URL = result.URL
+	if len(result.Headers) > 0 {
+		headers := make(map[string]string, len(cfg.Headers)+len(result.Headers))
+		for k, v := range cfg.Headers {
+			headers[k] = v
+		}
+		for k, v := range result.Headers {
+			headers[k] = v
+		}
+		cfg.Headers = headers
+	}
+	return nil
+}
+
 // Download is the CLI entry point (non-TUI) - convenience wrapper
 func Download(ctx context.Context, url, outPath string, verbose bool, progressCh chan<- any, id string) error {
 	cfg := types.DownloadConfig{
@@ -2,10 +2,14 @@ package download
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
@@ -14,18 +18,29 @@ import (
 
 // activeDownload tracks a download that's currently running
 type activeDownload struct {
+	config    types.DownloadConfig
+	cancel    context.CancelFunc
+	started   time.Time // When a worker picked it up
+	preempted bool      // Paused to shrink the pool; goes back to the queue once stopped
+}
+
+// scheduledDownload is a download held back until its start time
+type scheduledDownload struct {
 	config types.DownloadConfig
-	cancel context.CancelFunc
+	timer  *time.Timer
 }
 
 type WorkerPool struct {
-	taskChan     chan types.DownloadConfig
+	queue        []types.DownloadConfig        // Waiting downloads, highest priority first
+	queueReady   *sync.Cond                    // Signalled when queue gains an item (uses mu)
+	scheduled    map[string]*scheduledDownload // Downloads waiting for their StartAt
 	progressCh   chan<- any
-	downloads    map[string]*activeDownload      // Track active downloads for pause/resume
-	queued       map[string]types.DownloadConfig // Track queued downloads
+	downloads    map[string]*activeDownload // Track active downloads for pause/resume
 	mu           sync.RWMutex
 	wg           sync.WaitGroup // We use this to wait for all active downloads to pause before exiting the program
 	maxDownloads int
+	workers      int                  // Running worker goroutines; converges on maxDownloads after a resize
+	runtime      *types.RuntimeConfig // Latest settings from UpdateRuntime; nil keeps each download's own
 }
 
 func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
@@ -33,32 +48,364 @@ func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
 		maxDownloads = 3 // Default to 3 if invalid
 	}
 	pool := &WorkerPool{
-		taskChan:     make(chan types.DownloadConfig, 100), // We make it buffered to avoid blocking add
 		progressCh:   progressCh,
 		downloads:    make(map[string]*activeDownload),
-		queued:       make(map[string]types.DownloadConfig),
+		scheduled:    make(map[string]*scheduledDownload),
 		maxDownloads: maxDownloads,
 	}
+	pool.queueReady = sync.NewCond(&pool.mu)
+	pool.workers = maxDownloads
 	for i := 0; i < maxDownloads; i++ {
 		go pool.worker()
 	}
 	return pool
 }
 
-// Add adds a new download task to the pool
+// MaxDownloads returns how many downloads may run at once
+func (p *WorkerPool) MaxDownloads() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.maxDownloads
+}
+
+// SetMaxDownloads resizes the pool while it runs. Growing starts queued
+// downloads right away. When shrinking, idle workers exit at once; downloads
+// over the new limit either finish first (config.ShrinkFinish) or are paused
+// and put back at the front of the queue (config.ShrinkPause), newest and
+// lowest priority first.
+func (p *WorkerPool) SetMaxDownloads(n int, policy string) {
+	if n < 1 {
+		n = 1
+	}
+
+	p.mu.Lock()
+	p.maxDownloads = n
+	for p.workers < n {
+		p.workers++
+		go p.worker()
+	}
+	// Wake idle workers so surplus ones notice the lower limit and exit
+	p.queueReady.Broadcast()
+
+	var excess []*activeDownload
+	if policy == config.ShrinkPause {
+		var running []*activeDownload
+		for _, ad := range p.downloads {
+			if p.queueIndexLocked(ad.config.ID) >= 0 || ad.preempted {
+				continue
+			}
+			if s := ad.config.State; s != nil && !s.Done.Load() && !s.IsPaused() && !s.IsPausing() {
+				running = append(running, ad)
+			}
+		}
+		if len(running) > n {
+			sort.Slice(running, func(i, j int) bool {
+				if running[i].config.Priority != running[j].config.Priority {
+					return running[i].config.Priority < running[j].config.Priority
+				}
+				return running[i].started.After(running[j].started)
+			})
+			excess = running[:len(running)-n]
+			for _, ad := range excess {
+				ad.preempted = true
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	for _, ad := range excess {
+		utils.Debug("WorkerPool: pausing %s to shrink to %d downloads", ad.config.ID, n)
+		p.Pause(ad.config.ID)
+	}
+}
+
+// UpdateRuntime hands new settings to every running download and to those
+// that start later. Only the latest update is kept for a download that has
+// not yet applied the previous one.
+func (p *WorkerPool) UpdateRuntime(rc *types.RuntimeConfig) {
+	if rc == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.runtime = rc
+	for _, ad := range p.downloads {
+		ch := ad.config.RuntimeUpdates
+		if ch == nil {
+			continue
+		}
+		select {
+		case <-ch: // Drop an update the download has not picked up yet
+		default:
+		}
+		select {
+		case ch <- rc:
+		default:
+		}
+	}
+}
+
+// requeuePreempted puts a download paused by SetMaxDownloads back in the
+// queue, ahead of waiting downloads of the same priority
+func (p *WorkerPool) requeuePreempted(ad *activeDownload) {
+	cfg := p.requeueFront(ad)
+	if p.progressCh != nil {
+		p.progressCh <- events.DownloadResumedMsg{
+			DownloadID: cfg.ID,
+			Filename:   cfg.Filename,
+		}
+	}
+}
+
+// requeueFront clears the pause of a stopped download and puts it back in
+// the queue ahead of waiting downloads of the same priority
+func (p *WorkerPool) requeueFront(ad *activeDownload) types.DownloadConfig {
+	if ad.config.State != nil {
+		ad.config.State.Resume()
+		ad.config.State.SyncSessionStart()
+	}
+
+	p.mu.Lock()
+	ad.preempted = false
+	cfg := ad.config
+	cfg.IsResume = true
+	pos := len(p.queue)
+	for i, q := range p.queue {
+		if q.Priority <= cfg.Priority {
+			pos = i
+			break
+		}
+	}
+	p.queue = append(p.queue, types.DownloadConfig{})
+	copy(p.queue[pos+1:], p.queue[pos:])
+	p.queue[pos] = cfg
+	order := p.queueOrderLocked()
+	p.queueReady.Signal()
+	p.mu.Unlock()
+
+	p.publishQueueOrder(order)
+	return cfg
+}
+
+// Add adds a new download task to the pool. It is placed after every queued
+// download with the same or a higher priority. Downloads with a future
+// StartAt are held back until then.
 func (p *WorkerPool) Add(cfg types.DownloadConfig) {
+	if cfg.StartAt.After(time.Now()) {
+		p.schedule(cfg)
+		return
+	}
+
 	p.mu.Lock()
-	p.queued[cfg.ID] = cfg
+	pos := len(p.queue)
+	for i, q := range p.queue {
+		if q.Priority < cfg.Priority {
+			pos = i
+			break
+		}
+	}
+	p.queue = append(p.queue, types.DownloadConfig{})
+	copy(p.queue[pos+1:], p.queue[pos:])
+	p.queue[pos] = cfg
+	appended := pos == len(p.queue)-1
+	order := p.queueOrderLocked()
+	p.queueReady.Signal()
 	p.mu.Unlock()
 
+	if !cfg.IsResume {
+		persistWaiting(cfg, "queued")
+	}
+
 	if p.progressCh != nil && !cfg.IsResume {
 		p.progressCh <- events.DownloadQueuedMsg{
+			DownloadID:   cfg.ID,
+			Filename:     cfg.Filename,
+			Group:        cfg.Group,
+			DownloadMeta: cfg.DownloadMeta,
+		}
+	}
+
+	// Appending to the tail is implied by the queued/resumed message; only a
+	// priority jump ahead of other items needs the full order broadcast.
+	if appended {
+		saveQueueOrder(order)
+		return
+	}
+	p.publishQueueOrder(order)
+}
+
+// persistWaiting records a fresh download that has not started yet so it
+// survives a restart. Until the probe runs, DestPath is the output directory
+// joined with the requested filename, if any.
+func persistWaiting(cfg types.DownloadConfig, status string) {
+	var startAt int64
+	if !cfg.StartAt.IsZero() {
+		startAt = cfg.StartAt.Unix()
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:           cfg.ID,
+		URL:          cfg.URL,
+		URLHash:      state.URLHash(cfg.URL),
+		DestPath:     filepath.Join(cfg.OutputPath, cfg.Filename),
+		Filename:     cfg.Filename,
+		Status:       status,
+		Category:     cfg.Category,
+		Priority:     cfg.Priority,
+		StartAt:      startAt,
+		Group:        cfg.Group,
+		Headers:      cfg.Headers,
+		DownloadMeta: cfg.DownloadMeta,
+	}); err != nil {
+		utils.Debug("WorkerPool: failed to persist %s download: %v", status, err)
+	}
+}
+
+// schedule holds cfg back until cfg.StartAt, then queues it like a normal Add
+func (p *WorkerPool) schedule(cfg types.DownloadConfig) {
+	sd := &scheduledDownload{config: cfg}
+
+	p.mu.Lock()
+	if prev, ok := p.scheduled[cfg.ID]; ok {
+		prev.timer.Stop()
+	}
+	p.scheduled[cfg.ID] = sd
+	// The callback takes mu, so it cannot run before timer is assigned
+	sd.timer = time.AfterFunc(time.Until(cfg.StartAt), func() { p.startScheduled(sd) })
+	p.mu.Unlock()
+
+	if !cfg.IsResume {
+		persistWaiting(cfg, "scheduled")
+	}
+
+	if p.progressCh != nil {
+		p.progressCh <- events.DownloadScheduledMsg{
 			DownloadID: cfg.ID,
 			Filename:   cfg.Filename,
+			StartAt:    cfg.StartAt,
+		}
+	}
+}
+
+// startScheduled moves a scheduled download into the queue once its time comes
+func (p *WorkerPool) startScheduled(sd *scheduledDownload) {
+	id := sd.config.ID
+	p.mu.Lock()
+	if p.scheduled[id] != sd {
+		// Cancelled or rescheduled in the meantime
+		p.mu.Unlock()
+		return
+	}
+	delete(p.scheduled, id)
+	p.mu.Unlock()
+
+	cfg := sd.config
+	cfg.StartAt = time.Time{}
+	p.Add(cfg)
+}
+
+// queueOrderLocked snapshots the queue as persisted positions. Caller holds mu.
+func (p *WorkerPool) queueOrderLocked() []types.QueuePosition {
+	order := make([]types.QueuePosition, len(p.queue))
+	for i, cfg := range p.queue {
+		order[i] = types.QueuePosition{ID: cfg.ID, Priority: cfg.Priority}
+	}
+	return order
+}
+
+// publishQueueOrder persists the queue order and tells listeners about it
+func (p *WorkerPool) publishQueueOrder(order []types.QueuePosition) {
+	saveQueueOrder(order)
+
+	if p.progressCh != nil {
+		ids := make([]string, len(order))
+		for i, q := range order {
+			ids[i] = q.ID
+		}
+		p.progressCh <- events.QueueReorderedMsg{Order: ids}
+	}
+}
+
+// saveQueueOrder persists queue positions so a restart restores the same order
+func saveQueueOrder(order []types.QueuePosition) {
+	if len(order) == 0 {
+		return
+	}
+	if err := state.SaveQueueOrder(order); err != nil {
+		utils.Debug("WorkerPool: failed to persist queue order: %v", err)
+	}
+}
+
+// Move reorders a queued download. op is one of types.QueueMoveTop, Up, Down
+// or Bottom. A moved download takes on its new neighbour's priority where
+// needed, so the queue stays sorted by priority and the move sticks across
+// restarts.
+func (p *WorkerPool) Move(downloadID, op string) error {
+	p.mu.Lock()
+	idx := p.queueIndexLocked(downloadID)
+	if idx < 0 {
+		p.mu.Unlock()
+		return types.ErrNotQueued
+	}
+
+	cfg := p.queue[idx]
+	last := len(p.queue) - 1
+	switch op {
+	case types.QueueMoveTop:
+		if head := p.queue[0].Priority; head > cfg.Priority {
+			cfg.Priority = head
+		}
+		copy(p.queue[1:idx+1], p.queue[:idx])
+		p.queue[0] = cfg
+	case types.QueueMoveBottom:
+		if tail := p.queue[last].Priority; tail < cfg.Priority {
+			cfg.Priority = tail
+		}
+		copy(p.queue[idx:last], p.queue[idx+1:])
+		p.queue[last] = cfg
+	case types.QueueMoveUp:
+		if idx > 0 {
+			if prev := p.queue[idx-1].Priority; prev > cfg.Priority {
+				cfg.Priority = prev
+			}
+			p.queue[idx] = p.queue[idx-1]
+			p.queue[idx-1] = cfg
+		}
+	case types.QueueMoveDown:
+		if idx < last {
+			if next := p.queue[idx+1].Priority; next < cfg.Priority {
+				cfg.Priority = next
+			}
+			p.queue[idx] = p.queue[idx+1]
+			p.queue[idx+1] = cfg
 		}
+	default:
+		p.mu.Unlock()
+		return fmt.Errorf("unknown queue move %q", op)
 	}
+	order := p.queueOrderLocked()
+	p.mu.Unlock()
+
+	p.publishQueueOrder(order)
+	return nil
+}
+
+// QueuePosition returns the 1-based position of a waiting download, or 0 if
+// it is not queued
+func (p *WorkerPool) QueuePosition(downloadID string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.queueIndexLocked(downloadID) + 1
+}
 
-	p.taskChan <- cfg
+// queueIndexLocked returns the queue index of id or -1. Caller holds mu.
+func (p *WorkerPool) queueIndexLocked(id string) int {
+	for i, cfg := range p.queue {
+		if cfg.ID == id {
+			return i
+		}
+	}
+	return -1
 }
 
 // HasDownload checks if a download with the given URL already exists
@@ -95,8 +442,8 @@ func (p *WorkerPool) ActiveCount() int {
 			count++
 		}
 	}
-	// Also count queued
-	count += len(p.queued)
+	// Also count queued and scheduled
+	count += len(p.queue) + len(p.scheduled)
 	return count
 }
 
@@ -106,11 +453,15 @@ func (p *WorkerPool) GetAll() []types.DownloadConfig {
 	defer p.mu.RUnlock()
 
 	var configs []types.DownloadConfig
-	for _, ad := range p.downloads {
-		configs = append(configs, ad.config)
+	for id, ad := range p.downloads {
+		// A resumed download waits in the queue while its old entry lingers
+		if p.queueIndexLocked(id) < 0 {
+			configs = append(configs, ad.config)
+		}
 	}
-	for _, cfg := range p.queued {
-		configs = append(configs, cfg)
+	configs = append(configs, p.queue...)
+	for _, sd := range p.scheduled {
+		configs = append(configs, sd.config)
 	}
 	return configs
 }
@@ -122,7 +473,7 @@ func (p *WorkerPool) Pause(downloadID string) bool {
 	p.mu.RUnlock()
 
 	if !exists || ad == nil {
-		return false
+		return p.pauseWaiting(downloadID)
 	}
 
 	// Set paused flag and cancel context
@@ -150,6 +501,40 @@ func (p *WorkerPool) Pause(downloadID string) bool {
 	return true
 }
 
+// pauseWaiting takes a download that never started out of the queue (or off
+// its schedule) and marks it paused, so resuming it later starts it fresh.
+func (p *WorkerPool) pauseWaiting(downloadID string) bool {
+	p.mu.Lock()
+	var cfg types.DownloadConfig
+	found := false
+	if idx := p.queueIndexLocked(downloadID); idx >= 0 {
+		cfg = p.queue[idx]
+		p.queue = append(p.queue[:idx], p.queue[idx+1:]...)
+		found = true
+	} else if sd, ok := p.scheduled[downloadID]; ok {
+		sd.timer.Stop()
+		delete(p.scheduled, downloadID)
+		cfg = sd.config
+		found = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		return false
+	}
+
+	if err := state.UpdateStatus(downloadID, "paused"); err != nil {
+		utils.Debug("WorkerPool: failed to mark waiting download paused: %v", err)
+	}
+	if p.progressCh != nil {
+		p.progressCh <- events.DownloadPausedMsg{
+			DownloadID: downloadID,
+			Filename:   cfg.Filename,
+		}
+	}
+	return true
+}
+
 // PauseAll pauses all active downloads (for graceful shutdown)
 func (p *WorkerPool) PauseAll() {
 	p.mu.RLock()
@@ -170,12 +555,42 @@ func (p *WorkerPool) PauseAll() {
 // Cancel cancels and removes a download by ID
 func (p *WorkerPool) Cancel(downloadID string) {
 	p.mu.Lock()
+	if sd, ok := p.scheduled[downloadID]; ok {
+		sd.timer.Stop()
+		delete(p.scheduled, downloadID)
+		p.mu.Unlock()
+
+		if p.progressCh != nil {
+			p.progressCh <- events.DownloadRemovedMsg{
+				DownloadID: downloadID,
+				Filename:   sd.config.Filename,
+			}
+		}
+		return
+	}
 	ad, exists := p.downloads[downloadID]
 	if exists {
 		delete(p.downloads, downloadID)
 	}
+	var dequeued *types.DownloadConfig
+	if idx := p.queueIndexLocked(downloadID); idx >= 0 {
+		cfg := p.queue[idx]
+		dequeued = &cfg
+		p.queue = append(p.queue[:idx], p.queue[idx+1:]...)
+	}
 	p.mu.Unlock()
 
+	if dequeued != nil && !exists {
+		// Never started: nothing to stop, just report the removal
+		if p.progressCh != nil {
+			p.progressCh <- events.DownloadRemovedMsg{
+				DownloadID: downloadID,
+				Filename:   dequeued.Filename,
+			}
+		}
+		return
+	}
+
 	if !exists || ad == nil {
 		return
 	}
@@ -209,8 +624,14 @@ func (p *WorkerPool) Cancel(downloadID string) {
 func (p *WorkerPool) Resume(downloadID string) bool {
 	p.mu.RLock()
 	ad, exists := p.downloads[downloadID]
+	_, scheduled := p.scheduled[downloadID]
 	p.mu.RUnlock()
 
+	if scheduled {
+		// Already waiting for its start time
+		return true
+	}
+
 	if !exists || ad == nil {
 		return false
 	}
@@ -247,21 +668,241 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 	return true
 }
 
+// relocateStopTimeout bounds how long Relocate waits for a running download
+// to save its state and stop
+const relocateStopTimeout = 10 * time.Second
+
+// Relocate points a download at filename in dir, so it continues from there.
+// move does the work on disk and runs while nothing writes to the download's
+// files: a running download is stopped first and put back at the front of
+// the queue afterwards, without pause or resume events, and a waiting one is
+// held out of the queue. An empty filename keeps the current one. Downloads
+// the pool doesn't hold just run move.
+func (p *WorkerPool) Relocate(downloadID, dir, filename string, move func() error) error {
+	restart, err := p.stopRunning(downloadID)
+	if err != nil {
+		return err
+	}
+
+	// Hold a waiting download back so no worker starts it mid-move
+	h := p.hold(downloadID)
+	moveErr := move()
+	var update func(cfg *types.DownloadConfig)
+	if moveErr == nil {
+		update = func(cfg *types.DownloadConfig) { retarget(cfg, dir, filename) }
+	}
+	p.release(h, update)
+
+	if restart != nil {
+		p.requeueFront(restart)
+		// Stopping saved it as paused; it is running again
+		if err := state.UpdateStatus(downloadID, "queued"); err != nil {
+			utils.Debug("WorkerPool: failed to mark relocated download queued: %v", err)
+		}
+	}
+	return moveErr
+}
+
+// Relink points a stopped download at url, sending headers from then on, so
+// it resumes from there. relink records the change and runs while the
+// download is held out of the queue.
+// It returns types.ErrRunning, without calling relink, if the download is
+// running. Downloads the pool doesn't hold just run relink.
+func (p *WorkerPool) Relink(downloadID, url string, headers map[string]string, relink func() error) error {
+	h := p.hold(downloadID)
+
+	p.mu.RLock()
+	ad, ok := p.downloads[downloadID]
+	p.mu.RUnlock()
+	if ok && h.queueIdx < 0 && ad.config.State != nil &&
+		(!ad.config.State.IsPaused() || ad.config.State.IsPausing()) {
+		p.release(h, nil)
+		return types.ErrRunning
+	}
+
+	err := relink()
+	var update func(cfg *types.DownloadConfig)
+	if err == nil {
+		update = func(cfg *types.DownloadConfig) {
+			repoint(cfg, url)
+			cfg.Headers = headers
+		}
+	}
+	p.release(h, update)
+	return err
+}
+
+// heldDownload is a waiting download taken out of the queue or schedule
+type heldDownload struct {
+	id       string
+	queueIdx int // Position it was taken from, or -1 if it wasn't queued
+	queued   types.DownloadConfig
+	sd       *scheduledDownload
+}
+
+// hold takes a waiting download out of the queue and stops its schedule
+// timer, so no worker starts it until release
+func (p *WorkerPool) hold(downloadID string) *heldDownload {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := &heldDownload{id: downloadID, queueIdx: p.queueIndexLocked(downloadID)}
+	if h.queueIdx >= 0 {
+		h.queued = p.queue[h.queueIdx]
+		p.queue = append(p.queue[:h.queueIdx], p.queue[h.queueIdx+1:]...)
+	}
+	if sd := p.scheduled[downloadID]; sd != nil {
+		sd.timer.Stop()
+		delete(p.scheduled, downloadID)
+		h.sd = sd
+	}
+	return h
+}
+
+// release puts a held download back where it was. update, if not nil, is
+// first applied to every copy of its config the pool has.
+func (p *WorkerPool) release(h *heldDownload, update func(cfg *types.DownloadConfig)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if update != nil {
+		if h.queueIdx >= 0 {
+			update(&h.queued)
+		}
+		if h.sd != nil {
+			update(&h.sd.config)
+		}
+		if ad, ok := p.downloads[h.id]; ok {
+			update(&ad.config)
+		}
+	}
+	if h.queueIdx >= 0 {
+		pos := min(h.queueIdx, len(p.queue))
+		p.queue = append(p.queue, types.DownloadConfig{})
+		copy(p.queue[pos+1:], p.queue[pos:])
+		p.queue[pos] = h.queued
+		p.queueReady.Signal()
+	}
+	if sd := h.sd; sd != nil {
+		p.scheduled[h.id] = sd
+		sd.timer = time.AfterFunc(time.Until(sd.config.StartAt), func() { p.startScheduled(sd) })
+	}
+}
+
+// stopRunning pauses a running download without announcing it and waits for
+// its worker to save state and exit. It returns the download if it was
+// running, or nil if it was already stopped or isn't active.
+func (p *WorkerPool) stopRunning(downloadID string) (*activeDownload, error) {
+	p.mu.RLock()
+	ad, ok := p.downloads[downloadID]
+	queued := p.queueIndexLocked(downloadID) >= 0
+	p.mu.RUnlock()
+	if !ok || queued || ad.config.State == nil {
+		return nil, nil
+	}
+
+	st := ad.config.State
+	running := !st.IsPaused()
+	if running {
+		if st.Done.Load() {
+			return nil, fmt.Errorf("download is finishing")
+		}
+		st.SetPausing(true)
+		st.Pause()
+	}
+
+	// A user pause may also still be in progress
+	deadline := time.Now().Add(relocateStopTimeout)
+	for st.IsPausing() {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the download to stop")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !running {
+		return nil, nil
+	}
+
+	p.mu.RLock()
+	_, ok = p.downloads[downloadID]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("download finished before it could be moved")
+	}
+	return ad, nil
+}
+
+// retarget makes cfg start or resume from filename in dir
+func retarget(cfg *types.DownloadConfig, dir, filename string) {
+	cfg.OutputPath = dir
+	if filename != "" {
+		cfg.Filename = filename
+	}
+	cfg.DestPath = filepath.Join(dir, cfg.Filename)
+	cfg.SavedState = nil // Reloaded from the database, which has the new path
+	cfg.Router = nil     // The user chose where it goes
+	if cfg.State != nil {
+		cfg.State.DestPath = cfg.DestPath
+	}
+}
+
+// repoint makes cfg resume from url, dropping the old URL from its mirrors
+func repoint(cfg *types.DownloadConfig, url string) {
+	var mirrors []string
+	for _, m := range cfg.Mirrors {
+		if m != cfg.URL && m != url {
+			mirrors = append(mirrors, m)
+		}
+	}
+	cfg.URL = url
+	cfg.Mirrors = mirrors
+	cfg.SavedState = nil // Reloaded from the database, which has the new URL
+}
+
+// next blocks until a download is queued, then moves the highest-priority one
+// into the active set. It returns ok=false when the pool has shrunk and the
+// calling worker should exit.
+func (p *WorkerPool) next() (cfg types.DownloadConfig, ad *activeDownload, ctx context.Context, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.queue) == 0 && p.workers <= p.maxDownloads {
+		p.queueReady.Wait()
+	}
+	if p.workers > p.maxDownloads {
+		p.workers--
+		return cfg, nil, nil, false
+	}
+
+	cfg = p.queue[0]
+	p.queue = p.queue[1:]
+
+	// Downloads queued before a settings change start with the new settings
+	if p.runtime != nil {
+		cfg.Runtime = p.runtime
+	}
+	cfg.RuntimeUpdates = make(chan *types.RuntimeConfig, 1)
+
+	// Create cancellable context
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Register active download
+	ad = &activeDownload{
+		config:  cfg,
+		cancel:  cancel,
+		started: time.Now(),
+	}
+	p.downloads[cfg.ID] = ad
+	p.wg.Add(1)
+	return cfg, ad, ctx, true
+}
+
 func (p *WorkerPool) worker() {
-	for cfg := range p.taskChan {
-		p.wg.Add(1)
-		// Create cancellable context
-		ctx, cancel := context.WithCancel(context.Background())
-
-		// Register active download
-		ad := &activeDownload{
-			config: cfg,
-			cancel: cancel,
-		}
-		p.mu.Lock()
-		delete(p.queued, cfg.ID)
-		p.downloads[cfg.ID] = ad
-		p.mu.Unlock()
+	for {
+		cfg, ad, ctx, ok := p.next()
+		if !ok {
+			return
+		}
 
 		err := TUIDownload(ctx, &ad.config)
 
@@ -279,6 +920,12 @@ func (p *WorkerPool) worker() {
 		if isPaused {
 			utils.Debug("WorkerPool: Download %s paused cleanly", cfg.ID)
 			// If paused, we keep it in downloads map for potential resume
+			p.mu.RLock()
+			preempted := ad.preempted
+			p.mu.RUnlock()
+			if preempted {
+				p.requeuePreempted(ad)
+			}
 		} else if err != nil {
 			if cfg.State != nil {
 				cfg.State.SetError(err)
@@ -315,22 +962,46 @@ func (p *WorkerPool) worker() {
 // GetStatus returns the status of an active download
 func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	p.mu.RLock()
+	if sd, ok := p.scheduled[id]; ok {
+		p.mu.RUnlock()
+		return &types.DownloadStatus{
+			ID:           id,
+			URL:          sd.config.URL,
+			Filename:     sd.config.Filename,
+			Status:       "scheduled",
+			Category:     sd.config.Category,
+			Priority:     sd.config.Priority,
+			StartAt:      sd.config.StartAt.Unix(),
+			Group:        sd.config.Group,
+			DownloadMeta: sd.config.DownloadMeta,
+		}
+	}
 	ad, exists := p.downloads[id]
-	qCfg, qExists := p.queued[id]
+	qIdx := p.queueIndexLocked(id)
+	var qCfg types.DownloadConfig
+	if qIdx >= 0 {
+		qCfg = p.queue[qIdx]
+	}
 	p.mu.RUnlock()
 
-	if !exists && !qExists {
+	if !exists && qIdx < 0 {
 		return nil
 	}
 
-	if qExists {
+	if qIdx >= 0 {
 		return &types.DownloadStatus{
-			ID:         id,
-			URL:        qCfg.URL,
-			Filename:   qCfg.Filename,
-			Status:     "queued",
-			Downloaded: 0,
-			TotalSize:  0, // Metadata not yet fetched
+			ID:            id,
+			URL:           qCfg.URL,
+			Filename:      qCfg.Filename,
+			Status:        "queued",
+			Downloaded:    0,
+			TotalSize:     0, // Metadata not yet fetched
+			Category:      qCfg.Category,
+			Priority:      qCfg.Priority,
+			StartAt:       unixOrZero(qCfg.StartAt),
+			QueuePosition: qIdx + 1,
+			Group:         qCfg.Group,
+			DownloadMeta:  qCfg.DownloadMeta,
 		}
 	}
 
@@ -340,12 +1011,17 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	}
 
 	status := &types.DownloadStatus{
-		ID:         id,
-		URL:        ad.config.URL,
-		Filename:   ad.config.Filename,
-		TotalSize:  state.TotalSize,
-		Downloaded: state.Downloaded.Load(),
-		Status:     "downloading",
+		ID:           id,
+		URL:          ad.config.URL,
+		Filename:     ad.config.Filename,
+		TotalSize:    state.TotalSize,
+		Downloaded:   state.Downloaded.Load(),
+		Status:       "downloading",
+		Category:     ad.config.Category,
+		Priority:     ad.config.Priority,
+		StartAt:      unixOrZero(ad.config.StartAt),
+		Group:        ad.config.Group,
+		DownloadMeta: ad.config.DownloadMeta,
 	}
 
 	if ad.config.State.IsPausing() {
@@ -377,8 +1053,28 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	return status
 }
 
+// unixOrZero returns t as a Unix timestamp, or 0 for the zero time
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
 // GracefulShutdown pauses all downloads and waits for them to save state
 func (p *WorkerPool) GracefulShutdown() {
+	// Scheduled downloads stay "scheduled" in the DB and are re-armed on startup
+	p.mu.Lock()
+	for id, sd := range p.scheduled {
+		sd.timer.Stop()
+		delete(p.scheduled, id)
+	}
+	// Downloads paused by a shrink stay paused instead of re-entering the queue
+	for _, ad := range p.downloads {
+		ad.preempted = false
+	}
+	p.mu.Unlock()
+
 	// ... existing implementation
 	p.PauseAll()
 
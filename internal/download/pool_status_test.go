@@ -30,6 +30,8 @@ func TestWorkerPool_GetStatus_Active(t *testing.T) {
 			ID:       id,
 			URL:      "http://example.com/file",
 			Filename: "file",
+			Priority: 7,
+			Group:    "batch",
 			State:    state,
 		},
 	}
@@ -39,6 +41,9 @@ func TestWorkerPool_GetStatus_Active(t *testing.T) {
 	if status == nil {
 		t.Fatal("Expected status to be returned")
 	}
+	if status.Priority != 7 || status.Group != "batch" {
+		t.Errorf("Expected priority 7 and group batch, got %d and %q", status.Priority, status.Group)
+	}
 
 	if status.ID != id {
 		t.Errorf("Expected ID %s, got %s", id, status.ID)
@@ -20,8 +20,8 @@ func TestNewWorkerPool(t *testing.T) {
 		t.Fatal("Expected non-nil WorkerPool")
 	}
 
-	if pool.taskChan == nil {
-		t.Error("Expected taskChan to be initialized")
+	if pool.queueReady == nil {
+		t.Error("Expected queueReady to be initialized")
 	}
 
 	if pool.progressCh != ch {
@@ -537,7 +537,7 @@ func TestWorkerPool_Resume_SendsResumedMessage(t *testing.T) {
 
 	pool.Resume("test-id")
 
-	// We can't reliably read from pool.taskChan because worker goroutines may consume the config before us. Just verify the resumed message was sent.
+	// We can't reliably read from the queue because worker goroutines may consume the config before us. Just verify the resumed message was sent.
 	// Check for resumed message
 	select {
 	case msg := <-ch:
@@ -575,7 +575,7 @@ func TestWorkerPool_Resume_RequeuesDownload(t *testing.T) {
 
 	pool.Resume("test-id")
 
-	// Note: We can't reliably read from pool.taskChan because worker goroutines
+	// Note: We can't reliably read from the queue because worker goroutines
 	// may consume the config before us. Instead, verify Resume cleared the paused
 	// flag and sent the resumed message.
 
@@ -782,3 +782,39 @@ func TestWorkerPool_PauseResume_Idempotency(t *testing.T) {
 		// OK
 	}
 }
+
+func TestWorkerPool_UpdateRuntime(t *testing.T) {
+	pool := newIdlePool(nil)
+
+	// A running download receives only the latest update
+	updates := make(chan *types.RuntimeConfig, 1)
+	pool.downloads["running"] = &activeDownload{
+		config: types.DownloadConfig{ID: "running", RuntimeUpdates: updates},
+	}
+	first := &types.RuntimeConfig{MaxConnectionsPerHost: 2}
+	second := &types.RuntimeConfig{MaxConnectionsPerHost: 8}
+	pool.UpdateRuntime(first)
+	pool.UpdateRuntime(second)
+
+	select {
+	case rc := <-updates:
+		if rc != second {
+			t.Errorf("running download got %+v, want the latest settings", rc)
+		}
+	default:
+		t.Fatal("running download did not receive the update")
+	}
+
+	// A download queued with older settings starts with the new ones
+	pool.Add(types.DownloadConfig{ID: "queued", Runtime: &types.RuntimeConfig{MaxConnectionsPerHost: 1}})
+	cfg, ad, _, ok := pool.next()
+	if !ok {
+		t.Fatal("next() returned no download")
+	}
+	if cfg.Runtime != second {
+		t.Errorf("queued download started with %+v, want the latest settings", cfg.Runtime)
+	}
+	if ad.config.RuntimeUpdates == nil {
+		t.Error("started download has no update channel")
+	}
+}
@@ -0,0 +1,256 @@
+package download
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// newIdlePool returns a pool without workers so queued items stay put
+func newIdlePool(ch chan<- any) *WorkerPool {
+	pool := &WorkerPool{
+		progressCh:   ch,
+		downloads:    make(map[string]*activeDownload),
+		scheduled:    make(map[string]*scheduledDownload),
+		maxDownloads: 1,
+	}
+	pool.queueReady = sync.NewCond(&pool.mu)
+	return pool
+}
+
+func queueIDs(p *WorkerPool) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	ids := make([]string, len(p.queue))
+	for i, cfg := range p.queue {
+		ids[i] = cfg.ID
+	}
+	return ids
+}
+
+func TestWorkerPool_Add_OrdersByPriority(t *testing.T) {
+	pool := newIdlePool(nil)
+
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "b", IsResume: true, Priority: 5})
+	pool.Add(types.DownloadConfig{ID: "c", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "d", IsResume: true, Priority: 5})
+	pool.Add(types.DownloadConfig{ID: "e", IsResume: true, Priority: -1})
+
+	want := []string{"b", "d", "a", "c", "e"}
+	if got := queueIDs(pool); !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+	if pos := pool.QueuePosition("a"); pos != 3 {
+		t.Errorf("QueuePosition(a) = %d, want 3", pos)
+	}
+	if pos := pool.QueuePosition("missing"); pos != 0 {
+		t.Errorf("QueuePosition(missing) = %d, want 0", pos)
+	}
+}
+
+func TestWorkerPool_Add_PublishesOrderOnlyWhenJumping(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "b", IsResume: true})
+	select {
+	case msg := <-ch:
+		t.Fatalf("unexpected message for tail append: %T", msg)
+	default:
+	}
+
+	pool.Add(types.DownloadConfig{ID: "c", IsResume: true, Priority: 1})
+	select {
+	case msg := <-ch:
+		reordered, ok := msg.(events.QueueReorderedMsg)
+		if !ok {
+			t.Fatalf("expected QueueReorderedMsg, got %T", msg)
+		}
+		if want := []string{"c", "a", "b"}; !reflect.DeepEqual(reordered.Order, want) {
+			t.Errorf("order = %v, want %v", reordered.Order, want)
+		}
+	default:
+		t.Fatal("expected QueueReorderedMsg")
+	}
+}
+
+func TestWorkerPool_Move(t *testing.T) {
+	tests := []struct {
+		id, op string
+		want   []string
+	}{
+		{"c", types.QueueMoveTop, []string{"c", "a", "b", "d"}},
+		{"b", types.QueueMoveBottom, []string{"a", "c", "d", "b"}},
+		{"c", types.QueueMoveUp, []string{"a", "c", "b", "d"}},
+		{"b", types.QueueMoveDown, []string{"a", "c", "b", "d"}},
+		{"a", types.QueueMoveUp, []string{"a", "b", "c", "d"}},
+		{"d", types.QueueMoveDown, []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id+"_"+tt.op, func(t *testing.T) {
+			pool := newIdlePool(nil)
+			for _, id := range []string{"a", "b", "c", "d"} {
+				pool.Add(types.DownloadConfig{ID: id, IsResume: true})
+			}
+			if err := pool.Move(tt.id, tt.op); err != nil {
+				t.Fatalf("Move: %v", err)
+			}
+			if got := queueIDs(pool); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_Move_AdoptsNeighbourPriority(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.Add(types.DownloadConfig{ID: "high", IsResume: true, Priority: 10})
+	pool.Add(types.DownloadConfig{ID: "low", IsResume: true})
+
+	if err := pool.Move("low", types.QueueMoveTop); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if status := pool.GetStatus("low"); status == nil || status.Priority != 10 {
+		t.Fatalf("expected moved item to take priority 10, got %+v", status)
+	}
+
+	// A later default-priority add must still land behind both
+	pool.Add(types.DownloadConfig{ID: "new", IsResume: true})
+	if got, want := queueIDs(pool), []string{"low", "high", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestWorkerPool_Move_Errors(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+
+	if err := pool.Move("missing", types.QueueMoveTop); !errors.Is(err, types.ErrNotQueued) {
+		t.Errorf("expected ErrNotQueued, got %v", err)
+	}
+	if err := pool.Move("a", "sideways"); err == nil {
+		t.Error("expected error for unknown move")
+	}
+}
+
+func TestWorkerPool_Cancel_RemovesFromQueue(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "b", IsResume: true})
+
+	pool.Cancel("a")
+
+	if got, want := queueIDs(pool), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+	if pool.QueuePosition("b") != 1 {
+		t.Errorf("expected b to move to the head of the queue")
+	}
+}
+
+func TestWorkerPool_Add_SchedulesFutureStart(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+
+	startAt := time.Now().Add(150 * time.Millisecond)
+	pool.Add(types.DownloadConfig{ID: "later", IsResume: true, StartAt: startAt})
+
+	select {
+	case msg := <-ch:
+		scheduled, ok := msg.(events.DownloadScheduledMsg)
+		if !ok {
+			t.Fatalf("expected DownloadScheduledMsg, got %T", msg)
+		}
+		if !scheduled.StartAt.Equal(startAt) {
+			t.Errorf("StartAt = %v, want %v", scheduled.StartAt, startAt)
+		}
+	default:
+		t.Fatal("expected DownloadScheduledMsg")
+	}
+
+	status := pool.GetStatus("later")
+	if status == nil || status.Status != "scheduled" || status.StartAt != startAt.Unix() {
+		t.Fatalf("unexpected status while scheduled: %+v", status)
+	}
+	if pool.QueuePosition("later") != 0 {
+		t.Error("scheduled download should not be queued yet")
+	}
+	if !pool.Resume("later") {
+		t.Error("Resume should report a scheduled download as handled")
+	}
+
+	deadline := time.After(2 * time.Second)
+	for pool.QueuePosition("later") != 1 {
+		select {
+		case <-deadline:
+			t.Fatal("scheduled download was never queued")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if status := pool.GetStatus("later"); status == nil || status.Status != "queued" {
+		t.Errorf("expected queued status after start time, got %+v", status)
+	}
+}
+
+func TestWorkerPool_Cancel_StopsScheduled(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+
+	pool.Add(types.DownloadConfig{ID: "later", IsResume: true, StartAt: time.Now().Add(50 * time.Millisecond)})
+	<-ch // scheduled
+
+	pool.Cancel("later")
+	select {
+	case msg := <-ch:
+		if _, ok := msg.(events.DownloadRemovedMsg); !ok {
+			t.Fatalf("expected DownloadRemovedMsg, got %T", msg)
+		}
+	default:
+		t.Fatal("expected DownloadRemovedMsg")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if pool.QueuePosition("later") != 0 || pool.GetStatus("later") != nil {
+		t.Error("cancelled scheduled download should never be queued")
+	}
+}
+
+func TestWorkerPool_Add_PastStartQueuesImmediately(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.Add(types.DownloadConfig{ID: "late", IsResume: true, StartAt: time.Now().Add(-time.Minute)})
+
+	if pool.QueuePosition("late") != 1 {
+		t.Error("a start time in the past should queue right away")
+	}
+}
+
+func TestWorkerPool_Pause_HoldsQueuedDownload(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "b", IsResume: true, Filename: "b.bin"})
+
+	pool.Pause("b")
+
+	if got, want := queueIDs(pool), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+	select {
+	case msg := <-ch:
+		paused, ok := msg.(events.DownloadPausedMsg)
+		if !ok || paused.DownloadID != "b" {
+			t.Fatalf("expected DownloadPausedMsg for b, got %#v", msg)
+		}
+	default:
+		t.Fatal("expected DownloadPausedMsg")
+	}
+}
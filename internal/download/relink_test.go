@@ -0,0 +1,92 @@
+package download
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestWorkerPool_Relink_PausedDownload(t *testing.T) {
+	pool := newIdlePool(nil)
+
+	st := types.NewProgressState("p", 1000)
+	st.Pause()
+	pool.mu.Lock()
+	pool.downloads["p"] = &activeDownload{
+		config: types.DownloadConfig{
+			ID:         "p",
+			URL:        "https://old.example.com/p.bin",
+			Mirrors:    []string{"https://old.example.com/p.bin", "https://mirror.example.com/p.bin"},
+			Headers:    map[string]string{"Cookie": "session=old"},
+			SavedState: &types.DownloadState{},
+			State:      st,
+		},
+	}
+	pool.mu.Unlock()
+
+	headers := map[string]string{"Authorization": "Bearer new"}
+	if err := pool.Relink("p", "https://new.example.com/p.bin", headers, func() error { return nil }); err != nil {
+		t.Fatalf("Relink failed: %v", err)
+	}
+
+	cfg := pool.downloads["p"].config
+	if cfg.URL != "https://new.example.com/p.bin" {
+		t.Errorf("URL = %q, want the new URL", cfg.URL)
+	}
+	if !reflect.DeepEqual(cfg.Mirrors, []string{"https://mirror.example.com/p.bin"}) {
+		t.Errorf("Mirrors = %v, want the old URL dropped", cfg.Mirrors)
+	}
+	if cfg.SavedState != nil {
+		t.Error("saved state should be dropped so resume reloads it by the new URL")
+	}
+	if !reflect.DeepEqual(cfg.Headers, headers) {
+		t.Errorf("Headers = %v, want the ones passed to Relink", cfg.Headers)
+	}
+}
+
+func TestWorkerPool_Relink_RefusesRunningDownload(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.mu.Lock()
+	pool.downloads["run"] = &activeDownload{
+		config: types.DownloadConfig{ID: "run", URL: "https://old.example.com/run.bin", State: types.NewProgressState("run", 1000)},
+	}
+	pool.mu.Unlock()
+
+	called := false
+	err := pool.Relink("run", "https://new.example.com/run.bin", nil, func() error { called = true; return nil })
+	if !errors.Is(err, types.ErrRunning) {
+		t.Fatalf("Relink error = %v, want ErrRunning", err)
+	}
+	if called {
+		t.Error("relink should not run for a running download")
+	}
+	if cfg := pool.downloads["run"].config; cfg.URL != "https://old.example.com/run.bin" {
+		t.Errorf("URL = %q, want it unchanged", cfg.URL)
+	}
+}
+
+func TestWorkerPool_Relink_QueuedDownload(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "b", IsResume: true, URL: "https://old.example.com/b.bin"})
+
+	relinkErr := errors.New("database locked")
+	if err := pool.Relink("b", "https://new.example.com/b.bin", nil, func() error { return relinkErr }); !errors.Is(err, relinkErr) {
+		t.Fatalf("Relink error = %v, want %v", err, relinkErr)
+	}
+	if cfg := pool.queue[1]; cfg.URL != "https://old.example.com/b.bin" {
+		t.Errorf("URL = %q, want the old URL kept after a failed relink", cfg.URL)
+	}
+
+	if err := pool.Relink("b", "https://new.example.com/b.bin", nil, func() error { return nil }); err != nil {
+		t.Fatalf("Relink failed: %v", err)
+	}
+	if got := queueIDs(pool); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("queue = %v, want [a b]", got)
+	}
+	if cfg := pool.queue[1]; cfg.URL != "https://new.example.com/b.bin" {
+		t.Errorf("URL = %q, want the new URL", cfg.URL)
+	}
+}
@@ -0,0 +1,110 @@
+package download
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestWorkerPool_Relocate_HoldsQueuedDownload(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true})
+	pool.Add(types.DownloadConfig{ID: "b", IsResume: true, Filename: "b.bin", SavedState: &types.DownloadState{}})
+	pool.Add(types.DownloadConfig{ID: "c", IsResume: true})
+
+	dir := filepath.Join(t.TempDir(), "new")
+	err := pool.Relocate("b", dir, "renamed.bin", func() error {
+		if got, want := queueIDs(pool), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("queue during move = %v, want %v", got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Relocate failed: %v", err)
+	}
+
+	if got, want := queueIDs(pool), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+	cfg := pool.queue[1]
+	if cfg.OutputPath != dir || cfg.Filename != "renamed.bin" || cfg.DestPath != filepath.Join(dir, "renamed.bin") {
+		t.Errorf("config = %q %q %q, want it pointed at the new path", cfg.OutputPath, cfg.Filename, cfg.DestPath)
+	}
+	if cfg.SavedState != nil {
+		t.Error("saved state should be dropped so resume reloads the new path")
+	}
+}
+
+func TestWorkerPool_Relocate_FailedMoveKeepsPath(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.Add(types.DownloadConfig{ID: "a", IsResume: true, OutputPath: "/old", Filename: "a.bin"})
+
+	moveErr := errors.New("disk full")
+	if err := pool.Relocate("a", "/new", "", func() error { return moveErr }); !errors.Is(err, moveErr) {
+		t.Fatalf("Relocate error = %v, want %v", err, moveErr)
+	}
+	if got := queueIDs(pool); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("queue = %v, want [a]", got)
+	}
+	if cfg := pool.queue[0]; cfg.OutputPath != "/old" {
+		t.Errorf("OutputPath = %q, want the old path kept", cfg.OutputPath)
+	}
+}
+
+func TestWorkerPool_Relocate_StopsAndRestartsRunningDownload(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+
+	st := types.NewProgressState("run", 1000)
+	pool.mu.Lock()
+	pool.downloads["run"] = &activeDownload{
+		config: types.DownloadConfig{ID: "run", OutputPath: "/old", Filename: "run.bin", State: st},
+	}
+	pool.mu.Unlock()
+
+	// Stand in for the worker: stop once the pause is requested
+	go func() {
+		for !st.IsPaused() {
+			time.Sleep(5 * time.Millisecond)
+		}
+		st.SetPausing(false)
+	}()
+
+	moved := false
+	err := pool.Relocate("run", "/new", "", func() error {
+		if !st.IsPaused() || st.IsPausing() {
+			t.Error("download should be stopped while its files move")
+		}
+		moved = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Relocate failed: %v", err)
+	}
+	if !moved {
+		t.Fatal("move was not called")
+	}
+
+	if got := queueIDs(pool); !reflect.DeepEqual(got, []string{"run"}) {
+		t.Fatalf("queue = %v, want the download queued to continue", got)
+	}
+	if cfg := pool.queue[0]; !cfg.IsResume || cfg.DestPath != filepath.Join("/new", "run.bin") {
+		t.Errorf("config = resume %v, dest %q; want a resume from the new path", cfg.IsResume, cfg.DestPath)
+	}
+	if st.IsPaused() {
+		t.Error("download should no longer be paused")
+	}
+
+	// The stop and restart are not announced as a pause and resume
+	for len(ch) > 0 {
+		switch msg := (<-ch).(type) {
+		case events.DownloadPausedMsg, events.DownloadResumedMsg:
+			t.Errorf("unexpected %T", msg)
+		}
+	}
+}
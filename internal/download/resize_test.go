@@ -0,0 +1,111 @@
+package download
+
+import (
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func workerCount(p *WorkerPool) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.workers
+}
+
+func TestWorkerPool_SetMaxDownloads_GrowsAndShrinksWorkers(t *testing.T) {
+	pool := NewWorkerPool(nil, 2)
+
+	pool.SetMaxDownloads(4, config.ShrinkFinish)
+	if got := workerCount(pool); got != 4 {
+		t.Fatalf("workers after grow = %d, want 4", got)
+	}
+	if pool.MaxDownloads() != 4 {
+		t.Errorf("MaxDownloads = %d, want 4", pool.MaxDownloads())
+	}
+
+	// Idle workers exit as soon as they notice the lower limit
+	pool.SetMaxDownloads(1, config.ShrinkFinish)
+	deadline := time.After(2 * time.Second)
+	for workerCount(pool) != 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("workers after shrink = %d, want 1", workerCount(pool))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	pool.SetMaxDownloads(0, config.ShrinkFinish)
+	if pool.MaxDownloads() != 1 {
+		t.Errorf("limit below 1 should clamp to 1, got %d", pool.MaxDownloads())
+	}
+}
+
+// addRunning registers an active download without a worker behind it
+func addRunning(p *WorkerPool, id string, priority int, started time.Time) *types.ProgressState {
+	state := types.NewProgressState(id, 1000)
+	p.mu.Lock()
+	p.downloads[id] = &activeDownload{
+		config:  types.DownloadConfig{ID: id, Priority: priority, State: state},
+		cancel:  func() {},
+		started: started,
+	}
+	p.mu.Unlock()
+	return state
+}
+
+func TestWorkerPool_SetMaxDownloads_PausePolicyPreemptsNewestLowest(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := newIdlePool(ch)
+	pool.maxDownloads, pool.workers = 3, 3 // Pretend three workers are busy
+
+	now := time.Now()
+	oldest := addRunning(pool, "oldest", 0, now.Add(-3*time.Minute))
+	newest := addRunning(pool, "newest", 0, now.Add(-time.Minute))
+	important := addRunning(pool, "important", 5, now)
+
+	pool.SetMaxDownloads(2, config.ShrinkPause)
+	if !newest.IsPaused() || oldest.IsPaused() || important.IsPaused() {
+		t.Fatalf("expected only the newest default-priority download paused (oldest=%v newest=%v important=%v)",
+			oldest.IsPaused(), newest.IsPaused(), important.IsPaused())
+	}
+
+	// Once its worker stops, the preempted download goes back to the front of its band
+	pool.Add(types.DownloadConfig{ID: "waiting", IsResume: true})
+	pool.mu.RLock()
+	ad := pool.downloads["newest"]
+	pool.mu.RUnlock()
+	pool.requeuePreempted(ad)
+
+	if got := queueIDs(pool); len(got) != 2 || got[0] != "newest" {
+		t.Errorf("queue = %v, want newest first", got)
+	}
+	if newest.IsPaused() {
+		t.Error("requeued download should no longer be paused")
+	}
+
+	var sawResumed bool
+	for len(ch) > 0 {
+		if msg, ok := (<-ch).(events.DownloadResumedMsg); ok && msg.DownloadID == "newest" {
+			sawResumed = true
+		}
+	}
+	if !sawResumed {
+		t.Error("expected DownloadResumedMsg for the requeued download")
+	}
+}
+
+func TestWorkerPool_SetMaxDownloads_FinishPolicyLeavesRunning(t *testing.T) {
+	pool := newIdlePool(nil)
+	pool.maxDownloads, pool.workers = 2, 2
+
+	a := addRunning(pool, "a", 0, time.Now())
+	b := addRunning(pool, "b", 0, time.Now())
+
+	pool.SetMaxDownloads(1, config.ShrinkFinish)
+	if a.IsPaused() || b.IsPaused() {
+		t.Error("finish policy should let running downloads complete")
+	}
+}
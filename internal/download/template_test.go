@@ -0,0 +1,88 @@
+package download_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestTUIDownload_AppliesFilenameTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	server := testutil.NewMockServerT(t,
+		testutil.WithFileSize(32*1024),
+		testutil.WithRangeSupport(true),
+		testutil.WithFilename("report.pdf"),
+	)
+	defer server.Close()
+
+	outDir := filepath.Join(tmpDir, "downloads")
+	cfg := types.DownloadConfig{
+		URL:        server.URL(),
+		OutputPath: outDir,
+		ID:         "0123456789abcdef",
+		ProgressCh: make(chan any, 100),
+		State:      types.NewProgressState("template-test", 0),
+		Runtime: &types.RuntimeConfig{
+			FilenameTemplate: "{date}/{name}-{shortid}{ext}",
+		},
+	}
+
+	if err := download.TUIDownload(context.Background(), &cfg); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	want := filepath.Join(outDir, time.Now().Format("2006-01-02"), "report-01234567.pdf")
+	if cfg.DestPath != want {
+		t.Errorf("DestPath = %q, want %q", cfg.DestPath, want)
+	}
+	if _, err := os.Stat(cfg.DestPath); err != nil {
+		t.Errorf("templated file missing: %v", err)
+	}
+}
+
+func TestTUIDownload_ExplicitFilenameSkipsTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	server := testutil.NewMockServerT(t,
+		testutil.WithFileSize(16*1024),
+		testutil.WithRangeSupport(true),
+		testutil.WithFilename("report.pdf"),
+	)
+	defer server.Close()
+
+	cfg := types.DownloadConfig{
+		URL:        server.URL(),
+		OutputPath: tmpDir,
+		Filename:   "CON.pdf",
+		ID:         "explicit-test",
+		ProgressCh: make(chan any, 100),
+		State:      types.NewProgressState("explicit-test", 0),
+		Runtime: &types.RuntimeConfig{
+			FilenameTemplate: "{host}/{filename}",
+			FilenameProfile:  "windows",
+		},
+	}
+
+	if err := download.TUIDownload(context.Background(), &cfg); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	// The user's name wins over the template but still goes through the profile
+	if want := filepath.Join(tmpDir, "_CON.pdf"); cfg.DestPath != want {
+		t.Errorf("DestPath = %q, want %q", cfg.DestPath, want)
+	}
+}
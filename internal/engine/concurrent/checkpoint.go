@@ -0,0 +1,168 @@
+package concurrent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// byteRanges is a set of written byte ranges, kept sorted and merged.
+// Checkpoints are derived from what was written rather than from the task
+// queue, since a task being stolen or retried is briefly in neither the
+// queue nor the active set.
+type byteRanges struct {
+	mu     sync.Mutex
+	ranges []types.Task
+	total  int64
+}
+
+// newByteRanges returns the ranges of a file of size not covered by tasks
+func newByteRanges(tasks []types.Task, size int64) *byteRanges {
+	r := &byteRanges{}
+	r.ranges = complementRanges(tasks, size)
+	for _, t := range r.ranges {
+		r.total += t.Length
+	}
+	return r
+}
+
+// Add records that length bytes were written at offset
+func (r *byteRanges) Add(offset, length int64) {
+	if r == nil || length <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	end := offset + length
+	// First range that ends at or after offset; it may touch or overlap
+	i := sort.Search(len(r.ranges), func(i int) bool {
+		return r.ranges[i].Offset+r.ranges[i].Length >= offset
+	})
+	j := i
+	for j < len(r.ranges) && r.ranges[j].Offset <= end {
+		t := r.ranges[j]
+		r.total -= t.Length
+		offset = min(offset, t.Offset)
+		end = max(end, t.Offset+t.Length)
+		j++
+	}
+	merged := types.Task{Offset: offset, Length: end - offset}
+	r.total += merged.Length
+	if i == j {
+		r.ranges = append(r.ranges, types.Task{})
+		copy(r.ranges[i+1:], r.ranges[i:])
+		r.ranges[i] = merged
+		return
+	}
+	r.ranges[i] = merged
+	r.ranges = append(r.ranges[:i+1], r.ranges[j:]...)
+}
+
+// Snapshot returns a copy of the written ranges and their total length
+func (r *byteRanges) Snapshot() ([]types.Task, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]types.Task(nil), r.ranges...), r.total
+}
+
+// complementRanges returns the parts of [0, size) not covered by ranges
+func complementRanges(ranges []types.Task, size int64) []types.Task {
+	sorted := append([]types.Task(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+
+	var out []types.Task
+	var pos int64
+	for _, t := range sorted {
+		if t.Offset > pos {
+			out = append(out, types.Task{Offset: pos, Length: min(t.Offset, size) - pos})
+		}
+		pos = max(pos, t.Offset+t.Length)
+		if pos >= size {
+			return out
+		}
+	}
+	if pos < size {
+		out = append(out, types.Task{Offset: pos, Length: size - pos})
+	}
+	return out
+}
+
+// checkpointTarget describes the running download a checkpoint records
+type checkpointTarget struct {
+	file         *os.File
+	destPath     string
+	fileSize     int64
+	startTime    time.Time     // When this session started
+	savedElapsed time.Duration // Time spent in earlier sessions
+	mirrors      []string
+}
+
+// runCheckpoints saves progress every types.CheckpointInterval until ctx is
+// done. The returned channel closes once the last checkpoint has finished,
+// so the caller can write final state without racing it.
+func (d *ConcurrentDownloader) runCheckpoints(ctx context.Context, t checkpointTarget) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(types.CheckpointInterval)
+		defer ticker.Stop()
+
+		var saved int64 = -1
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				written, err := d.checkpoint(t, saved)
+				if err != nil {
+					utils.Debug("Checkpoint failed: %v", err)
+					continue
+				}
+				saved = written
+			}
+		}
+	}()
+	return done
+}
+
+// checkpoint makes the written ranges durable and records the rest as the
+// download's remaining tasks. The data is synced before the state is
+// written, so a checkpoint never claims bytes that a crash could lose.
+// It returns the bytes covered, and skips the write if that hasn't changed
+// since the previous checkpoint.
+func (d *ConcurrentDownloader) checkpoint(t checkpointTarget, previous int64) (int64, error) {
+	ranges, written := d.written.Snapshot()
+	if written == previous {
+		return written, nil
+	}
+	if err := t.file.Sync(); err != nil {
+		return previous, err
+	}
+
+	s := &types.DownloadState{
+		ID:         d.ID,
+		URL:        d.currentURL(),
+		DestPath:   t.destPath,
+		Filename:   filepath.Base(t.destPath),
+		TotalSize:  t.fileSize,
+		Downloaded: written,
+		Tasks:      complementRanges(ranges, t.fileSize),
+		Elapsed:    (t.savedElapsed + time.Since(t.startTime)).Nanoseconds(),
+		Mirrors:    t.mirrors,
+	}
+	if d.State != nil {
+		s.ChunkBitmap, _, _, s.ActualChunkSize, _ = d.State.GetBitmap()
+	}
+	if err := state.SaveCheckpoint(s); err != nil {
+		return previous, err
+	}
+	return written, nil
+}
@@ -0,0 +1,138 @@
+package concurrent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestByteRanges_Add(t *testing.T) {
+	r := newByteRanges([]types.Task{{Offset: 0, Length: 100}}, 100)
+	if ranges, total := r.Snapshot(); len(ranges) != 0 || total != 0 {
+		t.Fatalf("fresh download: ranges = %+v, total = %d, want none", ranges, total)
+	}
+
+	r.Add(50, 10) // [50,60)
+	r.Add(10, 10) // [10,20)
+	r.Add(20, 5)  // touches [10,20) -> [10,25)
+	r.Add(55, 20) // overlaps [50,60) -> [50,75)
+	r.Add(0, 0)   // no-op
+
+	ranges, total := r.Snapshot()
+	want := []types.Task{{Offset: 10, Length: 15}, {Offset: 50, Length: 25}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ranges = %+v, want %+v", ranges, want)
+	}
+	if total != 40 {
+		t.Errorf("total = %d, want 40", total)
+	}
+
+	r.Add(0, 100) // covers everything
+	ranges, total = r.Snapshot()
+	if !reflect.DeepEqual(ranges, []types.Task{{Offset: 0, Length: 100}}) || total != 100 {
+		t.Errorf("after full write: ranges = %+v, total = %d", ranges, total)
+	}
+
+	var nilRanges *byteRanges
+	nilRanges.Add(0, 10) // must not panic
+}
+
+func TestByteRanges_Resume(t *testing.T) {
+	// Resuming with two holes left: everything else is already written
+	r := newByteRanges([]types.Task{{Offset: 60, Length: 20}, {Offset: 10, Length: 10}}, 100)
+	ranges, total := r.Snapshot()
+	want := []types.Task{{Offset: 0, Length: 10}, {Offset: 20, Length: 40}, {Offset: 80, Length: 20}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ranges = %+v, want %+v", ranges, want)
+	}
+	if total != 70 {
+		t.Errorf("total = %d, want 70", total)
+	}
+}
+
+func TestComplementRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []types.Task
+		size   int64
+		want   []types.Task
+	}{
+		{"empty", nil, 100, []types.Task{{Offset: 0, Length: 100}}},
+		{"full", []types.Task{{Offset: 0, Length: 100}}, 100, nil},
+		{"middle", []types.Task{{Offset: 30, Length: 40}}, 100, []types.Task{{Offset: 0, Length: 30}, {Offset: 70, Length: 30}}},
+		{"unsorted overlapping", []types.Task{{Offset: 50, Length: 30}, {Offset: 0, Length: 60}}, 100, []types.Task{{Offset: 80, Length: 20}}},
+		{"past end", []types.Task{{Offset: 90, Length: 50}}, 100, []types.Task{{Offset: 0, Length: 90}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := complementRanges(tt.ranges, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("complementRanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentDownloader_Checkpoint(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	const fileSize = 1000
+	url := "https://example.com/checkpoint.bin"
+	destPath := filepath.Join(tmpDir, "checkpoint.bin")
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID: "checkpoint-id", URL: url, URLHash: state.URLHash(url), DestPath: destPath,
+		Filename: "checkpoint.bin", Status: "queued", TotalSize: fileSize,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Create(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	d := NewConcurrentDownloader("checkpoint-id", nil, nil, &types.RuntimeConfig{})
+	d.URL = url
+	d.written = newByteRanges([]types.Task{{Offset: 0, Length: fileSize}}, fileSize)
+	d.written.Add(0, 400)
+	d.written.Add(600, 100)
+
+	target := checkpointTarget{file: f, destPath: destPath, fileSize: fileSize, startTime: time.Now()}
+	written, err := d.checkpoint(target, -1)
+	if err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+	if written != 500 {
+		t.Errorf("written = %d, want 500", written)
+	}
+
+	saved, err := state.LoadState(url, destPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if saved.Downloaded != 500 {
+		t.Errorf("Downloaded = %d, want 500", saved.Downloaded)
+	}
+	want := []types.Task{{Offset: 400, Length: 200}, {Offset: 700, Length: 300}}
+	if !reflect.DeepEqual(saved.Tasks, want) {
+		t.Errorf("Tasks = %+v, want %+v", saved.Tasks, want)
+	}
+	if entry, _ := state.GetDownload("checkpoint-id"); entry == nil || entry.Status != "queued" {
+		t.Errorf("checkpoint changed the download's status: %+v", entry)
+	}
+
+	// Nothing new written: the checkpoint is skipped without touching the file
+	_ = f.Close()
+	if written, err := d.checkpoint(target, 500); err != nil || written != 500 {
+		t.Errorf("unchanged checkpoint = %d, %v; want 500, nil", written, err)
+	}
+	if _, err := d.checkpoint(target, 400); err == nil {
+		t.Error("checkpoint should fail when the file can't be synced")
+	}
+}
@@ -3,6 +3,7 @@ package concurrent
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -28,8 +29,17 @@ type ConcurrentDownloader struct {
 	URL          string // For pause/resume
 	DestPath     string // For pause/resume
 	Runtime      *types.RuntimeConfig
+	runtimeMu    sync.RWMutex // Protects Runtime once the download is running
 	bufPool      sync.Pool
 	Headers      map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
+	headersMu    sync.RWMutex      // Protects URL and Headers once workers are running
+
+	Refresher types.URLRefresher // Optional hook to renew expired URLs
+	refreshMu sync.Mutex         // Serializes refresh attempts across workers
+
+	RuntimeUpdates <-chan *types.RuntimeConfig // Optional settings edits applied while running
+	workers        *workerGroup                // Connection workers of the running download
+	written        *byteRanges                 // Byte ranges written so far, for checkpoints
 }
 
 // NewConcurrentDownloader creates a new concurrent downloader with all required parameters
@@ -53,8 +63,9 @@ func NewConcurrentDownloader(id string, progressCh chan<- any, progState *types.
 
 // getInitialConnections returns the starting number of connections based on file size
 func (d *ConcurrentDownloader) getInitialConnections(fileSize int64) int {
-	maxConns := d.Runtime.GetMaxConnectionsPerHost()
-	minChunkSize := d.Runtime.GetMinChunkSize() // e.g., 1MB or 5MB
+	runtime := d.runtime()
+	maxConns := runtime.GetMaxConnectionsPerHost()
+	minChunkSize := runtime.GetMinChunkSize() // e.g., 1MB or 5MB
 
 	if fileSize <= 0 {
 		return 1
@@ -113,13 +124,13 @@ func (d *ConcurrentDownloader) ReportMirrorError(url string) {
 func (d *ConcurrentDownloader) calculateChunkSize(fileSize int64, numConns int) int64 {
 	// Safety check
 	if numConns <= 0 {
-		return d.Runtime.GetMinChunkSize() // Fallback
+		return d.runtime().GetMinChunkSize() // Fallback
 	}
 
 	chunkSize := fileSize / int64(numConns)
 
 	// Clamp to min from config (but not max - we want large chunks)
-	minChunk := d.Runtime.GetMinChunkSize()
+	minChunk := d.runtime().GetMinChunkSize()
 
 	if chunkSize < minChunk {
 		chunkSize = minChunk
@@ -136,9 +147,9 @@ func (d *ConcurrentDownloader) calculateChunkSize(fileSize int64, numConns int)
 
 // determineChunkSize decides the strategy (Sequential vs Parallel)
 func (d *ConcurrentDownloader) determineChunkSize(fileSize int64, numConns int) int64 {
-	if d.Runtime.SequentialDownload {
+	if d.runtime().SequentialDownload {
 		// Sequential mode: Use small fixed chunks (MinChunkSize) to ensure strict ordering
-		chunkSize := d.Runtime.GetMinChunkSize()
+		chunkSize := d.runtime().GetMinChunkSize()
 		if chunkSize <= 0 {
 			chunkSize = 2 * 1024 * 1024 // Default 2MB if not configured
 		}
@@ -177,18 +188,19 @@ func createTasks(fileSize, chunkSize int64) []types.Task {
 // newConcurrentClient creates an http.Client tuned for concurrent downloads
 func (d *ConcurrentDownloader) newConcurrentClient(numConns int) *http.Client {
 	// Ensure we have enough connections per host
-	maxConns := d.Runtime.GetMaxConnectionsPerHost()
+	runtime := d.runtime()
+	maxConns := runtime.GetMaxConnectionsPerHost()
 	if numConns > maxConns {
 		maxConns = numConns
 	}
 
 	var proxyFunc func(*http.Request) (*url.URL, error)
-	if d.Runtime.ProxyURL != "" {
-		if parsedURL, err := url.Parse(d.Runtime.ProxyURL); err == nil {
+	if runtime.ProxyURL != "" {
+		if parsedURL, err := url.Parse(runtime.ProxyURL); err == nil {
 			proxyFunc = http.ProxyURL(parsedURL)
 		} else {
 			// Fallback or log error? For now fallback to environment
-			utils.Debug("Invalid proxy URL %s: %v", d.Runtime.ProxyURL, err)
+			utils.Debug("Invalid proxy URL %s: %v", runtime.ProxyURL, err)
 			proxyFunc = http.ProxyFromEnvironment
 		}
 	} else {
@@ -324,6 +336,14 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 
 	// Check for saved state BEFORE truncating (resume case)
 	savedState, err := state.LoadState(rawurl, destPath)
+	if err != nil && d.ID != "" {
+		// The URL may have been refreshed since the pause; fall back to the download ID
+		if states, loadErr := state.LoadStates([]string{d.ID}); loadErr == nil {
+			if s, ok := states[d.ID]; ok && s.DestPath == destPath {
+				savedState, err = s, nil
+			}
+		}
+	}
 	isResume := err == nil && savedState != nil && len(savedState.Tasks) > 0
 
 	if isResume {
@@ -360,10 +380,26 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	}
 	queue := NewTaskQueue()
 	queue.PushMultiple(tasks)
+	d.written = newByteRanges(tasks, fileSize)
 
 	// Start time for stats
 	startTime := time.Now()
 
+	// Connection workers; started below once the mirror list is known
+	var workerMirrors []string
+	var clientMu sync.Mutex
+	workerErrors := make(chan error, numConns)
+	d.workers = newWorkerGroup(func(workerID int) error {
+		clientMu.Lock()
+		workerClient := client
+		clientMu.Unlock()
+		return d.worker(downloadCtx, workerID, workerMirrors, outFile, queue, fileSize, startTime, verbose, workerClient)
+	}, func(err error) {
+		if err != nil && err != context.Canceled && !errors.Is(err, errWorkerRetired) {
+			workerErrors <- err
+		}
+	})
+
 	// Start balancer goroutine for dynamic chunk splitting
 	balancerCtx, cancelBalancer := context.WithCancel(downloadCtx)
 	defer cancelBalancer()
@@ -413,7 +449,8 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			case <-ticker.C:
 				// Ensure queue is empty (no pending retries) before considering byte count.
 				// This protects against cutting off active retries even if byte count seems high (due to overlaps etc).
-				if queue.Len() == 0 && (int(queue.IdleWorkers()) == numConns || d.State.Downloaded.Load() >= fileSize) {
+				// Idle workers may outnumber live ones while a lowered connection target takes effect
+				if queue.Len() == 0 && (int(queue.IdleWorkers()) >= d.workers.count() || d.State.Downloaded.Load() >= fileSize) {
 					queue.Close()
 					return
 				}
@@ -436,13 +473,8 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		}
 	}()
 
-	// Start workers
-	var wg sync.WaitGroup
-	workerErrors := make(chan error, numConns)
-
 	// Combine primary + secondary for workers
 	// We want to ensure the primary is included if it was valid (it should be, otherwise TUIDownload would have failed)
-	var workerMirrors []string
 
 	// Add primary if compatible (check active map or assume yes since we are here)
 	// TUIDownload checks primary support before calling us.
@@ -461,24 +493,37 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		workerMirrors = []string{rawurl}
 	}
 
-	for i := 0; i < numConns; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			err := d.worker(downloadCtx, workerID, workerMirrors, outFile, queue, fileSize, startTime, verbose, client)
-			if err != nil && err != context.Canceled {
-				workerErrors <- err
-			}
-		}(i)
-	}
+	// Start workers
+	d.workers.resize(numConns)
+
+	// Follow settings edits: a new per-host limit changes the connection target
+	go d.watchRuntime(balancerCtx, func() {
+		target := d.getInitialConnections(fileSize)
+		if target > d.workers.count() {
+			// Workers added later get a client sized for the new limit
+			clientMu.Lock()
+			client = d.newConcurrentClient(target)
+			clientMu.Unlock()
+		}
+		d.workers.resize(target)
+	})
 
 	// Wait for all workers to complete
 	go func() {
-		wg.Wait()
+		d.workers.wait()
 		close(workerErrors)
 		queue.Close()
 	}()
 
+	// Periodically make progress durable so a crash only loses the last few seconds
+	checkpointCtx, stopCheckpoints := context.WithCancel(balancerCtx)
+	defer stopCheckpoints()
+	target := checkpointTarget{file: outFile, destPath: destPath, fileSize: fileSize, startTime: startTime, mirrors: candidateMirrors}
+	if d.State != nil {
+		target.savedElapsed = d.State.SavedElapsed
+	}
+	checkpointsDone := d.runCheckpoints(checkpointCtx, target)
+
 	// Check for errors or pause
 	var downloadErr error
 	for err := range workerErrors {
@@ -487,6 +532,10 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		}
 	}
 
+	// No checkpoint may land after the pause state or completion below
+	stopCheckpoints()
+	<-checkpointsDone
+
 	// Handle pause: state saved
 	if d.State != nil && d.State.IsPaused() {
 		// 1. Collect active tasks as remaining work FIRST
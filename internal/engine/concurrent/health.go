@@ -58,7 +58,7 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 		taskDuration := now.Sub(active.StartTime)
 
 		// Skip workers that are still in their grace period
-		gracePeriod := d.Runtime.GetSlowWorkerGracePeriod()
+		gracePeriod := d.runtime().GetSlowWorkerGracePeriod()
 		if taskDuration < gracePeriod {
 			continue
 		}
@@ -67,7 +67,7 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 		// Only cancel if: below threshold
 		if meanSpeed > 0 {
 			workerSpeed := active.GetSpeed()
-			threshold := d.Runtime.GetSlowWorkerThreshold()
+			threshold := d.runtime().GetSlowWorkerThreshold()
 			isBelowThreshold := workerSpeed > 0 && workerSpeed < threshold*meanSpeed
 
 			if isBelowThreshold {
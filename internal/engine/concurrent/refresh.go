@@ -0,0 +1,88 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// currentURL returns the primary URL, which may have been replaced by a refresh
+func (d *ConcurrentDownloader) currentURL() string {
+	d.headersMu.RLock()
+	defer d.headersMu.RUnlock()
+	return d.URL
+}
+
+// headerSnapshot returns a copy of the custom headers safe to use while a refresh may update them
+func (d *ConcurrentDownloader) headerSnapshot() map[string]string {
+	d.headersMu.RLock()
+	defer d.headersMu.RUnlock()
+	if len(d.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(d.Headers))
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	return headers
+}
+
+// refreshURL asks the Refresher for a replacement of staleURL after the server rejected it.
+// Only one refresh runs at a time; workers that hit the same expired link while a refresh
+// is in flight pick up the already-renewed URL instead of triggering another one.
+func (d *ConcurrentDownloader) refreshURL(ctx context.Context, staleURL string, statusCode int) (string, error) {
+	if d.Refresher == nil {
+		return "", fmt.Errorf("no URL refresher configured")
+	}
+
+	d.refreshMu.Lock()
+	defer d.refreshMu.Unlock()
+
+	// Another worker already refreshed the primary URL
+	if current := d.currentURL(); current != staleURL {
+		return current, nil
+	}
+
+	utils.Debug("Refreshing expired URL for %s (status %d)", d.ID, statusCode)
+	result, err := d.Refresher(ctx, types.RefreshRequest{
+		ID:         d.ID,
+		URL:        staleURL,
+		DestPath:   d.DestPath,
+		StatusCode: statusCode,
+	})
+	if err != nil {
+		return "", fmt.Errorf("url refresh failed: %w", err)
+	}
+	if result == nil || result.URL == "" {
+		return "", fmt.Errorf("url refresh returned no URL")
+	}
+
+	d.headersMu.Lock()
+	d.URL = result.URL
+	if len(result.Headers) > 0 {
+		if d.Headers == nil {
+			d.Headers = make(map[string]string, len(result.Headers))
+		}
+		for k, v := range result.Headers {
+			d.Headers[k] = v
+		}
+	}
+	d.headersMu.Unlock()
+
+	// Keep mirror status in sync so the UI shows the renewed link
+	if d.State != nil {
+		mirrors := d.State.GetMirrors()
+		for i, m := range mirrors {
+			if m.URL == staleURL {
+				mirrors[i].URL = result.URL
+				mirrors[i].Error = false
+			}
+		}
+		d.State.SetMirrors(mirrors)
+	}
+
+	utils.Debug("URL refreshed for %s", d.ID)
+	return result.URL, nil
+}
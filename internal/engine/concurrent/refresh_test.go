@@ -0,0 +1,77 @@
+package concurrent
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestConcurrentDownloader_RefreshesExpiredURL(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(512 * types.KB)
+
+	// Expired link: every request is rejected
+	expired := testutil.NewMockServerT(t,
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(true),
+		testutil.WithHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}),
+	)
+	defer expired.Close()
+
+	fresh := testutil.NewMockServerT(t,
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(true),
+	)
+	defer fresh.Close()
+
+	destPath := filepath.Join(tmpDir, "refresh_test.bin")
+	state := types.NewProgressState("refresh-test", fileSize)
+	runtime := &types.RuntimeConfig{
+		MaxConnectionsPerHost: 4,
+		MaxTaskRetries:        2,
+		MinChunkSize:          64 * types.KB,
+	}
+
+	var calls atomic.Int32
+	downloader := NewConcurrentDownloader("refresh-id", nil, state, runtime)
+	downloader.Refresher = func(ctx context.Context, req types.RefreshRequest) (*types.RefreshResult, error) {
+		calls.Add(1)
+		if req.StatusCode != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", req.StatusCode)
+		}
+		if req.URL != expired.URL() {
+			t.Errorf("expected stale URL %s, got %s", expired.URL(), req.URL)
+		}
+		return &types.RefreshResult{URL: fresh.URL(), Headers: map[string]string{"X-Token": "fresh"}}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := downloader.Download(ctx, expired.URL(), nil, nil, destPath, fileSize, false); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if err := testutil.VerifyFileSize(destPath, fileSize); err != nil {
+		t.Error(err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected refresher to be called once, got %d", got)
+	}
+	if downloader.URL != fresh.URL() {
+		t.Errorf("expected downloader URL to be %s, got %s", fresh.URL(), downloader.URL)
+	}
+	if downloader.Headers["X-Token"] != "fresh" {
+		t.Errorf("expected refreshed headers to be merged, got %v", downloader.Headers)
+	}
+}
@@ -0,0 +1,137 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// errWorkerRetired is returned by a worker that stopped because the
+// connection target was lowered. It is not a download failure.
+var errWorkerRetired = errors.New("worker retired")
+
+// runtime returns the settings currently in force
+func (d *ConcurrentDownloader) runtime() *types.RuntimeConfig {
+	d.runtimeMu.RLock()
+	defer d.runtimeMu.RUnlock()
+	return d.Runtime
+}
+
+// SetRuntime swaps the settings used by a running download. Retries, health
+// checks and request headers pick up the new values on their next use.
+func (d *ConcurrentDownloader) SetRuntime(rc *types.RuntimeConfig) {
+	if rc == nil {
+		return
+	}
+	d.runtimeMu.Lock()
+	d.Runtime = rc
+	d.runtimeMu.Unlock()
+}
+
+// watchRuntime applies settings edits from RuntimeUpdates until ctx ends.
+// onChange runs after each swap so the caller can retarget connections.
+func (d *ConcurrentDownloader) watchRuntime(ctx context.Context, onChange func()) {
+	if d.RuntimeUpdates == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rc := <-d.RuntimeUpdates:
+			d.SetRuntime(rc)
+			utils.Debug("Download %s: settings updated", d.ID)
+			if onChange != nil {
+				onChange()
+			}
+		}
+	}
+}
+
+// workerGroup tracks a download's connection workers so their number can
+// follow the per-host connection limit while the download runs
+type workerGroup struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	live   int  // Workers still taking tasks
+	target int  // Workers wanted; extras retire between tasks
+	nextID int  // ID for the next spawned worker
+	closed bool // Every worker has exited; no more can be added
+	run    func(id int) error
+	onExit func(err error)
+}
+
+func newWorkerGroup(run func(id int) error, onExit func(err error)) *workerGroup {
+	return &workerGroup{run: run, onExit: onExit}
+}
+
+// resize sets the target and starts workers to reach it. Lowering it lets
+// workers finish their current task before they retire.
+func (g *workerGroup) resize(target int) {
+	if target < 1 {
+		target = 1
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.target = target
+	for g.live < g.target {
+		id := g.nextID
+		g.nextID++
+		g.live++
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			err := g.run(id)
+			if !errors.Is(err, errWorkerRetired) {
+				g.exit()
+			}
+			if g.onExit != nil {
+				g.onExit(err)
+			}
+		}()
+	}
+}
+
+// retire reports whether a worker should stop because the group is over its
+// target. Workers call it between tasks.
+func (g *workerGroup) retire() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.live <= g.target {
+		return false
+	}
+	g.live--
+	return true
+}
+
+// exit records a worker that stopped for any reason other than retiring
+func (g *workerGroup) exit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.live--
+	if g.live == 0 {
+		g.closed = true
+	}
+}
+
+// count returns how many workers are still taking tasks
+func (g *workerGroup) count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.live
+}
+
+// wait blocks until every worker has exited
+func (g *workerGroup) wait() {
+	g.wg.Wait()
+}
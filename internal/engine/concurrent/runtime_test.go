@@ -0,0 +1,140 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestWorkerGroup_ResizeAndRetire(t *testing.T) {
+	stop := make(chan struct{})
+	var g *workerGroup
+	g = newWorkerGroup(func(id int) error {
+		for {
+			if g.retire() {
+				return errWorkerRetired
+			}
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}, nil)
+
+	g.resize(3)
+	if got := g.count(); got != 3 {
+		t.Fatalf("count after resize(3) = %d, want 3", got)
+	}
+
+	g.resize(1)
+	deadline := time.Now().Add(2 * time.Second)
+	for g.count() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := g.count(); got != 1 {
+		t.Fatalf("count after resize(1) = %d, want 1", got)
+	}
+
+	close(stop)
+	g.wait()
+	if got := g.count(); got != 0 {
+		t.Errorf("count after all workers exited = %d, want 0", got)
+	}
+
+	// A finished group must not start new workers
+	g.resize(2)
+	if got := g.count(); got != 0 {
+		t.Errorf("resize after exit started %d workers", got)
+	}
+}
+
+func TestConcurrentDownloader_RuntimeUpdatesApplyLive(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(16 * types.MB)
+
+	var inFlight, peak atomic.Int32
+	var mu sync.Mutex
+	agents := make(map[string]bool)
+
+	server := testutil.NewHTTPServerT(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.Header.Get("User-Agent")] = true
+		mu.Unlock()
+
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			start, end = 0, fileSize-1
+		}
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", fmt.Sprint(end-start+1))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, fileSize))
+		w.WriteHeader(http.StatusPartialContent)
+
+		// Trickle the body so the download is still running when settings change
+		buf := make([]byte, 64*types.KB)
+		for remaining := end - start + 1; remaining > 0; {
+			n := min(remaining, int64(len(buf)))
+			if _, err := w.Write(buf[:n]); err != nil {
+				return
+			}
+			remaining -= n
+			time.Sleep(2 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "live_runtime.bin")
+	progState := types.NewProgressState("live-runtime", fileSize)
+	downloader := NewConcurrentDownloader("live-runtime", nil, progState, &types.RuntimeConfig{
+		MaxConnectionsPerHost: 1,
+		UserAgent:             "before/1.0",
+	})
+	updates := make(chan *types.RuntimeConfig, 1)
+	downloader.RuntimeUpdates = updates
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		updates <- &types.RuntimeConfig{MaxConnectionsPerHost: 4, UserAgent: "after/1.0"}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize, false); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if err := testutil.VerifyFileSize(destPath, fileSize); err != nil {
+		t.Error(err)
+	}
+	if got := downloader.runtime().UserAgent; got != "after/1.0" {
+		t.Errorf("runtime not swapped, user agent = %q", got)
+	}
+	if peak.Load() < 2 {
+		t.Errorf("peak connections = %d, want more than 1 after raising the per-host limit", peak.Load())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !agents["after/1.0"] {
+		t.Errorf("no request used the updated user agent, saw %v", agents)
+	}
+}
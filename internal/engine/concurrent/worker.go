@@ -27,6 +27,11 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 	currentMirrorIdx := id % len(mirrors)
 
 	for {
+		// Stop between tasks if the connection target was lowered
+		if d.workers.retire() {
+			return errWorkerRetired
+		}
+
 		// Get next task
 		task, ok := queue.Pop()
 
@@ -40,9 +45,11 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 		}
 
 		var lastErr error
-		maxRetries := d.Runtime.GetMaxTaskRetries()
+		refreshed := false
+		refreshes := 0
+		maxRetries := d.runtime().GetMaxTaskRetries()
 		for attempt := 0; attempt < maxRetries; attempt++ {
-			if attempt > 0 {
+			if attempt > 0 && !refreshed {
 
 				if len(mirrors) == 1 {
 					time.Sleep(time.Duration(1<<attempt) * types.RetryBaseDelay) // Exponential backoff incase of failure
@@ -56,8 +63,13 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 				utils.Debug("Worker %d: switching to mirror %s (attempt %d)", id, mirrors[currentMirrorIdx], attempt+1)
 			}
 
-			// Use current mirror
+			refreshed = false
+
+			// Use current mirror (the primary may have been renewed by a refresh)
 			currentURL := mirrors[currentMirrorIdx]
+			if currentMirrorIdx == 0 {
+				currentURL = d.currentURL()
+			}
 
 			// Register active task with per-task cancellable context
 			taskCtx, taskCancel := context.WithCancel(ctx)
@@ -156,6 +168,18 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			if current > task.Offset {
 				task = types.Task{Offset: current, Length: task.Offset + task.Length - current}
 			}
+
+			// Expired signed URL: ask for a fresh link and retry the same task on it.
+			// The task keeps its place, so no queued work is lost.
+			if status, ok := types.IsRefreshableError(lastErr); ok && currentMirrorIdx == 0 && d.Refresher != nil && refreshes < types.MaxURLRefreshesPerTask {
+				if _, err := d.refreshURL(ctx, currentURL, status); err == nil {
+					refreshes++
+					refreshed = true
+					attempt-- // A renewed link doesn't count against the retry budget
+				} else {
+					utils.Debug("Worker %d: %v", id, err)
+				}
+			}
 		}
 
 		// Update active workers
@@ -183,7 +207,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 	task := activeTask.Task
 
 	// Apply custom headers first (from browser extension: cookies, auth, referer, etc.)
-	for key, val := range d.Headers {
+	for key, val := range d.headerSnapshot() {
 		// Skip Range header - we set it ourselves for parallel downloads
 		if key != "Range" {
 			req.Header.Set(key, val)
@@ -192,7 +216,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 	// Set User-Agent from config only if not provided in custom headers
 	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+		req.Header.Set("User-Agent", d.runtime().GetUserAgent())
 	}
 	// Range header is always set for partial downloads (overrides any browser Range header)
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", task.Offset, task.Offset+task.Length-1))
@@ -220,7 +244,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 			return fmt.Errorf("server indicated success (200) but ignored range request (expected 206)")
 		}
 	} else if resp.StatusCode != http.StatusPartialContent {
-		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		return &types.StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Batching State
@@ -305,6 +329,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 			if writeErr != nil {
 				return fmt.Errorf("write error: %w", writeErr)
 			}
+			d.written.Add(offset, int64(readSoFar))
 
 			now := time.Now()
 			// oldOffset := offset // Unused since we use batch logic now, but logically here
@@ -339,7 +364,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 				recentSpeed := float64(windowBytes) / windowElapsed
 
 				activeTask.SpeedMu.Lock()
-				alpha := d.Runtime.GetSpeedEmaAlpha()
+				alpha := d.runtime().GetSpeedEmaAlpha()
 				if activeTask.Speed == 0 {
 					activeTask.Speed = recentSpeed
 				} else {
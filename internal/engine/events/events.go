@@ -97,7 +97,11 @@ type DownloadStartedMsg struct {
 	Filename   string
 	Total      int64
 	DestPath   string               // Full path to the destination file
+	Category   string               `json:",omitempty"` // Category rule the download was routed by
+	Group      string               `json:",omitempty"` // Download group, if any
 	State      *types.ProgressState `json:"-"`
+
+	types.DownloadMeta // Tags, note and referrer
 }
 
 type DownloadPausedMsg struct {
@@ -114,6 +118,26 @@ type DownloadResumedMsg struct {
 type DownloadQueuedMsg struct {
 	DownloadID string
 	Filename   string
+	Group      string `json:",omitempty"` // Download group, if any
+
+	types.DownloadMeta // Tags, note and referrer
+}
+
+// DownloadScheduledMsg is sent when a download is held back until StartAt
+type DownloadScheduledMsg struct {
+	DownloadID string
+	Filename   string
+	StartAt    time.Time
+}
+
+// ConcurrencyChangedMsg is sent when max_concurrent_downloads is changed at runtime
+type ConcurrencyChangedMsg struct {
+	MaxDownloads int
+}
+
+// QueueReorderedMsg carries the IDs of waiting downloads in the order they will start
+type QueueReorderedMsg struct {
+	Order []string
 }
 
 type DownloadRemovedMsg struct {
@@ -121,6 +145,28 @@ type DownloadRemovedMsg struct {
 	Filename   string
 }
 
+// DownloadMovedMsg is sent when a download's file is moved or renamed
+type DownloadMovedMsg struct {
+	DownloadID string
+	Filename   string
+	OldPath    string
+	DestPath   string
+}
+
+// DownloadRelinkedMsg is sent when a stopped download is pointed at a new URL
+type DownloadRelinkedMsg struct {
+	DownloadID string
+	Filename   string
+	OldURL     string
+	URL        string
+}
+
+// HistoryPrunedMsg is sent when the history janitor drops finished downloads
+type HistoryPrunedMsg struct {
+	DownloadIDs  []string
+	FilesDeleted int
+}
+
 // DownloadRequestMsg signals a request to start a download (e.g. from extension)
 // that may need user confirmation or duplicate checking
 type DownloadRequestMsg struct {
@@ -130,4 +176,63 @@ type DownloadRequestMsg struct {
 	Path     string
 	Mirrors  []string
 	Headers  map[string]string
+	Conflict string    // File conflict policy requested by the client, if any
+	Priority int       // Queue priority requested by the client
+	StartAt  time.Time // Scheduled start requested by the client, if any
+	Group    string    // Download group requested by the client, if any
+
+	types.DownloadMeta // Tags, note and referrer sent by the client
+}
+
+// URLRefreshRequestMsg asks a client (e.g. the browser extension) for a fresh URL
+// after the server rejected the current one. Answer via DownloadService.RefreshURL.
+type URLRefreshRequestMsg struct {
+	DownloadID string
+	URL        string
+	Filename   string
+	StatusCode int
+}
+
+// HookResultMsg reports the outcome of a user hook command
+type HookResultMsg struct {
+	DownloadID string
+	Filename   string
+	Event      string // "complete", "error" or "pause"
+	Command    string
+	ExitCode   int    // -1 if the command could not be started or was killed
+	Output     string `json:",omitempty"` // Tail of combined stdout/stderr
+	Err        string `json:",omitempty"`
+}
+
+// ExtractStartedMsg signals that a completed download is being unpacked
+type ExtractStartedMsg struct {
+	DownloadID string
+	Filename   string
+	Archive    string // Path to the archive
+	DestDir    string // Directory the archive is extracted into
+	Format     string
+}
+
+// ExtractProgressMsg reports extraction progress in bytes
+type ExtractProgressMsg struct {
+	DownloadID string
+	Done       int64
+	Total      int64
+}
+
+// ExtractCompleteMsg signals that extraction finished successfully
+type ExtractCompleteMsg struct {
+	DownloadID     string
+	Filename       string
+	DestDir        string
+	Entries        int
+	Elapsed        time.Duration
+	ArchiveDeleted bool
+}
+
+// ExtractErrorMsg signals that extraction failed. The downloaded archive is kept.
+type ExtractErrorMsg struct {
+	DownloadID string
+	Filename   string
+	Err        string
 }
@@ -24,6 +24,7 @@ type ProbeResult struct {
 	SupportsRange bool
 	Filename      string
 	ContentType   string
+	ETag          string
 }
 
 // ProbeServer sends GET with Range: bytes=0-0 to determine server capabilities
@@ -152,7 +153,7 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 		utils.Debug("Range NOT supported (got 200), file size: %d", result.FileSize)
 
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &types.StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Determine filename using strengthened logic
@@ -169,6 +170,7 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 	}
 
 	result.ContentType = resp.Header.Get("Content-Type")
+	result.ETag = resp.Header.Get("ETag")
 
 	utils.Debug("Probe complete - filename: %s, size: %d, range: %v",
 		result.Filename, result.FileSize, result.SupportsRange)
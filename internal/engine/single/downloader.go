@@ -60,7 +60,7 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &types.StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Use .surge extension for incomplete file
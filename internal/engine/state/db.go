@@ -40,49 +40,20 @@ func initDB() error {
 	// Ensure directory exists - caller should perhaps do this, but safe to do here if path is provided
 
 	// Open database
-	var err error
-	db, err = sql.Open("sqlite", dbPath)
+	// Migrations run before the connection is published, since GetDB
+	// checks db without holding dbMu.
+	// busy_timeout makes concurrent writers wait instead of failing with SQLITE_BUSY
+	conn, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Create tables
-	query := `
-	CREATE TABLE IF NOT EXISTS downloads (
-		id TEXT PRIMARY KEY,
-		url TEXT NOT NULL,
-		dest_path TEXT NOT NULL,
-		filename TEXT,
-		status TEXT,
-		total_size INTEGER,
-		downloaded INTEGER,
-		url_hash TEXT,
-		created_at INTEGER,
-		paused_at INTEGER,
-		completed_at INTEGER,
-		time_taken INTEGER
-	);
-
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		download_id TEXT,
-		offset INTEGER,
-		length INTEGER,
-		FOREIGN KEY(download_id) REFERENCES downloads(id) ON DELETE CASCADE
-	);
-	`
-
-	if _, err := db.Exec(query); err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
+	if err := migrate(conn, dbPath); err != nil {
+		_ = conn.Close()
+		return err
 	}
 
-	// Migration: Add mirrors column if not exists
-	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN mirrors TEXT")
-
-	// Migration: Add chunk bitmap columns
-	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN chunk_bitmap BLOB")
-	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN actual_chunk_size INTEGER")
-
+	db = conn
 	return nil
 }
 
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// ExportVersion is the format version written by ExportDownloads
+const ExportVersion = 1
+
+// Export is a portable snapshot of every tracked download
+type Export struct {
+	Version    int                `json:"version"`
+	ExportedAt int64              `json:"exported_at"` // Unix timestamp
+	Downloads  []ExportedDownload `json:"downloads"`
+}
+
+// ExportedDownload is a master list entry plus the resume state of an
+// unfinished download
+type ExportedDownload struct {
+	types.DownloadEntry
+
+	// Headers shadows the entry's field, which is left out of JSON
+	Headers map[string]string `json:"headers,omitempty"`
+
+	CreatedAt       int64        `json:"created_at,omitempty"` // Unix timestamp
+	PausedAt        int64        `json:"paused_at,omitempty"`  // Unix timestamp
+	Elapsed         int64        `json:"elapsed,omitempty"`    // Nanoseconds spent downloading so far
+	Tasks           []types.Task `json:"tasks,omitempty"`      // Remaining byte ranges
+	ChunkBitmap     []byte       `json:"chunk_bitmap,omitempty"`
+	ActualChunkSize int64        `json:"actual_chunk_size,omitempty"`
+}
+
+// HasProgress reports whether d carries resume state that refers to bytes
+// already in its partial file
+func (d ExportedDownload) HasProgress() bool {
+	return d.Status != "completed" && (len(d.Tasks) > 0 || len(d.ChunkBitmap) > 0 || d.Downloaded > 0)
+}
+
+// DropProgress clears resume state so the download starts over
+func (d *ExportedDownload) DropProgress() {
+	d.Downloaded = 0
+	d.Elapsed = 0
+	d.Tasks = nil
+	d.ChunkBitmap = nil
+	d.ActualChunkSize = 0
+}
+
+// ExportDownloads snapshots every download: queued, scheduled, paused and
+// failed ones with their remaining tasks, and completed history. Unfinished
+// downloads come first, in queue order.
+func ExportDownloads() (*Export, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range list.Downloads {
+		if e.Status != "completed" {
+			ids = append(ids, e.ID)
+		}
+	}
+	states, err := LoadStates(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &Export{Version: ExportVersion, ExportedAt: time.Now().Unix(), Downloads: []ExportedDownload{}}
+	for _, e := range list.Downloads {
+		d := ExportedDownload{DownloadEntry: e, Headers: e.Headers}
+		if s, ok := states[e.ID]; ok {
+			d.CreatedAt = s.CreatedAt
+			d.PausedAt = s.PausedAt
+			d.Elapsed = s.Elapsed
+			d.Tasks = s.Tasks
+			d.ChunkBitmap = s.ChunkBitmap
+			d.ActualChunkSize = s.ActualChunkSize
+		}
+		exp.Downloads = append(exp.Downloads, d)
+	}
+
+	sort.SliceStable(exp.Downloads, func(i, j int) bool {
+		a, b := exp.Downloads[i], exp.Downloads[j]
+		if (a.Status == "completed") != (b.Status == "completed") {
+			return b.Status == "completed"
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if (a.QueuePos == 0) != (b.QueuePos == 0) {
+			return a.QueuePos != 0
+		}
+		return a.QueuePos < b.QueuePos
+	})
+	return exp, nil
+}
+
+// ImportDownload stores an exported download with its tasks. It returns
+// false without changing anything if a download with the same ID exists.
+func ImportDownload(d ExportedDownload) (bool, error) {
+	if d.ID == "" {
+		return false, fmt.Errorf("download has no id")
+	}
+	if d.URLHash == "" {
+		d.URLHash = URLHash(d.URL)
+	}
+	if d.CreatedAt == 0 {
+		d.CreatedAt = time.Now().Unix()
+	}
+
+	inserted := false
+	err := withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			INSERT INTO downloads (
+				id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, completed_at, time_taken,
+				mirrors, chunk_bitmap, actual_chunk_size, category, etag, priority, queue_pos, start_at, group_name, tags, note, referrer, headers
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO NOTHING
+		`,
+			d.ID, d.URL, d.DestPath, d.Filename, d.Status, d.TotalSize, d.Downloaded, d.URLHash, d.CreatedAt, d.PausedAt, d.CompletedAt, importedTimeTaken(d),
+			strings.Join(d.Mirrors, ","), d.ChunkBitmap, d.ActualChunkSize, d.Category, d.ETag, d.Priority, d.QueuePos, d.StartAt, d.Group,
+			strings.Join(d.Tags, ","), d.Note, d.Referrer, encodeHeaders(d.Headers))
+		if err != nil {
+			return fmt.Errorf("failed to insert download: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil
+		}
+		inserted = true
+		return insertTasks(tx, d.ID, d.Tasks)
+	})
+	return inserted, err
+}
+
+// importedTimeTaken returns the time_taken column for d, which holds
+// milliseconds: the total for completed downloads, else elapsed so far
+func importedTimeTaken(d ExportedDownload) int64 {
+	if d.Status == "completed" || d.Elapsed == 0 {
+		return d.TimeTaken
+	}
+	return d.Elapsed / 1e6
+}
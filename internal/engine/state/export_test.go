@@ -0,0 +1,123 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	url := "https://example.com/big.iso"
+	dest := filepath.Join(tmpDir, "big.iso")
+	headers := map[string]string{"Cookie": "session=abc"}
+
+	// A paused download: recorded when queued, then paused mid-way
+	if err := AddToMasterList(types.DownloadEntry{
+		ID: "paused-1", URL: url, DestPath: dest, Filename: "big.iso", Status: "queued",
+		Headers: headers, Group: "isos", DownloadMeta: types.DownloadMeta{Tags: []string{"linux"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveState(url, dest, &types.DownloadState{
+		ID: "paused-1", URL: url, DestPath: dest, Filename: "big.iso",
+		TotalSize: 1000, Downloaded: 400, Elapsed: int64(3 * time.Second),
+		Tasks:       []types.Task{{Offset: 400, Length: 300}, {Offset: 700, Length: 300}},
+		Mirrors:     []string{url, "https://mirror.example.com/big.iso"},
+		ChunkBitmap: []byte{0x0f}, ActualChunkSize: 100,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddToMasterList(types.DownloadEntry{
+		ID: "done-1", URL: "https://example.com/small.zip", DestPath: filepath.Join(tmpDir, "small.zip"),
+		Filename: "small.zip", Status: "completed", TotalSize: 10, Downloaded: 10,
+		CompletedAt: time.Now().Unix(), TimeTaken: 1500,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp, err := ExportDownloads()
+	if err != nil {
+		t.Fatalf("ExportDownloads failed: %v", err)
+	}
+	if len(exp.Downloads) != 2 {
+		t.Fatalf("exported %d downloads, want 2", len(exp.Downloads))
+	}
+	if exp.Downloads[0].ID != "paused-1" {
+		t.Errorf("unfinished downloads should come first, got %s", exp.Downloads[0].ID)
+	}
+
+	// The file format must carry resume state and headers
+	data, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Export
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	p := decoded.Downloads[0]
+	if len(p.Tasks) != 2 || p.Headers["Cookie"] != "session=abc" || len(p.Mirrors) != 2 || p.ActualChunkSize != 100 {
+		t.Errorf("exported download lost state: %+v", p)
+	}
+
+	// Import into a fresh database
+	importDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(importDir) }()
+	for _, d := range decoded.Downloads {
+		ok, err := ImportDownload(d)
+		if err != nil || !ok {
+			t.Fatalf("ImportDownload(%s) = %v, %v", d.ID, ok, err)
+		}
+	}
+
+	s, err := LoadState(url, dest)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if len(s.Tasks) != 2 || s.Downloaded != 400 || s.Elapsed != int64(3*time.Second) || len(s.ChunkBitmap) != 1 {
+		t.Errorf("imported state mismatch: %+v", s)
+	}
+	e, err := GetDownload("paused-1")
+	if err != nil || e == nil {
+		t.Fatalf("GetDownload failed: %v", err)
+	}
+	if e.Status != "paused" || e.Group != "isos" || e.Headers["Cookie"] != "session=abc" || len(e.Tags) != 1 {
+		t.Errorf("imported entry mismatch: %+v", e)
+	}
+	done, _ := GetDownload("done-1")
+	if done == nil || done.Status != "completed" || done.TimeTaken != 1500 {
+		t.Errorf("imported history mismatch: %+v", done)
+	}
+
+	// Importing again leaves existing downloads alone
+	if ok, err := ImportDownload(decoded.Downloads[0]); err != nil || ok {
+		t.Errorf("re-import = %v, %v; want skipped", ok, err)
+	}
+}
+
+func TestExportedDownload_DropProgress(t *testing.T) {
+	d := ExportedDownload{
+		DownloadEntry: types.DownloadEntry{Status: "paused", Downloaded: 50},
+		Tasks:         []types.Task{{Offset: 50, Length: 50}},
+	}
+	if !d.HasProgress() {
+		t.Fatal("HasProgress = false for a half-finished download")
+	}
+	d.DropProgress()
+	if d.HasProgress() {
+		t.Errorf("HasProgress = true after DropProgress: %+v", d)
+	}
+
+	completed := ExportedDownload{DownloadEntry: types.DownloadEntry{Status: "completed", Downloaded: 100}}
+	if completed.HasProgress() {
+		t.Error("completed downloads have no partial file")
+	}
+}
@@ -0,0 +1,179 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// QueryHistory returns the finished downloads matching f, sorted and paged,
+// along with the number that matched before paging.
+func QueryHistory(f types.HistoryFilter) ([]types.DownloadEntry, int, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, 0, fmt.Errorf("database not initialized")
+	}
+	if f.Filename != "" {
+		if _, err := filepath.Match(f.Filename, ""); err != nil {
+			return nil, 0, fmt.Errorf("invalid filename pattern %q: %w", f.Filename, err)
+		}
+	}
+
+	// Status, date and size are narrowed in SQL; host and filename need
+	// parsing and globbing, so they are checked in Go
+	statuses := f.Statuses()
+	where := []string{"status IN (" + strings.TrimSuffix(strings.Repeat("?,", len(statuses)), ",") + ")"}
+	var args []any
+	for _, s := range statuses {
+		args = append(args, s)
+	}
+	if f.Since > 0 {
+		where = append(where, "completed_at >= ?")
+		args = append(args, f.Since)
+	}
+	if f.Until > 0 {
+		where = append(where, "completed_at < ?")
+		args = append(args, f.Until)
+	}
+	if f.MinSize > 0 {
+		where = append(where, "total_size >= ?")
+		args = append(args, f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		where = append(where, "total_size <= ?")
+		args = append(args, f.MaxSize)
+	}
+
+	host := strings.ToLower(f.Host)
+	cond := " WHERE " + strings.Join(where, " AND ")
+	order, ok := historyOrder[f.Sort]
+	if !ok || host != "" || f.Filename != "" {
+		// Filtered in Go, so the whole match is read before paging
+		entries, err := queryEntries(db, "SELECT "+entryColumns+" FROM downloads"+cond, args...)
+		if err != nil {
+			return nil, 0, err
+		}
+		kept := entries[:0]
+		for _, e := range entries {
+			if host != "" && !matchHost(e.Host(), host) {
+				continue
+			}
+			if f.Filename != "" {
+				if ok, _ := filepath.Match(f.Filename, e.Filename); !ok {
+					continue
+				}
+			}
+			kept = append(kept, e)
+		}
+		entries = kept
+
+		sortHistory(entries, f.Sort, f.Reverse)
+
+		total := len(entries)
+		if f.Offset > 0 {
+			if f.Offset >= len(entries) {
+				return []types.DownloadEntry{}, total, nil
+			}
+			entries = entries[f.Offset:]
+		}
+		if f.Limit > 0 && f.Limit < len(entries) {
+			entries = entries[:f.Limit]
+		}
+		return entries, total, nil
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM downloads"+cond, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count history: %w", err)
+	}
+
+	dir := "DESC"
+	if order.asc != f.Reverse {
+		dir = "ASC"
+	}
+	limit := -1 // SQLite's "no limit"
+	if f.Limit > 0 {
+		limit = f.Limit
+	}
+	// rowid keeps ties in insertion order, as the stable Go sort does
+	query := "SELECT " + entryColumns + " FROM downloads" + cond +
+		" ORDER BY " + order.expr + " " + dir + ", rowid LIMIT ? OFFSET ?"
+	entries, err := queryEntries(db, query, append(args, limit, max(f.Offset, 0))...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// historySQLOrder is how a history sort key orders rows in SQL
+type historySQLOrder struct {
+	expr string
+	asc  bool // Natural direction is ascending (names A-Z)
+}
+
+// historyOrder holds the sort keys SQL can order by. Speed is derived from
+// two columns, so it is sorted in Go along with the Go-side filters.
+var historyOrder = map[string]historySQLOrder{
+	"":                        {"COALESCE(completed_at, 0)", false},
+	types.HistorySortDate:     {"COALESCE(completed_at, 0)", false},
+	types.HistorySortSize:     {"COALESCE(total_size, 0)", false},
+	types.HistorySortName:     {"LOWER(COALESCE(filename, ''))", true},
+	types.HistorySortDuration: {"COALESCE(time_taken, 0)", false},
+}
+
+// queryEntries runs a query selecting entryColumns and scans every row
+func queryEntries(db *sql.DB, query string, args ...any) ([]types.DownloadEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			utils.Debug("Error closing rows: %v", err)
+		}
+	}()
+
+	entries := []types.DownloadEntry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// matchHost reports whether host is want or one of its subdomains
+func matchHost(host, want string) bool {
+	return host == want || strings.HasSuffix(host, "."+want)
+}
+
+// sortHistory orders entries by key. Dates, sizes, speeds and durations
+// sort largest first and names A-Z; reverse flips the order.
+func sortHistory(entries []types.DownloadEntry, key string, reverse bool) {
+	less := func(a, b types.DownloadEntry) bool {
+		switch key {
+		case types.HistorySortSize:
+			return a.TotalSize > b.TotalSize
+		case types.HistorySortName:
+			return strings.ToLower(a.Filename) < strings.ToLower(b.Filename)
+		case types.HistorySortSpeed:
+			return a.Speed() > b.Speed()
+		case types.HistorySortDuration:
+			return a.TimeTaken > b.TimeTaken
+		}
+		return a.CompletedAt > b.CompletedAt
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func seedHistory(t *testing.T) {
+	t.Helper()
+	entries := []types.DownloadEntry{
+		{ID: "h1", URL: "https://cdn.example.com/a.iso", Filename: "a.iso", Status: "completed", TotalSize: 4000, Downloaded: 4000, CompletedAt: 1000, TimeTaken: 4000},
+		{ID: "h2", URL: "https://example.com/b.zip", Filename: "b.zip", Status: "completed", TotalSize: 100, Downloaded: 100, CompletedAt: 3000, TimeTaken: 1000},
+		{ID: "h3", URL: "https://other.org/c.iso", Filename: "c.iso", Status: "error", TotalSize: 2000, Downloaded: 10, CompletedAt: 2000},
+		{ID: "h4", URL: "https://notexample.com/d.iso", Filename: "d.iso", Status: "completed", TotalSize: 50, Downloaded: 50, CompletedAt: 4000, TimeTaken: 10},
+		{ID: "p1", URL: "https://example.com/e.iso", Filename: "e.iso", Status: "paused", TotalSize: 9000},
+	}
+	for _, e := range entries {
+		e.DestPath = "/tmp/" + e.Filename
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func historyIDs(entries []types.DownloadEntry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestQueryHistory(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+	seedHistory(t)
+
+	all := []string{"completed", "error"}
+	tests := []struct {
+		name   string
+		filter types.HistoryFilter
+		want   []string
+		total  int
+	}{
+		{"default is completed newest first", types.HistoryFilter{}, []string{"h4", "h2", "h1"}, 3},
+		{"errors only", types.HistoryFilter{Status: []string{"error"}}, []string{"h3"}, 1},
+		{"date range", types.HistoryFilter{Status: all, Since: 2000, Until: 4000}, []string{"h2", "h3"}, 2},
+		{"host matches subdomains only", types.HistoryFilter{Host: "Example.com"}, []string{"h2", "h1"}, 2},
+		{"size range", types.HistoryFilter{Status: all, MinSize: 100, MaxSize: 2000}, []string{"h2", "h3"}, 2},
+		{"filename glob", types.HistoryFilter{Status: all, Filename: "*.iso"}, []string{"h4", "h3", "h1"}, 3},
+		{"sort by size", types.HistoryFilter{Sort: types.HistorySortSize}, []string{"h1", "h2", "h4"}, 3},
+		{"sort by name reversed", types.HistoryFilter{Sort: types.HistorySortName, Reverse: true}, []string{"h4", "h2", "h1"}, 3},
+		{"sort by speed", types.HistoryFilter{Sort: types.HistorySortSpeed}, []string{"h4", "h1", "h2"}, 3},
+		{"paging", types.HistoryFilter{Status: all, Limit: 2, Offset: 1}, []string{"h2", "h3"}, 4},
+		{"sorted paging", types.HistoryFilter{Sort: types.HistorySortSize, Reverse: true, Limit: 2}, []string{"h4", "h2"}, 3},
+		{"filtered paging", types.HistoryFilter{Host: "example.com", Limit: 1, Offset: 1}, []string{"h1"}, 2},
+		{"offset past end", types.HistoryFilter{Offset: 10}, []string{}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := QueryHistory(tt.filter)
+			if err != nil {
+				t.Fatalf("QueryHistory failed: %v", err)
+			}
+			ids := historyIDs(got)
+			if len(ids) != len(tt.want) || total != tt.total {
+				t.Fatalf("got %v (total %d), want %v (total %d)", ids, total, tt.want, tt.total)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryHistory_BadGlob(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if _, _, err := QueryHistory(types.HistoryFilter{Filename: "[abc"}); err == nil {
+		t.Error("expected an error for a malformed glob")
+	}
+}
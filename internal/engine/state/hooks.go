@@ -0,0 +1,56 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// SaveDownloadHooks stores the hook commands set for download id, so they
+// still run after a restart
+func SaveDownloadHooks(id string, hooks types.DownloadHooks) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`
+		INSERT OR REPLACE INTO download_hooks (download_id, on_complete, on_error, on_pause)
+		VALUES (?, ?, ?, ?)
+	`, id, hooks.OnComplete, hooks.OnError, hooks.OnPause); err != nil {
+		return fmt.Errorf("failed to save download hooks: %w", err)
+	}
+	return nil
+}
+
+// LoadDownloadHooks returns the hook commands set for download id, or zero
+// hooks if none were
+func LoadDownloadHooks(id string) (types.DownloadHooks, error) {
+	db := getDBHelper()
+	if db == nil {
+		return types.DownloadHooks{}, fmt.Errorf("database not initialized")
+	}
+
+	var onComplete, onError, onPause sql.NullString
+	err := db.QueryRow("SELECT on_complete, on_error, on_pause FROM download_hooks WHERE download_id = ?", id).
+		Scan(&onComplete, &onError, &onPause)
+	if err == sql.ErrNoRows {
+		return types.DownloadHooks{}, nil
+	}
+	if err != nil {
+		return types.DownloadHooks{}, fmt.Errorf("failed to load download hooks: %w", err)
+	}
+	return types.DownloadHooks{OnComplete: onComplete.String, OnError: onError.String, OnPause: onPause.String}, nil
+}
+
+// DeleteDownloadHooks forgets the hook commands set for download id
+func DeleteDownloadHooks(id string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec("DELETE FROM download_hooks WHERE download_id = ?", id)
+	return err
+}
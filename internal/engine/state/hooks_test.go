@@ -0,0 +1,37 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestDownloadHooks(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if hooks, err := LoadDownloadHooks("missing"); err != nil || !hooks.IsZero() {
+		t.Errorf("LoadDownloadHooks(missing) = %+v, %v; want no hooks", hooks, err)
+	}
+
+	want := types.DownloadHooks{OnComplete: "echo done", OnPause: "echo paused"}
+	if err := SaveDownloadHooks("hooked", want); err != nil {
+		t.Fatalf("SaveDownloadHooks failed: %v", err)
+	}
+	if got, err := LoadDownloadHooks("hooked"); err != nil || got != want {
+		t.Errorf("LoadDownloadHooks = %+v, %v; want %+v", got, err, want)
+	}
+
+	// Removing the download takes its hooks with it
+	if err := AddToMasterList(types.DownloadEntry{ID: "hooked", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "queued"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveFromMasterList("hooked"); err != nil {
+		t.Fatalf("RemoveFromMasterList failed: %v", err)
+	}
+	if got, _ := LoadDownloadHooks("hooked"); !got.IsZero() {
+		t.Errorf("hooks after removal = %+v, want none", got)
+	}
+}
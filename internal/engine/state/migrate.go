@@ -0,0 +1,261 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// migration is one numbered step of the state database schema. Each runs
+// in its own transaction together with the schema_version row recording it.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations are applied in order to bring a database to LatestSchemaVersion.
+// Never edit or reorder a released migration; append a new one instead.
+var migrations = []migration{
+	{1, "base schema", migrateBaseSchema},
+	{2, "indexes", execMigration(
+		"CREATE INDEX IF NOT EXISTS idx_downloads_url_hash ON downloads(url_hash)",
+		"CREATE INDEX IF NOT EXISTS idx_downloads_status ON downloads(status)",
+		"CREATE INDEX IF NOT EXISTS idx_tasks_download_id ON tasks(download_id)",
+		"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt ON webhook_deliveries(next_attempt_at)",
+	)},
+	// Kept apart from downloads: hooks are saved before the download's row exists
+	{3, "download hooks", execMigration(`CREATE TABLE IF NOT EXISTS download_hooks (
+		download_id TEXT PRIMARY KEY,
+		on_complete TEXT,
+		on_error TEXT,
+		on_pause TEXT
+	)`)},
+	// Endpoints sharing a URL may differ in secret, format or events
+	{4, "webhook endpoint keys", execMigration("ALTER TABLE webhook_deliveries ADD COLUMN endpoint_key TEXT")},
+}
+
+// LatestSchemaVersion is the schema version this build writes
+var LatestSchemaVersion = migrations[len(migrations)-1].version
+
+// migrateBaseSchema creates the tables as they were before versioning.
+// Databases from those releases may have any subset of the later columns,
+// so each one is added only if missing.
+func migrateBaseSchema(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS downloads (
+		id TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		dest_path TEXT NOT NULL,
+		filename TEXT,
+		status TEXT,
+		total_size INTEGER,
+		downloaded INTEGER,
+		url_hash TEXT,
+		created_at INTEGER,
+		paused_at INTEGER,
+		completed_at INTEGER,
+		time_taken INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		download_id TEXT,
+		offset INTEGER,
+		length INTEGER,
+		FOREIGN KEY(download_id) REFERENCES downloads(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		endpoint_url TEXT NOT NULL,
+		event TEXT NOT NULL,
+		payload BLOB NOT NULL,
+		attempts INTEGER DEFAULT 0,
+		next_attempt_at INTEGER,
+		last_error TEXT,
+		created_at INTEGER
+	);
+	`); err != nil {
+		return err
+	}
+
+	columns := []struct{ name, def string }{
+		{"mirrors", "TEXT"},
+		{"chunk_bitmap", "BLOB"},
+		{"actual_chunk_size", "INTEGER"},
+		{"category", "TEXT"},
+		{"etag", "TEXT"},
+		{"priority", "INTEGER DEFAULT 0"},
+		{"queue_pos", "INTEGER DEFAULT 0"},
+		{"start_at", "INTEGER DEFAULT 0"},
+		{"group_name", "TEXT"},
+		{"tags", "TEXT"},
+		{"note", "TEXT"},
+		{"referrer", "TEXT"},
+		{"headers", "TEXT"}, // JSON object of custom request headers
+	}
+	existing, err := tableColumns(tx, "downloads")
+	if err != nil {
+		return err
+	}
+	for _, c := range columns {
+		if existing[c.name] {
+			continue
+		}
+		if _, err := tx.Exec("ALTER TABLE downloads ADD COLUMN " + c.name + " " + c.def); err != nil {
+			return fmt.Errorf("add column %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// execMigration returns a migration that runs each statement in order
+func execMigration(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// tableColumns returns the set of column names in table
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			utils.Debug("Error closing rows: %v", err)
+		}
+	}()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
+
+// schemaVersion returns the highest migration applied to conn, or 0
+func schemaVersion(conn *sql.DB) (int, error) {
+	if _, err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at INTEGER
+	)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	var v int
+	if err := conn.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&v); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return v, nil
+}
+
+// hasTable reports whether conn has a table named name
+func hasTable(conn *sql.DB, name string) (bool, error) {
+	var n int
+	err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	return n > 0, err
+}
+
+// migrate brings conn up to LatestSchemaVersion. Existing databases are
+// copied to a backup file next to path before the first pending migration
+// runs. A database written by a newer Surge is refused rather than risk
+// corrupting it.
+func migrate(conn *sql.DB, path string) error {
+	current, err := schemaVersion(conn)
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion {
+		return fmt.Errorf("state database schema version %d is newer than this version of Surge supports (%d); upgrade Surge", current, LatestSchemaVersion)
+	}
+	if current == LatestSchemaVersion {
+		return nil
+	}
+
+	// Fresh databases have nothing worth backing up
+	if existing, err := hasTable(conn, "downloads"); err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	} else if existing {
+		backup, err := backupDB(conn, path, current)
+		if err != nil {
+			return fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+		utils.Debug("Backed up state database to %s before migrating from schema version %d", backup, current)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := func() error {
+			tx, err := conn.Begin()
+			if err != nil {
+				return err
+			}
+			// Another process opening the database may have got here first
+			var applied int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM schema_version WHERE version = ?", m.version).Scan(&applied); err != nil || applied > 0 {
+				_ = tx.Rollback()
+				return err
+			}
+			if err := m.up(tx); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+				m.version, m.name, time.Now().Unix()); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			return tx.Commit()
+		}()
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// backupPath is where the copy of path taken before migrating from version
+// is written
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// backupDB writes a consistent copy of conn next to path and returns its
+// path. An earlier backup from the same version is replaced.
+func backupDB(conn *sql.DB, path string, version int) (string, error) {
+	dst := backupPath(path, version)
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, err := conn.Exec("VACUUM INTO '" + strings.ReplaceAll(dst, "'", "''") + "'"); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// SchemaVersion returns the schema version of the state database
+func SchemaVersion() (int, error) {
+	d, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	return schemaVersion(d)
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	v, err := SchemaVersion()
+	if err != nil || v != LatestSchemaVersion {
+		t.Fatalf("SchemaVersion = %d, %v; want %d", v, err, LatestSchemaVersion)
+	}
+
+	d, _ := GetDB()
+	for _, index := range []string{"idx_downloads_url_hash", "idx_downloads_status"} {
+		var n int
+		if err := d.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", index).Scan(&n); err != nil || n != 1 {
+			t.Errorf("index %s missing", index)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(tmpDir, "*.bak"))
+	if len(matches) != 0 {
+		t.Errorf("fresh database should not be backed up, found %v", matches)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "surge.db")
+
+	// A database from before versioning, with only some later columns
+	legacy, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE downloads (id TEXT PRIMARY KEY, url TEXT NOT NULL, dest_path TEXT NOT NULL, filename TEXT, status TEXT,
+			total_size INTEGER, downloaded INTEGER, url_hash TEXT, created_at INTEGER, paused_at INTEGER, completed_at INTEGER, time_taken INTEGER)`,
+		`CREATE TABLE tasks (id INTEGER PRIMARY KEY AUTOINCREMENT, download_id TEXT, offset INTEGER, length INTEGER)`,
+		`ALTER TABLE downloads ADD COLUMN mirrors TEXT`,
+		`ALTER TABLE downloads ADD COLUMN category TEXT`,
+		`INSERT INTO downloads (id, url, dest_path, filename, status, total_size, downloaded, url_hash, completed_at, time_taken, mirrors)
+			VALUES ('old', 'https://example.com/a', '/tmp/a', 'a', 'paused', 10, 5, 'h', 0, 0, 'https://m.example.com/a')`,
+	} {
+		if _, err := legacy.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = legacy.Close()
+
+	CloseDB()
+	Configure(path)
+	defer CloseDB()
+
+	e, err := GetDownload("old")
+	if err != nil || e == nil {
+		t.Fatalf("GetDownload after migration = %v, %v", e, err)
+	}
+	if len(e.Mirrors) != 1 || e.Status != "paused" {
+		t.Errorf("row not preserved: %+v", e)
+	}
+	if v, _ := SchemaVersion(); v != LatestSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", v, LatestSchemaVersion)
+	}
+
+	// The backup is the database as it was before migrating
+	backup, err := sql.Open("sqlite", backupPath(path, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = backup.Close() }()
+	var n int
+	if err := backup.QueryRow("SELECT COUNT(*) FROM downloads").Scan(&n); err != nil || n != 1 {
+		t.Errorf("backup has %d downloads (%v), want 1", n, err)
+	}
+	if err := backup.QueryRow("SELECT COUNT(*) FROM pragma_table_info('downloads') WHERE name = 'headers'").Scan(&n); err != nil || n != 0 {
+		t.Errorf("backup should predate the migration")
+	}
+
+	// Reopening an up-to-date database doesn't migrate or back up again
+	_ = os.Remove(backupPath(path, 0))
+	CloseDB()
+	if _, err := GetDB(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backupPath(path, LatestSchemaVersion)); !os.IsNotExist(err) {
+		t.Error("up-to-date database was backed up")
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	d, _ := GetDB()
+	if _, err := d.Exec("INSERT INTO schema_version (version, name) VALUES (?, 'future')", LatestSchemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	CloseDB()
+
+	if _, err := GetDB(); err == nil {
+		t.Fatal("expected an error opening a database from a newer version")
+	}
+}
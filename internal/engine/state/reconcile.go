@@ -0,0 +1,293 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// State issue kinds found by CheckState
+const (
+	IssueOrphanPartial  = "orphan_partial"  // A .surge file no download owns
+	IssueMissingPartial = "missing_partial" // An unfinished download whose .surge file is gone
+	IssueMissingFile    = "missing_file"    // A completed download whose file is gone
+	IssueTaskMismatch   = "task_mismatch"   // Remaining tasks don't add up to the bytes left
+)
+
+// Fixes RepairState can apply
+const (
+	FixAdopt        = "adopt"         // Mark completed: the finished file is on disk
+	FixPurge        = "purge"         // Delete the row and its partial file, or the orphan file
+	FixMarkError    = "mark_error"    // Mark failed and drop its progress, so a retry starts over
+	FixRebuildTasks = "rebuild_tasks" // Recompute remaining tasks from the chunk bitmap
+)
+
+// StateIssue is a disagreement between the state database and the disk
+type StateIssue struct {
+	Kind       string   `json:"kind"`
+	DownloadID string   `json:"download_id,omitempty"`
+	Filename   string   `json:"filename,omitempty"`
+	Path       string   `json:"path"`
+	Detail     string   `json:"detail"`
+	Fix        string   `json:"fix"`   // Suggested fix, empty if the issue is only reported
+	Fixes      []string `json:"fixes"` // Every fix that applies
+}
+
+// CheckState cross-checks the downloads and tasks tables against the disk.
+// Partial files are looked for in the directories of known downloads and
+// in dirs.
+func CheckState(dirs []string) ([]StateIssue, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+	var unfinished []string
+	for _, e := range list.Downloads {
+		if e.Status != "completed" {
+			unfinished = append(unfinished, e.ID)
+		}
+	}
+	states, err := LoadStates(unfinished)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []StateIssue
+	owned := make(map[string]bool)
+	scan := make(map[string]bool)
+	for _, d := range dirs {
+		if d != "" {
+			scan[filepath.Clean(d)] = true
+		}
+	}
+
+	for _, e := range list.Downloads {
+		if e.DestPath == "" {
+			continue
+		}
+		scan[filepath.Dir(e.DestPath)] = true
+		issue := StateIssue{DownloadID: e.ID, Filename: e.Filename}
+
+		if e.Status == "completed" {
+			if _, err := os.Stat(e.DestPath); os.IsNotExist(err) {
+				issue.Kind, issue.Path = IssueMissingFile, e.DestPath
+				issue.Detail = "completed download's file is missing"
+				issue.Fixes = []string{FixPurge}
+				issues = append(issues, issue)
+			}
+			continue
+		}
+
+		partial := e.DestPath + types.IncompleteSuffix
+		owned[partial] = true
+		s := states[e.ID]
+		if s == nil || (s.Downloaded == 0 && len(s.Tasks) == 0) {
+			continue // Not started yet, so there is no partial file to check
+		}
+
+		if _, err := os.Stat(partial); os.IsNotExist(err) {
+			issue.Kind, issue.Path = IssueMissingPartial, partial
+			issue.Detail = fmt.Sprintf("%s downloaded but the partial file is missing", utils.ConvertBytesToHumanReadable(s.Downloaded))
+			if info, err := os.Stat(e.DestPath); err == nil && info.Mode().IsRegular() && e.TotalSize > 0 && info.Size() == e.TotalSize {
+				issue.Detail = "partial file is missing but the finished file is on disk"
+				issue.Fixes = []string{FixAdopt, FixMarkError, FixPurge}
+			} else {
+				issue.Fixes = []string{FixMarkError, FixPurge}
+			}
+			issues = append(issues, issue)
+			continue
+		}
+
+		if detail, ok := checkTasks(s); !ok {
+			issue.Kind, issue.Path = IssueTaskMismatch, partial
+			issue.Detail = detail
+			if _, ok := TasksFromBitmap(s.ChunkBitmap, s.ActualChunkSize, s.TotalSize); ok {
+				issue.Fixes = []string{FixRebuildTasks, FixMarkError, FixPurge}
+			} else {
+				issue.Fixes = []string{FixMarkError, FixPurge}
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	dirList := make([]string, 0, len(scan))
+	for d := range scan {
+		dirList = append(dirList, d)
+	}
+	sort.Strings(dirList)
+	for _, dir := range dirList {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Moved or unmounted directories have nothing to adopt
+		}
+		for _, de := range entries {
+			if de.IsDir() || !strings.HasSuffix(de.Name(), types.IncompleteSuffix) {
+				continue
+			}
+			path := filepath.Join(dir, de.Name())
+			if owned[path] {
+				continue
+			}
+			issues = append(issues, StateIssue{
+				Kind:     IssueOrphanPartial,
+				Filename: strings.TrimSuffix(de.Name(), types.IncompleteSuffix),
+				Path:     path,
+				Detail:   "partial file has no download",
+				Fixes:    []string{FixPurge},
+			})
+		}
+	}
+
+	for i := range issues {
+		// A stray partial file may be all that is left of a download, and
+		// Surge can't resume it without its URL, so deleting it is only
+		// done when asked for by name
+		if issues[i].Kind != IssueOrphanPartial {
+			issues[i].Fix = issues[i].Fixes[0]
+		}
+	}
+	return issues, nil
+}
+
+// checkTasks reports whether s's remaining tasks lie within the file and
+// add up to the bytes not yet downloaded
+func checkTasks(s *types.DownloadState) (string, bool) {
+	if s.TotalSize <= 0 {
+		return "", true // Unknown size: nothing to compare against
+	}
+	var remaining int64
+	for _, t := range s.Tasks {
+		if t.Offset < 0 || t.Length <= 0 || t.Offset+t.Length > s.TotalSize {
+			return fmt.Sprintf("task at offset %d (length %d) lies outside the file", t.Offset, t.Length), false
+		}
+		remaining += t.Length
+	}
+	if want := s.TotalSize - s.Downloaded; remaining != want {
+		return fmt.Sprintf("tasks cover %d bytes but %d are left to download", remaining, want), false
+	}
+	return "", true
+}
+
+// TasksFromBitmap returns the byte ranges of every chunk the bitmap does
+// not mark completed, merging neighbours. It returns false if the bitmap
+// doesn't describe a file of totalSize.
+func TasksFromBitmap(bitmap []byte, chunkSize, totalSize int64) ([]types.Task, bool) {
+	if chunkSize <= 0 || totalSize <= 0 {
+		return nil, false
+	}
+	numChunks := int((totalSize + chunkSize - 1) / chunkSize)
+	if len(bitmap) != (numChunks+3)/4 {
+		return nil, false
+	}
+
+	var tasks []types.Task
+	for i := 0; i < numChunks; i++ {
+		status := types.ChunkStatus((bitmap[i/4] >> ((i % 4) * 2)) & 3)
+		if status == types.ChunkCompleted {
+			continue
+		}
+		offset := int64(i) * chunkSize
+		length := min(chunkSize, totalSize-offset)
+		if n := len(tasks); n > 0 && tasks[n-1].Offset+tasks[n-1].Length == offset {
+			tasks[n-1].Length += length
+			continue
+		}
+		tasks = append(tasks, types.Task{Offset: offset, Length: length})
+	}
+	return tasks, true
+}
+
+// RepairState applies fix to the issue found by CheckState
+func RepairState(issue StateIssue, fix string) error {
+	allowed := false
+	for _, f := range issue.Fixes {
+		allowed = allowed || f == fix
+	}
+	if !allowed {
+		return fmt.Errorf("%s does not apply to %s", fix, issue.Kind)
+	}
+
+	switch fix {
+	case FixAdopt:
+		return withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM tasks WHERE download_id = ?", issue.DownloadID); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE downloads SET status = 'completed', downloaded = total_size, completed_at = ?,
+				chunk_bitmap = NULL, actual_chunk_size = 0, queue_pos = 0 WHERE id = ?`, time.Now().Unix(), issue.DownloadID)
+			return err
+		})
+
+	case FixMarkError:
+		return withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM tasks WHERE download_id = ?", issue.DownloadID); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE downloads SET status = 'error', downloaded = 0, completed_at = ?,
+				chunk_bitmap = NULL, actual_chunk_size = 0, queue_pos = 0 WHERE id = ?`, time.Now().Unix(), issue.DownloadID)
+			return err
+		})
+
+	case FixRebuildTasks:
+		states, err := LoadStates([]string{issue.DownloadID})
+		if err != nil {
+			return err
+		}
+		s, ok := states[issue.DownloadID]
+		if !ok {
+			return fmt.Errorf("%w: %s", types.ErrNotFound, issue.DownloadID)
+		}
+		tasks, ok := TasksFromBitmap(s.ChunkBitmap, s.ActualChunkSize, s.TotalSize)
+		if !ok {
+			return fmt.Errorf("download %s has no usable chunk bitmap", issue.DownloadID)
+		}
+		var remaining int64
+		for _, t := range tasks {
+			remaining += t.Length
+		}
+		return withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM tasks WHERE download_id = ?", issue.DownloadID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE downloads SET downloaded = ? WHERE id = ?", s.TotalSize-remaining, issue.DownloadID); err != nil {
+				return err
+			}
+			return insertTasks(tx, issue.DownloadID, tasks)
+		})
+
+	case FixPurge:
+		if issue.Kind == IssueOrphanPartial {
+			// No download owns the file, so there is no row to delete
+			if err := os.Remove(issue.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		if err := withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM tasks WHERE download_id = ?", issue.DownloadID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM downloads WHERE id = ?", issue.DownloadID); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM download_hooks WHERE download_id = ?", issue.DownloadID)
+			return err
+		}); err != nil {
+			return err
+		}
+		if issue.Kind != IssueMissingFile {
+			if err := os.Remove(issue.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown fix %q", fix)
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestTasksFromBitmap(t *testing.T) {
+	// Chunks 0, 1 and 3 completed; chunk 2 pending; the last chunk is short
+	bitmap := []byte{byte(types.ChunkCompleted) | byte(types.ChunkCompleted)<<2 | byte(types.ChunkCompleted)<<6}
+	tasks, ok := TasksFromBitmap(bitmap, 100, 350)
+	if !ok || len(tasks) != 1 || tasks[0] != (types.Task{Offset: 200, Length: 100}) {
+		t.Errorf("TasksFromBitmap = %v, %v", tasks, ok)
+	}
+
+	// Pending neighbours merge, and the short last chunk is clipped
+	tasks, ok = TasksFromBitmap([]byte{byte(types.ChunkCompleted)}, 100, 350)
+	if !ok || len(tasks) != 1 || tasks[0] != (types.Task{Offset: 100, Length: 250}) {
+		t.Errorf("TasksFromBitmap = %v, %v", tasks, ok)
+	}
+
+	if _, ok := TasksFromBitmap([]byte{0, 0}, 100, 350); ok {
+		t.Error("bitmap of the wrong length should be rejected")
+	}
+}
+
+func TestCheckAndRepairState(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	write := func(name string, size int) {
+		t.Helper()
+		if err := os.WriteFile(path(name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "done", URL: "https://example.com/done", DestPath: path("done.bin"), Filename: "done.bin", Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	paused := []*types.DownloadState{
+		// Partial file gone, nothing to adopt
+		{ID: "gone", DestPath: path("gone.bin"), TotalSize: 400, Downloaded: 100, Tasks: []types.Task{{Offset: 100, Length: 300}}},
+		// Partial file gone but the finished file is there
+		{ID: "fin", DestPath: path("fin.bin"), TotalSize: 400, Downloaded: 100, Tasks: []types.Task{{Offset: 100, Length: 300}}},
+		// Tasks disagree with downloaded; the bitmap says chunk 2 is left
+		{ID: "bad", DestPath: path("bad.bin"), TotalSize: 400, Downloaded: 300, Tasks: []types.Task{{Offset: 0, Length: 400}},
+			ChunkBitmap: []byte{byte(types.ChunkCompleted) | byte(types.ChunkCompleted)<<2 | byte(types.ChunkCompleted)<<6}, ActualChunkSize: 100},
+		// Consistent
+		{ID: "ok", DestPath: path("ok.bin"), TotalSize: 400, Downloaded: 100, Tasks: []types.Task{{Offset: 100, Length: 300}}},
+	}
+	for _, s := range paused {
+		s.URL = "https://example.com/" + s.ID
+		s.Filename = s.ID + ".bin"
+		if err := SaveState(s.URL, s.DestPath, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("fin.bin", 400)
+	write("bad.bin"+types.IncompleteSuffix, 400)
+	write("ok.bin"+types.IncompleteSuffix, 400)
+	write("stray.bin"+types.IncompleteSuffix, 10)
+
+	issues, err := CheckState(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct{ kind, fix string }{
+		"done": {IssueMissingFile, FixPurge},
+		"gone": {IssueMissingPartial, FixMarkError},
+		"fin":  {IssueMissingPartial, FixAdopt},
+		"bad":  {IssueTaskMismatch, FixRebuildTasks},
+		"":     {IssueOrphanPartial, ""},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("CheckState found %d issues, want %d: %+v", len(issues), len(want), issues)
+	}
+	for _, issue := range issues {
+		w, ok := want[issue.DownloadID]
+		if !ok || issue.Kind != w.kind || issue.Fix != w.fix {
+			t.Errorf("unexpected issue %+v", issue)
+		}
+		if issue.Kind == IssueOrphanPartial {
+			if err := RepairState(issue, issue.Fix); err == nil {
+				t.Error("expected an error repairing an orphan without naming a fix")
+			}
+			if _, err := os.Stat(issue.Path); err != nil {
+				t.Fatalf("orphan partial file touched without a fix: %v", err)
+			}
+			issue.Fix = FixPurge
+		}
+		if err := RepairState(issue, issue.Fix); err != nil {
+			t.Fatalf("RepairState(%s, %s): %v", issue.Kind, issue.Fix, err)
+		}
+	}
+
+	if issues, _ := CheckState(nil); len(issues) != 0 {
+		t.Errorf("issues left after repair: %+v", issues)
+	}
+	if e, _ := GetDownload("done"); e != nil {
+		t.Error("purged download still present")
+	}
+	if e, _ := GetDownload("gone"); e == nil || e.Status != "error" || e.Downloaded != 0 {
+		t.Errorf("gone = %+v, want error with no progress", e)
+	}
+	if e, _ := GetDownload("fin"); e == nil || e.Status != "completed" || e.Downloaded != 400 {
+		t.Errorf("fin = %+v, want completed", e)
+	}
+	states, _ := LoadStates([]string{"bad"})
+	if s := states["bad"]; s == nil || s.Downloaded != 300 || len(s.Tasks) != 1 || s.Tasks[0] != (types.Task{Offset: 200, Length: 100}) {
+		t.Errorf("bad = %+v, want tasks rebuilt from the bitmap", s)
+	}
+	if _, err := os.Stat(path("stray.bin" + types.IncompleteSuffix)); !os.IsNotExist(err) {
+		t.Error("orphan partial file not deleted")
+	}
+
+	if err := RepairState(StateIssue{Kind: IssueMissingFile, Fixes: []string{FixPurge}}, FixAdopt); err == nil {
+		t.Error("expected an error for a fix that doesn't apply")
+	}
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// RetentionPolicy selects finished downloads to drop from history. Zero
+// fields keep everything they cover.
+type RetentionPolicy struct {
+	AllCompleted bool          // Drop every completed download
+	MaxAge       time.Duration // Drop completed downloads finished longer ago than this
+	MaxEntries   int           // Keep only this many of the newest completed downloads
+	ErrorMaxAge  time.Duration // Drop failed downloads that failed longer ago than this
+}
+
+// IsZero reports whether the policy keeps everything
+func (p RetentionPolicy) IsZero() bool {
+	return p == RetentionPolicy{}
+}
+
+// finishedAt is when a row finished. Rows written before failures recorded
+// completed_at fall back to when they were last touched.
+const finishedAt = "COALESCE(NULLIF(completed_at, 0), NULLIF(paused_at, 0), created_at, 0)"
+
+// pruneBatchSize bounds the IDs bound into one DELETE statement
+const pruneBatchSize = 500
+
+// PruneHistory deletes the finished downloads selected by p and returns
+// them, so callers can clean up their files and notify clients. Queued,
+// scheduled and paused downloads are never touched: the rows are selected
+// by the same statement that deletes them, so a download retried meanwhile
+// no longer matches.
+func PruneHistory(p RetentionPolicy, now time.Time) ([]types.DownloadEntry, error) {
+	if p.IsZero() {
+		return nil, nil
+	}
+
+	var where []string
+	var args []any
+	switch {
+	case p.AllCompleted:
+		where = append(where, "status = 'completed'")
+	default:
+		if p.MaxAge > 0 {
+			where = append(where, "(status = 'completed' AND "+finishedAt+" < ?)")
+			args = append(args, now.Add(-p.MaxAge).Unix())
+		}
+		if p.MaxEntries > 0 {
+			where = append(where, `id IN (
+				SELECT id FROM downloads WHERE status = 'completed'
+				ORDER BY completed_at DESC, id LIMIT -1 OFFSET ?)`)
+			args = append(args, p.MaxEntries)
+		}
+	}
+	if p.ErrorMaxAge > 0 {
+		where = append(where, "(status = 'error' AND "+finishedAt+" < ?)")
+		args = append(args, now.Add(-p.ErrorMaxAge).Unix())
+	}
+
+	var pruned []types.DownloadEntry
+	err := withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query("DELETE FROM downloads WHERE "+strings.Join(where, " OR ")+" RETURNING "+entryColumns, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete downloads: %w", err)
+		}
+		for rows.Next() {
+			e, err := scanEntry(rows)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+			pruned = append(pruned, e)
+		}
+		if err := rows.Err(); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if err := rows.Close(); err != nil {
+			utils.Debug("Error closing rows: %v", err)
+		}
+
+		for start := 0; start < len(pruned); start += pruneBatchSize {
+			batch := pruned[start:min(start+pruneBatchSize, len(pruned))]
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+			ids := make([]any, len(batch))
+			for i, e := range batch {
+				ids[i] = e.ID
+			}
+			// FKs aren't enforced, so tasks are removed explicitly
+			if _, err := tx.Exec("DELETE FROM tasks WHERE download_id IN ("+placeholders+")", ids...); err != nil {
+				return fmt.Errorf("failed to delete tasks: %w", err)
+			}
+			if _, err := tx.Exec("DELETE FROM download_hooks WHERE download_id IN ("+placeholders+")", ids...); err != nil {
+				return fmt.Errorf("failed to delete download hooks: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
+
+// DestPathInUse reports whether any download still points at destPath
+func DestPathInUse(destPath string) (bool, error) {
+	db := getDBHelper()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM downloads WHERE dest_path = ?", destPath).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to query downloads: %w", err)
+	}
+	return n > 0, nil
+}
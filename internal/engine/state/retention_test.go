@@ -0,0 +1,56 @@
+package state
+
+import (
+	"os"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPruneHistory(t *testing.T) {
+	now := time.Unix(10000, 0)
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"zero policy keeps everything", RetentionPolicy{}, nil},
+		{"all completed", RetentionPolicy{AllCompleted: true}, []string{"h1", "h2", "h4"}},
+		{"completed by age", RetentionPolicy{MaxAge: 7000 * time.Second}, []string{"h1"}},
+		{"completed by count", RetentionPolicy{MaxEntries: 1}, []string{"h1", "h2"}},
+		{"errors by age", RetentionPolicy{ErrorMaxAge: 7000 * time.Second}, []string{"h3"}},
+		{"combined", RetentionPolicy{MaxAge: 7000 * time.Second, MaxEntries: 2, ErrorMaxAge: time.Hour}, []string{"h1", "h3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupTestDB(t)
+			defer func() { _ = os.RemoveAll(tmpDir) }()
+			defer CloseDB()
+			seedHistory(t)
+
+			pruned, err := PruneHistory(tt.policy, now)
+			if err != nil {
+				t.Fatalf("PruneHistory: %v", err)
+			}
+			got := historyIDs(pruned)
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pruned %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("pruned %v, want %v", got, tt.want)
+				}
+			}
+
+			for _, id := range got {
+				if e, _ := GetDownload(id); e != nil {
+					t.Errorf("%s still in the database", id)
+				}
+			}
+			if e, _ := GetDownload("p1"); e == nil || e.Status != "paused" {
+				t.Errorf("paused download was touched: %+v", e)
+			}
+		})
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -67,58 +69,88 @@ func SaveState(url string, destPath string, state *types.DownloadState) error {
 			return fmt.Errorf("failed to delete old tasks: %w", err)
 		}
 
-		// Insert new tasks using batch insert
-		// SQLite limit is often 999 or 32766 params. Safe batch size: 50 tasks * 3 params = 150 params.
-		const batchSize = 50
-		tasks := state.Tasks
-		numTasks := len(tasks)
+		return insertTasks(tx, state.ID, state.Tasks)
+	})
+}
 
-		if numTasks > 0 {
-			// Prepare statement for full batches
-			placeholders := strings.Repeat("(?, ?, ?),", batchSize)
-			placeholders = placeholders[:len(placeholders)-1] // remove trailing comma
-			stmt, err := tx.Prepare("INSERT INTO tasks (download_id, offset, length) VALUES " + placeholders)
-			if err != nil {
-				return fmt.Errorf("failed to prepare batch insert: %w", err)
-			}
-			defer func() { _ = stmt.Close() }()
-
-			for i := 0; i < numTasks; i += batchSize {
-				end := i + batchSize
-				if end > numTasks {
-					// Last batch (partial)
-					end = numTasks
-					batch := tasks[i:end]
-
-					var q strings.Builder
-					q.WriteString("INSERT INTO tasks (download_id, offset, length) VALUES ")
-					args := make([]interface{}, 0, len(batch)*3)
-					for j, task := range batch {
-						if j > 0 {
-							q.WriteString(",")
-						}
-						q.WriteString("(?, ?, ?)")
-						args = append(args, state.ID, task.Offset, task.Length)
-					}
-					if _, err := tx.Exec(q.String(), args...); err != nil {
-						return fmt.Errorf("failed to insert partial batch: %w", err)
-					}
-				} else {
-					// Full batch
-					batch := tasks[i:end]
-					args := make([]interface{}, 0, batchSize*3)
-					for _, task := range batch {
-						args = append(args, state.ID, task.Offset, task.Length)
-					}
-					if _, err := stmt.Exec(args...); err != nil {
-						return fmt.Errorf("failed to insert tasks batch: %w", err)
+// SaveCheckpoint records the progress of a running download: its size,
+// bytes downloaded, chunk map and remaining tasks. Unlike SaveState it leaves
+// the status alone, and it only updates an existing unfinished row, so a
+// checkpoint racing a delete or completion can't bring the download back.
+func SaveCheckpoint(state *types.DownloadState) error {
+	return withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE downloads SET dest_path = ?, filename = ?, total_size = ?, downloaded = ?, time_taken = ?,
+				mirrors = ?, chunk_bitmap = ?, actual_chunk_size = ?
+			WHERE id = ? AND status != 'completed'
+		`, state.DestPath, state.Filename, state.TotalSize, state.Downloaded, state.Elapsed/1e6,
+			strings.Join(state.Mirrors, ","), state.ChunkBitmap, state.ActualChunkSize, state.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update download: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec("DELETE FROM tasks WHERE download_id = ?", state.ID); err != nil {
+			return fmt.Errorf("failed to delete old tasks: %w", err)
+		}
+		return insertTasks(tx, state.ID, state.Tasks)
+	})
+}
+
+// insertTasks stores the remaining tasks of a download
+func insertTasks(tx *sql.Tx, id string, tasks []types.Task) error {
+	// Insert new tasks using batch insert
+	// SQLite limit is often 999 or 32766 params. Safe batch size: 50 tasks * 3 params = 150 params.
+	const batchSize = 50
+	numTasks := len(tasks)
+
+	if numTasks > 0 {
+		// Prepare statement for full batches
+		placeholders := strings.Repeat("(?, ?, ?),", batchSize)
+		placeholders = placeholders[:len(placeholders)-1] // remove trailing comma
+		stmt, err := tx.Prepare("INSERT INTO tasks (download_id, offset, length) VALUES " + placeholders)
+		if err != nil {
+			return fmt.Errorf("failed to prepare batch insert: %w", err)
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for i := 0; i < numTasks; i += batchSize {
+			end := i + batchSize
+			if end > numTasks {
+				// Last batch (partial)
+				end = numTasks
+				batch := tasks[i:end]
+
+				var q strings.Builder
+				q.WriteString("INSERT INTO tasks (download_id, offset, length) VALUES ")
+				args := make([]interface{}, 0, len(batch)*3)
+				for j, task := range batch {
+					if j > 0 {
+						q.WriteString(",")
 					}
+					q.WriteString("(?, ?, ?)")
+					args = append(args, id, task.Offset, task.Length)
+				}
+				if _, err := tx.Exec(q.String(), args...); err != nil {
+					return fmt.Errorf("failed to insert partial batch: %w", err)
+				}
+			} else {
+				// Full batch
+				batch := tasks[i:end]
+				args := make([]interface{}, 0, batchSize*3)
+				for _, task := range batch {
+					args = append(args, id, task.Offset, task.Length)
+				}
+				if _, err := stmt.Exec(args...); err != nil {
+					return fmt.Errorf("failed to insert tasks batch: %w", err)
 				}
 			}
 		}
+	}
 
-		return nil
-	})
+	return nil
 }
 
 // LoadState loads download state from SQLite
@@ -236,6 +268,59 @@ func DeleteState(id string, url string, destPath string) error {
 
 // ================== Master List Functions ==================
 
+// entryColumns are the downloads columns read by scanEntry, in order
+const entryColumns = "id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, category, etag, priority, queue_pos, start_at, group_name, tags, note, referrer, headers"
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEntry reads a master list entry selected with entryColumns
+func scanEntry(row rowScanner) (types.DownloadEntry, error) {
+	var e types.DownloadEntry
+	var completedAt, timeTaken sql.NullInt64                             // handle nulls
+	var filename, urlHash, mirrors, category, etag, group sql.NullString // handle nulls
+	var tags, note, referrer, headers sql.NullString
+	var priority, queuePos, startAt sql.NullInt64
+
+	if err := row.Scan(
+		&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
+		&completedAt, &timeTaken, &urlHash, &mirrors, &category, &etag, &priority, &queuePos, &startAt, &group, &tags, &note, &referrer, &headers,
+	); err != nil {
+		return e, err
+	}
+
+	if completedAt.Valid {
+		e.CompletedAt = completedAt.Int64
+	}
+	if timeTaken.Valid {
+		e.TimeTaken = timeTaken.Int64
+	}
+	if filename.Valid {
+		e.Filename = filename.String
+	}
+	if urlHash.Valid {
+		e.URLHash = urlHash.String
+	}
+	if mirrors.Valid && mirrors.String != "" {
+		e.Mirrors = strings.Split(mirrors.String, ",")
+	}
+	e.Category = category.String
+	e.ETag = etag.String
+	e.Priority = int(priority.Int64)
+	e.QueuePos = int(queuePos.Int64)
+	e.StartAt = startAt.Int64
+	e.Group = group.String
+	if tags.String != "" {
+		e.Tags = strings.Split(tags.String, ",")
+	}
+	e.Note = note.String
+	e.Referrer = referrer.String
+	e.Headers = decodeHeaders(headers.String)
+	return e, nil
+}
+
 // LoadMasterList loads ALL downloads (paused and completed)
 func LoadMasterList() (*types.MasterList, error) {
 	db := getDBHelper()
@@ -244,10 +329,7 @@ func LoadMasterList() (*types.MasterList, error) {
 		return &types.MasterList{Downloads: []types.DownloadEntry{}}, nil
 	}
 
-	rows, err := db.Query(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors 
-		FROM downloads
-	`)
+	rows, err := db.Query("SELECT " + entryColumns + " FROM downloads")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query downloads: %w", err)
 	}
@@ -259,40 +341,19 @@ func LoadMasterList() (*types.MasterList, error) {
 
 	var list types.MasterList
 	for rows.Next() {
-		var e types.DownloadEntry
-		var completedAt, timeTaken sql.NullInt64      // handle nulls
-		var filename, urlHash, mirrors sql.NullString // handle nulls
-
-		if err := rows.Scan(
-			&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-			&completedAt, &timeTaken, &urlHash, &mirrors,
-		); err != nil {
+		e, err := scanEntry(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		if completedAt.Valid {
-			e.CompletedAt = completedAt.Int64
-		}
-		if timeTaken.Valid {
-			e.TimeTaken = timeTaken.Int64
-		}
-		if filename.Valid {
-			e.Filename = filename.String
-		}
-		if urlHash.Valid {
-			e.URLHash = urlHash.String
-		}
-		if mirrors.Valid && mirrors.String != "" {
-			e.Mirrors = strings.Split(mirrors.String, ",")
-		}
-
 		list.Downloads = append(list.Downloads, e)
 	}
 
 	return &list, nil
 }
 
-// AddToMasterList adds or updates a download entry
+// AddToMasterList adds or updates a download entry. Tags, note and referrer
+// are always written, so empty ones clear what was saved; headers are kept
+// when entry.Headers is nil and cleared when it is empty.
 func AddToMasterList(entry types.DownloadEntry) error {
 	// Ensure ID
 	if entry.ID == "" {
@@ -304,11 +365,16 @@ func AddToMasterList(entry types.DownloadEntry) error {
 		}
 	}
 
+	var headers any // NULL keeps the saved headers
+	if entry.Headers != nil {
+		headers = encodeHeaders(entry.Headers)
+	}
+
 	return withTx(func(tx *sql.Tx) error {
 		_, err := tx.Exec(`
 			INSERT INTO downloads (
-				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, category, etag, priority, start_at, group_name, tags, note, referrer, headers
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -319,24 +385,128 @@ func AddToMasterList(entry types.DownloadEntry) error {
 				completed_at=excluded.completed_at,
 				time_taken=excluded.time_taken,
 				url_hash=excluded.url_hash,
-				mirrors=excluded.mirrors
+				mirrors=excluded.mirrors,
+				category=COALESCE(NULLIF(excluded.category, ''), downloads.category),
+				etag=COALESCE(NULLIF(excluded.etag, ''), downloads.etag),
+				start_at=excluded.start_at,
+				group_name=COALESCE(NULLIF(excluded.group_name, ''), downloads.group_name),
+				tags=excluded.tags,
+				note=excluded.note,
+				referrer=excluded.referrer,
+				headers=COALESCE(excluded.headers, downloads.headers)
 		`,
 			entry.ID, entry.URL, entry.DestPath, entry.Filename, entry.Status, entry.TotalSize, entry.Downloaded,
-			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","))
+			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","), entry.Category, entry.ETag, entry.Priority, entry.StartAt, entry.Group,
+			strings.Join(entry.Tags, ","), entry.Note, entry.Referrer, headers)
 
 		return err
 	})
 }
 
-// RemoveFromMasterList removes a download entry
-func RemoveFromMasterList(id string) error {
+// SetDownloadCategory records the category rule a download was routed by
+func SetDownloadCategory(id, category string) error {
 	db := getDBHelper()
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
+	if _, err := db.Exec("UPDATE downloads SET category = ? WHERE id = ?", category, id); err != nil {
+		return fmt.Errorf("failed to set category: %w", err)
+	}
+	return nil
+}
 
-	_, err := db.Exec("DELETE FROM downloads WHERE id = ?", id)
-	return err
+// SetDestPath records that a download's file now lives at destPath. The
+// filename is left alone when empty, as for downloads not yet probed.
+func SetDestPath(id, destPath, filename string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	result, err := db.Exec("UPDATE downloads SET dest_path = ?, filename = COALESCE(NULLIF(?, ''), filename) WHERE id = ?", destPath, filename, id)
+	if err != nil {
+		return fmt.Errorf("failed to set destination: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", types.ErrNotFound, id)
+	}
+	return nil
+}
+
+// SetURL points an unfinished download at a new primary URL, keeping its
+// progress and remaining tasks. The old URL is dropped from its mirrors, etag
+// replaces the recorded ETag unless it is empty, and headers replace the
+// saved request headers.
+func SetURL(id, url, etag string, headers map[string]string) error {
+	return withTx(func(tx *sql.Tx) error {
+		var oldURL string
+		var mirrors sql.NullString
+		err := tx.QueryRow("SELECT url, mirrors FROM downloads WHERE id = ? AND status != 'completed'", id).Scan(&oldURL, &mirrors)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", types.ErrNotFound, id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query download: %w", err)
+		}
+
+		var kept []string
+		if mirrors.Valid && mirrors.String != "" {
+			for _, m := range strings.Split(mirrors.String, ",") {
+				if m != oldURL && m != url {
+					kept = append(kept, m)
+				}
+			}
+		}
+		if _, err := tx.Exec(
+			"UPDATE downloads SET url = ?, url_hash = ?, mirrors = ?, etag = COALESCE(NULLIF(?, ''), etag), headers = ? WHERE id = ?",
+			url, URLHash(url), strings.Join(kept, ","), etag, encodeHeaders(headers), id,
+		); err != nil {
+			return fmt.Errorf("failed to set url: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetETag records the ETag the server reported when a download started, so
+// a later source can be checked against it
+func SetETag(id, etag string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if _, err := db.Exec("UPDATE downloads SET etag = ? WHERE id = ?", etag, id); err != nil {
+		return fmt.Errorf("failed to set etag: %w", err)
+	}
+	return nil
+}
+
+// SaveQueueOrder records the position and priority of each waiting download
+// so the queue comes back in the same order after a restart
+func SaveQueueOrder(queue []types.QueuePosition) error {
+	return withTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare("UPDATE downloads SET queue_pos = ?, priority = ? WHERE id = ?")
+		if err != nil {
+			return fmt.Errorf("failed to prepare queue update: %w", err)
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for i, q := range queue {
+			if _, err := stmt.Exec(i+1, q.Priority, q.ID); err != nil {
+				return fmt.Errorf("failed to save queue order: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveFromMasterList removes a download entry along with its saved hooks
+func RemoveFromMasterList(id string) error {
+	return withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM downloads WHERE id = ?", id); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM download_hooks WHERE download_id = ?", id)
+		return err
+	})
 }
 
 // GetDownload returns a single download by ID
@@ -346,46 +516,90 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 		return nil, fmt.Errorf("database not initialized")
 	}
 
-	var e types.DownloadEntry
-	var completedAt, timeTaken sql.NullInt64
-	var urlHash, filename, mirrors sql.NullString
-
-	row := db.QueryRow(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors 
-		FROM downloads
-		WHERE id = ?
-	`, id)
-
-	if err := row.Scan(
-		&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-		&completedAt, &timeTaken, &urlHash, &mirrors,
-	); err != nil {
+	row := db.QueryRow("SELECT "+entryColumns+" FROM downloads WHERE id = ?", id)
+	e, err := scanEntry(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
 		}
 		return nil, fmt.Errorf("failed to query download: %w", err)
 	}
+	return &e, nil
+}
 
-	if completedAt.Valid {
-		e.CompletedAt = completedAt.Int64
+// encodeHeaders stores request headers as a JSON object, or "" for none
+func encodeHeaders(headers map[string]string) string {
+	if len(headers) == 0 {
+		return ""
 	}
-	if timeTaken.Valid {
-		e.TimeTaken = timeTaken.Int64
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return ""
 	}
-	if urlHash.Valid {
-		e.URLHash = urlHash.String
+	return string(data)
+}
+
+// decodeHeaders reverses encodeHeaders; bad data reads as no headers
+func decodeHeaders(s string) map[string]string {
+	if s == "" {
+		return nil
 	}
-	if filename.Valid {
-		e.Filename = filename.String
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(s), &headers); err != nil {
+		utils.Debug("Ignoring unreadable saved headers: %v", err)
+		return nil
 	}
-	if mirrors.Valid && mirrors.String != "" {
-		e.Mirrors = strings.Split(mirrors.String, ",")
+	return headers
+}
+
+// UnfinishedDownloadAt returns the ID of a download other than exceptID that
+// is still writing to destPath, or "" if there is none
+func UnfinishedDownloadAt(destPath, exceptID string) (string, error) {
+	db := getDBHelper()
+	if db == nil {
+		return "", fmt.Errorf("database not initialized")
 	}
 
-	return &e, nil
+	var id string
+	err := db.QueryRow(`
+		SELECT id FROM downloads
+		WHERE dest_path = ? AND id != ? AND status != 'completed'
+		LIMIT 1
+	`, destPath, exceptID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query downloads: %w", err)
+	}
+	return id, nil
+}
+
+// CompletedETag returns the ETag recorded for the most recent completed
+// download saved at destPath, or "" if none is known
+func CompletedETag(destPath string) (string, error) {
+	db := getDBHelper()
+	if db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	var etag sql.NullString
+	err := db.QueryRow(`
+		SELECT etag FROM downloads
+		WHERE dest_path = ? AND status = 'completed'
+		ORDER BY completed_at DESC
+		LIMIT 1
+	`, destPath).Scan(&etag)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query etag: %w", err)
+	}
+	return etag.String, nil
 }
 
-// LoadPausedDownloads returns all paused downloads
+// LoadPausedDownloads returns all paused, queued and scheduled downloads
 func LoadPausedDownloads() ([]types.DownloadEntry, error) {
 	// Reuse LoadMasterList logic or optimize with WHERE
 	list, err := LoadMasterList()
@@ -395,10 +609,22 @@ func LoadPausedDownloads() ([]types.DownloadEntry, error) {
 
 	var paused []types.DownloadEntry
 	for _, e := range list.Downloads {
-		if e.Status == "paused" || e.Status == "queued" {
+		if e.Status == "paused" || e.Status == "queued" || e.Status == "scheduled" {
 			paused = append(paused, e)
 		}
 	}
+
+	// Return them in queue order so resuming them in turn rebuilds the queue
+	sort.SliceStable(paused, func(i, j int) bool {
+		a, b := paused[i], paused[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if (a.QueuePos == 0) != (b.QueuePos == 0) {
+			return a.QueuePos != 0 // Never-queued entries go last
+		}
+		return a.QueuePos < b.QueuePos
+	})
 	return paused, nil
 }
 
@@ -488,18 +714,11 @@ func ListAllDownloads() ([]types.DownloadEntry, error) {
 
 // RemoveCompletedDownloads removes all completed downloads and returns count
 func RemoveCompletedDownloads() (int64, error) {
-	db := getDBHelper()
-	if db == nil {
-		return 0, fmt.Errorf("database not initialized")
-	}
-
-	result, err := db.Exec("DELETE FROM downloads WHERE status = 'completed'")
+	pruned, err := PruneHistory(RetentionPolicy{AllCompleted: true}, time.Now())
 	if err != nil {
 		return 0, fmt.Errorf("failed to remove completed downloads: %w", err)
 	}
-
-	count, _ := result.RowsAffected()
-	return count, nil
+	return int64(len(pruned)), nil
 }
 
 // LoadStates loads multiple download states from SQLite in batch
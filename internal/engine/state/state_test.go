@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -173,6 +175,184 @@ func TestDeleteState(t *testing.T) {
 	}
 }
 
+func TestSaveCheckpoint(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	testURL := "https://test.example.com/checkpoint.zip"
+	destPath := filepath.Join(tmpDir, "checkpoint.zip")
+	id := "test-id-checkpoint"
+	if err := AddToMasterList(types.DownloadEntry{
+		ID: id, URL: testURL, URLHash: URLHash(testURL), DestPath: destPath,
+		Filename: "checkpoint.zip", Status: "queued", TotalSize: 1000,
+	}); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	checkpoint := &types.DownloadState{
+		ID:         id,
+		DestPath:   destPath,
+		Filename:   "checkpoint.zip",
+		TotalSize:  1000,
+		Downloaded: 600,
+		Tasks:      []types.Task{{Offset: 200, Length: 100}, {Offset: 700, Length: 300}},
+		Elapsed:    int64(3 * time.Second),
+	}
+	if err := SaveCheckpoint(checkpoint); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	loaded, err := LoadState(testURL, destPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if loaded.Downloaded != 600 {
+		t.Errorf("Downloaded = %d, want 600", loaded.Downloaded)
+	}
+	if len(loaded.Tasks) != 2 || loaded.Tasks[1].Offset != 700 {
+		t.Errorf("Tasks = %+v, want the checkpointed tasks", loaded.Tasks)
+	}
+	if loaded.Elapsed != int64(3*time.Second) {
+		t.Errorf("Elapsed = %d, want %d", loaded.Elapsed, int64(3*time.Second))
+	}
+
+	// The status of a running download must not change
+	entry, err := GetDownload(id)
+	if err != nil {
+		t.Fatalf("GetDownload failed: %v", err)
+	}
+	if entry.Status != "queued" {
+		t.Errorf("Status = %s, want queued", entry.Status)
+	}
+
+	// A checkpoint racing a delete must not bring the download back
+	if err := DeleteState(id, testURL, destPath); err != nil {
+		t.Fatalf("DeleteState failed: %v", err)
+	}
+	if err := SaveCheckpoint(checkpoint); err != nil {
+		t.Fatalf("SaveCheckpoint after delete failed: %v", err)
+	}
+	if entry, _ := GetDownload(id); entry != nil {
+		t.Errorf("checkpoint recreated a deleted download: %+v", entry)
+	}
+}
+
+func TestSetDestPath(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	testURL := "https://test.example.com/move.zip"
+	oldPath := filepath.Join(tmpDir, "move.zip")
+	newPath := filepath.Join(tmpDir, "moved", "renamed.zip")
+	state := &types.DownloadState{
+		ID:         "test-id-move",
+		URL:        testURL,
+		DestPath:   oldPath,
+		Filename:   "move.zip",
+		TotalSize:  1000,
+		Downloaded: 400,
+		Tasks:      []types.Task{{Offset: 400, Length: 600}},
+	}
+	if err := SaveState(testURL, oldPath, state); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	if err := SetDestPath(state.ID, newPath, "renamed.zip"); err != nil {
+		t.Fatalf("SetDestPath failed: %v", err)
+	}
+
+	// Resume looks the state up by the new path
+	if _, err := LoadState(testURL, oldPath); err == nil {
+		t.Error("state should no longer be found at the old path")
+	}
+	loaded, err := LoadState(testURL, newPath)
+	if err != nil {
+		t.Fatalf("LoadState at new path failed: %v", err)
+	}
+	if loaded.Filename != "renamed.zip" || len(loaded.Tasks) != 1 {
+		t.Errorf("loaded = %+v, want the renamed download with its tasks", loaded)
+	}
+
+	// An empty filename keeps the current one
+	if err := SetDestPath(state.ID, oldPath, ""); err != nil {
+		t.Fatalf("SetDestPath failed: %v", err)
+	}
+	if entry, _ := GetDownload(state.ID); entry == nil || entry.Filename != "renamed.zip" || entry.DestPath != oldPath {
+		t.Errorf("entry = %+v, want filename kept and path updated", entry)
+	}
+
+	if err := SetDestPath("missing", newPath, ""); err == nil {
+		t.Error("SetDestPath should fail for an unknown download")
+	}
+}
+
+func TestSetURL(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	oldURL := "https://old.example.com/relink.zip"
+	newURL := "https://new.example.com/relink.zip"
+	mirror := "https://mirror.example.com/relink.zip"
+	destPath := filepath.Join(tmpDir, "relink.zip")
+	state := &types.DownloadState{
+		ID:         "test-id-relink",
+		URL:        oldURL,
+		DestPath:   destPath,
+		Filename:   "relink.zip",
+		TotalSize:  1000,
+		Downloaded: 400,
+		Tasks:      []types.Task{{Offset: 400, Length: 600}},
+		Mirrors:    []string{oldURL, mirror},
+	}
+	if err := SaveState(oldURL, destPath, state); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	if err := SetETag(state.ID, `"v1"`); err != nil {
+		t.Fatalf("SetETag failed: %v", err)
+	}
+
+	if err := SetURL(state.ID, newURL, "", nil); err != nil {
+		t.Fatalf("SetURL failed: %v", err)
+	}
+
+	// Resume looks the state up by the new URL, with its tasks intact
+	if _, err := LoadState(oldURL, destPath); err == nil {
+		t.Error("state should no longer be found by the old URL")
+	}
+	loaded, err := LoadState(newURL, destPath)
+	if err != nil {
+		t.Fatalf("LoadState by new URL failed: %v", err)
+	}
+	if loaded.Downloaded != 400 || len(loaded.Tasks) != 1 {
+		t.Errorf("loaded = %+v, want the saved progress", loaded)
+	}
+	if !reflect.DeepEqual(loaded.Mirrors, []string{mirror}) {
+		t.Errorf("Mirrors = %v, want the old URL dropped", loaded.Mirrors)
+	}
+
+	entry, _ := GetDownload(state.ID)
+	if entry.URLHash != URLHash(newURL) || entry.ETag != `"v1"` {
+		t.Errorf("entry = %+v, want the new URL hash and the ETag kept", entry)
+	}
+	if err := SetURL(state.ID, oldURL, `"v2"`, nil); err != nil {
+		t.Fatalf("SetURL failed: %v", err)
+	}
+	if entry, _ := GetDownload(state.ID); entry.ETag != `"v2"` {
+		t.Errorf("ETag = %q, want it replaced", entry.ETag)
+	}
+
+	// Finished downloads keep their URL
+	if err := UpdateStatus(state.ID, "completed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetURL(state.ID, newURL, "", nil); err == nil {
+		t.Error("SetURL should fail for a completed download")
+	}
+}
+
 func TestStateOverwrite(t *testing.T) {
 	tmpDir := setupTestDB(t)
 	defer func() { _ = os.RemoveAll(tmpDir) }()
@@ -612,3 +792,128 @@ func TestMirrorsPersistence(t *testing.T) {
 		t.Error("Completed download not found in list")
 	}
 }
+
+func TestSaveQueueOrder_RestoresOrder(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, id := range []string{"q-1", "q-2", "q-3"} {
+		if err := AddToMasterList(types.DownloadEntry{ID: id, URL: "https://q.com/" + id, DestPath: "/tmp/" + id, Status: "queued"}); err != nil {
+			t.Fatalf("AddToMasterList failed: %v", err)
+		}
+	}
+	// Never queued, paused mid-download
+	if err := AddToMasterList(types.DownloadEntry{ID: "p-1", URL: "https://q.com/p-1", DestPath: "/tmp/p-1", Status: "paused"}); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	order := []types.QueuePosition{{ID: "q-3", Priority: 2}, {ID: "q-1"}, {ID: "q-2"}}
+	if err := SaveQueueOrder(order); err != nil {
+		t.Fatalf("SaveQueueOrder failed: %v", err)
+	}
+
+	entries, err := LoadPausedDownloads()
+	if err != nil {
+		t.Fatalf("LoadPausedDownloads failed: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.ID)
+	}
+	want := []string{"q-3", "q-1", "q-2", "p-1"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+
+	entry, err := GetDownload("q-3")
+	if err != nil || entry == nil {
+		t.Fatalf("GetDownload failed: %v", err)
+	}
+	if entry.Priority != 2 || entry.QueuePos != 1 {
+		t.Errorf("q-3 priority/pos = %d/%d, want 2/1", entry.Priority, entry.QueuePos)
+	}
+}
+
+func TestDownloadMetaPersistence(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	entry := types.DownloadEntry{
+		ID:       "meta-1",
+		URL:      "https://meta.com/file.iso",
+		DestPath: "/tmp/file.iso",
+		Status:   "queued",
+		DownloadMeta: types.DownloadMeta{
+			Tags:     []string{"linux", "iso"},
+			Note:     "for the lab machines",
+			Referrer: "https://meta.com/downloads",
+		},
+	}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	// A later update (e.g. on completion) writes what the download carries
+	entry.Status = "completed"
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList update failed: %v", err)
+	}
+
+	got, err := GetDownload("meta-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetDownload failed: %v", err)
+	}
+	if strings.Join(got.Tags, ",") != "linux,iso" || got.Note != "for the lab machines" || got.Referrer != "https://meta.com/downloads" {
+		t.Errorf("unexpected metadata: %+v", got.DownloadMeta)
+	}
+
+	all, err := ListAllDownloads()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("ListAllDownloads failed: %v (%d entries)", err, len(all))
+	}
+	if !all[0].HasTags([]string{"ISO"}) {
+		t.Errorf("expected listed entry to carry tag iso, got %v", all[0].Tags)
+	}
+
+	// Empty metadata clears it
+	entry.DownloadMeta = types.DownloadMeta{}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList clear failed: %v", err)
+	}
+	if got, _ := GetDownload("meta-1"); got == nil || !got.DownloadMeta.IsZero() {
+		t.Errorf("metadata after clearing = %+v, want none", got)
+	}
+}
+
+func TestAddToMasterList_Headers(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	entry := types.DownloadEntry{ID: "hdr", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "queued",
+		Headers: map[string]string{"Authorization": "Bearer x"}}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates that don't carry headers keep them
+	entry.Headers = nil
+	entry.Status = "completed"
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetDownload("hdr"); got == nil || got.Headers["Authorization"] != "Bearer x" {
+		t.Fatalf("headers after update = %+v, want them kept", got)
+	}
+
+	// An empty map clears them
+	entry.Headers = map[string]string{}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetDownload("hdr"); got == nil || len(got.Headers) != 0 {
+		t.Errorf("headers after clearing = %+v, want none", got)
+	}
+}
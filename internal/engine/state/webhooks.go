@@ -0,0 +1,103 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// EnqueueWebhookDelivery persists a webhook delivery so it survives restarts
+func EnqueueWebhookDelivery(d types.WebhookDelivery) (int64, error) {
+	db := getDBHelper()
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now().Unix()
+	if d.CreatedAt == 0 {
+		d.CreatedAt = now
+	}
+	if d.NextAttemptAt == 0 {
+		d.NextAttemptAt = now
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO webhook_deliveries (endpoint_url, endpoint_key, event, payload, attempts, next_attempt_at, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.EndpointURL, d.EndpointKey, d.Event, d.Payload, d.Attempts, d.NextAttemptAt, d.LastError, d.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// LoadDueWebhookDeliveries returns up to limit deliveries whose next attempt is due, oldest first
+func LoadDueWebhookDeliveries(now int64, limit int) ([]types.WebhookDelivery, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, endpoint_url, endpoint_key, event, payload, attempts, next_attempt_at, last_error, created_at
+		FROM webhook_deliveries
+		WHERE next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			utils.Debug("Error closing rows: %v", err)
+		}
+	}()
+
+	var deliveries []types.WebhookDelivery
+	for rows.Next() {
+		var d types.WebhookDelivery
+		var endpointKey, lastError sql.NullString
+		if err := rows.Scan(&d.ID, &d.EndpointURL, &endpointKey, &d.Event, &d.Payload, &d.Attempts, &d.NextAttemptAt, &lastError, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.EndpointKey = endpointKey.String
+		if lastError.Valid {
+			d.LastError = lastError.String
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RescheduleWebhookDelivery records a failed attempt and when to try again
+func RescheduleWebhookDelivery(id int64, attempts int, nextAttemptAt int64, lastError string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?
+	`, attempts, nextAttemptAt, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhookDelivery removes a delivered (or abandoned) webhook from the queue
+func DeleteWebhookDelivery(id int64) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec("DELETE FROM webhook_deliveries WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete webhook delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestWebhookDeliveryQueue(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	now := time.Now().Unix()
+
+	dueID, err := EnqueueWebhookDelivery(types.WebhookDelivery{
+		EndpointURL: "https://hooks.example.com/a",
+		Event:       "completed",
+		Payload:     []byte(`{"event":"completed"}`),
+	})
+	if err != nil {
+		t.Fatalf("EnqueueWebhookDelivery failed: %v", err)
+	}
+
+	if _, err := EnqueueWebhookDelivery(types.WebhookDelivery{
+		EndpointURL:   "https://hooks.example.com/b",
+		Event:         "error",
+		Payload:       []byte(`{"event":"error"}`),
+		NextAttemptAt: now + 3600,
+	}); err != nil {
+		t.Fatalf("EnqueueWebhookDelivery failed: %v", err)
+	}
+
+	due, err := LoadDueWebhookDeliveries(now, 10)
+	if err != nil {
+		t.Fatalf("LoadDueWebhookDeliveries failed: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != dueID {
+		t.Fatalf("expected only the due delivery, got %+v", due)
+	}
+	if string(due[0].Payload) != `{"event":"completed"}` || due[0].Event != "completed" {
+		t.Errorf("unexpected delivery contents: %+v", due[0])
+	}
+
+	// A failed attempt pushes it into the future
+	if err := RescheduleWebhookDelivery(dueID, 1, now+60, "HTTP 500"); err != nil {
+		t.Fatalf("RescheduleWebhookDelivery failed: %v", err)
+	}
+	due, _ = LoadDueWebhookDeliveries(now, 10)
+	if len(due) != 0 {
+		t.Fatalf("expected no due deliveries after reschedule, got %d", len(due))
+	}
+	due, _ = LoadDueWebhookDeliveries(now+60, 10)
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "HTTP 500" {
+		t.Fatalf("expected rescheduled delivery, got %+v", due)
+	}
+
+	if err := DeleteWebhookDelivery(dueID); err != nil {
+		t.Fatalf("DeleteWebhookDelivery failed: %v", err)
+	}
+	due, _ = LoadDueWebhookDeliveries(now+3600, 10)
+	if len(due) != 1 || due[0].EndpointURL != "https://hooks.example.com/b" {
+		t.Fatalf("expected only the remaining delivery, got %+v", due)
+	}
+}
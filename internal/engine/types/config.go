@@ -61,8 +61,25 @@ type DownloadConfig struct {
 	Runtime    *RuntimeConfig    // Dynamic settings from user config
 	Mirrors    []string          // List of mirror URLs (including primary)
 	Headers    map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
+	Refresher  URLRefresher      // Optional hook to renew expired URLs (nil = fail as before)
+	Router     CategoryRouter    // Optional: picks the directory by category when no explicit path was given
+	Category   string            // Category the download was routed by (set after probing)
+	Conflict   string            // Policy when a fresh download's file already exists (empty = rename)
+	Priority   int               // Queue priority; higher starts first, ties keep insertion order
+	StartAt    time.Time         // Hold the download back until this time (zero = start when a worker is free)
+	Group      string            // Download group the download belongs to, if any
+
+	// RuntimeUpdates delivers settings edits while the download runs. The
+	// worker pool creates it; the concurrent downloader applies them live.
+	RuntimeUpdates chan *RuntimeConfig
+
+	DownloadMeta // Tags, note and referrer kept with the download
 }
 
+// CategoryRouter picks a destination directory for a fresh download from its
+// probed metadata. ok is false when no rule applies.
+type CategoryRouter func(url, filename, contentType string) (category, dir string, ok bool)
+
 // RuntimeConfig holds dynamic settings that can override defaults
 type RuntimeConfig struct {
 	MaxConnectionsPerHost int
@@ -78,6 +95,9 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+
+	FilenameTemplate string
+	FilenameProfile  string
 }
 
 // GetUserAgent returns the configured user agent or the default
@@ -116,12 +136,20 @@ const (
 	MaxTaskRetries = 3
 	RetryBaseDelay = 200 * time.Millisecond
 
+	// URL refresh constants
+	MaxURLRefreshesPerTask = 2                // Refreshes a single task may trigger before failing normally
+	DefaultURLRefreshWait  = 60 * time.Second // How long to wait for a refreshed URL
+
 	// Health check constants
 	HealthCheckInterval = 1 * time.Second // How often to check worker health
 	SlowWorkerThreshold = 0.50            // Restart if speed < x times of mean
 	SlowWorkerGrace     = 5 * time.Second // Grace period before checking speed
 	StallTimeout        = 5 * time.Second // Restart if no data for x seconds
 	SpeedEMAAlpha       = 0.3             // EMA smoothing factor
+
+	// CheckpointInterval is how often a running download's progress is
+	// synced to disk and saved, bounding the work lost to a crash
+	CheckpointInterval = 5 * time.Second
 )
 
 // GetMaxTaskRetries returns configured value or default
@@ -163,3 +191,19 @@ func (r *RuntimeConfig) GetSpeedEmaAlpha() float64 {
 	}
 	return r.SpeedEmaAlpha
 }
+
+// GetFilenameTemplate returns the configured output filename template, empty if unset
+func (r *RuntimeConfig) GetFilenameTemplate() string {
+	if r == nil {
+		return ""
+	}
+	return r.FilenameTemplate
+}
+
+// GetFilenameProfile returns the configured sanitization profile or posix
+func (r *RuntimeConfig) GetFilenameProfile() string {
+	if r == nil || r.FilenameProfile == "" {
+		return "posix"
+	}
+	return r.FilenameProfile
+}
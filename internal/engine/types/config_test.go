@@ -129,6 +129,7 @@ func TestTimeoutConstants(t *testing.T) {
 		"KeepAliveDuration":            KeepAliveDuration,
 		"ProbeTimeout":                 ProbeTimeout,
 		"HealthCheckInterval":          HealthCheckInterval,
+		"CheckpointInterval":           CheckpointInterval,
 		"SlowWorkerGrace":              SlowWorkerGrace,
 		"StallTimeout":                 StallTimeout,
 		"RetryBaseDelay":               RetryBaseDelay,
@@ -1,8 +1,49 @@
 package types
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 // Common errors
 var (
-	ErrPaused = errors.New("download paused")
+	ErrPaused     = errors.New("download paused")
+	ErrNotFound   = errors.New("download not found")
+	ErrFileExists = errors.New("destination file already exists")
+	ErrNotQueued  = errors.New("download is not queued")
+	ErrNoGroup    = errors.New("no downloads in group")
+	ErrRunning    = errors.New("download is running")
+	ErrCompleted  = errors.New("download already completed")
+
+	// ErrSourceMismatch means a new URL for a download serves a different file
+	ErrSourceMismatch = errors.New("new URL serves a different file")
 )
+
+// StatusError reports an HTTP response with a status code the engine cannot use
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// IsRefreshable reports whether the status indicates an expired or revoked link
+// that a fresh URL (e.g. a re-signed S3/GCS link) might fix
+func (e *StatusError) IsRefreshable() bool {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// IsRefreshableError reports whether err wraps a StatusError worth refreshing the URL for
+func IsRefreshableError(err error) (int, bool) {
+	var se *StatusError
+	if errors.As(err, &se) && se.IsRefreshable() {
+		return se.StatusCode, true
+	}
+	return 0, false
+}
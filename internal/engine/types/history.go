@@ -0,0 +1,236 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// History sort keys for HistoryFilter.Sort
+const (
+	HistorySortDate     = "date" // When it finished (default)
+	HistorySortSize     = "size"
+	HistorySortName     = "name"
+	HistorySortSpeed    = "speed"
+	HistorySortDuration = "duration"
+)
+
+// IsValidHistorySort reports whether key is a known history sort key
+func IsValidHistorySort(key string) bool {
+	switch key {
+	case "", HistorySortDate, HistorySortSize, HistorySortName, HistorySortSpeed, HistorySortDuration:
+		return true
+	}
+	return false
+}
+
+// HistoryFilter selects and orders finished downloads. Zero fields don't
+// filter; an empty Status means completed downloads only.
+type HistoryFilter struct {
+	Status   []string // "completed" and/or "error"
+	Since    int64    // Finished at or after (Unix timestamp)
+	Until    int64    // Finished before (Unix timestamp)
+	Host     string   // Source host; subdomains match too
+	MinSize  int64    // Bytes
+	MaxSize  int64    // Bytes
+	Filename string   // Glob such as "*.iso"
+
+	Sort    string // One of the HistorySort keys; date sorts newest first
+	Reverse bool   // Reverse the sort order
+
+	Limit  int // Page size (0 = everything)
+	Offset int // Entries to skip
+}
+
+// Statuses returns the statuses the filter selects
+func (f HistoryFilter) Statuses() []string {
+	if len(f.Status) == 0 {
+		return []string{"completed"}
+	}
+	return f.Status
+}
+
+// Values encodes the filter as URL query parameters
+func (f HistoryFilter) Values() url.Values {
+	v := url.Values{}
+	if len(f.Status) > 0 {
+		v.Set("status", strings.Join(f.Status, ","))
+	}
+	setInt := func(key string, n int64) {
+		if n != 0 {
+			v.Set(key, strconv.FormatInt(n, 10))
+		}
+	}
+	setInt("since", f.Since)
+	setInt("until", f.Until)
+	setInt("min_size", f.MinSize)
+	setInt("max_size", f.MaxSize)
+	setInt("limit", int64(f.Limit))
+	setInt("offset", int64(f.Offset))
+	if f.Host != "" {
+		v.Set("host", f.Host)
+	}
+	if f.Filename != "" {
+		v.Set("filename", f.Filename)
+	}
+	if f.Sort != "" {
+		v.Set("sort", f.Sort)
+	}
+	if f.Reverse {
+		v.Set("reverse", "true")
+	}
+	return v
+}
+
+// ParseHistoryFilter decodes query parameters written by Values
+func ParseHistoryFilter(v url.Values) (HistoryFilter, error) {
+	var f HistoryFilter
+	if s := v.Get("status"); s != "" {
+		for _, status := range strings.Split(s, ",") {
+			status = strings.TrimSpace(status)
+			if status != "completed" && status != "error" {
+				return f, fmt.Errorf("invalid status %q (want completed or error)", status)
+			}
+			f.Status = append(f.Status, status)
+		}
+	}
+
+	ints := []struct {
+		key string
+		dst *int64
+	}{
+		{"since", &f.Since}, {"until", &f.Until}, {"min_size", &f.MinSize}, {"max_size", &f.MaxSize},
+	}
+	for _, p := range ints {
+		if s := v.Get(p.key); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				return f, fmt.Errorf("invalid %s %q", p.key, s)
+			}
+			*p.dst = n
+		}
+	}
+	for key, dst := range map[string]*int{"limit": &f.Limit, "offset": &f.Offset} {
+		if s := v.Get(key); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return f, fmt.Errorf("invalid %s %q", key, s)
+			}
+			*dst = n
+		}
+	}
+
+	f.Host = v.Get("host")
+	f.Filename = v.Get("filename")
+	f.Sort = v.Get("sort")
+	if !IsValidHistorySort(f.Sort) {
+		return f, fmt.Errorf("invalid sort %q", f.Sort)
+	}
+	if s := v.Get("reverse"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return f, fmt.Errorf("invalid reverse %q", s)
+		}
+		f.Reverse = b
+	}
+	return f, nil
+}
+
+// Host returns the lower-case host name of the download's URL
+func (e DownloadEntry) Host() string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// Speed returns the average speed of a completed download in bytes per
+// second, or 0 if unknown
+func (e DownloadEntry) Speed() float64 {
+	if e.TimeTaken <= 0 {
+		return 0
+	}
+	return float64(e.Downloaded) / (float64(e.TimeTaken) / 1000)
+}
+
+// HostStats summarizes the downloads from one host
+type HostStats struct {
+	Host      string `json:"host"`
+	Downloads int    `json:"downloads"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// HistoryStats aggregates a set of finished downloads
+type HistoryStats struct {
+	Downloads   int         `json:"downloads"`
+	Completed   int         `json:"completed"`
+	Failed      int         `json:"failed"`
+	TotalBytes  int64       `json:"total_bytes"`   // Bytes of completed downloads
+	TotalTime   int64       `json:"total_time_ms"` // Time spent on completed downloads
+	AvgSpeed    float64     `json:"avg_speed"`     // Bytes per second across completed downloads
+	FailureRate float64     `json:"failure_rate"`  // Failed / (completed + failed), 0-1
+	TopHosts    []HostStats `json:"top_hosts,omitempty"`
+}
+
+// MaxTopHosts is how many hosts HistoryStats lists
+const MaxTopHosts = 5
+
+// NewHistoryStats aggregates entries. Hosts are ranked by number of
+// downloads, then bytes.
+func NewHistoryStats(entries []DownloadEntry) HistoryStats {
+	var st HistoryStats
+	hosts := make(map[string]*HostStats)
+	for _, e := range entries {
+		st.Downloads++
+		switch e.Status {
+		case "completed":
+			st.Completed++
+			st.TotalBytes += e.Downloaded
+			st.TotalTime += e.TimeTaken
+		case "error":
+			st.Failed++
+		}
+
+		host := e.Host()
+		if host == "" {
+			continue
+		}
+		h, ok := hosts[host]
+		if !ok {
+			h = &HostStats{Host: host}
+			hosts[host] = h
+		}
+		h.Downloads++
+		if e.Status == "completed" {
+			h.Bytes += e.Downloaded
+		}
+	}
+
+	if st.TotalTime > 0 {
+		st.AvgSpeed = float64(st.TotalBytes) / (float64(st.TotalTime) / 1000)
+	}
+	if finished := st.Completed + st.Failed; finished > 0 {
+		st.FailureRate = float64(st.Failed) / float64(finished)
+	}
+
+	for _, h := range hosts {
+		st.TopHosts = append(st.TopHosts, *h)
+	}
+	sort.Slice(st.TopHosts, func(i, j int) bool {
+		a, b := st.TopHosts[i], st.TopHosts[j]
+		if a.Downloads != b.Downloads {
+			return a.Downloads > b.Downloads
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Host < b.Host
+	})
+	if len(st.TopHosts) > MaxTopHosts {
+		st.TopHosts = st.TopHosts[:MaxTopHosts]
+	}
+	return st
+}
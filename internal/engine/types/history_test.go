@@ -0,0 +1,59 @@
+package types
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestHistoryFilter_ValuesRoundTrip(t *testing.T) {
+	f := HistoryFilter{
+		Status: []string{"completed", "error"}, Since: 100, Until: 200, Host: "example.com",
+		MinSize: 1, MaxSize: 2, Filename: "*.iso", Sort: HistorySortSize, Reverse: true, Limit: 10, Offset: 20,
+	}
+	got, err := ParseHistoryFilter(f.Values())
+	if err != nil {
+		t.Fatalf("ParseHistoryFilter failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, f) {
+		t.Errorf("round trip = %+v, want %+v", got, f)
+	}
+
+	empty, err := ParseHistoryFilter(url.Values{})
+	if err != nil || !reflect.DeepEqual(empty, HistoryFilter{}) {
+		t.Errorf("empty query = %+v, %v", empty, err)
+	}
+}
+
+func TestParseHistoryFilter_Invalid(t *testing.T) {
+	for _, q := range []string{"status=running", "since=yesterday", "limit=-1", "sort=color", "reverse=maybe"} {
+		v, _ := url.ParseQuery(q)
+		if _, err := ParseHistoryFilter(v); err == nil {
+			t.Errorf("ParseHistoryFilter(%q) succeeded, want error", q)
+		}
+	}
+}
+
+func TestNewHistoryStats(t *testing.T) {
+	entries := []DownloadEntry{
+		{URL: "https://a.com/1", Status: "completed", Downloaded: 3000, TimeTaken: 1000},
+		{URL: "https://a.com/2", Status: "completed", Downloaded: 1000, TimeTaken: 1000},
+		{URL: "https://b.com/3", Status: "completed", Downloaded: 8000, TimeTaken: 2000},
+		{URL: "https://c.com/4", Status: "error", Downloaded: 500},
+	}
+	st := NewHistoryStats(entries)
+
+	if st.Downloads != 4 || st.Completed != 3 || st.Failed != 1 {
+		t.Errorf("counts = %+v", st)
+	}
+	if st.TotalBytes != 12000 || st.TotalTime != 4000 || st.AvgSpeed != 3000 {
+		t.Errorf("totals = %d bytes, %d ms, %v B/s", st.TotalBytes, st.TotalTime, st.AvgSpeed)
+	}
+	if st.FailureRate != 0.25 {
+		t.Errorf("FailureRate = %v, want 0.25", st.FailureRate)
+	}
+	want := []HostStats{{"a.com", 2, 4000}, {"b.com", 1, 8000}, {"c.com", 1, 0}}
+	if !reflect.DeepEqual(st.TopHosts, want) {
+		t.Errorf("TopHosts = %+v, want %+v", st.TopHosts, want)
+	}
+}
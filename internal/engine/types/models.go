@@ -1,5 +1,10 @@
 package types
 
+import (
+	"sort"
+	"strings"
+)
+
 // Task represents a byte range to download
 type Task struct {
 	Offset int64 `json:"offset"`
@@ -36,9 +41,21 @@ type DownloadEntry struct {
 	Status      string   `json:"status"`       // "paused", "completed", "error"
 	TotalSize   int64    `json:"total_size"`   // File size in bytes
 	Downloaded  int64    `json:"downloaded"`   // Bytes downloaded
-	CompletedAt int64    `json:"completed_at"` // Unix timestamp when completed
+	CompletedAt int64    `json:"completed_at"` // Unix timestamp when completed (or failed)
 	TimeTaken   int64    `json:"time_taken"`   // Duration in milliseconds (for completed)
 	Mirrors     []string `json:"mirrors,omitempty"`
+	Category    string   `json:"category,omitempty"` // Matched category rule, if any
+	ETag        string   `json:"etag,omitempty"`     // Server ETag of the completed file
+	Priority    int      `json:"priority,omitempty"` // Queue priority, higher starts first
+	QueuePos    int      `json:"queue_pos,omitempty"`
+	StartAt     int64    `json:"start_at,omitempty"` // Unix timestamp a scheduled download starts at
+	Group       string   `json:"group,omitempty"`    // Download group (batch file, --group), if any
+
+	// Custom request headers (cookies, auth). Kept out of JSON so history
+	// listings don't expose them.
+	Headers map[string]string `json:"-"`
+
+	DownloadMeta
 }
 
 // MasterList holds all tracked downloads
@@ -56,9 +73,222 @@ type DownloadStatus struct {
 	Downloaded  int64   `json:"downloaded"`
 	Progress    float64 `json:"progress"` // Percentage 0-100
 	Speed       float64 `json:"speed"`    // MB/s
-	Status      string  `json:"status"`   // "queued", "paused", "downloading", "completed", "error"
+	Status      string  `json:"status"`   // "scheduled", "queued", "paused", "downloading", "completed", "error"
 	Error       string  `json:"error,omitempty"`
 	ETA         int64   `json:"eta"`         // Estimated seconds remaining
 	Connections int     `json:"connections"` // Active connections
 	AddedAt     int64   `json:"added_at"`    // Unix timestamp when added
+	Category    string  `json:"category,omitempty"`
+
+	Priority      int `json:"priority,omitempty"`       // Queue priority, higher starts first
+	QueuePosition int `json:"queue_position,omitempty"` // 1-based place in the queue while waiting
+
+	StartAt int64  `json:"start_at,omitempty"` // Unix timestamp a scheduled download starts at
+	Group   string `json:"group,omitempty"`    // Download group, if any
+
+	DownloadMeta
+}
+
+// DownloadHooks are hook commands set for a single download, overriding the
+// hooks settings. Empty fields fall back to the settings.
+type DownloadHooks struct {
+	OnComplete string `json:"on_complete,omitempty"`
+	OnError    string `json:"on_error,omitempty"`
+	OnPause    string `json:"on_pause,omitempty"`
+}
+
+// IsZero reports whether no hook is set
+func (h DownloadHooks) IsZero() bool {
+	return h.OnComplete == "" && h.OnError == "" && h.OnPause == ""
+}
+
+// DownloadMeta is user-supplied context kept with a download so it can be
+// found and understood later
+type DownloadMeta struct {
+	Tags     []string `json:"tags,omitempty"`
+	Note     string   `json:"note,omitempty"`     // Free-text note
+	Referrer string   `json:"referrer,omitempty"` // Page the download was started from
+}
+
+// IsZero reports whether no metadata is set
+func (m DownloadMeta) IsZero() bool {
+	return len(m.Tags) == 0 && m.Note == "" && m.Referrer == ""
+}
+
+// HasTags reports whether the download carries every one of tags, ignoring case
+func (m DownloadMeta) HasTags(tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, t := range m.Tags {
+			if strings.EqualFold(t, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchText returns the metadata as one string for free-text filtering
+func (m DownloadMeta) SearchText() string {
+	return strings.Join(append(append([]string{}, m.Tags...), m.Note, m.Referrer), " ")
+}
+
+// NormalizeTags splits comma-separated tags, trims and lowercases them, and
+// drops empties and duplicates while keeping the original order
+func NormalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, raw := range tags {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" || seen[t] {
+				continue
+			}
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// GroupStatus is the combined progress of all downloads sharing a group name
+type GroupStatus struct {
+	Name       string  `json:"name"`
+	Count      int     `json:"count"` // Downloads in the group
+	Completed  int     `json:"completed"`
+	Active     int     `json:"active"`
+	Queued     int     `json:"queued"` // Queued or scheduled
+	Paused     int     `json:"paused"`
+	Failed     int     `json:"failed"`
+	TotalSize  int64   `json:"total_size"`
+	Downloaded int64   `json:"downloaded"`
+	Progress   float64 `json:"progress"` // Percentage 0-100 of the known total size
+	Speed      float64 `json:"speed"`    // MB/s
+	ETA        int64   `json:"eta"`      // Estimated seconds remaining
+	Status     string  `json:"status"`   // "downloading", "queued", "paused", "error" or "completed"
+}
+
+// Add counts one member download into the group totals
+func (g *GroupStatus) Add(s DownloadStatus) {
+	g.Count++
+	g.TotalSize += s.TotalSize
+	g.Downloaded += s.Downloaded
+	g.Speed += s.Speed
+
+	switch s.Status {
+	case "completed", "extracting":
+		g.Completed++
+	case "queued", "scheduled":
+		g.Queued++
+	case "paused", "pausing":
+		g.Paused++
+	case "error":
+		g.Failed++
+	default:
+		g.Active++
+	}
+
+	if g.TotalSize > 0 {
+		g.Progress = float64(g.Downloaded) * 100 / float64(g.TotalSize)
+	}
+	g.ETA = 0
+	if remaining := g.TotalSize - g.Downloaded; remaining > 0 && g.Speed > 0 {
+		g.ETA = int64(float64(remaining) / (g.Speed * 1024 * 1024))
+	}
+
+	switch {
+	case g.Active > 0:
+		g.Status = "downloading"
+	case g.Queued > 0:
+		g.Status = "queued"
+	case g.Paused > 0:
+		g.Status = "paused"
+	case g.Failed > 0:
+		g.Status = "error"
+	default:
+		g.Status = "completed"
+	}
+}
+
+// SummarizeGroups folds download statuses into per-group totals, ordered by name.
+// Downloads without a group are skipped.
+func SummarizeGroups(statuses []DownloadStatus) []GroupStatus {
+	byName := make(map[string]*GroupStatus)
+	var names []string
+	for _, s := range statuses {
+		if s.Group == "" {
+			continue
+		}
+		g, ok := byName[s.Group]
+		if !ok {
+			g = &GroupStatus{Name: s.Group}
+			byName[s.Group] = g
+			names = append(names, s.Group)
+		}
+		g.Add(s)
+	}
+
+	sort.Strings(names)
+	groups := make([]GroupStatus, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, *byName[name])
+	}
+	return groups
+}
+
+// Group operations for DownloadService.GroupAction
+const (
+	GroupPause  = "pause"
+	GroupResume = "resume"
+	GroupDelete = "delete"
+	GroupRetry  = "retry"
+)
+
+// IsValidGroupAction reports whether action is a known group operation
+func IsValidGroupAction(action string) bool {
+	switch action {
+	case GroupPause, GroupResume, GroupDelete, GroupRetry:
+		return true
+	}
+	return false
+}
+
+// QueuePosition is a waiting download's persisted place in the queue
+type QueuePosition struct {
+	ID       string
+	Priority int
+}
+
+// Queue reorder operations for WorkerPool.Move
+const (
+	QueueMoveTop    = "top"
+	QueueMoveUp     = "up"
+	QueueMoveDown   = "down"
+	QueueMoveBottom = "bottom"
+)
+
+// IsValidQueueMove reports whether op is a known queue reorder operation
+func IsValidQueueMove(op string) bool {
+	switch op {
+	case QueueMoveTop, QueueMoveUp, QueueMoveDown, QueueMoveBottom:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is a queued webhook POST waiting to be delivered
+type WebhookDelivery struct {
+	ID            int64  `json:"id"`
+	EndpointURL   string `json:"endpoint_url"`
+	EndpointKey   string `json:"endpoint_key"` // Identifies the endpoint among those sharing a URL
+	Event         string `json:"event"`
+	Payload       []byte `json:"payload"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"next_attempt_at"` // Unix timestamp
+	LastError     string `json:"last_error,omitempty"`
+	CreatedAt     int64  `json:"created_at"` // Unix timestamp
 }
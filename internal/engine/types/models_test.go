@@ -0,0 +1,78 @@
+package types
+
+import "testing"
+
+func TestSummarizeGroups(t *testing.T) {
+	statuses := []DownloadStatus{
+		{ID: "1", Group: "isos", Status: "completed", TotalSize: 100, Downloaded: 100},
+		{ID: "2", Group: "isos", Status: "downloading", TotalSize: 300, Downloaded: 100, Speed: 2},
+		{ID: "3", Group: "isos", Status: "queued", TotalSize: 100},
+		{ID: "4", Status: "downloading"},
+		{ID: "5", Group: "docs", Status: "paused", TotalSize: 10, Downloaded: 5},
+		{ID: "6", Group: "docs", Status: "error"},
+	}
+
+	groups := SummarizeGroups(statuses)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	docs, isos := groups[0], groups[1]
+	if docs.Name != "docs" || isos.Name != "isos" {
+		t.Fatalf("groups not sorted by name: %q, %q", docs.Name, isos.Name)
+	}
+
+	if isos.Count != 3 || isos.Completed != 1 || isos.Active != 1 || isos.Queued != 1 {
+		t.Errorf("unexpected isos counts: %+v", isos)
+	}
+	if isos.TotalSize != 500 || isos.Downloaded != 200 || isos.Progress != 40 {
+		t.Errorf("unexpected isos progress: %+v", isos)
+	}
+	if isos.Status != "downloading" || isos.Speed != 2 {
+		t.Errorf("unexpected isos status/speed: %+v", isos)
+	}
+
+	if docs.Paused != 1 || docs.Failed != 1 || docs.Status != "paused" {
+		t.Errorf("unexpected docs summary: %+v", docs)
+	}
+}
+
+func TestGroupStatus_CompletedWhenAllDone(t *testing.T) {
+	var g GroupStatus
+	g.Add(DownloadStatus{Status: "completed", TotalSize: 10, Downloaded: 10})
+	g.Add(DownloadStatus{Status: "extracting", TotalSize: 10, Downloaded: 10})
+
+	if g.Status != "completed" || g.Progress != 100 || g.ETA != 0 {
+		t.Errorf("unexpected summary: %+v", g)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Linux, iso", "ISO", "", "work,,"})
+	want := []string{"linux", "iso", "work"}
+	if len(got) != len(want) {
+		t.Fatalf("NormalizeTags = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("NormalizeTags = %v, want %v", got, want)
+		}
+	}
+	if NormalizeTags(nil) != nil {
+		t.Error("expected nil for no tags")
+	}
+}
+
+func TestDownloadMeta_HasTags(t *testing.T) {
+	m := DownloadMeta{Tags: []string{"linux", "iso"}}
+
+	if !m.HasTags(nil) {
+		t.Error("no wanted tags should always match")
+	}
+	if !m.HasTags([]string{"ISO", "linux"}) {
+		t.Error("expected case-insensitive match on all tags")
+	}
+	if m.HasTags([]string{"linux", "work"}) {
+		t.Error("expected no match when one tag is missing")
+	}
+}
@@ -0,0 +1,21 @@
+package types
+
+import "context"
+
+// RefreshRequest describes a download whose URL was rejected by the server
+type RefreshRequest struct {
+	ID         string
+	URL        string // URL that was rejected
+	DestPath   string
+	StatusCode int // 401, 403 or 410
+}
+
+// RefreshResult carries a replacement URL and optional headers.
+// Headers are merged over the download's existing headers.
+type RefreshResult struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// URLRefresher obtains a fresh URL for a download whose link expired mid-transfer
+type URLRefresher func(ctx context.Context, req RefreshRequest) (*RefreshResult, error)
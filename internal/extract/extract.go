@@ -0,0 +1,528 @@
+// Package extract unpacks downloaded archives (zip, tar, tar.gz, tar.xz, tar.zst).
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/matchers"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format identifies a supported archive format
+type Format string
+
+const (
+	FormatNone   Format = ""
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarXz  Format = "tar.xz"
+	FormatTarZst Format = "tar.zst"
+)
+
+// sniffLen is enough to cover the tar magic at offset 257
+const sniffLen = 512
+
+// ErrUnsafePath is returned for entries that would be written outside the destination (zip-slip)
+var ErrUnsafePath = errors.New("archive entry escapes destination directory")
+
+// ErrTooLarge is returned when an archive unpacks to more than the limits allow (a decompression bomb)
+var ErrTooLarge = errors.New("archive exceeds extraction limits")
+
+// Extraction limits. An archive may unpack to maxExpansion times its own
+// size, but never less than minSizeLimit, and hold at most maxEntries entries.
+var (
+	maxExpansion int64 = 100
+	minSizeLimit int64 = 1 << 30
+	maxEntries         = 100_000
+)
+
+// ProgressFunc is called as extraction proceeds with bytes processed so far and the total
+type ProgressFunc func(done, total int64)
+
+// Detect sniffs the file content and returns its archive format, or FormatNone.
+// Compressed files only count as archives when they contain a tar stream.
+func Detect(archivePath string) (Format, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return FormatNone, err
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return FormatNone, err
+	}
+	header = header[:n]
+
+	kind, _ := filetype.Match(header)
+	var wrapped Format
+	switch kind {
+	case matchers.TypeZip:
+		return FormatZip, nil
+	case matchers.TypeTar:
+		return FormatTar, nil
+	case matchers.TypeGz:
+		wrapped = FormatTarGz
+	case matchers.TypeXz:
+		wrapped = FormatTarXz
+	case matchers.TypeZstd:
+		wrapped = FormatTarZst
+	default:
+		return FormatNone, nil
+	}
+
+	// Peek inside the compressed stream for a tar header
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return FormatNone, err
+	}
+	r, closeFn, err := decompressor(f, wrapped)
+	if err != nil {
+		return FormatNone, nil // Corrupt or unsupported stream - not something we can extract
+	}
+	defer closeFn()
+
+	inner := make([]byte, sniffLen)
+	n, _ = io.ReadFull(r, inner)
+	if matchers.Tar(inner[:n]) {
+		return wrapped, nil
+	}
+	return FormatNone, nil
+}
+
+// DestDir returns a sibling directory for archivePath named after the archive
+// without its extension, adding " (n)" if that name is already taken.
+func DestDir(archivePath string) string {
+	dir := filepath.Dir(archivePath)
+	base := filepath.Base(archivePath)
+	lower := strings.ToLower(base)
+
+	name := base
+	for _, ext := range []string{".tar.gz", ".tar.xz", ".tar.zst", ".tgz", ".txz", ".tzst", ".tar", ".zip", ".gz", ".xz", ".zst"} {
+		if strings.HasSuffix(lower, ext) && len(base) > len(ext) {
+			name = base[:len(base)-len(ext)]
+			break
+		}
+	}
+	if name == base {
+		name = base + "_extracted"
+	}
+
+	candidate := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = filepath.Join(dir, fmt.Sprintf("%s (%d)", name, i))
+	}
+}
+
+// Extract unpacks archivePath into destDir and returns the number of entries written.
+// If extraction fails, a destDir created by this call is removed again.
+func Extract(ctx context.Context, archivePath, destDir string, format Format, progress ProgressFunc) (int, error) {
+	if progress == nil {
+		progress = func(int64, int64) {}
+	}
+
+	_, statErr := os.Stat(destDir)
+	created := os.IsNotExist(statErr)
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", destDir, err)
+	}
+	root, err := filepath.Abs(destDir)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	lim := newLimits(info.Size())
+
+	var count int
+	switch format {
+	case FormatZip:
+		count, err = extractZip(ctx, archivePath, root, lim, progress)
+	case FormatTar, FormatTarGz, FormatTarXz, FormatTarZst:
+		count, err = extractTar(ctx, archivePath, root, format, lim, progress)
+	default:
+		err = fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	if err != nil && created {
+		_ = os.RemoveAll(destDir)
+	}
+	return count, err
+}
+
+func extractZip(ctx context.Context, archivePath, root string, lim *limits, progress ProgressFunc) (int, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	// The directory states the sizes up front, so most bombs are refused
+	// before anything is written; the reads below catch sizes that lie
+	if len(zr.File) > maxEntries {
+		return 0, fmt.Errorf("%w: more than %d entries", ErrTooLarge, maxEntries)
+	}
+	var total int64
+	for _, f := range zr.File {
+		if f.UncompressedSize64 > uint64(lim.bytesLeft-total) {
+			return 0, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, lim.maxBytes)
+		}
+		total += int64(f.UncompressedSize64)
+	}
+
+	var done int64
+	count := 0
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		if err := lim.entry(); err != nil {
+			return count, err
+		}
+
+		target, err := safeJoin(root, f.Name)
+		if err != nil {
+			return count, err
+		}
+		mode := f.Mode()
+
+		switch {
+		case mode.IsDir() || strings.HasSuffix(f.Name, "/"):
+			if err := mkdirSafe(root, target); err != nil {
+				return count, err
+			}
+		case mode&os.ModeSymlink != 0:
+			linkTarget, err := readZipEntry(f)
+			if err != nil {
+				return count, err
+			}
+			if err := symlinkSafe(root, target, linkTarget); err != nil {
+				return count, err
+			}
+		default:
+			rc, err := f.Open()
+			if err != nil {
+				return count, err
+			}
+			n, err := writeFile(ctx, root, target, lim.reader(rc), mode.Perm())
+			_ = rc.Close()
+			if err != nil {
+				return count, err
+			}
+			done += n
+			progress(done, total)
+		}
+		count++
+	}
+	progress(total, total)
+	return count, nil
+}
+
+func readZipEntry(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+	data, err := io.ReadAll(io.LimitReader(rc, 4096))
+	return string(data), err
+}
+
+func extractTar(ctx context.Context, archivePath, root string, format Format, lim *limits, progress ProgressFunc) (int, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	total := info.Size()
+
+	// Progress follows how much of the (possibly compressed) archive has been consumed
+	counter := &countingReader{r: f}
+	var src io.Reader = counter
+	if format != FormatTar {
+		r, closeFn, err := decompressor(counter, format)
+		if err != nil {
+			return 0, err
+		}
+		defer closeFn()
+		src = r
+	}
+
+	tr := tar.NewReader(src)
+	count := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := lim.entry(); err != nil {
+			return count, err
+		}
+
+		target, err := safeJoin(root, hdr.Name)
+		if err != nil {
+			return count, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirSafe(root, target); err != nil {
+				return count, err
+			}
+		case tar.TypeReg:
+			if _, err := writeFile(ctx, root, target, lim.reader(tr), hdr.FileInfo().Mode().Perm()); err != nil {
+				return count, err
+			}
+		case tar.TypeSymlink:
+			if err := symlinkSafe(root, target, hdr.Linkname); err != nil {
+				return count, err
+			}
+		case tar.TypeLink:
+			source, err := safeJoin(root, hdr.Linkname)
+			if err != nil {
+				return count, err
+			}
+			if err := checkParents(root, target); err != nil {
+				return count, err
+			}
+			if err := checkParents(root, source); err != nil {
+				return count, err
+			}
+			if err := os.Link(source, target); err != nil {
+				return count, err
+			}
+		default:
+			// Devices, FIFOs and the like are skipped
+			continue
+		}
+		count++
+		progress(counter.n, total)
+	}
+	progress(total, total)
+	return count, nil
+}
+
+// decompressor wraps r with the decompressor for a compressed tar format
+func decompressor(r io.Reader, format Format) (io.Reader, func(), error) {
+	switch format {
+	case FormatTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, func() { _ = gz.Close() }, nil
+	case FormatTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return xr, func() {}, nil
+	case FormatTarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported compression %q", format)
+}
+
+// safeJoin resolves an entry name under root, rejecting absolute paths and ".." escapes
+func safeJoin(root, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	target := filepath.Join(root, filepath.FromSlash(name))
+	if !within(root, target) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	return target, nil
+}
+
+// within reports whether target is root or lies below it
+func within(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// checkParents refuses to write through a symlink created by an earlier entry
+func checkParents(root, target string) error {
+	return checkNoSymlinks(root, filepath.Dir(target))
+}
+
+// checkNoSymlinks verifies no existing component of dir below root is a symlink
+func checkNoSymlinks(root, dir string) error {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return err
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q is a symlink", ErrUnsafePath, current)
+		}
+	}
+	return nil
+}
+
+func mkdirSafe(root, dir string) error {
+	if err := checkNoSymlinks(root, dir); err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// symlinkSafe creates a relative symlink whose target stays inside root
+func symlinkSafe(root, target, linkname string) error {
+	linkname = filepath.FromSlash(strings.ReplaceAll(linkname, "\\", "/"))
+	if linkname == "" || filepath.IsAbs(linkname) || filepath.VolumeName(linkname) != "" {
+		return fmt.Errorf("%w: symlink %q -> %q", ErrUnsafePath, target, linkname)
+	}
+	if !within(root, filepath.Join(filepath.Dir(target), linkname)) {
+		return fmt.Errorf("%w: symlink %q -> %q", ErrUnsafePath, target, linkname)
+	}
+	if err := mkdirSafe(root, filepath.Dir(target)); err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
+
+// writeFile copies r to a new file at target
+func writeFile(ctx context.Context, root, target string, r io.Reader, perm os.FileMode) (int64, error) {
+	if err := mkdirSafe(root, filepath.Dir(target)); err != nil {
+		return 0, err
+	}
+	if perm == 0 {
+		perm = 0o644
+	}
+	// Never follow a symlink left by an earlier entry with the same name
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return 0, err
+		}
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0o200)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, &contextReader{ctx: ctx, r: r})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// limits tracks what an archive may still unpack before hitting the extraction limits
+type limits struct {
+	maxBytes    int64
+	bytesLeft   int64
+	entriesLeft int
+}
+
+func newLimits(archiveSize int64) *limits {
+	maxBytes := minSizeLimit
+	if archiveSize > maxBytes/maxExpansion {
+		maxBytes = archiveSize * maxExpansion
+	}
+	return &limits{maxBytes: maxBytes, bytesLeft: maxBytes, entriesLeft: maxEntries}
+}
+
+// entry counts one more archive entry
+func (l *limits) entry() error {
+	if l.entriesLeft <= 0 {
+		return fmt.Errorf("%w: more than %d entries", ErrTooLarge, maxEntries)
+	}
+	l.entriesLeft--
+	return nil
+}
+
+// reader counts the bytes read from r against the size limit
+func (l *limits) reader(r io.Reader) io.Reader {
+	return &limitedReader{r: r, l: l}
+}
+
+// limitedReader fails once more bytes have been read than the limits allow
+type limitedReader struct {
+	r io.Reader
+	l *limits
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	// Read one byte past the limit so an archive ending exactly on it still extracts
+	if int64(len(p)) > lr.l.bytesLeft+1 {
+		p = p[:lr.l.bytesLeft+1]
+	}
+	n, err := lr.r.Read(p)
+	lr.l.bytesLeft -= int64(n)
+	if lr.l.bytesLeft < 0 {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, lr.l.maxBytes)
+	}
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// contextReader stops long copies when the context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,298 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type entry struct {
+	name     string
+	body     string
+	typeflag byte
+	linkname string
+}
+
+func buildTar(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: e.typeflag, Linkname: e.linkname}
+		switch e.typeflag {
+		case 0:
+			hdr.Typeflag = tar.TypeReg
+		case tar.TypeDir:
+			hdr.Mode = 0o755
+			hdr.Size = 0
+		case tar.TypeSymlink, tar.TypeLink:
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func compress(t *testing.T, data []byte, format Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch format {
+	case FormatTar:
+		return data
+	case FormatTarGz:
+		w = gzip.NewWriter(&buf)
+	case FormatTarXz:
+		w, err = xz.NewWriter(&buf)
+	case FormatTarZst:
+		w, err = zstd.NewWriter(&buf)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDetectAndExtract_AllFormats(t *testing.T) {
+	entries := []entry{
+		{name: "docs/", typeflag: tar.TypeDir},
+		{name: "docs/readme.txt", body: "hello"},
+		{name: "bin/tool", body: "binary"},
+	}
+
+	cases := map[Format]string{
+		FormatTar:    "pkg.tar",
+		FormatTarGz:  "pkg.tar.gz",
+		FormatTarXz:  "pkg.tar.xz",
+		FormatTarZst: "pkg.tar.zst",
+		FormatZip:    "pkg.zip",
+	}
+
+	for format, name := range cases {
+		t.Run(string(format), func(t *testing.T) {
+			var data []byte
+			if format == FormatZip {
+				data = buildZip(t, entries)
+			} else {
+				data = compress(t, buildTar(t, entries), format)
+			}
+			archive := writeArchive(t, name, data)
+
+			got, err := Detect(archive)
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			if got != format {
+				t.Fatalf("Detect = %q, want %q", got, format)
+			}
+
+			dest := DestDir(archive)
+			if filepath.Base(dest) != "pkg" {
+				t.Errorf("DestDir = %q, want sibling named pkg", dest)
+			}
+
+			var lastDone, lastTotal int64
+			count, err := Extract(context.Background(), archive, dest, format, func(done, total int64) {
+				lastDone, lastTotal = done, total
+			})
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if count != 3 {
+				t.Errorf("extracted %d entries, want 3", count)
+			}
+			if lastDone != lastTotal || lastTotal == 0 {
+				t.Errorf("final progress %d/%d, want complete", lastDone, lastTotal)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dest, "docs", "readme.txt"))
+			if err != nil || string(content) != "hello" {
+				t.Errorf("readme.txt = %q, %v", content, err)
+			}
+		})
+	}
+}
+
+func TestDetect_NonArchives(t *testing.T) {
+	plain := writeArchive(t, "notes.txt", []byte("just text"))
+	if f, err := Detect(plain); err != nil || f != FormatNone {
+		t.Errorf("Detect(text) = %q, %v", f, err)
+	}
+
+	// A gzip that does not wrap a tar (e.g. a .gz log) is not extracted
+	gz := writeArchive(t, "log.gz", compress(t, []byte("line one\nline two\n"), FormatTarGz))
+	if f, err := Detect(gz); err != nil || f != FormatNone {
+		t.Errorf("Detect(plain gzip) = %q, %v", f, err)
+	}
+}
+
+func TestExtract_ZipSlipRejected(t *testing.T) {
+	parent := t.TempDir()
+	archive := filepath.Join(parent, "evil.zip")
+	if err := os.WriteFile(archive, buildZip(t, []entry{{name: "../escaped.txt", body: "pwned"}}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := DestDir(archive)
+	_, err := Extract(context.Background(), archive, dest, FormatZip, nil)
+	if !errors.Is(err, ErrUnsafePath) {
+		t.Fatalf("expected ErrUnsafePath, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escaped.txt")); !os.IsNotExist(err) {
+		t.Error("file escaped the destination directory")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("partially extracted directory was not cleaned up")
+	}
+}
+
+func TestExtract_TarSymlinkEscapesRejected(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+
+	tests := map[string][]entry{
+		"absolute symlink": {{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc"}},
+		"relative escape":  {{name: "link", typeflag: tar.TypeSymlink, linkname: "../../outside"}},
+		"write through link": {
+			{name: "dir", typeflag: tar.TypeSymlink, linkname: "sub"},
+			{name: "dir/file.txt", body: "x"},
+		},
+		"absolute name": {{name: "/tmp/evil.txt", body: "x"}},
+	}
+
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			archive := writeArchive(t, "evil.tar", buildTar(t, entries))
+			_, err := Extract(context.Background(), archive, DestDir(archive), FormatTar, nil)
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Fatalf("expected ErrUnsafePath, got %v", err)
+			}
+		})
+	}
+}
+
+// useLimits lowers the extraction limits for one test
+func useLimits(t *testing.T, minSize int64, entries int) {
+	t.Helper()
+	oldSize, oldEntries := minSizeLimit, maxEntries
+	minSizeLimit, maxEntries = minSize, entries
+	t.Cleanup(func() { minSizeLimit, maxEntries = oldSize, oldEntries })
+}
+
+func TestExtract_SizeLimit(t *testing.T) {
+	useLimits(t, 64*1024, maxEntries)
+	bomb := []entry{{name: "zeros.bin", body: string(make([]byte, 1<<20))}}
+
+	for _, tt := range []struct {
+		name   string
+		format Format
+		data   []byte
+	}{
+		{"bomb.zip", FormatZip, buildZip(t, bomb)},
+		{"bomb.tar.gz", FormatTarGz, compress(t, buildTar(t, bomb), FormatTarGz)},
+	} {
+		t.Run(string(tt.format), func(t *testing.T) {
+			archive := writeArchive(t, tt.name, tt.data)
+			dest := DestDir(archive)
+			_, err := Extract(context.Background(), archive, dest, tt.format, nil)
+			if !errors.Is(err, ErrTooLarge) {
+				t.Fatalf("expected ErrTooLarge, got %v", err)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Error("partially extracted directory was not cleaned up")
+			}
+		})
+	}
+
+	// Contents ending exactly at the limit are fine, one byte more is not
+	lim := &limits{maxBytes: 10, bytesLeft: 10}
+	if _, err := io.ReadAll(lim.reader(bytes.NewReader(make([]byte, 10)))); err != nil {
+		t.Errorf("read at the limit: %v", err)
+	}
+	lim = &limits{maxBytes: 10, bytesLeft: 10}
+	if _, err := io.ReadAll(lim.reader(bytes.NewReader(make([]byte, 11)))); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("read past the limit: expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestExtract_EntryLimit(t *testing.T) {
+	useLimits(t, minSizeLimit, 3)
+	entries := []entry{{name: "a"}, {name: "b"}, {name: "c"}, {name: "d"}}
+
+	tarArchive := writeArchive(t, "many.tar", buildTar(t, entries))
+	if _, err := Extract(context.Background(), tarArchive, DestDir(tarArchive), FormatTar, nil); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("tar: expected ErrTooLarge, got %v", err)
+	}
+	zipArchive := writeArchive(t, "many.zip", buildZip(t, entries))
+	if _, err := Extract(context.Background(), zipArchive, DestDir(zipArchive), FormatZip, nil); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("zip: expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestDestDir_AvoidsExisting(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "data.tgz")
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := DestDir(archive), filepath.Join(dir, "data (1)"); got != want {
+		t.Errorf("DestDir = %q, want %q", got, want)
+	}
+}
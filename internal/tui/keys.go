@@ -31,6 +31,17 @@ type DashboardKeyMap struct {
 	Log         key.Binding
 	History     key.Binding
 	OpenFile    key.Binding
+	Move        key.Binding
+	Relink      key.Binding
+	// Queue ordering (queued tab only)
+	MoveUp     key.Binding
+	MoveDown   key.Binding
+	MoveTop    key.Binding
+	MoveBottom key.Binding
+	// Group actions (selected download's group)
+	GroupPause  key.Binding
+	GroupDelete key.Binding
+	GroupRetry  key.Binding
 	Quit        key.Binding
 	ForceQuit   key.Binding
 	// Navigation
@@ -74,9 +85,11 @@ type HistoryKeyMap struct {
 
 // DuplicateKeyMap defines keybindings for duplicate warning
 type DuplicateKeyMap struct {
-	Continue key.Binding
-	Focus    key.Binding
-	Cancel   key.Binding
+	Continue  key.Binding
+	Overwrite key.Binding
+	Skip      key.Binding
+	Focus     key.Binding
+	Cancel    key.Binding
 }
 
 // ExtensionKeyMap defines keybindings for extension confirmation
@@ -176,6 +189,42 @@ var Keys = KeyMap{
 			key.WithKeys("o"),
 			key.WithHelp("o", "open file"),
 		),
+		Move: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "move/rename"),
+		),
+		Relink: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "new URL"),
+		),
+		MoveUp: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "move up in queue"),
+		),
+		MoveDown: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "move down in queue"),
+		),
+		MoveTop: key.NewBinding(
+			key.WithKeys("{"),
+			key.WithHelp("{", "move to queue top"),
+		),
+		MoveBottom: key.NewBinding(
+			key.WithKeys("}"),
+			key.WithHelp("}", "move to queue bottom"),
+		),
+		GroupPause: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "pause/resume group"),
+		),
+		GroupDelete: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "delete group"),
+		),
+		GroupRetry: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "retry group"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("ctrl+c", "ctrl+q"),
 			key.WithHelp("ctrl+q", "quit"),
@@ -288,6 +337,14 @@ var Keys = KeyMap{
 			key.WithKeys("c", "C"),
 			key.WithHelp("c", "continue"),
 		),
+		Overwrite: key.NewBinding(
+			key.WithKeys("o", "O"),
+			key.WithHelp("o", "overwrite"),
+		),
+		Skip: key.NewBinding(
+			key.WithKeys("s", "S"),
+			key.WithHelp("s", "skip if same"),
+		),
 		Focus: key.NewBinding(
 			key.WithKeys("f", "F"),
 			key.WithHelp("f", "focus existing"),
@@ -318,15 +375,15 @@ var Keys = KeyMap{
 		),
 		Tab2: key.NewBinding(
 			key.WithKeys("2"),
-			key.WithHelp("2", "connections"),
+			key.WithHelp("2", "network"),
 		),
 		Tab3: key.NewBinding(
 			key.WithKeys("3"),
-			key.WithHelp("3", "chunks"),
+			key.WithHelp("3", "performance"),
 		),
 		Tab4: key.NewBinding(
 			key.WithKeys("4"),
-			key.WithHelp("4", "performance"),
+			key.WithHelp("4", "automation"),
 		),
 		NextTab: key.NewBinding(
 			key.WithKeys("right"),
@@ -406,7 +463,9 @@ func (k DashboardKeyMap) ShortHelp() []key.Binding {
 func (k DashboardKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.TabQueued, k.TabActive, k.TabDone, k.NextTab},
-		{k.Add, k.Search, k.Pause, k.Delete, k.Settings},
+		{k.Add, k.Search, k.Pause, k.Delete, k.Move, k.Relink, k.Settings},
+		{k.MoveUp, k.MoveDown, k.MoveTop, k.MoveBottom},
+		{k.GroupPause, k.GroupDelete, k.GroupRetry},
 		{k.Log, k.History, k.Quit},
 	}
 }
@@ -436,11 +495,11 @@ func (k HistoryKeyMap) FullHelp() [][]key.Binding {
 }
 
 func (k DuplicateKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Continue, k.Focus, k.Cancel}
+	return []key.Binding{k.Continue, k.Overwrite, k.Skip, k.Focus, k.Cancel}
 }
 
 func (k DuplicateKeyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{{k.Continue, k.Focus, k.Cancel}}
+	return [][]key.Binding{{k.Continue, k.Overwrite, k.Skip, k.Focus, k.Cancel}}
 }
 
 func (k ExtensionKeyMap) ShortHelp() []key.Binding {
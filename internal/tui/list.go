@@ -3,7 +3,10 @@ package tui
 import (
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/tui/colors"
 	"github.com/surge-downloader/surge/internal/tui/components"
 	"github.com/surge-downloader/surge/internal/utils"
@@ -31,6 +34,10 @@ func (i DownloadItem) Description() string {
 	if d.pausing {
 		// Custom "Pausing..." style using existing colors
 		styledStatus = lipgloss.NewStyle().Foreground(colors.StatePaused).Render("⏸ Pausing...")
+	} else if !d.done && !d.startAt.IsZero() && time.Now().Before(d.startAt) {
+		styledStatus = lipgloss.NewStyle().Foreground(colors.StatePaused).Render("⏰ Starts in " + formatCountdown(time.Until(d.startAt)))
+	} else if d.extracting {
+		styledStatus = lipgloss.NewStyle().Foreground(colors.StateDownloading).Render(fmt.Sprintf("⇲ Extracting %.0f%%", d.extractPct))
 	} else {
 		styledStatus = components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded).Render()
 	}
@@ -51,7 +58,33 @@ func (i DownloadItem) Description() string {
 		speedInfo = fmt.Sprintf(" • %.2f MB/s", d.Speed/Megabyte)
 	}
 
-	return fmt.Sprintf("%s • %.0f%%%s • %s", styledStatus, pct, speedInfo, sizeInfo)
+	categoryInfo := ""
+	if d.Category != "" {
+		categoryInfo = " • " + lipgloss.NewStyle().Foreground(colors.NeonCyan).Render(d.Category)
+	}
+	if d.Group != "" {
+		categoryInfo += " • " + lipgloss.NewStyle().Foreground(colors.NeonPurple).Render("⧉ "+d.Group)
+	}
+	if len(d.Tags) > 0 {
+		categoryInfo += " • " + lipgloss.NewStyle().Foreground(colors.NeonPink).Render("#"+strings.Join(d.Tags, " #"))
+	}
+
+	return fmt.Sprintf("%s • %.0f%%%s • %s%s", styledStatus, pct, speedInfo, sizeInfo, categoryInfo)
+}
+
+// formatCountdown renders the time left until a scheduled start, e.g. "1h 05m"
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
 }
 
 func (i DownloadItem) FilterValue() string {
@@ -279,3 +312,49 @@ func (m *RootModel) GetSelectedDownload() *DownloadModel {
 	}
 	return nil
 }
+
+// groupMemberIDs returns the IDs of every download in the named group
+func (m *RootModel) groupMemberIDs(name string) []string {
+	var ids []string
+	for _, d := range m.downloads {
+		if d.Group == name {
+			ids = append(ids, d.ID)
+		}
+	}
+	return ids
+}
+
+// groupSummary aggregates the TUI's view of a group's downloads, or nil if
+// no download belongs to it
+func (m *RootModel) groupSummary(name string) *types.GroupStatus {
+	var statuses []types.DownloadStatus
+	for _, d := range m.downloads {
+		if d.Group != name {
+			continue
+		}
+		status := "downloading"
+		switch {
+		case d.done:
+			status = "completed"
+		case d.err != nil:
+			status = "error"
+		case d.paused || d.pausing:
+			status = "paused"
+		case !d.startAt.IsZero() && time.Now().Before(d.startAt), d.Speed == 0 && d.Downloaded == 0:
+			status = "queued"
+		}
+		statuses = append(statuses, types.DownloadStatus{
+			ID:         d.ID,
+			Status:     status,
+			TotalSize:  d.Total,
+			Downloaded: d.Downloaded,
+			Speed:      d.Speed / Megabyte,
+			Group:      d.Group,
+		})
+	}
+	groups := types.SummarizeGroups(statuses)
+	if len(groups) == 0 {
+		return nil
+	}
+	return &groups[0]
+}
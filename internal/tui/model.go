@@ -3,6 +3,7 @@ package tui
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -50,11 +51,15 @@ type DownloadModel struct {
 	Filename      string
 	FilenameLower string
 	Destination   string // Full path to the destination file
+	Category      string // Category rule the download was routed by, if any
+	Group         string // Download group (batch file, --group), if any
 	Total         int64
 	Downloaded    int64
 	Speed         float64
 	Connections   int
 
+	types.DownloadMeta // Tags, note and referrer
+
 	StartTime time.Time
 	Elapsed   time.Duration
 
@@ -67,8 +72,12 @@ type DownloadModel struct {
 	done          bool
 	err           error
 	paused        bool
-	pausing       bool // UI state: transitioning to pause
-	pendingResume bool // UI state: waiting for async resume
+	pausing       bool      // UI state: transitioning to pause
+	pendingResume bool      // UI state: waiting for async resume
+	startAt       time.Time // Scheduled start; zero once started or if never scheduled
+
+	extracting bool    // Completed archive is being unpacked
+	extractPct float64 // Extraction progress (0-100)
 }
 
 type RootModel struct {
@@ -103,7 +112,8 @@ type RootModel struct {
 	pendingFilename string   // Filename pending confirmation
 	pendingMirrors  []string // Mirrors pending confirmation
 	pendingHeaders  map[string]string
-	duplicateInfo   string // Info about the duplicate
+	pendingOptions  core.DownloadOptions // Per-download options (conflict, priority, start time) pending confirmation
+	duplicateInfo   string               // Info about the duplicate
 
 	// Graph Data
 	SpeedHistory           []float64 // Stores the last ~60 ticks of speed data
@@ -123,6 +133,12 @@ type RootModel struct {
 	SettingsInput        textinput.Model  // Input for editing string/int values
 	SettingsFileBrowsing bool             // Whether browsing for a directory
 
+	// Queue order from the engine (1-based position by download ID)
+	queueOrder map[string]int
+
+	// Whether the once-a-second countdown refresh for scheduled downloads is running
+	scheduleTicking bool
+
 	// Selection persistence
 	SelectedDownloadID string // ID of the currently selected download
 	ManualTabSwitch    bool   // Whether the last tab switch was manual
@@ -132,6 +148,11 @@ type RootModel struct {
 	searchActive bool            // Whether search mode is active
 	searchQuery  string          // Current search query
 
+	// Prompt for the selected download: a new path (move/rename) or a new URL
+	promptInput textinput.Model
+	promptKind  string // promptMove or promptRelink; "" while closed
+	promptID    string
+
 	// Batch import
 	pendingBatchURLs []string // URLs pending batch import
 	batchFilePath    string   // Path to the batch file
@@ -216,6 +237,8 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 
 	// Load paused downloads from master list (now uses global config directory)
 	var downloads []*DownloadModel
+	queueOrder := make(map[string]int)
+	scheduleTicking := false
 	// Note: With Service abstraction, we might want to let the Service handle loading.
 	// But LocalDownloadService's List() calls state.ListAllDownloads().
 	// For TUI initialization, we should probably call Service.List() to populate the model.
@@ -228,6 +251,9 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 			for _, s := range statuses {
 				dm := NewDownloadModel(s.ID, s.URL, s.Filename, s.TotalSize)
 				dm.Downloaded = s.Downloaded
+				dm.Category = s.Category
+				dm.Group = s.Group
+				dm.DownloadMeta = s.DownloadMeta
 				if s.DestPath != "" {
 					dm.Destination = s.DestPath
 				} else {
@@ -238,6 +264,10 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 				case "completed":
 					dm.done = true
 					dm.progress.SetPercent(1.0)
+				case "extracting":
+					dm.done = true
+					dm.extracting = true
+					dm.progress.SetPercent(1.0)
 				case "pausing":
 					dm.pausing = true
 				case "paused":
@@ -251,11 +281,22 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 					// Always resume queued items
 					dm.pendingResume = true
 					dm.paused = true // Will update when resume event received
+				case "scheduled":
+					// Re-arm the timer; it keeps its original start time
+					dm.pendingResume = true
+					dm.paused = true
+					if s.StartAt > 0 {
+						dm.startAt = time.Unix(s.StartAt, 0)
+						scheduleTicking = true
+					}
 				}
 
 				if s.TotalSize > 0 {
 					dm.progress.SetPercent(s.Progress / 100.0)
 				}
+				if s.QueuePosition > 0 {
+					queueOrder[s.ID] = s.QueuePosition
+				}
 
 				downloads = append(downloads, dm)
 			}
@@ -281,8 +322,15 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 	searchInput.Width = 30
 	searchInput.Prompt = ""
 
+	// Initialize move/relink prompt input
+	promptInput := textinput.New()
+	promptInput.Width = 40
+	promptInput.Prompt = ""
+
 	m := RootModel{
 		downloads:             downloads,
+		queueOrder:            queueOrder,
+		scheduleTicking:       scheduleTicking,
 		inputs:                []textinput.Model{urlInput, mirrorsInput, pathInput, filenameInput},
 		state:                 DashboardState,
 		filepicker:            fp,
@@ -296,6 +344,7 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 		Settings:              settings,
 		SettingsInput:         settingsInput,
 		searchInput:           searchInput,
+		promptInput:           promptInput,
 		keys:                  Keys,
 		ServerPort:            serverPort,
 		CurrentVersion:        currentVersion,
@@ -323,6 +372,10 @@ func (m RootModel) Init() tea.Cmd {
 		cmds = append(cmds, checkForUpdateCmd(m.CurrentVersion))
 	}
 
+	if m.scheduleTicking {
+		cmds = append(cmds, scheduleTickCmd())
+	}
+
 	// Async resume of downloads
 	var resumeIDs []string
 	for _, d := range m.downloads {
@@ -358,10 +411,56 @@ type resumeResultMsg struct {
 	err error
 }
 
+// Dashboard prompts
+const (
+	promptMove   = "move"
+	promptRelink = "relink"
+)
+
+// promptResultMsg reports the outcome of a move or relink started from the
+// dashboard prompt
+type promptResultMsg struct {
+	kind string
+	id   string
+	err  error
+}
+
+// parseSearchQuery splits "tag:<name>" terms out of a search query; the rest
+// is matched as one lowercase substring
+func parseSearchQuery(query string) (tags []string, text string) {
+	var rest []string
+	for _, term := range strings.Fields(query) {
+		if tag, ok := strings.CutPrefix(strings.ToLower(term), "tag:"); ok {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+			continue
+		}
+		rest = append(rest, term)
+	}
+	if len(tags) == 0 {
+		// Keep plain queries verbatim so spacing still matters
+		return nil, strings.ToLower(query)
+	}
+	return tags, strings.ToLower(strings.Join(rest, " "))
+}
+
+// matchesSearch reports whether d carries every tag and text appears in its
+// filename, tags, note or referrer
+func (d *DownloadModel) matchesSearch(tags []string, text string) bool {
+	if !d.HasTags(tags) {
+		return false
+	}
+	if text == "" || strings.Contains(d.FilenameLower, text) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(d.SearchText()), text)
+}
+
 // Helper to get downloads for the current tab
 func (m RootModel) getFilteredDownloads() []*DownloadModel {
 	var filtered []*DownloadModel
-	searchLower := strings.ToLower(m.searchQuery)
+	tags, text := parseSearchQuery(m.searchQuery)
 
 	for _, d := range m.downloads {
 		// Apply tab filter first
@@ -381,14 +480,24 @@ func (m RootModel) getFilteredDownloads() []*DownloadModel {
 		}
 
 		// Apply search filter if query is set
-		if m.searchQuery != "" {
-			if !strings.Contains(strings.ToLower(d.FilenameLower), searchLower) {
-				continue
-			}
+		if m.searchQuery != "" && !d.matchesSearch(tags, text) {
+			continue
 		}
 
 		filtered = append(filtered, d)
 	}
+
+	if m.activeTab == TabQueued && len(m.queueOrder) > 0 {
+		// Known positions first in queue order; anything the engine hasn't
+		// placed yet was appended after them, so keep it in arrival order.
+		sort.SliceStable(filtered, func(i, j int) bool {
+			pi, pj := m.queueOrder[filtered[i].ID], m.queueOrder[filtered[j].ID]
+			if pi == 0 || pj == 0 {
+				return pi != 0 && pj == 0
+			}
+			return pi < pj
+		})
+	}
 	return filtered
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/tui/components"
+	"github.com/surge-downloader/surge/internal/utils"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -228,13 +229,20 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["clipboard_monitor"] = m.Settings.General.ClipboardMonitor
 		values["theme"] = m.Settings.General.Theme
 		values["log_retention_count"] = m.Settings.General.LogRetentionCount
+		values["filename_template"] = m.Settings.General.FilenameTemplate
+		values["filename_profile"] = m.Settings.General.FilenameProfile
+		values["conflict_policy"] = m.Settings.General.ConflictPolicy
 
 	case "Network":
 		values["max_connections_per_host"] = m.Settings.Connections.MaxConnectionsPerHost
 		values["max_global_connections"] = m.Settings.Connections.MaxGlobalConnections
 		values["max_concurrent_downloads"] = m.Settings.Connections.MaxConcurrentDownloads
+		values["concurrent_shrink_policy"] = m.Settings.Connections.ConcurrentShrinkPolicy
 		values["user_agent"] = m.Settings.Connections.UserAgent
 		values["sequential_download"] = m.Settings.Connections.SequentialDownload
+		values["url_refresh_command"] = m.Settings.Connections.URLRefreshCommand
+		values["url_refresh_extension"] = m.Settings.Connections.URLRefreshExtension
+		values["url_refresh_timeout"] = m.Settings.Connections.URLRefreshTimeout
 		values["min_chunk_size"] = m.Settings.Chunks.MinChunkSize
 		values["worker_buffer_size"] = m.Settings.Chunks.WorkerBufferSize
 	case "Performance":
@@ -243,6 +251,19 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["slow_worker_grace_period"] = m.Settings.Performance.SlowWorkerGracePeriod
 		values["stall_timeout"] = m.Settings.Performance.StallTimeout
 		values["speed_ema_alpha"] = m.Settings.Performance.SpeedEmaAlpha
+	case "Automation":
+		values["on_complete"] = m.Settings.Hooks.OnComplete
+		values["on_error"] = m.Settings.Hooks.OnError
+		values["on_pause"] = m.Settings.Hooks.OnPause
+		values["hook_timeout"] = m.Settings.Hooks.HookTimeout
+		values["extract_archives"] = m.Settings.Hooks.ExtractArchives
+		values["delete_archive_after_extract"] = m.Settings.Hooks.DeleteArchiveAfterExtract
+		values["webhook_max_attempts"] = m.Settings.Webhooks.WebhookMaxAttempts
+		values["webhook_timeout"] = m.Settings.Webhooks.WebhookTimeout
+		values["history_retention_days"] = m.Settings.History.HistoryRetentionDays
+		values["history_max_entries"] = m.Settings.History.HistoryMaxEntries
+		values["error_retention_days"] = m.Settings.History.ErrorRetentionDays
+		values["delete_history_files"] = m.Settings.History.DeleteHistoryFiles
 	}
 
 	return values
@@ -273,6 +294,8 @@ func (m *RootModel) setSettingValue(category, key, value string) error {
 		}
 	case "Performance":
 		return m.setPerformanceSetting(key, value, meta.Type)
+	case "Automation":
+		return m.setAutomationSetting(key, value, meta.Type)
 	}
 
 	return nil
@@ -324,6 +347,20 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 			}
 			m.Settings.General.LogRetentionCount = v
 		}
+	case "filename_template":
+		m.Settings.General.FilenameTemplate = strings.TrimSpace(value)
+	case "filename_profile":
+		profile := strings.ToLower(strings.TrimSpace(value))
+		if !utils.IsValidFilenameProfile(profile) {
+			return nil // Invalid value
+		}
+		m.Settings.General.FilenameProfile = profile
+	case "conflict_policy":
+		policy := strings.ToLower(strings.TrimSpace(value))
+		if !config.IsValidConflictPolicy(policy) {
+			return nil // Invalid value
+		}
+		m.Settings.General.ConflictPolicy = policy
 	}
 	return nil
 }
@@ -340,13 +377,19 @@ func (m *RootModel) setConnectionsSetting(key, value, typ string) error {
 		}
 	case "max_concurrent_downloads":
 		if v, err := strconv.Atoi(value); err == nil {
-			if v < 1 {
-				v = 1
-			} else if v > 10 {
-				v = 10
+			if v < config.MinConcurrentDownloads {
+				v = config.MinConcurrentDownloads
+			} else if v > config.MaxConcurrentDownloads {
+				v = config.MaxConcurrentDownloads
 			}
 			m.Settings.Connections.MaxConcurrentDownloads = v
 		}
+	case "concurrent_shrink_policy":
+		policy := strings.ToLower(strings.TrimSpace(value))
+		if !config.IsValidShrinkPolicy(policy) {
+			return nil // Invalid value
+		}
+		m.Settings.Connections.ConcurrentShrinkPolicy = policy
 	case "user_agent":
 		m.Settings.Connections.UserAgent = value
 	case "sequential_download":
@@ -358,6 +401,18 @@ func (m *RootModel) setConnectionsSetting(key, value, typ string) error {
 			b, _ := strconv.ParseBool(value)
 			m.Settings.Connections.SequentialDownload = b
 		}
+	case "url_refresh_command":
+		m.Settings.Connections.URLRefreshCommand = value
+	case "url_refresh_extension":
+		m.Settings.Connections.URLRefreshExtension = !m.Settings.Connections.URLRefreshExtension
+	case "url_refresh_timeout":
+		// Check if it's just a number, if so add "s"
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			value += "s"
+		}
+		if v, err := time.ParseDuration(value); err == nil {
+			m.Settings.Connections.URLRefreshTimeout = v
+		}
 	}
 	return nil
 }
@@ -424,6 +479,59 @@ func (m *RootModel) setPerformanceSetting(key, value, typ string) error {
 	return nil
 }
 
+func (m *RootModel) setAutomationSetting(key, value, typ string) error {
+	switch key {
+	case "on_complete":
+		m.Settings.Hooks.OnComplete = value
+	case "on_error":
+		m.Settings.Hooks.OnError = value
+	case "on_pause":
+		m.Settings.Hooks.OnPause = value
+	case "hook_timeout":
+		// Check if it's just a number, if so add "s"
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			value += "s"
+		}
+		if v, err := time.ParseDuration(value); err == nil {
+			m.Settings.Hooks.HookTimeout = v
+		}
+	case "extract_archives":
+		m.Settings.Hooks.ExtractArchives = !m.Settings.Hooks.ExtractArchives
+	case "delete_archive_after_extract":
+		m.Settings.Hooks.DeleteArchiveAfterExtract = !m.Settings.Hooks.DeleteArchiveAfterExtract
+	case "webhook_max_attempts":
+		if v, err := strconv.Atoi(value); err == nil {
+			if v < 1 {
+				v = 1
+			}
+			m.Settings.Webhooks.WebhookMaxAttempts = v
+		}
+	case "webhook_timeout":
+		// Check if it's just a number, if so add "s"
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			value += "s"
+		}
+		if v, err := time.ParseDuration(value); err == nil {
+			m.Settings.Webhooks.WebhookTimeout = v
+		}
+	case "history_retention_days":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			m.Settings.History.HistoryRetentionDays = v
+		}
+	case "history_max_entries":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			m.Settings.History.HistoryMaxEntries = v
+		}
+	case "error_retention_days":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			m.Settings.History.ErrorRetentionDays = v
+		}
+	case "delete_history_files":
+		m.Settings.History.DeleteHistoryFiles = !m.Settings.History.DeleteHistoryFiles
+	}
+	return nil
+}
+
 // getCurrentSettingKey returns the key of the currently selected setting
 func (m RootModel) getCurrentSettingKey() string {
 	categories := config.CategoryOrder()
@@ -468,7 +576,7 @@ func (m RootModel) getSettingUnit() string {
 		return " KB"
 	case "max_task_retries":
 		return " retries"
-	case "slow_worker_grace_period", "stall_timeout":
+	case "slow_worker_grace_period", "stall_timeout", "url_refresh_timeout", "hook_timeout", "webhook_timeout":
 		return " seconds"
 	case "slow_worker_threshold", "speed_ema_alpha":
 		return " (0.0-1.0)"
@@ -491,7 +599,7 @@ func formatSettingValueForEdit(value interface{}, typ, key string) string {
 			kb := float64(v.Int()) / 1024
 			return fmt.Sprintf("%.0f", kb)
 		}
-	case "slow_worker_grace_period", "stall_timeout":
+	case "slow_worker_grace_period", "stall_timeout", "url_refresh_timeout", "hook_timeout", "webhook_timeout":
 		// Show duration as plain seconds number (e.g., "5" instead of "5s")
 		if d, ok := value.(time.Duration); ok {
 			return fmt.Sprintf("%.0f", d.Seconds())
@@ -593,6 +701,12 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.General.Theme = defaults.General.Theme
 		case "log_retention_count":
 			m.Settings.General.LogRetentionCount = defaults.General.LogRetentionCount
+		case "filename_template":
+			m.Settings.General.FilenameTemplate = defaults.General.FilenameTemplate
+		case "filename_profile":
+			m.Settings.General.FilenameProfile = defaults.General.FilenameProfile
+		case "conflict_policy":
+			m.Settings.General.ConflictPolicy = defaults.General.ConflictPolicy
 		}
 
 	case "Network":
@@ -604,10 +718,18 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.Connections.MaxGlobalConnections = defaults.Connections.MaxGlobalConnections
 		case "max_concurrent_downloads":
 			m.Settings.Connections.MaxConcurrentDownloads = defaults.Connections.MaxConcurrentDownloads
+		case "concurrent_shrink_policy":
+			m.Settings.Connections.ConcurrentShrinkPolicy = defaults.Connections.ConcurrentShrinkPolicy
 		case "user_agent":
 			m.Settings.Connections.UserAgent = defaults.Connections.UserAgent
 		case "sequential_download":
 			m.Settings.Connections.SequentialDownload = defaults.Connections.SequentialDownload
+		case "url_refresh_command":
+			m.Settings.Connections.URLRefreshCommand = defaults.Connections.URLRefreshCommand
+		case "url_refresh_extension":
+			m.Settings.Connections.URLRefreshExtension = defaults.Connections.URLRefreshExtension
+		case "url_refresh_timeout":
+			m.Settings.Connections.URLRefreshTimeout = defaults.Connections.URLRefreshTimeout
 		case "min_chunk_size":
 			m.Settings.Chunks.MinChunkSize = defaults.Chunks.MinChunkSize
 		case "worker_buffer_size":
@@ -626,5 +748,32 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 		case "speed_ema_alpha":
 			m.Settings.Performance.SpeedEmaAlpha = defaults.Performance.SpeedEmaAlpha
 		}
+	case "Automation":
+		switch key {
+		case "on_complete":
+			m.Settings.Hooks.OnComplete = defaults.Hooks.OnComplete
+		case "on_error":
+			m.Settings.Hooks.OnError = defaults.Hooks.OnError
+		case "on_pause":
+			m.Settings.Hooks.OnPause = defaults.Hooks.OnPause
+		case "hook_timeout":
+			m.Settings.Hooks.HookTimeout = defaults.Hooks.HookTimeout
+		case "extract_archives":
+			m.Settings.Hooks.ExtractArchives = defaults.Hooks.ExtractArchives
+		case "delete_archive_after_extract":
+			m.Settings.Hooks.DeleteArchiveAfterExtract = defaults.Hooks.DeleteArchiveAfterExtract
+		case "webhook_max_attempts":
+			m.Settings.Webhooks.WebhookMaxAttempts = defaults.Webhooks.WebhookMaxAttempts
+		case "webhook_timeout":
+			m.Settings.Webhooks.WebhookTimeout = defaults.Webhooks.WebhookTimeout
+		case "history_retention_days":
+			m.Settings.History.HistoryRetentionDays = defaults.History.HistoryRetentionDays
+		case "history_max_entries":
+			m.Settings.History.HistoryMaxEntries = defaults.History.HistoryMaxEntries
+		case "error_retention_days":
+			m.Settings.History.ErrorRetentionDays = defaults.History.ErrorRetentionDays
+		case "delete_history_files":
+			m.Settings.History.DeleteHistoryFiles = defaults.History.DeleteHistoryFiles
+		}
 	}
 }
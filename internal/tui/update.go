@@ -12,6 +12,7 @@ import (
 
 	"github.com/surge-downloader/surge/internal/clipboard"
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
@@ -24,6 +25,13 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// scheduleTickMsg refreshes countdowns while downloads wait for their start time
+type scheduleTickMsg struct{}
+
+func scheduleTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return scheduleTickMsg{} })
+}
+
 // notificationTickMsg is sent to check if a notification should be cleared
 type notificationTickMsg struct{}
 
@@ -136,6 +144,20 @@ func (m *RootModel) removeDownloadByID(id string) bool {
 	return false
 }
 
+// openPrompt opens the move or relink prompt for a download, prefilled with
+// its current path or URL
+func (m *RootModel) openPrompt(kind, id, value string) {
+	m.promptKind = kind
+	m.promptID = id
+	m.promptInput.Placeholder = "New path or directory"
+	if kind == promptRelink {
+		m.promptInput.Placeholder = "New URL"
+	}
+	m.promptInput.SetValue(value)
+	m.promptInput.CursorEnd()
+	m.promptInput.Focus()
+}
+
 // checkForDuplicate checks if a compatible download already exists
 func (m RootModel) checkForDuplicate(url string) *DownloadModel {
 	if !m.Settings.General.WarnOnDuplicate {
@@ -157,20 +179,41 @@ func (m RootModel) checkForDuplicate(url string) *DownloadModel {
 
 // startDownload initiates a new download
 func (m RootModel) startDownload(url string, mirrors []string, headers map[string]string, path, filename, id string) (RootModel, tea.Cmd) {
+	return m.startDownloadWithOptions(url, mirrors, headers, path, filename, id, core.DownloadOptions{})
+}
+
+// pendingOptionsWithConflict returns the pending options with the conflict
+// policy picked in the duplicate warning
+func (m RootModel) pendingOptionsWithConflict(policy string) core.DownloadOptions {
+	opts := m.pendingOptions
+	opts.Conflict = policy
+	return opts
+}
+
+// startDownloadWithOptions is startDownload with per-download options such as the conflict policy
+func (m RootModel) startDownloadWithOptions(url string, mirrors []string, headers map[string]string, path, filename, id string, opts core.DownloadOptions) (RootModel, tea.Cmd) {
 	// Enforce absolute path
 	path = utils.EnsureAbsPath(path)
 
 	// Generate unique filename to avoid overwriting (if not provided)
 	// For Local Service, we can generate it here. For Remote, the server might do it,
 	// but sending a unique filename is safer.
-	finalFilename := m.generateUniqueFilename(path, filename)
+	// Other conflict policies need the original name to find the existing file.
+	finalFilename := filename
+	conflict := opts.Conflict
+	if conflict == "" {
+		conflict = m.Settings.General.ConflictPolicy
+	}
+	if conflict == "" || conflict == config.ConflictRename {
+		finalFilename = m.generateUniqueFilename(path, filename)
+	}
 
 	// Call Service Add
 	// Note: We don't construct DownloadConfig/DownloadModel manually here for the queue
 	// We rely on the event stream to update the UI, OR we add it optimistically.
 	// Optimistic addition gives better UX.
 
-	newID, err := m.Service.Add(url, path, finalFilename, mirrors, headers)
+	newID, err := m.Service.AddWithOptions(url, path, finalFilename, mirrors, headers, opts)
 	if err != nil {
 		m.addLogEntry(LogStyleError.Render("✖ Failed to add download: " + err.Error()))
 		return m, nil
@@ -179,6 +222,8 @@ func (m RootModel) startDownload(url string, mirrors []string, headers map[strin
 	// Create optimistic model
 	newDownload := NewDownloadModel(newID, url, "Queued", 0)
 	newDownload.Destination = filepath.Join(path, finalFilename)
+	newDownload.Group = opts.Group
+	newDownload.DownloadMeta = opts.DownloadMeta
 	m.downloads = append(m.downloads, newDownload)
 
 	m.SelectedDownloadID = newID
@@ -230,6 +275,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingHeaders = msg.Headers
 			m.pendingPath = path
 			m.pendingFilename = msg.Filename
+			m.pendingOptions = core.DownloadOptions{Conflict: msg.Conflict, Priority: msg.Priority, StartAt: msg.StartAt, Group: msg.Group, DownloadMeta: msg.DownloadMeta}
 			m.duplicateInfo = duplicate.Filename
 			m.state = DuplicateWarningState
 			return m, nil
@@ -241,11 +287,12 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingHeaders = msg.Headers
 			m.pendingPath = path
 			m.pendingFilename = msg.Filename
+			m.pendingOptions = core.DownloadOptions{Conflict: msg.Conflict, Priority: msg.Priority, StartAt: msg.StartAt, Group: msg.Group, DownloadMeta: msg.DownloadMeta}
 			m.state = ExtensionConfirmationState
 			return m, nil
 		}
 
-		return m.startDownload(msg.URL, msg.Mirrors, msg.Headers, path, msg.Filename, msg.ID)
+		return m.startDownloadWithOptions(msg.URL, msg.Mirrors, msg.Headers, path, msg.Filename, msg.ID, core.DownloadOptions{Conflict: msg.Conflict, Priority: msg.Priority, StartAt: msg.StartAt, Group: msg.Group, DownloadMeta: msg.DownloadMeta})
 
 	case events.DownloadStartedMsg:
 		found := false
@@ -255,7 +302,17 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				d.FilenameLower = strings.ToLower(msg.Filename)
 				d.Total = msg.Total
 				d.Destination = msg.DestPath
+				if msg.Category != "" {
+					d.Category = msg.Category
+				}
+				if msg.Group != "" {
+					d.Group = msg.Group
+				}
+				if !msg.DownloadMeta.IsZero() {
+					d.DownloadMeta = msg.DownloadMeta
+				}
 				d.StartTime = time.Now()
+				d.startAt = time.Time{}
 				d.paused = false
 				d.pausing = false
 				d.pendingResume = false
@@ -277,6 +334,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if !found {
 			newDownload := NewDownloadModel(msg.DownloadID, msg.URL, msg.Filename, msg.Total)
 			newDownload.Destination = msg.DestPath
+			newDownload.Category = msg.Category
 			if msg.State != nil {
 				newDownload.state = msg.State
 			}
@@ -396,6 +454,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.UpdateListItems()
 		return m, tea.Batch(cmds...)
 
+	case events.ConcurrencyChangedMsg:
+		if m.Settings != nil && m.Settings.Connections.MaxConcurrentDownloads != msg.MaxDownloads {
+			m.Settings.Connections.MaxConcurrentDownloads = msg.MaxDownloads
+		}
+		m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⇅ Max concurrent downloads: %d", msg.MaxDownloads)))
+		return m, tea.Batch(cmds...)
+
 	case events.DownloadResumedMsg:
 		for _, d := range m.downloads {
 			if d.ID == msg.DownloadID {
@@ -415,17 +480,71 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for _, d := range m.downloads {
 			if d.ID == msg.DownloadID {
 				found = true
+				if msg.Group != "" {
+					d.Group = msg.Group
+				}
+				if !msg.DownloadMeta.IsZero() {
+					d.DownloadMeta = msg.DownloadMeta
+				}
 				break
 			}
 		}
 		if !found {
 			// Add placeholder
 			newDownload := NewDownloadModel(msg.DownloadID, "", msg.Filename, 0)
+			newDownload.Group = msg.Group
+			newDownload.DownloadMeta = msg.DownloadMeta
 			m.downloads = append(m.downloads, newDownload)
 			m.UpdateListItems()
 		}
 		return m, tea.Batch(cmds...)
 
+	case events.DownloadScheduledMsg:
+		var target *DownloadModel
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				target = d
+				break
+			}
+		}
+		if target == nil {
+			target = NewDownloadModel(msg.DownloadID, "", msg.Filename, 0)
+			m.downloads = append(m.downloads, target)
+		}
+		target.startAt = msg.StartAt
+		target.paused = false
+		target.pendingResume = false
+		m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⏰ Scheduled for %s: %s", msg.StartAt.Local().Format("2006-01-02 15:04"), target.Filename)))
+		if !m.scheduleTicking {
+			m.scheduleTicking = true
+			cmds = append(cmds, scheduleTickCmd())
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
+	case scheduleTickMsg:
+		waiting := false
+		for _, d := range m.downloads {
+			if !d.startAt.IsZero() && time.Now().Before(d.startAt) {
+				waiting = true
+				break
+			}
+		}
+		m.UpdateListItems()
+		if !waiting {
+			m.scheduleTicking = false
+			return m, nil
+		}
+		return m, scheduleTickCmd()
+
+	case events.QueueReorderedMsg:
+		m.queueOrder = make(map[string]int, len(msg.Order))
+		for i, id := range msg.Order {
+			m.queueOrder[id] = i + 1
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
 	case events.DownloadRemovedMsg:
 		if m.removeDownloadByID(msg.DownloadID) {
 			if msg.Filename != "" {
@@ -435,6 +554,123 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Batch(cmds...)
 
+	case events.DownloadMovedMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.Filename = msg.Filename
+				d.Destination = msg.DestPath
+				break
+			}
+		}
+		m.addLogEntry(LogStyleStarted.Render("➜ Moved: " + msg.Filename + " → " + filepath.Dir(msg.DestPath)))
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
+	case events.DownloadRelinkedMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.URL = msg.URL
+				break
+			}
+		}
+		m.addLogEntry(LogStyleStarted.Render("➜ Relinked: " + msg.Filename))
+		return m, tea.Batch(cmds...)
+
+	case promptResultMsg:
+		if msg.err != nil {
+			action := "Move"
+			if msg.kind == promptRelink {
+				action = "Relink"
+			}
+			m.addLogEntry(LogStyleError.Render("✖ " + action + " failed: " + msg.err.Error()))
+		}
+		return m, nil
+
+	case events.HistoryPrunedMsg:
+		removed := 0
+		for _, id := range msg.DownloadIDs {
+			if m.removeDownloadByID(id) {
+				removed++
+			}
+		}
+		if removed > 0 {
+			m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("✖ History cleanup removed %d downloads", removed)))
+			m.UpdateListItems()
+		}
+		return m, tea.Batch(cmds...)
+
+	case events.URLRefreshRequestMsg:
+		name := msg.Filename
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID && d.Filename != "" {
+				name = d.Filename
+				break
+			}
+		}
+		m.addLogEntry(LogStylePaused.Render(fmt.Sprintf("↻ Link expired (HTTP %d), waiting for refresh: %s", msg.StatusCode, name)))
+		return m, tea.Batch(cmds...)
+
+	case events.HookResultMsg:
+		name := msg.Filename
+		if name == "" {
+			name = msg.DownloadID
+		}
+		switch {
+		case msg.Err != "":
+			m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Hook %s failed for %s: %s", msg.Event, name, msg.Err)))
+		case msg.ExitCode != 0:
+			m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Hook %s for %s exited with status %d", msg.Event, name, msg.ExitCode)))
+		default:
+			m.addLogEntry(LogStyleComplete.Render(fmt.Sprintf("⚙ Hook %s for %s exited with status 0", msg.Event, name)))
+		}
+		return m, tea.Batch(cmds...)
+
+	case events.ExtractStartedMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.extracting = true
+				d.extractPct = 0
+				m.addLogEntry(LogStyleStarted.Render("⇲ Extracting: " + d.Filename))
+				break
+			}
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
+	case events.ExtractProgressMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				if msg.Total > 0 {
+					d.extractPct = float64(msg.Done) * 100 / float64(msg.Total)
+				}
+				break
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case events.ExtractCompleteMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.extracting = false
+				d.extractPct = 100
+				m.addLogEntry(LogStyleComplete.Render(fmt.Sprintf("✔ Extracted: %s → %s", d.Filename, msg.DestDir)))
+				break
+			}
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
+	case events.ExtractErrorMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.extracting = false
+				m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Extract failed: %s: %s", d.Filename, msg.Err)))
+				break
+			}
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -553,6 +789,38 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+			// Handle the move/relink prompt the same way while it is open
+			if m.promptKind != "" {
+				switch msg.String() {
+				case "esc":
+					m.promptKind = ""
+					m.promptInput.Blur()
+					return m, nil
+				case "enter":
+					kind, id, value := m.promptKind, m.promptID, strings.TrimSpace(m.promptInput.Value())
+					m.promptKind = ""
+					m.promptInput.Blur()
+					if value == "" {
+						return m, nil
+					}
+					service := m.Service
+					// Stopping a running download or probing a URL can take a moment
+					return m, func() tea.Msg {
+						var err error
+						if kind == promptRelink {
+							err = service.Relink(id, value, nil, false)
+						} else {
+							err = service.Move(id, value)
+						}
+						return promptResultMsg{kind: kind, id: id, err: err}
+					}
+				default:
+					var cmd tea.Cmd
+					m.promptInput, cmd = m.promptInput.Update(msg)
+					return m, cmd
+				}
+			}
+
 			// Toggle search with F
 			if key.Matches(msg, m.keys.Dashboard.Search) {
 				if m.searchQuery != "" {
@@ -658,6 +926,57 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+			// Queue reordering
+			if m.activeTab == TabQueued {
+				moves := []struct {
+					binding key.Binding
+					op      string
+				}{
+					{m.keys.Dashboard.MoveUp, types.QueueMoveUp},
+					{m.keys.Dashboard.MoveDown, types.QueueMoveDown},
+					{m.keys.Dashboard.MoveTop, types.QueueMoveTop},
+					{m.keys.Dashboard.MoveBottom, types.QueueMoveBottom},
+				}
+				for _, mv := range moves {
+					if !key.Matches(msg, mv.binding) {
+						continue
+					}
+					if d := m.GetSelectedDownload(); d != nil {
+						if err := m.Service.ReorderQueue(d.ID, mv.op); err != nil {
+							m.addLogEntry(LogStyleError.Render("✖ Reorder failed: " + err.Error()))
+						}
+					}
+					return m, nil
+				}
+			}
+
+			// Group actions apply to every download sharing the selection's group
+			if d := m.GetSelectedDownload(); d != nil && d.Group != "" {
+				action := ""
+				switch {
+				case key.Matches(msg, m.keys.Dashboard.GroupPause):
+					action = types.GroupPause
+					if g := m.groupSummary(d.Group); g != nil && g.Status == "paused" {
+						action = types.GroupResume
+					}
+				case key.Matches(msg, m.keys.Dashboard.GroupDelete):
+					action = types.GroupDelete
+				case key.Matches(msg, m.keys.Dashboard.GroupRetry):
+					action = types.GroupRetry
+				}
+				if action != "" {
+					if err := m.Service.GroupAction(d.Group, action); err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Group " + action + " failed: " + err.Error()))
+					} else if action == types.GroupDelete {
+						for _, id := range m.groupMemberIDs(d.Group) {
+							m.removeDownloadByID(id)
+						}
+					}
+					m.UpdateListItems()
+					return m, nil
+				}
+			}
+
 			// History
 			if key.Matches(msg, m.keys.Dashboard.History) {
 				// Note: accessing state directly here breaks abstraction.
@@ -697,6 +1016,22 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
+			// Move/rename the selected download
+			if key.Matches(msg, m.keys.Dashboard.Move) {
+				if d := m.GetSelectedDownload(); d != nil {
+					m.openPrompt(promptMove, d.ID, d.Destination)
+				}
+				return m, nil
+			}
+
+			// Point the selected download at a new URL
+			if key.Matches(msg, m.keys.Dashboard.Relink) {
+				if d := m.GetSelectedDownload(); d != nil && !d.done {
+					m.openPrompt(promptRelink, d.ID, d.URL)
+				}
+				return m, nil
+			}
+
 			// Open file
 			if key.Matches(msg, m.keys.Dashboard.OpenFile) {
 				if d := m.GetSelectedDownload(); d != nil {
@@ -860,6 +1195,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.pendingHeaders = nil
 					m.pendingPath = path
 					m.pendingFilename = filename
+					m.pendingOptions = core.DownloadOptions{}
 					m.duplicateInfo = d.Filename
 					m.state = DuplicateWarningState
 					return m, nil
@@ -981,9 +1317,17 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case DuplicateWarningState:
 			if key.Matches(msg, m.keys.Duplicate.Continue) {
-				// Continue anyway - startDownload handles unique filename generation
+				// Continue anyway - the conflict policy (rename by default) handles existing files
+				m.state = DashboardState
+				return m.startDownloadWithOptions(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingFilename, "", m.pendingOptions)
+			}
+			if key.Matches(msg, m.keys.Duplicate.Overwrite) {
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingFilename, "")
+				return m.startDownloadWithOptions(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingFilename, "", m.pendingOptionsWithConflict(config.ConflictOverwrite))
+			}
+			if key.Matches(msg, m.keys.Duplicate.Skip) {
+				m.state = DashboardState
+				return m.startDownloadWithOptions(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingFilename, "", m.pendingOptionsWithConflict(config.ConflictSkip))
 			}
 			if key.Matches(msg, m.keys.Duplicate.Cancel) {
 				// Cancel - don't add
@@ -1015,7 +1359,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// No duplicate (or warning disabled) - add to queue
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, nil, m.pendingHeaders, m.pendingPath, m.pendingFilename, "")
+				return m.startDownloadWithOptions(m.pendingURL, nil, m.pendingHeaders, m.pendingPath, m.pendingFilename, "", m.pendingOptions)
 			}
 			if key.Matches(msg, m.keys.Extension.No) {
 				// Cancelled
@@ -1087,6 +1431,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if path == "" {
 					path = "."
 				}
+				opts := core.DownloadOptions{Group: utils.BatchGroupName(m.batchFilePath)}
 
 				added := 0
 				skipped := 0
@@ -1096,7 +1441,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						skipped++
 						continue
 					}
-					m, _ = m.startDownload(url, nil, nil, path, "", "")
+					m, _ = m.startDownloadWithOptions(url, nil, nil, path, "", "", opts)
 					added++
 				}
 
@@ -1148,6 +1493,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if key.Matches(msg, m.keys.Settings.Close) {
 				// Save settings and exit
 				_ = config.SaveSettings(m.Settings)
+				// Running downloads and the pool pick up the changes live; no restart needed
+				if m.Service != nil {
+					// Reloading also resizes the pool to max_concurrent_downloads
+					if err := m.Service.ReloadSettings(); err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Settings reload failed: " + err.Error()))
+					}
+				}
 				m.state = DashboardState
 				return m, nil
 			}
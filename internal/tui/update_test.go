@@ -367,3 +367,45 @@ func TestUpdate_DownloadRequestMsg(t *testing.T) {
 		t.Errorf("Expected no prompt state, got %v", newRoot.state)
 	}
 }
+
+func TestGetFilteredDownloads_SearchesMetadata(t *testing.T) {
+	iso := NewDownloadModel("id-1", "http://example.com/a.iso", "a.iso", 100)
+	iso.Tags = []string{"linux", "iso"}
+	iso.Note = "lab machines"
+	doc := NewDownloadModel("id-2", "http://example.com/b.pdf", "b.pdf", 100)
+	doc.Referrer = "https://papers.example.com"
+	done := NewDownloadModel("id-3", "http://example.com/c.iso", "c.iso", 100)
+	done.Tags = []string{"linux"}
+
+	m := RootModel{downloads: []*DownloadModel{iso, doc, done}, activeTab: TabQueued}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"a.iso", []string{"id-1"}},
+		{"lab mach", []string{"id-1"}},
+		{"papers", []string{"id-2"}},
+		{"tag:linux", []string{"id-1", "id-3"}},
+		{"tag:linux tag:iso", []string{"id-1"}},
+		{"tag:LINUX c.iso", []string{"id-3"}},
+		{"tag:work", nil},
+	}
+	for _, tt := range tests {
+		m.searchQuery = tt.query
+		var got []string
+		for _, d := range m.getFilteredDownloads() {
+			got = append(got, d.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/tui/components"
 	"github.com/surge-downloader/surge/internal/utils"
 
@@ -94,7 +95,7 @@ func (m RootModel) View() string {
 			Keys:        m.keys.Duplicate,
 			Help:        m.help,
 			BorderColor: ColorNeonPink,
-			Width:       60,
+			Width:       76,
 			Height:      10,
 		}
 		box := modal.RenderWithBtopBox(renderBtopBox, PaneTitleStyle)
@@ -201,7 +202,11 @@ func (m RootModel) View() string {
 	}
 
 	if selected != nil {
-		detailContent = renderFocusedDetails(selected, detailWidth)
+		var group *types.GroupStatus
+		if selected.Group != "" {
+			group = m.groupSummary(selected.Group)
+		}
+		detailContent = renderFocusedDetails(selected, detailWidth, group)
 	} else {
 		// Default Placeholder
 		detailContent = lipgloss.Place(detailWidth, 8, lipgloss.Center, lipgloss.Center,
@@ -517,9 +522,19 @@ func (m RootModel) View() string {
 	// Tab Bar
 	tabBar := renderTabs(m.activeTab, active, queued, downloaded)
 
-	// Search bar (shown when search is active or has a query)
+	// Search bar (shown when search is active or has a query); the move or
+	// relink prompt takes its place while open
 	var leftTitle string
-	if m.searchActive || m.searchQuery != "" {
+	if m.promptKind != "" {
+		label, action := "Move to: ", "move"
+		if m.promptKind == promptRelink {
+			label, action = "New URL: ", "relink"
+		}
+		promptIcon := lipgloss.NewStyle().Foreground(ColorNeonCyan).Render(label)
+		promptDisplay := m.promptInput.View() +
+			lipgloss.NewStyle().Foreground(ColorGray).Render(" [enter "+action+", esc cancel]")
+		leftTitle = " " + lipgloss.JoinHorizontal(lipgloss.Left, promptIcon, promptDisplay) + " "
+	} else if m.searchActive || m.searchQuery != "" {
 		searchIcon := lipgloss.NewStyle().Foreground(ColorNeonCyan).Render("> ")
 		var searchDisplay string
 		if m.searchActive {
@@ -633,7 +648,7 @@ func (m RootModel) View() string {
 }
 
 // Helper to render the detailed info pane
-func renderFocusedDetails(d *DownloadModel, w int) string {
+func renderFocusedDetails(d *DownloadModel, w int, group *types.GroupStatus) string {
 	pct := 0.0
 	if d.Total > 0 {
 		pct = float64(d.Downloaded) / float64(d.Total)
@@ -667,11 +682,34 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 	statusBox := statusStyle.Render(statusStr)
 
 	// --- 2. File Information Section ---
-	fileInfoContent := lipgloss.JoinVertical(lipgloss.Left,
+	fileInfoLines := []string{
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("File: "), StatsValueStyle.Render(truncateString(d.Filename, contentWidth-8))),
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Path: "), StatsValueStyle.Render(truncateString(d.Destination, contentWidth-8))),
-		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("ID:   "), lipgloss.NewStyle().Foreground(ColorLightGray).Render(d.ID)),
-	)
+	}
+	if d.Category != "" {
+		fileInfoLines = append(fileInfoLines, lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Cat:  "), StatsValueStyle.Render(truncateString(d.Category, contentWidth-8))))
+	}
+	if group != nil {
+		groupStr := fmt.Sprintf("%s • %d/%d done • %.0f%%", group.Name, group.Completed, group.Count, group.Progress)
+		if group.Speed > 0 {
+			groupStr += fmt.Sprintf(" • %.2f MB/s", group.Speed)
+		}
+		if group.ETA > 0 {
+			groupStr += " • " + (time.Duration(group.ETA) * time.Second).String()
+		}
+		fileInfoLines = append(fileInfoLines, lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Group:"), StatsValueStyle.Render(truncateString(" "+groupStr, contentWidth-8))))
+	}
+	if len(d.Tags) > 0 {
+		fileInfoLines = append(fileInfoLines, lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Tags: "), StatsValueStyle.Render(truncateString(strings.Join(d.Tags, ", "), contentWidth-8))))
+	}
+	if d.Referrer != "" {
+		fileInfoLines = append(fileInfoLines, lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("From: "), StatsValueStyle.Render(truncateString(d.Referrer, contentWidth-8))))
+	}
+	if d.Note != "" {
+		fileInfoLines = append(fileInfoLines, lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Note: "), StatsValueStyle.Render(truncateString(d.Note, contentWidth-8))))
+	}
+	fileInfoLines = append(fileInfoLines, lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("ID:   "), lipgloss.NewStyle().Foreground(ColorLightGray).Render(d.ID)))
+	fileInfoContent := lipgloss.JoinVertical(lipgloss.Left, fileInfoLines...)
 	fileSection := sectionStyle.Render(fileInfoContent)
 
 	// --- 3. Progress Section ---
@@ -707,6 +745,9 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 			speedStr = "N/A"
 		}
 		etaStr = "Done"
+		if d.extracting {
+			etaStr = fmt.Sprintf("Extracting %.0f%%", d.extractPct)
+		}
 	} else if d.paused || d.Speed == 0 {
 		speedStr = "Paused"
 		etaStr = "∞"
@@ -809,6 +850,9 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 }
 
 func getDownloadStatus(d *DownloadModel) string {
+	if d.extracting {
+		return components.StatusExtracting.Render()
+	}
 	status := components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded)
 	return status.Render()
 }
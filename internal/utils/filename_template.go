@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Sanitization profiles accepted by SanitizeFilename
+const (
+	ProfilePOSIX   = "posix"   // Native Linux/macOS filesystems, 255-byte names
+	ProfileWindows = "windows" // NTFS/SMB shares, reserved names, 255 UTF-16 units
+	ProfileASCII   = "ascii"   // Windows rules plus plain ASCII only, 255 bytes
+)
+
+// MaxFilenameLength is the per-component name limit shared by ext4, APFS and NTFS
+const MaxFilenameLength = 255
+
+// FilenameProfiles lists the valid sanitization profile names
+var FilenameProfiles = []string{ProfilePOSIX, ProfileWindows, ProfileASCII}
+
+// IsValidFilenameProfile reports whether profile is a known profile name
+func IsValidFilenameProfile(profile string) bool {
+	for _, p := range FilenameProfiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename cleans a single path component for the given profile.
+// Unknown or empty profiles fall back to posix.
+func SanitizeFilename(name, profile string) string {
+	name = sanitizeFilename(name)
+	if name == "." || name == ".." {
+		return "_"
+	}
+
+	switch profile {
+	case ProfileWindows:
+		name = windowsSafe(name)
+		name = truncateFilename(name, utf16Len)
+	case ProfileASCII:
+		name = windowsSafe(asciiOnly(name))
+		name = truncateFilename(name, func(s string) int { return len(s) })
+	default:
+		name = stripControl(name)
+		name = truncateFilename(name, func(s string) int { return len(s) })
+	}
+
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// stripControl replaces ASCII control characters, which no filesystem handles gracefully
+func stripControl(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// windowsSafe applies the NTFS/Win32 naming rules on top of the base sanitizer
+func windowsSafe(name string) string {
+	name = stripControl(name)
+
+	// Win32 silently drops trailing dots and spaces, which breaks round-trips over SMB
+	name = strings.TrimRight(name, ". ")
+
+	stem := name
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	if windowsReserved[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+	return name
+}
+
+// asciiOnly folds accented letters to their base form and replaces anything else outside ASCII
+func asciiOnly(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// Drop combining marks left over from decomposition
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// truncateFilename shortens name to MaxFilenameLength as measured by size,
+// keeping the extension and never splitting a rune
+func truncateFilename(name string, size func(string) int) string {
+	if size(name) <= MaxFilenameLength {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if size(ext) >= MaxFilenameLength/2 {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+
+	budget := MaxFilenameLength - size(ext)
+	var b strings.Builder
+	used := 0
+	for _, r := range stem {
+		w := size(string(r))
+		if used+w > budget {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return strings.TrimRight(b.String(), " ") + ext
+}
+
+// FilenameVars holds the values available to a filename template
+type FilenameVars struct {
+	Filename string    // Name chosen by DetermineFilename (or the user)
+	URL      string    // Source URL
+	ID       string    // Download ID
+	Time     time.Time // When the download was added
+	Category string    // Category assigned by routing rules, if any
+}
+
+var templateVar = regexp.MustCompile(`\{([a-z]+)(?::(-?\d+))?\}`)
+
+// RenderFilenameTemplate expands a template such as "{host}/{date}/{name}{ext}"
+// into a relative path. Supported variables:
+//
+//	{name}      filename without extension
+//	{ext}       extension including the dot (".iso"), empty if none
+//	{filename}  full filename
+//	{host}      URL host without port
+//	{date}      YYYY-MM-DD, {time} HH-MM-SS
+//	{id}        download ID, {shortid} its first 8 characters
+//	{category}  routing category
+//	{path:N}    URL path segment N (1-based; negative counts from the end)
+//
+// Unknown variables are kept literally. "/" in the template creates
+// subdirectories; every component is sanitized with profile and "." / ".."
+// components are dropped so the result can never leave the output directory.
+// An empty result falls back to the sanitized filename.
+func RenderFilenameTemplate(tmpl string, vars FilenameVars, profile string) string {
+	filename := vars.Filename
+	ext := filepath.Ext(filename)
+	if ext == filename {
+		// Dotfiles like ".gitignore" have no extension
+		ext = ""
+	}
+
+	var host string
+	var segments []string
+	if u, err := url.Parse(vars.URL); err == nil {
+		host = u.Hostname()
+		for _, s := range strings.Split(u.EscapedPath(), "/") {
+			if s != "" {
+				segments = append(segments, s)
+			}
+		}
+	}
+
+	shortID := vars.ID
+	if len(shortID) > 8 {
+		shortID = shortID[:8]
+	}
+
+	values := map[string]string{
+		"name":     strings.TrimSuffix(filename, ext),
+		"ext":      ext,
+		"filename": filename,
+		"host":     host,
+		"date":     vars.Time.Format("2006-01-02"),
+		"time":     vars.Time.Format("15-04-05"),
+		"id":       vars.ID,
+		"shortid":  shortID,
+		"category": vars.Category,
+	}
+
+	expanded := templateVar.ReplaceAllStringFunc(tmpl, func(m string) string {
+		sub := templateVar.FindStringSubmatch(m)
+		key, idx := sub[1], sub[2]
+		if key == "path" && idx != "" {
+			n, _ := strconv.Atoi(idx)
+			if n < 0 {
+				n = len(segments) + n + 1
+			}
+			if n >= 1 && n <= len(segments) {
+				if seg, err := url.PathUnescape(segments[n-1]); err == nil {
+					return seg
+				}
+				return segments[n-1]
+			}
+			return ""
+		}
+		if idx != "" {
+			return m
+		}
+		if v, ok := values[key]; ok {
+			return v
+		}
+		return m
+	})
+
+	// Split on the template's own separators; values were expanded before
+	// splitting so a "/" inside a path segment also becomes a directory, which
+	// is then sanitized like any other component.
+	expanded = strings.ReplaceAll(expanded, "\\", "/")
+	var parts []string
+	for _, p := range strings.Split(expanded, "/") {
+		p = strings.TrimSpace(p)
+		if p == "" || p == "." || p == ".." {
+			continue
+		}
+		parts = append(parts, SanitizeFilename(p, profile))
+	}
+
+	if len(parts) == 0 {
+		return SanitizeFilename(filename, profile)
+	}
+	return filepath.Join(parts...)
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestSanitizeFilename_Profiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		input   string
+		want    string
+	}{
+		{"posix keeps unicode", ProfilePOSIX, "résumé.pdf", "résumé.pdf"},
+		{"posix strips control", ProfilePOSIX, "a\x01b.txt", "a_b.txt"},
+		{"posix keeps trailing dot", ProfilePOSIX, "file.", "file."},
+		{"unknown falls back to posix", "bogus", "résumé.pdf", "résumé.pdf"},
+		{"dot-dot is never a name", ProfilePOSIX, "..", "_"},
+		{"windows reserved name", ProfileWindows, "CON", "_CON"},
+		{"windows reserved with extension", ProfileWindows, "nul.txt", "_nul.txt"},
+		{"windows reserved lookalike", ProfileWindows, "CONSOLE.txt", "CONSOLE.txt"},
+		{"windows trailing dots and spaces", ProfileWindows, "file. . ", "file"},
+		{"windows keeps unicode", ProfileWindows, "文件.zip", "文件.zip"},
+		{"ascii folds accents", ProfileASCII, "Crème Brûlée.txt", "Creme Brulee.txt"},
+		{"ascii replaces others", ProfileASCII, "文件.zip", "__.zip"},
+		{"ascii applies windows rules", ProfileASCII, "aux.", "_aux"},
+		{"empty becomes placeholder", ProfileWindows, "...", "_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.input, tt.profile); got != tt.want {
+				t.Errorf("SanitizeFilename(%q, %q) = %q, want %q", tt.input, tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilename_LengthLimits(t *testing.T) {
+	// 200 three-byte runes: 600 bytes but only 200 UTF-16 units
+	long := strings.Repeat("文", 200) + ".iso"
+
+	posix := SanitizeFilename(long, ProfilePOSIX)
+	if len(posix) > MaxFilenameLength {
+		t.Errorf("posix name is %d bytes, want <= %d", len(posix), MaxFilenameLength)
+	}
+	if !utf8.ValidString(posix) || !strings.HasSuffix(posix, ".iso") {
+		t.Errorf("posix truncation broke the name: %q", posix)
+	}
+
+	if got := SanitizeFilename(long, ProfileWindows); got != long {
+		t.Errorf("windows profile truncated a name within 255 UTF-16 units")
+	}
+
+	tooLong := strings.Repeat("a", 300) + ".tar.gz"
+	win := SanitizeFilename(tooLong, ProfileWindows)
+	if utf16Len(win) != MaxFilenameLength || !strings.HasSuffix(win, ".gz") {
+		t.Errorf("windows truncation = %d units (%q)", utf16Len(win), win[len(win)-8:])
+	}
+}
+
+func TestRenderFilenameTemplate(t *testing.T) {
+	vars := FilenameVars{
+		Filename: "ubuntu-24.04.iso",
+		URL:      "https://releases.example.com:8443/ubuntu/24.04/ubuntu-24.04.iso",
+		ID:       "abcdef0123456789",
+		Time:     time.Date(2026, 3, 9, 14, 5, 6, 0, time.UTC),
+		Category: "ISOs",
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{name}{ext}", "ubuntu-24.04.iso"},
+		{"{host}/{date}/{filename}", filepath.Join("releases.example.com", "2026-03-09", "ubuntu-24.04.iso")},
+		{"{path:1}/{path:-2}/{name}-{shortid}{ext}", filepath.Join("ubuntu", "24.04", "ubuntu-24.04-abcdef01.iso")},
+		{"{category}/{time} {filename}", filepath.Join("ISOs", "14-05-06 ubuntu-24.04.iso")},
+		{"{unknown}-{filename}", "{unknown}-ubuntu-24.04.iso"},
+		{"{path:9}/{filename}", "ubuntu-24.04.iso"},
+		{"../../{filename}", "ubuntu-24.04.iso"},
+		{"{path:9}", "ubuntu-24.04.iso"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := RenderFilenameTemplate(tt.tmpl, vars, ProfilePOSIX); got != tt.want {
+				t.Errorf("RenderFilenameTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFilenameTemplate_SanitizesComponents(t *testing.T) {
+	vars := FilenameVars{
+		Filename: "data.csv",
+		URL:      "https://example.com/a%2F..%2Fb/con/data.csv",
+	}
+
+	got := RenderFilenameTemplate("{path:2}/{path:1}/{filename}", vars, ProfileWindows)
+	if strings.Contains(got, "..") {
+		t.Errorf("template escaped the output directory: %q", got)
+	}
+	if want := filepath.Join("_con", "a", "b", "data.csv"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFilenameProfiles_MatchConfig(t *testing.T) {
+	if strings.Join(FilenameProfiles, ",") != strings.Join(config.FilenameProfiles, ",") {
+		t.Errorf("config.FilenameProfiles = %v, want %v", config.FilenameProfiles, FilenameProfiles)
+	}
+}
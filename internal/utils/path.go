@@ -1,7 +1,13 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
 // EnsureAbsPath takes a clean path and forces it to be absolute.
@@ -16,3 +22,62 @@ func EnsureAbsPath(path string) string {
 	}
 	return path
 }
+
+// BatchGroupName names the download group for URLs imported from a batch
+// file: the file name without its extension ("isos.txt" -> "isos").
+func BatchGroupName(path string) string {
+	base := filepath.Base(path)
+	if name := strings.TrimSuffix(base, filepath.Ext(base)); name != "" {
+		return name
+	}
+	return base
+}
+
+// MoveFile moves src to dst, copying and then removing src when they are on
+// different filesystems. It refuses to replace an existing dst.
+func MoveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Rename fails across filesystems; fall back to copying
+	if err := copyFile(src, dst); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies src to a new file dst and syncs it to disk
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyBuffer(out, in, make([]byte, 1024*1024)); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -48,3 +48,68 @@ func TestEnsureAbsPath(t *testing.T) {
 		})
 	}
 }
+
+func TestBatchGroupName(t *testing.T) {
+	tests := map[string]string{
+		"urls.txt":                     "urls",
+		filepath.Join("a", "b.c.list"): "b.c",
+		"plain":                        "plain",
+		".hidden":                      ".hidden",
+	}
+	for in, want := range tests {
+		if got := BatchGroupName(in); got != want {
+			t.Errorf("BatchGroupName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.bin")
+	dst := filepath.Join(dir, "sub", "b.bin")
+	if err := os.WriteFile(src, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MoveFile(src, dst); err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("source should be gone after the move")
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "data" {
+		t.Errorf("destination = %q, %v; want the moved data", data, err)
+	}
+
+	// An existing destination is never replaced
+	if err := os.WriteFile(src, []byte("other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveFile(src, dst); err == nil {
+		t.Error("MoveFile should refuse to replace an existing file")
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "data" {
+		t.Errorf("destination was overwritten with %q", data)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.bin")
+	dst := filepath.Join(dir, "b.bin")
+	if err := os.WriteFile(src, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(src, dst); err != nil {
+		t.Fatalf("copyFile failed: %v", err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "data" {
+		t.Errorf("copy = %q, %v; want the source data", data, err)
+	}
+	if err := copyFile(src, dst); err == nil {
+		t.Error("copyFile should not overwrite an existing file")
+	}
+}
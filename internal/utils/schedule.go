@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startTimeLayouts are the absolute forms accepted by ParseStartTime, tried in order
+var startTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseStartTime turns the --at / --after style inputs into an absolute start
+// time. at is a date-time in local time ("2026-10-17 02:00", RFC 3339, or a
+// bare "02:00" meaning its next occurrence) and must be in the future; after
+// is a delay such as "2h", "90m" or "1d12h". At most one may be set; both
+// empty returns the zero time.
+func ParseStartTime(at, after string, now time.Time) (time.Time, error) {
+	at, after = strings.TrimSpace(at), strings.TrimSpace(after)
+	switch {
+	case at != "" && after != "":
+		return time.Time{}, fmt.Errorf("use either at or after, not both")
+	case after != "":
+		d, err := ParseDelay(after)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	case at == "":
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation("15:04", at, now.Location()); err == nil {
+		next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next, nil
+	}
+
+	for _, layout := range startTimeLayouts {
+		if t, err := time.ParseInLocation(layout, at, now.Location()); err == nil {
+			if !t.After(now) {
+				// Most likely a typo in the date; starting right away would hide it
+				return time.Time{}, fmt.Errorf("start time %q is in the past", at)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid start time %q (want e.g. \"2006-01-02 15:04\" or \"15:04\")", at)
+}
+
+// ParseDelay parses a Go duration with an optional leading day count, so
+// "1d12h" and "2d" work alongside "90m". Negative delays are rejected.
+func ParseDelay(s string) (time.Duration, error) {
+	orig := s
+	var days time.Duration
+	if i := strings.IndexByte(s, 'd'); i > 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid delay %q", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[i+1:]
+	}
+
+	var d time.Duration
+	if s != "" {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid delay %q", orig)
+		}
+	}
+	if days+d < 0 {
+		return 0, fmt.Errorf("delay must not be negative")
+	}
+	return days + d, nil
+}
+
+// ParsePastTime parses a date range bound: a date-time in local time as
+// accepted by ParseStartTime, or a delay such as "7d" or "12h" meaning that
+// long before now.
+func ParsePastTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, err := ParseDelay(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range startTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want e.g. \"2006-01-02\" or \"7d\")", s)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStartTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 22, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		at, after string
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "empty", want: time.Time{}},
+		{name: "after hours", after: "2h", want: now.Add(2 * time.Hour)},
+		{name: "after days", after: "1d12h", want: now.Add(36 * time.Hour)},
+		{name: "at date time", at: "2026-10-17 02:00", want: time.Date(2026, 10, 17, 2, 0, 0, 0, time.Local)},
+		{name: "at rfc3339", at: "2026-10-17T02:00:00Z", want: time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)},
+		{name: "at clock later today", at: "23:15", want: time.Date(2026, 10, 16, 23, 15, 0, 0, time.Local)},
+		{name: "at clock rolls to tomorrow", at: "02:00", want: time.Date(2026, 10, 17, 2, 0, 0, 0, time.Local)},
+		{name: "both", at: "02:00", after: "1h", wantErr: true},
+		{name: "bad at", at: "tomorrow", wantErr: true},
+		{name: "at in the past", at: "2026-10-16 08:00", wantErr: true},
+		{name: "at date already begun", at: "2026-10-16", wantErr: true},
+		{name: "bad after", after: "soon", wantErr: true},
+		{name: "negative after", after: "-5m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStartTime(tt.at, tt.after, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePastTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 22, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "7d", want: now.Add(-7 * 24 * time.Hour)},
+		{in: "12h", want: now.Add(-12 * time.Hour)},
+		{in: "2026-10-01", want: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+		{in: "2026-10-01 08:00", want: time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)},
+		{in: "last week", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePastTime(tt.in, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePastTime(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParsePastTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
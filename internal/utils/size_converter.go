@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 // ConvertBytesToHumanReadable converts a given number of bytes into a human-readable format (e.g., KB, MB, GB).
@@ -20,3 +22,25 @@ func ConvertBytesToHumanReadable(bytes int64) string {
 	pre := "KMGTPE"[exp-1]
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/math.Pow(unit, float64(exp)), pre)
 }
+
+// ParseSize parses a size such as "1024", "500MB", "1.5G" or "10 MiB".
+// Units are binary (1 KB = 1024 bytes), matching ConvertBytesToHumanReadable.
+func ParseSize(s string) (int64, error) {
+	orig := s
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	mult := 1.0
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGTPE", s[n-1]); i >= 0 {
+			mult = math.Pow(1024, float64(i+1))
+			s = s[:n-1]
+		}
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q (want e.g. 500MB or 1.5GB)", orig)
+	}
+	return int64(f * mult), nil
+}
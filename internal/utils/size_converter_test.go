@@ -84,3 +84,32 @@ func TestConvertBytesToHumanReadable_BoundaryValues(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1024", want: 1024},
+		{in: "500MB", want: 500 << 20},
+		{in: "1.5G", want: 3 << 29},
+		{in: "10 MiB", want: 10 << 20},
+		{in: "2kb", want: 2048},
+		{in: "", wantErr: true},
+		{in: "lots", wantErr: true},
+		{in: "-1MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSize(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}